@@ -110,6 +110,7 @@ type AccessLogRecord struct {
 	FailedAttempts         int
 	RoundTripSuccessful    bool
 	ExtraFields            []string
+	Shadow                 bool
 	record                 []byte
 
 	// See the handlers.RequestInfo struct for details on these timings.
@@ -281,6 +282,12 @@ func (r *AccessLogRecord) makeRecord(performTruncate bool) []byte {
 	b.WriteString(`instance_id:`)
 	b.WriteDashOrStringValue(instanceId)
 
+	if r.Shadow {
+		// #nosec  G104 - ignore errors from writing the access log as it will only cause more errors to log this error
+		b.WriteString(`shadow:`)
+		b.WriteStringValues("true")
+	}
+
 	// We have to consider the impact of iterating over a list. This technically allows to repeat
 	// some of the fields but it allows us to iterate over the list only once instead of once per
 	// field when we perform a [slices.Contains] check. When loading the fields the list is
@@ -76,6 +76,13 @@ var _ = Describe("AccessLogRecord", func() {
 			Eventually(r).Should(Say(`app_index:"3"`))
 			Eventually(r).Should(Say(`instance_id:"FakeInstanceId"`))
 			Eventually(r).Should(Say(`x_cf_routererror:"some-router-error"`))
+			Expect(record.LogMessage()).NotTo(ContainSubstring("shadow:"))
+		})
+
+		It("marks copies of requests sent to a shadow route", func() {
+			record.Shadow = true
+			r := BufferReader(bytes.NewBufferString(record.LogMessage()))
+			Eventually(r).Should(Say(`instance_id:"FakeInstanceId" shadow:"true" x_cf_routererror:"some-router-error"`))
 		})
 
 		Context("when the AccessLogRecord is too large for UDP", func() {
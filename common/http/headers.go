@@ -11,6 +11,7 @@ const (
 	CfAppInstance         = "X-CF-APP-INSTANCE"
 	CfProcessInstance     = "X-CF-PROCESS-INSTANCE"
 	CfRouterError         = "X-Cf-RouterError"
+	CfShadow              = "X-Cf-Shadow"
 )
 
 func SetTraceHeaders(responseWriter http.ResponseWriter, routerIp, addr string) {
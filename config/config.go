@@ -7,10 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/url"
 	"os"
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,8 +26,18 @@ import (
 const (
 	LOAD_BALANCE_RR           string = "round-robin"
 	LOAD_BALANCE_LC           string = "least-connection"
+	LOAD_BALANCE_WRR          string = "weighted-round-robin"
+	LOAD_BALANCE_EWMA         string = "latency-ewma"
+	LOAD_BALANCE_CH           string = "consistent-hash"
+	LOAD_BALANCE_P2C          string = "p2c"
+	HASH_KEY_CLIENT_IP        string = "client_ip"
+	HASH_KEY_PATH             string = "path"
+	HASH_KEY_HEADER_PREFIX    string = "header:"
+	HASH_KEY_QUERY_PREFIX     string = "query:"
 	AZ_PREF_NONE              string = "none"
 	AZ_PREF_LOCAL             string = "locally-optimistic"
+	AZ_PREF_LOCAL_ONLY        string = "local-only-with-failover"
+	AZ_PREF_ZONE_AWARE        string = "zone-aware"
 	SHARD_ALL                 string = "all"
 	SHARD_SEGMENTS            string = "segments"
 	SHARD_SHARED_AND_SEGMENTS string = "shared-and-segments"
@@ -38,8 +50,8 @@ const (
 )
 
 var (
-	LoadBalancingStrategies         = []string{LOAD_BALANCE_RR, LOAD_BALANCE_LC}
-	AZPreferences                   = []string{AZ_PREF_NONE, AZ_PREF_LOCAL}
+	LoadBalancingStrategies         = []string{LOAD_BALANCE_RR, LOAD_BALANCE_LC, LOAD_BALANCE_WRR, LOAD_BALANCE_EWMA, LOAD_BALANCE_CH, LOAD_BALANCE_P2C}
+	AZPreferences                   = []string{AZ_PREF_NONE, AZ_PREF_LOCAL, AZ_PREF_LOCAL_ONLY, AZ_PREF_ZONE_AWARE}
 	AllowedShardingModes            = []string{SHARD_ALL, SHARD_SEGMENTS, SHARD_SHARED_AND_SEGMENTS}
 	AllowedForwardedClientCertModes = []string{ALWAYS_FORWARD, FORWARD, SANITIZE_SET}
 	AllowedQueryParmRedactionModes  = []string{REDACT_QUERY_PARMS_NONE, REDACT_QUERY_PARMS_ALL, REDACT_QUERY_PARMS_HASH}
@@ -94,6 +106,9 @@ type StatusTLSConfig struct {
 
 type StatusRoutesConfig struct {
 	Port uint16 `yaml:"port"`
+	// ChangeFeedBufferSize is the number of recent changes of the routing
+	// table which clients of the change feed can resume from.
+	ChangeFeedBufferSize int `yaml:"change_feed_buffer_size"`
 }
 
 var defaultStatusTLSConfig = StatusTLSConfig{
@@ -108,7 +123,8 @@ var defaultStatusConfig = StatusConfig{
 	EnableNonTLSHealthChecks: true,
 	TLS:                      defaultStatusTLSConfig,
 	Routes: StatusRoutesConfig{
-		Port: 8082,
+		Port:                 8082,
+		ChangeFeedBufferSize: 1024,
 	},
 }
 
@@ -131,6 +147,8 @@ type MetersConfig struct {
 	RouteRegistrationLatencyHistogramBuckets []float64 `yaml:"route_registration_latency_histogram_buckets,omitempty"`
 	RoutingResponseLatencyHistogramBuckets   []float64 `yaml:"routing_response_latency_histogram_buckets,omitempty"`
 	HTTPLatencyHistogramBuckets              []float64 `yaml:"http_latency_histogram_buckets,omitempty"`
+	MaxConnsQueueWaitTimeHistogramBuckets    []float64 `yaml:"max_conns_queue_wait_time_histogram_buckets,omitempty"`
+	ConcurrencyLimitHistogramBuckets         []float64 `yaml:"concurrency_limit_histogram_buckets,omitempty"`
 }
 
 var defaultMetersConfig = MetersConfig{
@@ -139,6 +157,8 @@ var defaultMetersConfig = MetersConfig{
 	RouteRegistrationLatencyHistogramBuckets: []float64{0.1, 0.5, 1, 1.5, 2, 2.5, 3, 3.5, 4},
 	RoutingResponseLatencyHistogramBuckets:   []float64{1, 2, 4, 6, 8, 10, 20, 40, 50, 100, 500, 1000},
 	HTTPLatencyHistogramBuckets:              []float64{0.1, 0.2, 0.4, 0.8, 1.6, 3.2, 6.4, 12.8, 25.6},
+	MaxConnsQueueWaitTimeHistogramBuckets:    []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	ConcurrencyLimitHistogramBuckets:         []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000},
 }
 
 type NatsConfig struct {
@@ -187,6 +207,255 @@ type BackendConfig struct {
 	MaxConns              int64            `yaml:"max_conns"`
 	MaxAttempts           int              `yaml:"max_attempts"`
 	TLSPem                `yaml:",inline"` // embed to get cert_chain and private_key for client authentication
+
+	// The maximum values routes may set through their request_timeout,
+	// dial_timeout and max_attempts registration options. They default to
+	// endpoint_timeout, endpoint_dial_timeout and max_attempts, so that routes
+	// may only lower the global values unless the operator allows more.
+	MaxRequestTimeout time.Duration `yaml:"max_request_timeout"`
+	MaxDialTimeout    time.Duration `yaml:"max_dial_timeout"`
+	MaxRouteAttempts  int           `yaml:"max_route_attempts"`
+
+	// RetryStatusCodes are the status codes of backend responses on which
+	// idempotent requests are retried. Routes may set their own through the
+	// retry_status_codes registration option.
+	RetryStatusCodes []int `yaml:"retry_status_codes"`
+}
+
+// OutlierDetectionConfig configures the passive detection of endpoints which
+// respond with server errors. Such endpoints are ejected from their pool for
+// an interval that doubles with every consecutive ejection.
+type OutlierDetectionConfig struct {
+	Enabled              bool          `yaml:"enabled"`
+	Consecutive5xx       int           `yaml:"consecutive_5xx"`
+	ErrorRateThreshold   float64       `yaml:"error_rate_threshold"`
+	ErrorRateMinRequests int           `yaml:"error_rate_min_requests"`
+	Interval             time.Duration `yaml:"interval"`
+	BaseEjectionTime     time.Duration `yaml:"base_ejection_time"`
+	MaxEjectionTime      time.Duration `yaml:"max_ejection_time"`
+	MaxEjectionPercent   int           `yaml:"max_ejection_percent"`
+}
+
+var defaultOutlierDetectionConfig = OutlierDetectionConfig{
+	Enabled:              false,
+	Consecutive5xx:       5,
+	ErrorRateThreshold:   0,
+	ErrorRateMinRequests: 20,
+	Interval:             10 * time.Second,
+	BaseEjectionTime:     30 * time.Second,
+	MaxEjectionTime:      300 * time.Second,
+	MaxEjectionPercent:   50,
+}
+
+// ActiveHealthCheckConfig configures the periodic probing of endpoints. The
+// probe path can be overwritten per route. Endpoints which fail
+// UnhealthyThreshold consecutive probes are skipped by the load balancing
+// algorithms until they pass HealthyThreshold consecutive probes.
+type ActiveHealthCheckConfig struct {
+	Enabled            bool          `yaml:"enabled"`
+	Path               string        `yaml:"path"`
+	Interval           time.Duration `yaml:"interval"`
+	Timeout            time.Duration `yaml:"timeout"`
+	HealthyThreshold   int           `yaml:"healthy_threshold"`
+	UnhealthyThreshold int           `yaml:"unhealthy_threshold"`
+	MaxConcurrency     int           `yaml:"max_concurrency"`
+}
+
+var defaultActiveHealthCheckConfig = ActiveHealthCheckConfig{
+	Enabled:            false,
+	Path:               "/",
+	Interval:           10 * time.Second,
+	Timeout:            2 * time.Second,
+	HealthyThreshold:   1,
+	UnhealthyThreshold: 2,
+	MaxConcurrency:     100,
+}
+
+// RetryBudgetConfig limits the retries of requests to backends, for each route
+// and across all routes, to RetryRatio of the requests plus
+// MinRetriesPerSecond. Unused budget accumulates up to MaxTokens retries.
+type RetryBudgetConfig struct {
+	Enabled             bool    `yaml:"enabled"`
+	RetryRatio          float64 `yaml:"retry_ratio"`
+	MinRetriesPerSecond float64 `yaml:"min_retries_per_second"`
+	MaxTokens           float64 `yaml:"max_tokens"`
+}
+
+var defaultRetryBudgetConfig = RetryBudgetConfig{
+	Enabled:             false,
+	RetryRatio:          0.2,
+	MinRetriesPerSecond: 10,
+	MaxTokens:           100,
+}
+
+// MaxConnsQueueConfig queues requests for routes whose endpoints have all
+// reached backends.max_conns instead of rejecting them. At most MaxDepth
+// requests wait for each route, for up to Timeout each.
+type MaxConnsQueueConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	MaxDepth int           `yaml:"max_depth"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+var defaultMaxConnsQueueConfig = MaxConnsQueueConfig{
+	Enabled:  false,
+	MaxDepth: 100,
+	Timeout:  5 * time.Second,
+}
+
+// AdaptiveConcurrencyConfig limits the requests in flight to each endpoint to a
+// limit which adapts to its response times. While an endpoint is busy, the
+// limit grows by one with every response. It is multiplied by BackoffRatio
+// whenever a request fails or takes longer than LatencyTolerance times the
+// fastest recent response, and stays between MinLimit and MaxLimit.
+type AdaptiveConcurrencyConfig struct {
+	Enabled          bool    `yaml:"enabled"`
+	InitialLimit     int64   `yaml:"initial_limit"`
+	MinLimit         int64   `yaml:"min_limit"`
+	MaxLimit         int64   `yaml:"max_limit"`
+	LatencyTolerance float64 `yaml:"latency_tolerance"`
+	BackoffRatio     float64 `yaml:"backoff_ratio"`
+}
+
+var defaultAdaptiveConcurrencyConfig = AdaptiveConcurrencyConfig{
+	Enabled:          false,
+	InitialLimit:     20,
+	MinLimit:         1,
+	MaxLimit:         1000,
+	LatencyTolerance: 2,
+	BackoffRatio:     0.9,
+}
+
+// StickySessionCookieEncryptionConfig makes the __VCAP_ID__ cookie opaque
+// and tamper-proof by encrypting its value with Secret. Cookies encrypted with
+// one of DecryptOnlySecrets are still accepted, so that the secret can be
+// rotated.
+type StickySessionCookieEncryptionConfig struct {
+	Enabled            bool     `yaml:"enabled"`
+	Secret             string   `yaml:"secret"`
+	DecryptOnlySecrets []string `yaml:"decrypt_only_secrets"`
+}
+
+// HedgingConfig limits the hedged requests sent for routes which enable
+// hedging, across all routes, to Ratio of their requests plus MinPerSecond.
+// Unused budget accumulates up to MaxTokens hedged requests.
+type HedgingConfig struct {
+	Ratio        float64 `yaml:"ratio"`
+	MinPerSecond float64 `yaml:"min_per_second"`
+	MaxTokens    float64 `yaml:"max_tokens"`
+}
+
+var defaultHedgingConfig = HedgingConfig{
+	Ratio:        0.1,
+	MinPerSecond: 1,
+	MaxTokens:    10,
+}
+
+// ShadowConfig limits the copies of requests which are sent to the shadow
+// route of a route. Requests with a body larger than MaxBodyBytes are not
+// copied, and neither are requests arriving while MaxConcurrency copies are
+// in flight. Copies are only sent to shadow routes in the space of the route
+// and to the AllowedRoutes.
+type ShadowConfig struct {
+	MaxBodyBytes   int64         `yaml:"max_body_bytes"`
+	Timeout        time.Duration `yaml:"timeout"`
+	MaxConcurrency int           `yaml:"max_concurrency"`
+	AllowedRoutes  []string      `yaml:"allowed_routes"`
+}
+
+var defaultShadowConfig = ShadowConfig{
+	MaxBodyBytes:   64 * 1024,
+	Timeout:        10 * time.Second,
+	MaxConcurrency: 100,
+}
+
+// RoutingTableSnapshotConfig makes the route registry write the routing table
+// to Path every Interval and when the router stops, and load it on startup so
+// that routes are served before they are registered again. Loaded endpoints
+// are pruned after StaleThreshold unless a registration confirms them.
+type RoutingTableSnapshotConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Path           string        `yaml:"path"`
+	Interval       time.Duration `yaml:"interval"`
+	StaleThreshold time.Duration `yaml:"stale_threshold"`
+}
+
+var defaultRoutingTableSnapshotConfig = RoutingTableSnapshotConfig{
+	Enabled:        false,
+	Interval:       30 * time.Second,
+	StaleThreshold: 60 * time.Second,
+}
+
+// StaticRoutesConfig registers the routes of the route file at Path, which is
+// checked for changes every ReloadInterval. Static routes are never pruned.
+type StaticRoutesConfig struct {
+	Path           string        `yaml:"path"`
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+var defaultStaticRoutesConfig = StaticRoutesConfig{
+	Path:           "",
+	ReloadInterval: 5 * time.Second,
+}
+
+// Enabled returns true if a route file is configured.
+func (c StaticRoutesConfig) Enabled() bool {
+	return c.Path != ""
+}
+
+// FallbackBackendConfig sends requests for unknown routes, for routes without
+// endpoints, or both, to a fallback backend instead of rejecting them. The
+// fallback backend is either the static Address (host:port) or the endpoints
+// registered for Route. The Host header of the request is passed on unchanged
+// and also in OriginalHostHeader.
+type FallbackBackendConfig struct {
+	UnknownRoutes      bool   `yaml:"unknown_routes"`
+	EmptyPools         bool   `yaml:"empty_pools"`
+	Address            string `yaml:"address"`
+	Route              string `yaml:"route"`
+	OriginalHostHeader string `yaml:"original_host_header"`
+}
+
+var defaultFallbackBackendConfig = FallbackBackendConfig{
+	UnknownRoutes:      false,
+	EmptyPools:         false,
+	OriginalHostHeader: "X-Cf-Original-Host",
+}
+
+// Enabled reports whether any requests are sent to the fallback backend.
+func (c FallbackBackendConfig) Enabled() bool {
+	return c.UnknownRoutes || c.EmptyPools
+}
+
+// RoutingRule sends requests carrying a header, cookie or query parameter to
+// the endpoints of a route with a tag. Exactly one of Header, Cookie and Query
+// must be set. If Value is empty, any value matches. If TagValue is empty,
+// endpoints with any value for Tag match.
+type RoutingRule struct {
+	Header   string `yaml:"header" json:"header,omitempty"`
+	Cookie   string `yaml:"cookie" json:"cookie,omitempty"`
+	Query    string `yaml:"query" json:"query,omitempty"`
+	Value    string `yaml:"value" json:"value,omitempty"`
+	Tag      string `yaml:"tag" json:"tag"`
+	TagValue string `yaml:"tag_value" json:"tag_value,omitempty"`
+}
+
+// Validate checks that the rule matches on exactly one of a header, cookie or
+// query parameter and selects endpoints by a tag.
+func (r RoutingRule) Validate() error {
+	sources := 0
+	for _, name := range []string{r.Header, r.Cookie, r.Query} {
+		if name != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return errors.New("routing rule must match exactly one of header, cookie or query")
+	}
+	if r.Tag == "" {
+		return errors.New("routing rule must select endpoints by tag")
+	}
+	return nil
 }
 
 type RouteServiceConfig struct {
@@ -449,6 +718,10 @@ type Config struct {
 
 	RouteLatencyMetricMuzzleDuration time.Duration `yaml:"route_latency_metric_muzzle_duration,omitempty"`
 
+	RoutingTableSnapshot RoutingTableSnapshotConfig `yaml:"routing_table_snapshot,omitempty"`
+
+	StaticRoutes StaticRoutesConfig `yaml:"static_routes,omitempty"`
+
 	DrainWait                      time.Duration `yaml:"drain_wait,omitempty"`
 	DrainTimeout                   time.Duration `yaml:"drain_timeout,omitempty"`
 	SecureCookies                  bool          `yaml:"secure_cookies,omitempty"`
@@ -456,6 +729,13 @@ type Config struct {
 	StickySessionsForAuthNegotiate bool          `yaml:"sticky_sessions_for_auth_negotiate"`
 	HealthCheckUserAgent           string        `yaml:"healthcheck_user_agent,omitempty"`
 
+	StickySessionCookieEncryption StickySessionCookieEncryptionConfig `yaml:"sticky_session_cookie_encryption,omitempty"`
+
+	// StickySessionHeader is the name of a header through which clients which
+	// do not keep cookies can stick to an instance, such as
+	// X-CF-Session-Affinity. Empty disables header-based sticky sessions.
+	StickySessionHeader string `yaml:"sticky_session_header,omitempty"`
+
 	OAuth                             OAuthConfig      `yaml:"oauth,omitempty"`
 	RoutingApi                        RoutingApiConfig `yaml:"routing_api,omitempty"`
 	RouteServiceSecret                string           `yaml:"route_services_secret,omitempty"`
@@ -481,6 +761,33 @@ type Config struct {
 	PidFile                 string `yaml:"pid_file,omitempty"`
 	LoadBalance             string `yaml:"balancing_algorithm,omitempty"`
 	LoadBalanceAZPreference string `yaml:"balancing_algorithm_az_preference,omitempty"`
+	LoadBalanceHashKey      string `yaml:"balancing_algorithm_hash_key,omitempty"`
+
+	// LoadBalanceAZFailoverPercent is the percentage of the endpoints of a
+	// route in the local AZ which must be unhealthy before the
+	// local-only-with-failover AZ preference sends requests to all AZs.
+	LoadBalanceAZFailoverPercent int `yaml:"balancing_algorithm_az_failover_percent,omitempty"`
+
+	// SlowStartDuration is the time over which the share of traffic sent to a
+	// newly registered endpoint rises to a full share. Zero disables slow start.
+	SlowStartDuration time.Duration `yaml:"slow_start_duration,omitempty"`
+
+	OutlierDetection OutlierDetectionConfig `yaml:"outlier_detection,omitempty"`
+
+	ActiveHealthCheck ActiveHealthCheckConfig `yaml:"active_health_check,omitempty"`
+
+	RetryBudget RetryBudgetConfig `yaml:"retry_budget,omitempty"`
+
+	MaxConnsQueue MaxConnsQueueConfig `yaml:"max_conns_queue,omitempty"`
+
+	AdaptiveConcurrency AdaptiveConcurrencyConfig `yaml:"adaptive_concurrency,omitempty"`
+
+	Hedging HedgingConfig `yaml:"hedging,omitempty"`
+
+	// RoutingRules apply to all routes, after the rules registered for a route.
+	RoutingRules []RoutingRule `yaml:"routing_rules,omitempty"`
+
+	Shadow ShadowConfig `yaml:"shadow,omitempty"`
 
 	DisableKeepAlives            bool `yaml:"disable_keep_alives"`
 	MaxIdleConns                 int  `yaml:"max_idle_conns,omitempty"`
@@ -496,6 +803,8 @@ type Config struct {
 	EmptyPoolResponseCode503 bool          `yaml:"empty_pool_response_code_503,omitempty"`
 	EmptyPoolTimeout         time.Duration `yaml:"empty_pool_timeout,omitempty"`
 
+	FallbackBackend FallbackBackendConfig `yaml:"fallback_backend,omitempty"`
+
 	HTMLErrorTemplateFile string `yaml:"html_error_template_file,omitempty"`
 
 	// Old metric, to eventually be replaced by prometheus reporting
@@ -561,9 +870,21 @@ var defaultConfig = Config{
 	// This is set to twice the defaults from the NATS library
 	NatsClientMessageBufferSize: 131072,
 
-	HealthCheckUserAgent:    "HTTP-Monitor/1.1",
-	LoadBalance:             LOAD_BALANCE_RR,
-	LoadBalanceAZPreference: AZ_PREF_NONE,
+	HealthCheckUserAgent:         "HTTP-Monitor/1.1",
+	LoadBalance:                  LOAD_BALANCE_RR,
+	LoadBalanceAZPreference:      AZ_PREF_NONE,
+	LoadBalanceAZFailoverPercent: 50,
+	LoadBalanceHashKey:           HASH_KEY_CLIENT_IP,
+	OutlierDetection:             defaultOutlierDetectionConfig,
+	ActiveHealthCheck:            defaultActiveHealthCheckConfig,
+	RetryBudget:                  defaultRetryBudgetConfig,
+	MaxConnsQueue:                defaultMaxConnsQueueConfig,
+	AdaptiveConcurrency:          defaultAdaptiveConcurrencyConfig,
+	Hedging:                      defaultHedgingConfig,
+	Shadow:                       defaultShadowConfig,
+	FallbackBackend:              defaultFallbackBackendConfig,
+	RoutingTableSnapshot:         defaultRoutingTableSnapshotConfig,
+	StaticRoutes:                 defaultStaticRoutesConfig,
 
 	ForwardedClientCert:      "always_forward",
 	RoutingTableShardingMode: "all",
@@ -597,6 +918,21 @@ func IsLoadBalancingAlgorithmValid(lbAlgo string) bool {
 	return slices.Contains(LoadBalancingStrategies, lbAlgo)
 }
 
+// IsHashKeyValid checks whether the request key used by the consistent-hash
+// load balancing algorithm is one of client_ip, path, header:<name> or query:<name>.
+func IsHashKeyValid(hashKey string) bool {
+	switch {
+	case hashKey == HASH_KEY_CLIENT_IP, hashKey == HASH_KEY_PATH:
+		return true
+	case strings.HasPrefix(hashKey, HASH_KEY_HEADER_PREFIX):
+		return len(hashKey) > len(HASH_KEY_HEADER_PREFIX)
+	case strings.HasPrefix(hashKey, HASH_KEY_QUERY_PREFIX):
+		return len(hashKey) > len(HASH_KEY_QUERY_PREFIX)
+	default:
+		return false
+	}
+}
+
 func (c *Config) Process() error {
 	if c.GoMaxProcs == -1 {
 		c.GoMaxProcs = runtime.NumCPU()
@@ -615,6 +951,24 @@ func (c *Config) Process() error {
 		c.WebsocketDialTimeout = c.EndpointDialTimeout
 	}
 
+	if c.Backends.MaxRequestTimeout < 0 || c.Backends.MaxDialTimeout < 0 || c.Backends.MaxRouteAttempts < 0 {
+		return errors.New("Invalid backends max_request_timeout, max_dial_timeout or max_route_attempts. Must not be negative")
+	}
+	if c.Backends.MaxRequestTimeout == 0 {
+		c.Backends.MaxRequestTimeout = c.EndpointTimeout
+	}
+	if c.Backends.MaxDialTimeout == 0 {
+		c.Backends.MaxDialTimeout = c.EndpointDialTimeout
+	}
+	if c.Backends.MaxRouteAttempts == 0 {
+		c.Backends.MaxRouteAttempts = max(c.Backends.MaxAttempts, 1)
+	}
+	for _, code := range c.Backends.RetryStatusCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("Invalid backends retry_status_codes: %d. Must be an HTTP status code", code)
+		}
+	}
+
 	var localIPErr error
 	c.Ip, localIPErr = localip.LocalIP()
 	if localIPErr != nil {
@@ -767,6 +1121,174 @@ func (c *Config) Process() error {
 	if !validAZPref {
 		return fmt.Errorf("Invalid load balancing AZ preference %s. Allowed values are %s", c.LoadBalanceAZPreference, AZPreferences)
 	}
+	if c.LoadBalanceAZFailoverPercent < 1 || c.LoadBalanceAZFailoverPercent > 100 {
+		return fmt.Errorf("Invalid load balancing AZ failover percent %d. Must be between 1 and 100", c.LoadBalanceAZFailoverPercent)
+	}
+
+	if !IsHashKeyValid(c.LoadBalanceHashKey) {
+		return fmt.Errorf("Invalid load balancing hash key %s. Allowed values are %s, %s, %s<name> and %s<name>", c.LoadBalanceHashKey, HASH_KEY_CLIENT_IP, HASH_KEY_PATH, HASH_KEY_HEADER_PREFIX, HASH_KEY_QUERY_PREFIX)
+	}
+
+	if c.SlowStartDuration < 0 {
+		return fmt.Errorf("Invalid slow start duration: %s", c.SlowStartDuration)
+	}
+
+	if c.OutlierDetection.Enabled {
+		od := c.OutlierDetection
+		if od.Consecutive5xx < 0 {
+			return fmt.Errorf("Invalid outlier detection consecutive_5xx: %d", od.Consecutive5xx)
+		}
+		if od.ErrorRateThreshold < 0 || od.ErrorRateThreshold > 1 {
+			return fmt.Errorf("Invalid outlier detection error_rate_threshold: %v. Must be between 0 and 1", od.ErrorRateThreshold)
+		}
+		if od.Consecutive5xx == 0 && od.ErrorRateThreshold == 0 {
+			return errors.New("Outlier detection requires consecutive_5xx or error_rate_threshold to be set")
+		}
+		if od.Interval <= 0 || od.BaseEjectionTime <= 0 {
+			return errors.New("Outlier detection requires a positive interval and base_ejection_time")
+		}
+		if od.MaxEjectionTime < od.BaseEjectionTime {
+			return fmt.Errorf("Invalid outlier detection max_ejection_time: %s. Must not be less than base_ejection_time %s", od.MaxEjectionTime, od.BaseEjectionTime)
+		}
+		if od.MaxEjectionPercent < 0 || od.MaxEjectionPercent > 100 {
+			return fmt.Errorf("Invalid outlier detection max_ejection_percent: %d. Must be between 0 and 100", od.MaxEjectionPercent)
+		}
+	}
+
+	if c.ActiveHealthCheck.Enabled {
+		hc := c.ActiveHealthCheck
+		if !strings.HasPrefix(hc.Path, "/") {
+			return fmt.Errorf("Invalid active health check path: %q. Must start with /", hc.Path)
+		}
+		if hc.Interval <= 0 || hc.Timeout <= 0 {
+			return errors.New("Active health check requires a positive interval and timeout")
+		}
+		if hc.Timeout > hc.Interval {
+			return fmt.Errorf("Invalid active health check timeout: %s. Must not be greater than interval %s", hc.Timeout, hc.Interval)
+		}
+		if hc.HealthyThreshold < 1 || hc.UnhealthyThreshold < 1 {
+			return errors.New("Active health check healthy_threshold and unhealthy_threshold must be at least 1")
+		}
+		if hc.MaxConcurrency < 1 {
+			return fmt.Errorf("Invalid active health check max_concurrency: %d. Must be at least 1", hc.MaxConcurrency)
+		}
+	}
+
+	if c.RetryBudget.Enabled {
+		rb := c.RetryBudget
+		if rb.RetryRatio < 0 || rb.MinRetriesPerSecond < 0 {
+			return errors.New("Retry budget retry_ratio and min_retries_per_second must not be negative")
+		}
+		if rb.RetryRatio == 0 && rb.MinRetriesPerSecond == 0 {
+			return errors.New("Retry budget requires retry_ratio or min_retries_per_second to be set")
+		}
+		if rb.MaxTokens < 1 {
+			return fmt.Errorf("Invalid retry budget max_tokens: %v. Must be at least 1", rb.MaxTokens)
+		}
+	}
+
+	if c.MaxConnsQueue.Enabled {
+		if c.Backends.MaxConns < 1 {
+			return errors.New("Max conns queue requires backends max_conns to be set")
+		}
+		if c.MaxConnsQueue.MaxDepth < 1 {
+			return fmt.Errorf("Invalid max conns queue max_depth: %d. Must be at least 1", c.MaxConnsQueue.MaxDepth)
+		}
+		if c.MaxConnsQueue.Timeout <= 0 {
+			return fmt.Errorf("Invalid max conns queue timeout: %s. Must be positive", c.MaxConnsQueue.Timeout)
+		}
+	}
+
+	if c.AdaptiveConcurrency.Enabled {
+		ac := c.AdaptiveConcurrency
+		if ac.MinLimit < 1 || ac.MaxLimit < ac.MinLimit {
+			return fmt.Errorf("Invalid adaptive concurrency limits: min_limit %d, max_limit %d. Must be at least 1 and min_limit must not exceed max_limit", ac.MinLimit, ac.MaxLimit)
+		}
+		if ac.InitialLimit < ac.MinLimit || ac.InitialLimit > ac.MaxLimit {
+			return fmt.Errorf("Invalid adaptive concurrency initial_limit: %d. Must be between min_limit and max_limit", ac.InitialLimit)
+		}
+		if ac.LatencyTolerance < 1 {
+			return fmt.Errorf("Invalid adaptive concurrency latency_tolerance: %v. Must be at least 1", ac.LatencyTolerance)
+		}
+		if ac.BackoffRatio <= 0 || ac.BackoffRatio >= 1 {
+			return fmt.Errorf("Invalid adaptive concurrency backoff_ratio: %v. Must be between 0 and 1", ac.BackoffRatio)
+		}
+	}
+
+	c.StickySessionHeader = strings.TrimSpace(c.StickySessionHeader)
+	if strings.ContainsAny(c.StickySessionHeader, " \t:") {
+		return fmt.Errorf("Invalid sticky_session_header: %q", c.StickySessionHeader)
+	}
+
+	if c.StickySessionCookieEncryption.Enabled {
+		if c.StickySessionCookieEncryption.Secret == "" {
+			return errors.New("Sticky session cookie encryption requires a secret")
+		}
+		if slices.Contains(c.StickySessionCookieEncryption.DecryptOnlySecrets, "") {
+			return errors.New("Sticky session cookie decrypt_only_secrets must not be empty")
+		}
+	}
+
+	if c.Hedging.Ratio < 0 || c.Hedging.MinPerSecond < 0 {
+		return errors.New("Hedging ratio and min_per_second must not be negative")
+	}
+	if c.Hedging.MaxTokens < 1 {
+		return fmt.Errorf("Invalid hedging max_tokens: %v. Must be at least 1", c.Hedging.MaxTokens)
+	}
+
+	for i, rule := range c.RoutingRules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("Invalid routing_rules entry %d: %s", i, err)
+		}
+	}
+
+	if c.Shadow.MaxBodyBytes < 0 {
+		return fmt.Errorf("Invalid shadow max_body_bytes: %d", c.Shadow.MaxBodyBytes)
+	}
+	if c.Shadow.Timeout <= 0 {
+		return fmt.Errorf("Invalid shadow timeout: %s. Must be positive", c.Shadow.Timeout)
+	}
+	if c.Shadow.MaxConcurrency < 1 {
+		return fmt.Errorf("Invalid shadow max_concurrency: %d. Must be at least 1", c.Shadow.MaxConcurrency)
+	}
+
+	if c.RoutingTableSnapshot.Enabled {
+		if c.RoutingTableSnapshot.Path == "" {
+			return errors.New("Routing table snapshot requires path to be set")
+		}
+		if c.RoutingTableSnapshot.Interval <= 0 {
+			return fmt.Errorf("Invalid routing table snapshot interval: %s. Must be positive", c.RoutingTableSnapshot.Interval)
+		}
+		if c.RoutingTableSnapshot.StaleThreshold <= 0 {
+			return fmt.Errorf("Invalid routing table snapshot stale_threshold: %s. Must be positive", c.RoutingTableSnapshot.StaleThreshold)
+		}
+	}
+
+	if c.Status.Routes.ChangeFeedBufferSize < 0 {
+		return fmt.Errorf("Invalid routes change_feed_buffer_size: %d. Must not be negative", c.Status.Routes.ChangeFeedBufferSize)
+	}
+
+	if c.StaticRoutes.Enabled() && c.StaticRoutes.ReloadInterval <= 0 {
+		return fmt.Errorf("Invalid static routes reload_interval: %s. Must be positive", c.StaticRoutes.ReloadInterval)
+	}
+
+	if c.FallbackBackend.Enabled() {
+		if (c.FallbackBackend.Address == "") == (c.FallbackBackend.Route == "") {
+			return errors.New("Fallback backend requires exactly one of address and route")
+		}
+		if c.FallbackBackend.Address != "" {
+			_, port, err := net.SplitHostPort(c.FallbackBackend.Address)
+			if err == nil {
+				_, err = strconv.ParseUint(port, 10, 16)
+			}
+			if err != nil {
+				return fmt.Errorf("Invalid fallback backend address %s: %s", c.FallbackBackend.Address, err)
+			}
+		}
+		if c.FallbackBackend.OriginalHostHeader == "" {
+			return errors.New("Fallback backend requires original_host_header to be set")
+		}
+	}
 
 	if c.LoadBalancerHealthyThreshold < 0 {
 		return fmt.Errorf("Invalid load balancer healthy threshold: %s", c.LoadBalancerHealthyThreshold)
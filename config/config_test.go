@@ -78,7 +78,7 @@ balancing_algorithm: least-connection
 				Expect(err).ToNot(HaveOccurred())
 				cfgForSnippet.LoadBalance = "foo-bar"
 				cfg.Initialize(createYMLSnippet(cfgForSnippet))
-				Expect(cfg.Process()).To(MatchError("Invalid load balancing algorithm foo-bar. Allowed values are [round-robin least-connection]"))
+				Expect(cfg.Process()).To(MatchError("Invalid load balancing algorithm foo-bar. Allowed values are [round-robin least-connection weighted-round-robin latency-ewma consistent-hash p2c]"))
 			})
 		})
 
@@ -103,7 +103,719 @@ balancing_algorithm_az_preference: locally-optimistic
 				Expect(err).ToNot(HaveOccurred())
 				cfgForSnippet.LoadBalanceAZPreference = "meow-only"
 				cfg.Initialize(createYMLSnippet(cfgForSnippet))
-				Expect(cfg.Process()).To(MatchError("Invalid load balancing AZ preference meow-only. Allowed values are [none locally-optimistic]"))
+				Expect(cfg.Process()).To(MatchError("Invalid load balancing AZ preference meow-only. Allowed values are [none locally-optimistic local-only-with-failover zone-aware]"))
+			})
+
+			It("can set the strict and zone-aware az preferences", func() {
+				for _, pref := range []string{AZ_PREF_LOCAL_ONLY, AZ_PREF_ZONE_AWARE} {
+					cfg, err := DefaultConfig()
+					Expect(err).ToNot(HaveOccurred())
+					cfgForSnippet.LoadBalanceAZPreference = pref
+					Expect(cfg.Initialize(createYMLSnippet(cfgForSnippet))).To(Succeed())
+					Expect(cfg.Process()).To(Succeed())
+					Expect(cfg.LoadBalanceAZPreference).To(Equal(pref))
+				}
+			})
+
+			It("sets the default az failover percent", func() {
+				Expect(config.LoadBalanceAZFailoverPercent).To(Equal(50))
+			})
+
+			It("does not allow an az failover percent above 100", func() {
+				cfg, err := DefaultConfig()
+				Expect(err).ToNot(HaveOccurred())
+				cfgForSnippet.LoadBalanceAZFailoverPercent = 101
+				cfg.Initialize(createYMLSnippet(cfgForSnippet))
+				Expect(cfg.Process()).To(MatchError("Invalid load balancing AZ failover percent 101. Must be between 1 and 100"))
+			})
+		})
+
+		Context("load balance hash key config", func() {
+			It("sets default load balance hash key", func() {
+				Expect(config.LoadBalanceHashKey).To(Equal(HASH_KEY_CLIENT_IP))
+			})
+
+			It("can override the load balance hash key", func() {
+				cfg, err := DefaultConfig()
+				Expect(err).ToNot(HaveOccurred())
+				var b = []byte(`
+balancing_algorithm_hash_key: header:X-User-Id
+`)
+				cfg.Initialize(b)
+				cfg.Process()
+				Expect(cfg.LoadBalanceHashKey).To(Equal("header:X-User-Id"))
+			})
+
+			It("does not allow an invalid load balance hash key", func() {
+				cfg, err := DefaultConfig()
+				Expect(err).ToNot(HaveOccurred())
+				cfgForSnippet.LoadBalanceHashKey = "header:"
+				cfg.Initialize(createYMLSnippet(cfgForSnippet))
+				Expect(cfg.Process()).To(MatchError("Invalid load balancing hash key header:. Allowed values are client_ip, path, header:<name> and query:<name>"))
+			})
+
+			DescribeTable("IsHashKeyValid",
+				func(hashKey string, valid bool) {
+					Expect(IsHashKeyValid(hashKey)).To(Equal(valid))
+				},
+				Entry("client ip", "client_ip", true),
+				Entry("path", "path", true),
+				Entry("header", "header:X-User-Id", true),
+				Entry("query param", "query:user", true),
+				Entry("header without name", "header:", false),
+				Entry("query param without name", "query:", false),
+				Entry("unknown", "cookie:JSESSIONID", false),
+			)
+		})
+
+		Context("slow start config", func() {
+			It("disables slow start by default", func() {
+				Expect(config.SlowStartDuration).To(Equal(time.Duration(0)))
+			})
+
+			It("can set the slow start duration", func() {
+				cfg, err := DefaultConfig()
+				Expect(err).ToNot(HaveOccurred())
+				var b = []byte(`
+slow_start_duration: 30s
+`)
+				cfg.Initialize(b)
+				cfg.Process()
+				Expect(cfg.SlowStartDuration).To(Equal(30 * time.Second))
+			})
+
+			It("does not allow a negative slow start duration", func() {
+				cfg, err := DefaultConfig()
+				Expect(err).ToNot(HaveOccurred())
+				cfgForSnippet.SlowStartDuration = -1 * time.Second
+				cfg.Initialize(createYMLSnippet(cfgForSnippet))
+				Expect(cfg.Process()).To(MatchError("Invalid slow start duration: -1s"))
+			})
+		})
+
+		Context("outlier detection config", func() {
+			It("disables outlier detection by default", func() {
+				Expect(config.OutlierDetection.Enabled).To(BeFalse())
+				Expect(config.OutlierDetection.Consecutive5xx).To(Equal(5))
+				Expect(config.OutlierDetection.BaseEjectionTime).To(Equal(30 * time.Second))
+				Expect(config.OutlierDetection.MaxEjectionTime).To(Equal(300 * time.Second))
+				Expect(config.OutlierDetection.MaxEjectionPercent).To(Equal(50))
+			})
+
+			It("can configure outlier detection", func() {
+				cfg, err := DefaultConfig()
+				Expect(err).ToNot(HaveOccurred())
+				var b = []byte(`
+outlier_detection:
+  enabled: true
+  consecutive_5xx: 3
+  error_rate_threshold: 0.5
+  error_rate_min_requests: 10
+  interval: 5s
+  base_ejection_time: 10s
+  max_ejection_time: 1m
+  max_ejection_percent: 30
+`)
+				cfg.Initialize(b)
+				cfg.Process()
+				Expect(cfg.OutlierDetection).To(Equal(OutlierDetectionConfig{
+					Enabled:              true,
+					Consecutive5xx:       3,
+					ErrorRateThreshold:   0.5,
+					ErrorRateMinRequests: 10,
+					Interval:             5 * time.Second,
+					BaseEjectionTime:     10 * time.Second,
+					MaxEjectionTime:      time.Minute,
+					MaxEjectionPercent:   30,
+				}))
+			})
+
+			Context("when outlier detection is enabled", func() {
+				BeforeEach(func() {
+					cfgForSnippet.OutlierDetection = OutlierDetectionConfig{
+						Enabled:            true,
+						Consecutive5xx:     5,
+						Interval:           10 * time.Second,
+						BaseEjectionTime:   30 * time.Second,
+						MaxEjectionTime:    300 * time.Second,
+						MaxEjectionPercent: 50,
+					}
+				})
+
+				It("does not allow an error rate threshold above 1", func() {
+					cfgForSnippet.OutlierDetection.ErrorRateThreshold = 1.5
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Invalid outlier detection error_rate_threshold: 1.5. Must be between 0 and 1"))
+				})
+
+				It("requires a detection criterion", func() {
+					cfgForSnippet.OutlierDetection.Consecutive5xx = 0
+					cfgForSnippet.OutlierDetection.ErrorRateThreshold = 0
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Outlier detection requires consecutive_5xx or error_rate_threshold to be set"))
+				})
+
+				It("does not allow a max ejection time below the base ejection time", func() {
+					cfgForSnippet.OutlierDetection.BaseEjectionTime = time.Minute
+					cfgForSnippet.OutlierDetection.MaxEjectionTime = time.Second
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Invalid outlier detection max_ejection_time: 1s. Must not be less than base_ejection_time 1m0s"))
+				})
+
+				It("does not allow a max ejection percent above 100", func() {
+					cfgForSnippet.OutlierDetection.MaxEjectionPercent = 101
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Invalid outlier detection max_ejection_percent: 101. Must be between 0 and 100"))
+				})
+			})
+		})
+
+		Context("active health check config", func() {
+			It("disables active health checks by default", func() {
+				Expect(config.ActiveHealthCheck.Enabled).To(BeFalse())
+				Expect(config.ActiveHealthCheck.Path).To(Equal("/"))
+				Expect(config.ActiveHealthCheck.Interval).To(Equal(10 * time.Second))
+				Expect(config.ActiveHealthCheck.Timeout).To(Equal(2 * time.Second))
+				Expect(config.ActiveHealthCheck.HealthyThreshold).To(Equal(1))
+				Expect(config.ActiveHealthCheck.UnhealthyThreshold).To(Equal(2))
+				Expect(config.ActiveHealthCheck.MaxConcurrency).To(Equal(100))
+			})
+
+			It("can configure active health checks", func() {
+				cfg, err := DefaultConfig()
+				Expect(err).ToNot(HaveOccurred())
+				var b = []byte(`
+active_health_check:
+  enabled: true
+  path: /healthz
+  interval: 5s
+  timeout: 1s
+  healthy_threshold: 2
+  unhealthy_threshold: 3
+  max_concurrency: 20
+`)
+				cfg.Initialize(b)
+				cfg.Process()
+				Expect(cfg.ActiveHealthCheck).To(Equal(ActiveHealthCheckConfig{
+					Enabled:            true,
+					Path:               "/healthz",
+					Interval:           5 * time.Second,
+					Timeout:            time.Second,
+					HealthyThreshold:   2,
+					UnhealthyThreshold: 3,
+					MaxConcurrency:     20,
+				}))
+			})
+
+			Context("when active health checks are enabled", func() {
+				BeforeEach(func() {
+					cfgForSnippet.ActiveHealthCheck = ActiveHealthCheckConfig{
+						Enabled:            true,
+						Path:               "/",
+						Interval:           10 * time.Second,
+						Timeout:            2 * time.Second,
+						HealthyThreshold:   1,
+						UnhealthyThreshold: 2,
+						MaxConcurrency:     100,
+					}
+				})
+
+				It("requires the path to be absolute", func() {
+					cfgForSnippet.ActiveHealthCheck.Path = "healthz"
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError(`Invalid active health check path: "healthz". Must start with /`))
+				})
+
+				It("does not allow a timeout greater than the interval", func() {
+					cfgForSnippet.ActiveHealthCheck.Timeout = time.Minute
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Invalid active health check timeout: 1m0s. Must not be greater than interval 10s"))
+				})
+
+				It("requires thresholds of at least 1", func() {
+					cfgForSnippet.ActiveHealthCheck.UnhealthyThreshold = 0
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Active health check healthy_threshold and unhealthy_threshold must be at least 1"))
+				})
+
+				It("requires a max concurrency of at least 1", func() {
+					cfgForSnippet.ActiveHealthCheck.MaxConcurrency = 0
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Invalid active health check max_concurrency: 0. Must be at least 1"))
+				})
+			})
+		})
+
+		Context("routing rules config", func() {
+			It("has no routing rules by default", func() {
+				Expect(config.RoutingRules).To(BeEmpty())
+			})
+
+			It("can configure routing rules", func() {
+				cfg, err := DefaultConfig()
+				Expect(err).ToNot(HaveOccurred())
+				var b = []byte(`
+routing_rules:
+- header: X-Canary
+  value: "true"
+  tag: canary
+- cookie: beta
+  value: "1"
+  tag: track
+  tag_value: beta
+`)
+				Expect(cfg.Initialize(b)).To(Succeed())
+				Expect(cfg.Process()).To(Succeed())
+				Expect(cfg.RoutingRules).To(Equal([]RoutingRule{
+					{Header: "X-Canary", Value: "true", Tag: "canary"},
+					{Cookie: "beta", Value: "1", Tag: "track", TagValue: "beta"},
+				}))
+			})
+
+			It("requires a rule to match exactly one source", func() {
+				cfgForSnippet.RoutingRules = []RoutingRule{{Header: "X-Canary", Query: "canary", Tag: "canary"}}
+				config.Initialize(createYMLSnippet(cfgForSnippet))
+				Expect(config.Process()).To(MatchError("Invalid routing_rules entry 0: routing rule must match exactly one of header, cookie or query"))
+			})
+
+			It("requires a rule to select a tag", func() {
+				cfgForSnippet.RoutingRules = []RoutingRule{{Header: "X-Canary"}}
+				config.Initialize(createYMLSnippet(cfgForSnippet))
+				Expect(config.Process()).To(MatchError("Invalid routing_rules entry 0: routing rule must select endpoints by tag"))
+			})
+		})
+
+		Context("retry budget config", func() {
+			It("is disabled by default", func() {
+				Expect(config.RetryBudget).To(Equal(RetryBudgetConfig{
+					Enabled:             false,
+					RetryRatio:          0.2,
+					MinRetriesPerSecond: 10,
+					MaxTokens:           100,
+				}))
+			})
+
+			It("can configure the retry budget", func() {
+				var b = []byte(`
+retry_budget:
+  enabled: true
+  retry_ratio: 0.1
+  min_retries_per_second: 5
+  max_tokens: 50
+`)
+				Expect(config.Initialize(b)).To(Succeed())
+				Expect(config.Process()).To(Succeed())
+				Expect(config.RetryBudget).To(Equal(RetryBudgetConfig{
+					Enabled:             true,
+					RetryRatio:          0.1,
+					MinRetriesPerSecond: 5,
+					MaxTokens:           50,
+				}))
+			})
+
+			Context("when enabled", func() {
+				BeforeEach(func() {
+					cfgForSnippet.RetryBudget = RetryBudgetConfig{
+						Enabled:             true,
+						RetryRatio:          0.2,
+						MinRetriesPerSecond: 10,
+						MaxTokens:           100,
+					}
+				})
+
+				It("does not allow a negative ratio", func() {
+					cfgForSnippet.RetryBudget.RetryRatio = -1
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Retry budget retry_ratio and min_retries_per_second must not be negative"))
+				})
+
+				It("requires a ratio or a minimum rate", func() {
+					cfgForSnippet.RetryBudget.RetryRatio = 0
+					cfgForSnippet.RetryBudget.MinRetriesPerSecond = 0
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Retry budget requires retry_ratio or min_retries_per_second to be set"))
+				})
+
+				It("requires max_tokens of at least 1", func() {
+					cfgForSnippet.RetryBudget.MaxTokens = 0.5
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Invalid retry budget max_tokens: 0.5. Must be at least 1"))
+				})
+			})
+		})
+
+		Context("max conns queue config", func() {
+			It("is disabled by default", func() {
+				Expect(config.MaxConnsQueue).To(Equal(MaxConnsQueueConfig{
+					Enabled:  false,
+					MaxDepth: 100,
+					Timeout:  5 * time.Second,
+				}))
+			})
+
+			It("can configure the queue", func() {
+				var b = []byte(`
+backends:
+  max_conns: 10
+max_conns_queue:
+  enabled: true
+  max_depth: 20
+  timeout: 2s
+`)
+				Expect(config.Initialize(b)).To(Succeed())
+				Expect(config.Process()).To(Succeed())
+				Expect(config.MaxConnsQueue).To(Equal(MaxConnsQueueConfig{
+					Enabled:  true,
+					MaxDepth: 20,
+					Timeout:  2 * time.Second,
+				}))
+			})
+
+			Context("when enabled", func() {
+				BeforeEach(func() {
+					cfgForSnippet.Backends.MaxConns = 10
+					cfgForSnippet.MaxConnsQueue = MaxConnsQueueConfig{
+						Enabled:  true,
+						MaxDepth: 100,
+						Timeout:  5 * time.Second,
+					}
+				})
+
+				It("requires max_conns to be set", func() {
+					cfgForSnippet.Backends.MaxConns = 0
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Max conns queue requires backends max_conns to be set"))
+				})
+
+				It("requires max_depth of at least 1", func() {
+					cfgForSnippet.MaxConnsQueue.MaxDepth = 0
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Invalid max conns queue max_depth: 0. Must be at least 1"))
+				})
+
+				It("requires a positive timeout", func() {
+					cfgForSnippet.MaxConnsQueue.Timeout = 0
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Invalid max conns queue timeout: 0s. Must be positive"))
+				})
+			})
+		})
+
+		Context("adaptive concurrency config", func() {
+			It("is disabled by default", func() {
+				Expect(config.AdaptiveConcurrency).To(Equal(AdaptiveConcurrencyConfig{
+					Enabled:          false,
+					InitialLimit:     20,
+					MinLimit:         1,
+					MaxLimit:         1000,
+					LatencyTolerance: 2,
+					BackoffRatio:     0.9,
+				}))
+			})
+
+			It("can configure adaptive concurrency", func() {
+				var b = []byte(`
+adaptive_concurrency:
+  enabled: true
+  initial_limit: 10
+  min_limit: 2
+  max_limit: 100
+  latency_tolerance: 1.5
+  backoff_ratio: 0.8
+`)
+				Expect(config.Initialize(b)).To(Succeed())
+				Expect(config.Process()).To(Succeed())
+				Expect(config.AdaptiveConcurrency).To(Equal(AdaptiveConcurrencyConfig{
+					Enabled:          true,
+					InitialLimit:     10,
+					MinLimit:         2,
+					MaxLimit:         100,
+					LatencyTolerance: 1.5,
+					BackoffRatio:     0.8,
+				}))
+			})
+
+			Context("when enabled", func() {
+				BeforeEach(func() {
+					cfgForSnippet.AdaptiveConcurrency = AdaptiveConcurrencyConfig{
+						Enabled:          true,
+						InitialLimit:     20,
+						MinLimit:         1,
+						MaxLimit:         1000,
+						LatencyTolerance: 2,
+						BackoffRatio:     0.9,
+					}
+				})
+
+				It("requires a min_limit of at least 1", func() {
+					cfgForSnippet.AdaptiveConcurrency.MinLimit = 0
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Invalid adaptive concurrency limits: min_limit 0, max_limit 1000. Must be at least 1 and min_limit must not exceed max_limit"))
+				})
+
+				It("requires max_limit not to be lower than min_limit", func() {
+					cfgForSnippet.AdaptiveConcurrency.MinLimit = 10
+					cfgForSnippet.AdaptiveConcurrency.MaxLimit = 5
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Invalid adaptive concurrency limits: min_limit 10, max_limit 5. Must be at least 1 and min_limit must not exceed max_limit"))
+				})
+
+				It("requires initial_limit to be between the limits", func() {
+					cfgForSnippet.AdaptiveConcurrency.InitialLimit = 2000
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Invalid adaptive concurrency initial_limit: 2000. Must be between min_limit and max_limit"))
+				})
+
+				It("requires a latency_tolerance of at least 1", func() {
+					cfgForSnippet.AdaptiveConcurrency.LatencyTolerance = 0.5
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Invalid adaptive concurrency latency_tolerance: 0.5. Must be at least 1"))
+				})
+
+				It("requires a backoff_ratio between 0 and 1", func() {
+					cfgForSnippet.AdaptiveConcurrency.BackoffRatio = 1
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Invalid adaptive concurrency backoff_ratio: 1. Must be between 0 and 1"))
+				})
+			})
+		})
+
+		Context("hedging config", func() {
+			It("sets default values", func() {
+				Expect(config.Hedging).To(Equal(HedgingConfig{
+					Ratio:        0.1,
+					MinPerSecond: 1,
+					MaxTokens:    10,
+				}))
+			})
+
+			It("can configure the hedging budget", func() {
+				var b = []byte(`
+hedging:
+  ratio: 0.05
+  min_per_second: 2
+  max_tokens: 20
+`)
+				Expect(config.Initialize(b)).To(Succeed())
+				Expect(config.Process()).To(Succeed())
+				Expect(config.Hedging).To(Equal(HedgingConfig{
+					Ratio:        0.05,
+					MinPerSecond: 2,
+					MaxTokens:    20,
+				}))
+			})
+
+			It("does not allow a negative ratio", func() {
+				cfgForSnippet.Hedging.Ratio = -1
+				config.Initialize(createYMLSnippet(cfgForSnippet))
+				Expect(config.Process()).To(MatchError("Hedging ratio and min_per_second must not be negative"))
+			})
+
+			It("requires max_tokens of at least 1", func() {
+				cfgForSnippet.Hedging = HedgingConfig{Ratio: 0.1, MaxTokens: 0.5}
+				config.Initialize(createYMLSnippet(cfgForSnippet))
+				Expect(config.Process()).To(MatchError("Invalid hedging max_tokens: 0.5. Must be at least 1"))
+			})
+		})
+
+		Context("shadow config", func() {
+			It("sets default values", func() {
+				Expect(config.Shadow.MaxBodyBytes).To(Equal(int64(64 * 1024)))
+				Expect(config.Shadow.Timeout).To(Equal(10 * time.Second))
+				Expect(config.Shadow.MaxConcurrency).To(Equal(100))
+			})
+
+			It("can configure the shadow limits", func() {
+				var b = []byte(`
+shadow:
+  max_body_bytes: 1024
+  timeout: 2s
+  max_concurrency: 10
+  allowed_routes: [app-v2.example.com]
+`)
+				Expect(config.Initialize(b)).To(Succeed())
+				Expect(config.Process()).To(Succeed())
+				Expect(config.Shadow).To(Equal(ShadowConfig{
+					MaxBodyBytes:   1024,
+					Timeout:        2 * time.Second,
+					MaxConcurrency: 10,
+					AllowedRoutes:  []string{"app-v2.example.com"},
+				}))
+			})
+
+			It("rejects a non-positive timeout", func() {
+				cfgForSnippet.Shadow = ShadowConfig{Timeout: -time.Second, MaxConcurrency: 10}
+				config.Initialize(createYMLSnippet(cfgForSnippet))
+				Expect(config.Process()).To(MatchError("Invalid shadow timeout: -1s. Must be positive"))
+			})
+
+			It("rejects a max_concurrency below one", func() {
+				cfgForSnippet.Shadow = ShadowConfig{Timeout: time.Second, MaxConcurrency: -1}
+				config.Initialize(createYMLSnippet(cfgForSnippet))
+				Expect(config.Process()).To(MatchError("Invalid shadow max_concurrency: -1. Must be at least 1"))
+			})
+		})
+
+		Context("fallback backend config", func() {
+			It("is disabled by default", func() {
+				Expect(config.FallbackBackend).To(Equal(FallbackBackendConfig{
+					OriginalHostHeader: "X-Cf-Original-Host",
+				}))
+				Expect(config.FallbackBackend.Enabled()).To(BeFalse())
+			})
+
+			It("can configure a static fallback backend for unknown routes", func() {
+				var b = []byte(`
+fallback_backend:
+  unknown_routes: true
+  address: 10.0.0.1:8080
+`)
+				Expect(config.Initialize(b)).To(Succeed())
+				Expect(config.Process()).To(Succeed())
+				Expect(config.FallbackBackend).To(Equal(FallbackBackendConfig{
+					UnknownRoutes:      true,
+					Address:            "10.0.0.1:8080",
+					OriginalHostHeader: "X-Cf-Original-Host",
+				}))
+			})
+
+			It("can configure a registered route as fallback backend for empty pools", func() {
+				var b = []byte(`
+fallback_backend:
+  empty_pools: true
+  route: parking.example.com
+  original_host_header: X-Requested-Host
+`)
+				Expect(config.Initialize(b)).To(Succeed())
+				Expect(config.Process()).To(Succeed())
+				Expect(config.FallbackBackend.EmptyPools).To(BeTrue())
+				Expect(config.FallbackBackend.UnknownRoutes).To(BeFalse())
+				Expect(config.FallbackBackend.Route).To(Equal("parking.example.com"))
+				Expect(config.FallbackBackend.OriginalHostHeader).To(Equal("X-Requested-Host"))
+			})
+
+			Context("when enabled", func() {
+				BeforeEach(func() {
+					cfgForSnippet.FallbackBackend = FallbackBackendConfig{
+						UnknownRoutes:      true,
+						OriginalHostHeader: "X-Cf-Original-Host",
+					}
+				})
+
+				It("requires an address or a route", func() {
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Fallback backend requires exactly one of address and route"))
+				})
+
+				It("does not allow both an address and a route", func() {
+					cfgForSnippet.FallbackBackend.Address = "10.0.0.1:8080"
+					cfgForSnippet.FallbackBackend.Route = "parking.example.com"
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Fallback backend requires exactly one of address and route"))
+				})
+
+				It("requires the address to have a valid port", func() {
+					cfgForSnippet.FallbackBackend.Address = "10.0.0.1:99999"
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError(ContainSubstring("Invalid fallback backend address 10.0.0.1:99999")))
+				})
+
+				It("requires the address to have a port", func() {
+					cfgForSnippet.FallbackBackend.Address = "10.0.0.1"
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError(ContainSubstring("Invalid fallback backend address 10.0.0.1")))
+				})
+
+				It("requires an original host header", func() {
+					cfgForSnippet.FallbackBackend.Route = "parking.example.com"
+					cfgForSnippet.FallbackBackend.OriginalHostHeader = ""
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Fallback backend requires original_host_header to be set"))
+				})
+			})
+		})
+
+		Context("routing table snapshot config", func() {
+			It("is disabled by default", func() {
+				Expect(config.RoutingTableSnapshot).To(Equal(RoutingTableSnapshotConfig{
+					Interval:       30 * time.Second,
+					StaleThreshold: 60 * time.Second,
+				}))
+			})
+
+			It("can be configured", func() {
+				var b = []byte(`
+routing_table_snapshot:
+  enabled: true
+  path: /var/vcap/data/gorouter/routes.json
+  interval: 10s
+  stale_threshold: 2m
+`)
+				Expect(config.Initialize(b)).To(Succeed())
+				Expect(config.Process()).To(Succeed())
+				Expect(config.RoutingTableSnapshot).To(Equal(RoutingTableSnapshotConfig{
+					Enabled:        true,
+					Path:           "/var/vcap/data/gorouter/routes.json",
+					Interval:       10 * time.Second,
+					StaleThreshold: 2 * time.Minute,
+				}))
+			})
+
+			Context("when enabled", func() {
+				BeforeEach(func() {
+					cfgForSnippet.RoutingTableSnapshot = RoutingTableSnapshotConfig{
+						Enabled:        true,
+						Path:           "/var/vcap/data/gorouter/routes.json",
+						Interval:       30 * time.Second,
+						StaleThreshold: 60 * time.Second,
+					}
+				})
+
+				It("requires a path", func() {
+					cfgForSnippet.RoutingTableSnapshot.Path = ""
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Routing table snapshot requires path to be set"))
+				})
+
+				It("requires a positive interval", func() {
+					cfgForSnippet.RoutingTableSnapshot.Interval = 0
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Invalid routing table snapshot interval: 0s. Must be positive"))
+				})
+
+				It("requires a positive stale threshold", func() {
+					cfgForSnippet.RoutingTableSnapshot.StaleThreshold = -time.Second
+					config.Initialize(createYMLSnippet(cfgForSnippet))
+					Expect(config.Process()).To(MatchError("Invalid routing table snapshot stale_threshold: -1s. Must be positive"))
+				})
+			})
+		})
+
+		Context("static routes config", func() {
+			It("is disabled by default", func() {
+				Expect(config.StaticRoutes).To(Equal(StaticRoutesConfig{ReloadInterval: 5 * time.Second}))
+				Expect(config.StaticRoutes.Enabled()).To(BeFalse())
+			})
+
+			It("can be configured", func() {
+				var b = []byte(`
+static_routes:
+  path: /var/vcap/jobs/gorouter/config/routes.yml
+  reload_interval: 1s
+`)
+				Expect(config.Initialize(b)).To(Succeed())
+				Expect(config.Process()).To(Succeed())
+				Expect(config.StaticRoutes).To(Equal(StaticRoutesConfig{
+					Path:           "/var/vcap/jobs/gorouter/config/routes.yml",
+					ReloadInterval: time.Second,
+				}))
+				Expect(config.StaticRoutes.Enabled()).To(BeTrue())
+			})
+
+			It("requires a positive reload interval", func() {
+				cfgForSnippet.StaticRoutes = StaticRoutesConfig{Path: "/var/vcap/jobs/gorouter/config/routes.yml"}
+				config.Initialize(createYMLSnippet(cfgForSnippet))
+				Expect(config.Process()).To(MatchError("Invalid static routes reload_interval: 0s. Must be positive"))
 			})
 		})
 
@@ -125,6 +837,24 @@ status:
 			Expect(config.Status.User).To(Equal("user"))
 			Expect(config.Status.Pass).To(Equal("pass"))
 			Expect(config.Status.Routes.Port).To(Equal(uint16(8082)))
+			Expect(config.Status.Routes.ChangeFeedBufferSize).To(Equal(1024))
+		})
+
+		It("sets the buffer size of the routes change feed", func() {
+			var b = []byte(`
+status:
+  routes:
+    change_feed_buffer_size: 10
+`)
+			Expect(config.Initialize(b)).To(Succeed())
+			Expect(config.Process()).To(Succeed())
+			Expect(config.Status.Routes.ChangeFeedBufferSize).To(Equal(10))
+		})
+
+		It("rejects a negative buffer size of the routes change feed", func() {
+			cfgForSnippet.Status.Routes.ChangeFeedBufferSize = -1
+			config.Initialize(createYMLSnippet(cfgForSnippet))
+			Expect(config.Process()).To(MatchError("Invalid routes change_feed_buffer_size: -1. Must not be negative"))
 		})
 		Context("when neither tls nor nontls health endpoints are enabled", func() {
 			JustBeforeEach(func() {
@@ -1066,6 +1796,63 @@ load_balancer_healthy_threshold: 10s
 			})
 		})
 
+		Describe("StickySessionHeader", func() {
+			It("is disabled by default", func() {
+				Expect(config.Initialize(createYMLSnippet(cfgForSnippet))).To(Succeed())
+				Expect(config.Process()).To(Succeed())
+				Expect(config.StickySessionHeader).To(BeEmpty())
+			})
+
+			It("can configure the header", func() {
+				cfgForSnippet.StickySessionHeader = "X-CF-Session-Affinity"
+				Expect(config.Initialize(createYMLSnippet(cfgForSnippet))).To(Succeed())
+				Expect(config.Process()).To(Succeed())
+				Expect(config.StickySessionHeader).To(Equal("X-CF-Session-Affinity"))
+			})
+
+			It("does not allow invalid header names", func() {
+				cfgForSnippet.StickySessionHeader = "X-CF: Session"
+				Expect(config.Initialize(createYMLSnippet(cfgForSnippet))).To(Succeed())
+				Expect(config.Process()).To(MatchError(`Invalid sticky_session_header: "X-CF: Session"`))
+			})
+		})
+
+		Describe("StickySessionCookieEncryption", func() {
+			It("is disabled by default", func() {
+				Expect(config.Initialize(createYMLSnippet(cfgForSnippet))).To(Succeed())
+				Expect(config.Process()).To(Succeed())
+				Expect(config.StickySessionCookieEncryption.Enabled).To(BeFalse())
+			})
+
+			It("can configure the secrets", func() {
+				cfgForSnippet.StickySessionCookieEncryption = StickySessionCookieEncryptionConfig{
+					Enabled:            true,
+					Secret:             "new-secret",
+					DecryptOnlySecrets: []string{"old-secret"},
+				}
+				Expect(config.Initialize(createYMLSnippet(cfgForSnippet))).To(Succeed())
+				Expect(config.Process()).To(Succeed())
+				Expect(config.StickySessionCookieEncryption.Secret).To(Equal("new-secret"))
+				Expect(config.StickySessionCookieEncryption.DecryptOnlySecrets).To(Equal([]string{"old-secret"}))
+			})
+
+			It("requires a secret when enabled", func() {
+				cfgForSnippet.StickySessionCookieEncryption = StickySessionCookieEncryptionConfig{Enabled: true}
+				Expect(config.Initialize(createYMLSnippet(cfgForSnippet))).To(Succeed())
+				Expect(config.Process()).To(MatchError("Sticky session cookie encryption requires a secret"))
+			})
+
+			It("does not allow empty decrypt-only secrets", func() {
+				cfgForSnippet.StickySessionCookieEncryption = StickySessionCookieEncryptionConfig{
+					Enabled:            true,
+					Secret:             "new-secret",
+					DecryptOnlySecrets: []string{""},
+				}
+				Expect(config.Initialize(createYMLSnippet(cfgForSnippet))).To(Succeed())
+				Expect(config.Process()).To(MatchError("Sticky session cookie decrypt_only_secrets must not be empty"))
+			})
+		})
+
 		Context("When secure cookies is set to false", func() {
 			BeforeEach(func() {
 				cfgForSnippet.SecureCookies = false
@@ -1997,6 +2784,88 @@ drain_timeout: 60s
 
 				Expect(config.DrainTimeout).To(Equal(60 * time.Second))
 			})
+
+			It("limits per-route timeouts and attempts to the global values when not set", func() {
+				b = []byte(`
+endpoint_timeout: 10s
+endpoint_dial_timeout: 6s
+backends:
+  max_attempts: 3
+`)
+				err := config.Initialize(b)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Process()).To(Succeed())
+
+				Expect(config.Backends.MaxRequestTimeout).To(Equal(10 * time.Second))
+				Expect(config.Backends.MaxDialTimeout).To(Equal(6 * time.Second))
+				Expect(config.Backends.MaxRouteAttempts).To(Equal(3))
+			})
+
+			It("can configure the limits of per-route timeouts and attempts", func() {
+				b = []byte(`
+endpoint_timeout: 10s
+backends:
+  max_attempts: 3
+  max_request_timeout: 5m
+  max_dial_timeout: 10s
+  max_route_attempts: 5
+`)
+				err := config.Initialize(b)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Process()).To(Succeed())
+
+				Expect(config.Backends.MaxRequestTimeout).To(Equal(5 * time.Minute))
+				Expect(config.Backends.MaxDialTimeout).To(Equal(10 * time.Second))
+				Expect(config.Backends.MaxRouteAttempts).To(Equal(5))
+			})
+
+			It("does not allow negative limits of per-route timeouts and attempts", func() {
+				b = []byte(`
+backends:
+  max_route_attempts: -1
+`)
+				err := config.Initialize(b)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Process()).To(MatchError("Invalid backends max_request_timeout, max_dial_timeout or max_route_attempts. Must not be negative"))
+			})
+
+			It("does not retry on any status code by default", func() {
+				b = []byte(`
+backends:
+  max_attempts: 3
+`)
+				err := config.Initialize(b)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Process()).To(Succeed())
+				Expect(config.Backends.RetryStatusCodes).To(BeEmpty())
+			})
+
+			It("can configure the status codes on which requests are retried", func() {
+				b = []byte(`
+backends:
+  retry_status_codes: [502, 503, 504]
+`)
+				err := config.Initialize(b)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Process()).To(Succeed())
+				Expect(config.Backends.RetryStatusCodes).To(Equal([]int{502, 503, 504}))
+			})
+
+			It("does not allow invalid retry status codes", func() {
+				b = []byte(`
+backends:
+  retry_status_codes: [503, 999]
+`)
+				err := config.Initialize(b)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Process()).To(MatchError("Invalid backends retry_status_codes: 999. Must be an HTTP status code"))
+			})
 		})
 
 		Describe("configuring client (mTLS) authentication to backends", func() {
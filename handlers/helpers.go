@@ -63,21 +63,45 @@ func upgradeHeader(request *http.Request) string {
 	return ""
 }
 
-func EndpointIteratorForRequest(logger *slog.Logger, request *http.Request, stickySessionCookieNames config.StringSet, authNegotiateSticky bool, azPreference string, az string) (route.EndpointIterator, error) {
+func EndpointIteratorForRequest(logger *slog.Logger, request *http.Request, stickySessionCookieNames config.StringSet, authNegotiateSticky bool, stickySessionHeader string, stickySessionCodec *StickySessionCodec, azPreference string, az string) (route.EndpointIterator, error) {
 	reqInfo, err := ContextRequestInfo(request)
 	if err != nil {
 		return nil, fmt.Errorf("could not find reqInfo in context")
 	}
-	stickyEndpointID, mustBeSticky := GetStickySession(request, stickySessionCookieNames, authNegotiateSticky)
-	return reqInfo.RoutePool.Endpoints(logger, stickyEndpointID, mustBeSticky, azPreference, az), nil
+	stickyEndpointID, mustBeSticky := GetStickySession(request, stickySessionCookieNames, authNegotiateSticky, stickySessionHeader, stickySessionCodec)
+	return reqInfo.RoutePool.Endpoints(logger, stickyEndpointID, mustBeSticky, azPreference, az, reqInfo.RoutePool.RequestHashKey(request), reqInfo.RoutingRule), nil
 }
 
-func GetStickySession(request *http.Request, stickySessionCookieNames config.StringSet, authNegotiateSticky bool) (string, bool) {
+// StickySessionNeedsRenewal reports whether the request carries a sticky
+// session cookie along with a __VCAP_ID__ cookie which was not encrypted with
+// the current secret, because it cannot be decoded or was encrypted with a
+// decrypt-only secret. Such a cookie must be replaced for the client to keep
+// its sticky session.
+func StickySessionNeedsRenewal(request *http.Request, stickySessionCookieNames config.StringSet, stickySessionCodec *StickySessionCodec) bool {
+	sticky, err := request.Cookie(VcapCookieId)
+	if err != nil {
+		return false
+	}
+	for stickyCookieName := range stickySessionCookieNames {
+		if _, err := request.Cookie(stickyCookieName); err == nil {
+			return !stickySessionCodec.IsCurrent(sticky.Value)
+		}
+	}
+	return false
+}
+
+// GetStickySession returns the ID of the instance the request is pinned to by
+// its __VCAP_ID__ cookie or its sticky session header, and whether the request
+// must be sent to it. Values which the codec cannot decode are ignored in
+// favour of the next source.
+func GetStickySession(request *http.Request, stickySessionCookieNames config.StringSet, authNegotiateSticky bool, stickySessionHeader string, stickySessionCodec *StickySessionCodec) (string, bool) {
 	if authNegotiateSticky {
 		containsAuthNegotiateHeader := strings.HasPrefix(strings.ToLower(request.Header.Get("Authorization")), "negotiate")
 		if containsAuthNegotiateHeader {
 			if sticky, err := request.Cookie(VcapCookieId); err == nil {
-				return sticky.Value, true
+				if instanceID, ok := stickySessionCodec.Decode(sticky.Value); ok {
+					return instanceID, true
+				}
 			}
 		}
 	}
@@ -85,7 +109,17 @@ func GetStickySession(request *http.Request, stickySessionCookieNames config.Str
 	for stickyCookieName := range stickySessionCookieNames {
 		if _, err := request.Cookie(stickyCookieName); err == nil {
 			if sticky, err := request.Cookie(VcapCookieId); err == nil {
-				return sticky.Value, false
+				if instanceID, ok := stickySessionCodec.Decode(sticky.Value); ok {
+					return instanceID, false
+				}
+			}
+		}
+	}
+	// Try choosing a backend using the sticky session header
+	if stickySessionHeader != "" {
+		if sticky := request.Header.Get(stickySessionHeader); sticky != "" {
+			if instanceID, ok := stickySessionCodec.Decode(sticky); ok {
+				return instanceID, false
 			}
 		}
 	}
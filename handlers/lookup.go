@@ -1,14 +1,21 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
 	"regexp"
+	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/urfave/negroni/v3"
 
 	router_http "code.cloudfoundry.org/gorouter/common/http"
+	"code.cloudfoundry.org/gorouter/config"
 	"code.cloudfoundry.org/gorouter/errorwriter"
 	log "code.cloudfoundry.org/gorouter/logger"
 	"code.cloudfoundry.org/gorouter/metrics"
@@ -40,6 +47,16 @@ type lookupHandler struct {
 	logger                   *slog.Logger
 	errorWriter              errorwriter.ErrorWriter
 	EmptyPoolResponseCode503 bool
+
+	// fallback configures the backend which receives requests for unknown
+	// routes or empty pools; fallbackPool holds its endpoint if it is a
+	// static address
+	fallback     config.FallbackBackendConfig
+	fallbackPool *route.EndpointPool
+
+	// queued is the number of requests waiting in the max conns queues of
+	// all pools
+	queued atomic.Int64
 }
 
 // NewLookup creates a handler responsible for looking up a route.
@@ -49,6 +66,7 @@ func NewLookup(
 	logger *slog.Logger,
 	ew errorwriter.ErrorWriter,
 	emptyPoolResponseCode503 bool,
+	fallback config.FallbackBackendConfig,
 ) negroni.Handler {
 	return &lookupHandler{
 		registry:                 registry,
@@ -56,7 +74,31 @@ func NewLookup(
 		logger:                   logger,
 		errorWriter:              ew,
 		EmptyPoolResponseCode503: emptyPoolResponseCode503,
+		fallback:                 fallback,
+		fallbackPool:             newFallbackPool(fallback, logger),
+	}
+}
+
+// newFallbackPool returns a pool with the static address of the fallback
+// backend as its only endpoint, or nil if there is no static fallback backend.
+// The address was validated with the config.
+func newFallbackPool(fallback config.FallbackBackendConfig, logger *slog.Logger) *route.EndpointPool {
+	if !fallback.Enabled() || fallback.Address == "" {
+		return nil
 	}
+
+	host, port, _ := net.SplitHostPort(fallback.Address)
+	portNum, _ := strconv.ParseUint(port, 10, 16)
+	pool := route.NewPool(&route.PoolOpts{
+		Logger:                 logger,
+		Host:                   host,
+		LoadBalancingAlgorithm: config.LOAD_BALANCE_RR,
+	})
+	pool.Put(route.NewEndpoint(&route.EndpointOpts{
+		Host: host,
+		Port: uint16(portNum),
+	}))
+	return pool
 }
 
 func (l *lookupHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
@@ -93,23 +135,27 @@ func (l *lookupHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request, next
 	}
 
 	if pool == nil {
-		l.handleMissingRoute(rw, r, logger)
-		return
-	}
-
-	if pool.IsEmpty() {
-		if l.EmptyPoolResponseCode503 {
-			l.handleUnavailableRoute(rw, r, logger)
-			return
-		} else {
+		if pool = l.lookupFallback(r, l.fallback.UnknownRoutes, logger); pool == nil {
 			l.handleMissingRoute(rw, r, logger)
 			return
 		}
+	} else if pool.IsEmpty() {
+		if pool = l.lookupFallback(r, l.fallback.EmptyPools, logger); pool == nil {
+			if l.EmptyPoolResponseCode503 {
+				l.handleUnavailableRoute(rw, r, logger)
+				return
+			} else {
+				l.handleMissingRoute(rw, r, logger)
+				return
+			}
+		}
 	}
 
 	if pool.IsOverloaded() {
-		l.handleOverloadedRoute(rw, r, logger)
-		return
+		if err := l.waitForCapacity(r, pool, logger); err != nil {
+			l.handleOverloadedRoute(rw, r, pool, err, logger)
+			return
+		}
 	}
 
 	requestInfo, err := ContextRequestInfo(r)
@@ -210,11 +256,67 @@ func (l *lookupHandler) handleUnavailableRoute(rw http.ResponseWriter, r *http.R
 	)
 }
 
-func (l *lookupHandler) handleOverloadedRoute(rw http.ResponseWriter, r *http.Request, logger *slog.Logger) {
+// lookupFallback returns the pool of the fallback backend for a request whose
+// route is unknown or has no endpoints, and passes on the Host header of the
+// request in the original host header. It returns nil if the fallback backend
+// is not enabled for the case, has no endpoints, or if the request asked for a
+// specific app or process instance.
+func (l *lookupHandler) lookupFallback(r *http.Request, enabled bool, logger *slog.Logger) *route.EndpointPool {
+	if !enabled || r.Header.Get(router_http.CfAppInstance) != "" || r.Header.Get(router_http.CfProcessInstance) != "" {
+		return nil
+	}
+
+	pool := l.fallbackPool
+	if l.fallback.Route != "" {
+		pool = l.registry.Lookup(route.Uri(l.fallback.Route))
+	}
+	if pool == nil || pool.IsEmpty() {
+		logger.Info("fallback-backend-unavailable", slog.String("route", l.fallback.Route))
+		return nil
+	}
+
+	logger.Debug("routing-to-fallback-backend", slog.String("host", r.Host))
+	r.Header.Set(l.fallback.OriginalHostHeader, r.Host)
+	return pool
+}
+
+// waitForCapacity queues the request until an endpoint of the overloaded pool
+// drops below the connection limit. It returns route.ErrQueueFull if the pool
+// does not queue requests or its queue is full, and route.ErrQueueTimeout if the
+// request timed out waiting.
+func (l *lookupHandler) waitForCapacity(r *http.Request, pool *route.EndpointPool, logger *slog.Logger) error {
+	if pool.QueueTimeout() == 0 {
+		return route.ErrQueueFull
+	}
+
+	l.reporter.CaptureMaxConnsQueueDepth(int(l.queued.Add(1)))
+	start := time.Now()
+	err := pool.WaitForCapacity(r.Context())
+	l.reporter.CaptureMaxConnsQueueDepth(int(l.queued.Add(-1)))
+
+	if errors.Is(err, route.ErrQueueFull) {
+		logger.Info("max-conns-queue-full")
+		return err
+	}
+	l.reporter.CaptureMaxConnsQueueWaitTime(time.Since(start))
+	if err != nil {
+		logger.Info("max-conns-queue-wait-failed", log.ErrAttr(err))
+	}
+	return err
+}
+
+// handleOverloadedRoute rejects a request which could not wait for the
+// overloaded pool because of err. Requests which timed out waiting are asked
+// to retry after the queue timeout.
+func (l *lookupHandler) handleOverloadedRoute(rw http.ResponseWriter, r *http.Request, pool *route.EndpointPool, err error, logger *slog.Logger) {
 	l.reporter.CaptureBackendExhaustedConns()
 	l.logger.Info("connection-limit-reached")
 
 	AddRouterErrorHeader(rw, "Connection Limit Reached")
+	if errors.Is(err, route.ErrQueueTimeout) {
+		timeout := pool.QueueTimeout()
+		rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(timeout.Seconds()))))
+	}
 
 	l.errorWriter.WriteError(
 		rw,
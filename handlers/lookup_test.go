@@ -13,6 +13,7 @@ import (
 	"github.com/urfave/negroni/v3"
 	"go.uber.org/zap/zapcore"
 
+	"code.cloudfoundry.org/gorouter/config"
 	"code.cloudfoundry.org/gorouter/errorwriter"
 	"code.cloudfoundry.org/gorouter/handlers"
 	log "code.cloudfoundry.org/gorouter/logger"
@@ -60,7 +61,7 @@ var _ = Describe("Lookup", func() {
 		req = test_util.NewRequest("GET", "example.com", "/", nil)
 		resp = httptest.NewRecorder()
 		handler.Use(handlers.NewRequestInfo())
-		handler.Use(handlers.NewLookup(reg, rep, logger, ew, true))
+		handler.Use(handlers.NewLookup(reg, rep, logger, ew, true, config.FallbackBackendConfig{}))
 		handler.UseHandler(nextHandler)
 	})
 
@@ -252,7 +253,7 @@ var _ = Describe("Lookup", func() {
 				emptyPoolResponseCode503 := true
 				handler = negroni.New()
 				handler.Use(handlers.NewRequestInfo())
-				handler.Use(handlers.NewLookup(reg, rep, logger, ew, emptyPoolResponseCode503))
+				handler.Use(handlers.NewLookup(reg, rep, logger, ew, emptyPoolResponseCode503, config.FallbackBackendConfig{}))
 				handler.UseHandler(nextHandler)
 
 				pool = route.NewPool(&route.PoolOpts{
@@ -292,7 +293,7 @@ var _ = Describe("Lookup", func() {
 				emptyPoolResponseCode503 := false
 				handler = negroni.New()
 				handler.Use(handlers.NewRequestInfo())
-				handler.Use(handlers.NewLookup(reg, rep, logger, ew, emptyPoolResponseCode503))
+				handler.Use(handlers.NewLookup(reg, rep, logger, ew, emptyPoolResponseCode503, config.FallbackBackendConfig{}))
 				handler.UseHandler(nextHandler)
 
 				pool = route.NewPool(&route.PoolOpts{
@@ -324,6 +325,133 @@ var _ = Describe("Lookup", func() {
 		})
 	})
 
+	Context("when a fallback backend is configured", func() {
+		var (
+			fallback     config.FallbackBackendConfig
+			fallbackPool *route.EndpointPool
+			emptyPool    *route.EndpointPool
+			routePool    *route.EndpointPool
+		)
+
+		BeforeEach(func() {
+			fallback = config.FallbackBackendConfig{
+				UnknownRoutes:      true,
+				EmptyPools:         true,
+				Route:              "parking.example.com",
+				OriginalHostHeader: "X-Cf-Original-Host",
+			}
+			fallbackPool = route.NewPool(&route.PoolOpts{Logger: logger, Host: "parking.example.com"})
+			fallbackPool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "10.0.0.9", Port: 8080}))
+			emptyPool = route.NewPool(&route.PoolOpts{Logger: logger, Host: "example.com"})
+			routePool = nil
+			reg.LookupStub = func(uri route.Uri) *route.EndpointPool {
+				if uri == "parking.example.com" {
+					return fallbackPool
+				}
+				return routePool
+			}
+		})
+
+		JustBeforeEach(func() {
+			handler = negroni.New()
+			handler.Use(handlers.NewRequestInfo())
+			handler.Use(handlers.NewLookup(reg, rep, logger, ew, true, fallback))
+			handler.UseHandler(nextHandler)
+			handler.ServeHTTP(resp, req)
+		})
+
+		expectFallback := func() {
+			Expect(nextCalled).To(BeTrue())
+			requestInfo, err := handlers.ContextRequestInfo(nextRequest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requestInfo.RoutePool).To(Equal(fallbackPool))
+			Expect(nextRequest.Host).To(Equal("example.com"))
+			Expect(nextRequest.Header.Get("X-Cf-Original-Host")).To(Equal("example.com"))
+			Expect(rep.CaptureBadRequestCallCount()).To(Equal(0))
+		}
+
+		It("sends requests for unknown routes to the fallback route", func() {
+			expectFallback()
+		})
+
+		Context("when the pool of the route is empty", func() {
+			BeforeEach(func() {
+				routePool = emptyPool
+			})
+
+			It("sends the request to the fallback route", func() {
+				expectFallback()
+			})
+
+			Context("when the fallback backend is only enabled for unknown routes", func() {
+				BeforeEach(func() {
+					fallback.EmptyPools = false
+				})
+
+				It("returns a 503", func() {
+					Expect(nextCalled).To(BeFalse())
+					Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+					Expect(resp.Header().Get("X-Cf-RouterError")).To(Equal("no_endpoints"))
+				})
+			})
+		})
+
+		Context("when the fallback backend is only enabled for empty pools", func() {
+			BeforeEach(func() {
+				fallback.UnknownRoutes = false
+			})
+
+			It("returns a 404 for unknown routes", func() {
+				Expect(nextCalled).To(BeFalse())
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+				Expect(resp.Header().Get("X-Cf-RouterError")).To(Equal("unknown_route"))
+			})
+		})
+
+		Context("when the fallback route has no endpoints", func() {
+			BeforeEach(func() {
+				fallbackPool = emptyPool
+			})
+
+			It("returns a 404", func() {
+				Expect(nextCalled).To(BeFalse())
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+				Expect(resp.Header().Get("X-Cf-RouterError")).To(Equal("unknown_route"))
+			})
+		})
+
+		Context("when the request asks for a specific app instance", func() {
+			BeforeEach(func() {
+				req.Header.Set("X-CF-App-Instance", fakeAppGUID+":1")
+			})
+
+			It("does not send the request to the fallback route", func() {
+				Expect(nextCalled).To(BeFalse())
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when the fallback backend is a static address", func() {
+			BeforeEach(func() {
+				fallback.Route = ""
+				fallback.Address = "10.0.0.10:9090"
+				fallback.OriginalHostHeader = "X-Requested-Host"
+			})
+
+			It("sends the request to the address", func() {
+				Expect(nextCalled).To(BeTrue())
+				requestInfo, err := handlers.ContextRequestInfo(nextRequest)
+				Expect(err).ToNot(HaveOccurred())
+				var addrs []string
+				requestInfo.RoutePool.Each(func(e *route.Endpoint) {
+					addrs = append(addrs, e.CanonicalAddr())
+				})
+				Expect(addrs).To(Equal([]string{"10.0.0.10:9090"}))
+				Expect(nextRequest.Header.Get("X-Requested-Host")).To(Equal("example.com"))
+			})
+		})
+	})
+
 	Context("when there is a pool that matches the request, and it has endpoints", func() {
 		Context("when conn limit is set to unlimited", func() {
 			BeforeEach(func() {
@@ -428,6 +556,93 @@ var _ = Describe("Lookup", func() {
 			It("increments the backend_exhausted_conn metric", func() {
 				Expect(rep.CaptureBackendExhaustedConnsCallCount()).To(Equal(1))
 			})
+
+			It("does not queue the request", func() {
+				Expect(resp.Header().Get("Retry-After")).To(BeEmpty())
+				Expect(rep.CaptureMaxConnsQueueDepthCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when conn limit is reached for all endpoints of a pool which queues requests", func() {
+			var (
+				pool         *route.EndpointPool
+				testEndpoint *route.Endpoint
+				queueTimeout time.Duration
+				queueDepth   int
+			)
+
+			BeforeEach(func() {
+				queueTimeout = time.Minute
+				queueDepth = 10
+			})
+
+			JustBeforeEach(func() {
+				pool = route.NewPool(&route.PoolOpts{
+					Logger:             logger,
+					RetryAfterFailure:  2 * time.Minute,
+					Host:               "example.com",
+					ContextPath:        "/",
+					MaxConnsPerBackend: maxConnections,
+					MaxConnsQueue:      &config.MaxConnsQueueConfig{Enabled: true, MaxDepth: queueDepth, Timeout: queueTimeout},
+				})
+				testEndpoint = route.NewEndpoint(&route.EndpointOpts{Host: "1.3.5.6", Port: 5679})
+				testEndpoint.Stats.NumberConnections.Increment()
+				testEndpoint.Stats.NumberConnections.Increment()
+				pool.Put(testEndpoint)
+				reg.LookupReturns(pool)
+			})
+
+			It("calls next with the pool once a connection is released", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					handler.ServeHTTP(resp, req)
+					close(done)
+				}()
+				Eventually(pool.QueueDepth).Should(Equal(1))
+				Consistently(done).ShouldNot(BeClosed())
+
+				route.NewRoundRobin(logger, pool, "", false, false, "").PostRequest(testEndpoint)
+				Eventually(done).Should(BeClosed())
+
+				Expect(nextCalled).To(BeTrue())
+				Expect(rep.CaptureBackendExhaustedConnsCallCount()).To(BeZero())
+				Expect(rep.CaptureMaxConnsQueueDepthCallCount()).To(Equal(2))
+				Expect(rep.CaptureMaxConnsQueueDepthArgsForCall(0)).To(Equal(1))
+				Expect(rep.CaptureMaxConnsQueueDepthArgsForCall(1)).To(Equal(0))
+				Expect(rep.CaptureMaxConnsQueueWaitTimeCallCount()).To(Equal(1))
+			})
+
+			Context("when no connection is released in time", func() {
+				BeforeEach(func() {
+					queueTimeout = 1500 * time.Millisecond
+				})
+
+				It("returns a 503 with a Retry-After header", func() {
+					handler.ServeHTTP(resp, req)
+					Expect(nextCalled).To(BeFalse())
+					Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+					Expect(resp.Header().Get("Retry-After")).To(Equal("2"))
+					Expect(rep.CaptureBackendExhaustedConnsCallCount()).To(Equal(1))
+					Expect(rep.CaptureMaxConnsQueueWaitTimeCallCount()).To(Equal(1))
+					Expect(rep.CaptureMaxConnsQueueWaitTimeArgsForCall(0)).To(BeNumerically(">=", queueTimeout))
+				})
+			})
+
+			Context("when the queue is full", func() {
+				BeforeEach(func() {
+					queueDepth = 0
+				})
+
+				It("returns a 503 without a Retry-After header", func() {
+					handler.ServeHTTP(resp, req)
+					Expect(nextCalled).To(BeFalse())
+					Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+					Expect(resp.Header().Get("Retry-After")).To(BeEmpty())
+					Expect(rep.CaptureBackendExhaustedConnsCallCount()).To(Equal(1))
+					Expect(rep.CaptureMaxConnsQueueWaitTimeCallCount()).To(BeZero())
+				})
+			})
 		})
 
 		Context("when a specific app instance is requested", func() {
@@ -709,7 +924,7 @@ var _ = Describe("Lookup", func() {
 		Context("when request info is not set on the request context", func() {
 			BeforeEach(func() {
 				handler = negroni.New()
-				handler.Use(handlers.NewLookup(reg, rep, logger, ew, true))
+				handler.Use(handlers.NewLookup(reg, rep, logger, ew, true, config.FallbackBackendConfig{}))
 				handler.UseHandler(nextHandler)
 
 				pool := route.NewPool(&route.PoolOpts{
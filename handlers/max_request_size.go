@@ -11,17 +11,18 @@ import (
 )
 
 type MaxRequestSize struct {
-	cfg      *config.Config
-	MaxSize  int
-	MaxCount int
-	logger   *slog.Logger
+	cfg                *config.Config
+	stickySessionCodec *StickySessionCodec
+	MaxSize            int
+	MaxCount           int
+	logger             *slog.Logger
 }
 
 const ONE_MB = 1024 * 1024 // bytes * kb
 
 // NewAccessLog creates a new handler that handles logging requests to the
 // access log
-func NewMaxRequestSize(cfg *config.Config, logger *slog.Logger) *MaxRequestSize {
+func NewMaxRequestSize(cfg *config.Config, stickySessionCodec *StickySessionCodec, logger *slog.Logger) *MaxRequestSize {
 	maxSize := cfg.MaxRequestHeaderBytes
 
 	if maxSize < 1 {
@@ -34,10 +35,11 @@ func NewMaxRequestSize(cfg *config.Config, logger *slog.Logger) *MaxRequestSize
 	}
 
 	return &MaxRequestSize{
-		MaxSize:  maxSize,
-		MaxCount: cfg.MaxRequestHeaders,
-		logger:   logger,
-		cfg:      cfg,
+		MaxSize:            maxSize,
+		MaxCount:           cfg.MaxRequestHeaders,
+		logger:             logger,
+		cfg:                cfg,
+		stickySessionCodec: stickySessionCodec,
 	}
 }
 
@@ -69,7 +71,7 @@ func (m *MaxRequestSize) ServeHTTP(rw http.ResponseWriter, r *http.Request, next
 		if err != nil {
 			logger.Error("request-info-err", log.ErrAttr(err))
 		} else {
-			endpointIterator, err := EndpointIteratorForRequest(logger, r, m.cfg.StickySessionCookieNames, m.cfg.StickySessionsForAuthNegotiate, m.cfg.LoadBalanceAZPreference, m.cfg.Zone)
+			endpointIterator, err := EndpointIteratorForRequest(logger, r, m.cfg.StickySessionCookieNames, m.cfg.StickySessionsForAuthNegotiate, m.cfg.StickySessionHeader, m.stickySessionCodec, m.cfg.LoadBalanceAZPreference, m.cfg.Zone)
 			if err != nil {
 				logger.Error("failed-to-find-endpoint-for-req-during-431-short-circuit", log.ErrAttr(err))
 			} else {
@@ -77,7 +77,7 @@ var _ = Describe("MaxRequestSize", func() {
 	JustBeforeEach(func() {
 		logger = test_util.NewTestLogger("test")
 		handler = negroni.New()
-		rh = handlers.NewMaxRequestSize(cfg, logger.Logger)
+		rh = handlers.NewMaxRequestSize(cfg, nil, logger.Logger)
 		handler.Use(rh)
 		handler.Use(nextHandler)
 
@@ -15,6 +15,7 @@ import (
 	"github.com/urfave/negroni/v3"
 
 	"code.cloudfoundry.org/gorouter/common/uuid"
+	"code.cloudfoundry.org/gorouter/config"
 	"code.cloudfoundry.org/gorouter/proxy/utils"
 	"code.cloudfoundry.org/gorouter/route"
 )
@@ -81,6 +82,10 @@ type RequestInfo struct {
 	TraceInfo TraceInfo
 
 	BackendReqHeaders http.Header
+
+	// RoutingRule is the routing rule matching the request, if any. It
+	// selects the endpoints of RoutePool the request prefers.
+	RoutingRule *config.RoutingRule
 }
 
 func (r *RequestInfo) ProvideTraceInfo() (TraceInfo, error) {
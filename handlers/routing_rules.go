@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/urfave/negroni/v3"
+
+	"code.cloudfoundry.org/gorouter/config"
+	log "code.cloudfoundry.org/gorouter/logger"
+)
+
+type routingRules struct {
+	rules  []config.RoutingRule
+	logger *slog.Logger
+}
+
+// NewRoutingRules creates a handler that records the first routing rule
+// matching a request, so that the request prefers the endpoints with the tag
+// selected by the rule. Rules registered for the route are evaluated before
+// the given rules. It must run after the route has been looked up.
+func NewRoutingRules(rules []config.RoutingRule, logger *slog.Logger) negroni.Handler {
+	return &routingRules{
+		rules:  rules,
+		logger: logger,
+	}
+}
+
+func (h *routingRules) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	logger := LoggerWithTraceInfo(h.logger, r)
+	requestInfo, err := ContextRequestInfo(r)
+	if err != nil {
+		log.Panic(logger, "request-info-err", log.ErrAttr(err))
+		return
+	}
+
+	if requestInfo.RoutePool != nil {
+		rule := matchRoutingRule(r, requestInfo.RoutePool.RoutingRules())
+		if rule == nil {
+			rule = matchRoutingRule(r, h.rules)
+		}
+		if rule != nil {
+			logger.Debug("routing-rule-matched",
+				slog.String("tag", rule.Tag),
+				slog.String("tag-value", rule.TagValue))
+			requestInfo.RoutingRule = rule
+		}
+	}
+
+	next(rw, r)
+}
+
+// matchRoutingRule returns the first of rules which matches the request, or
+// nil if none matches.
+func matchRoutingRule(r *http.Request, rules []config.RoutingRule) *config.RoutingRule {
+	for i := range rules {
+		if routingRuleMatches(r, &rules[i]) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+func routingRuleMatches(r *http.Request, rule *config.RoutingRule) bool {
+	var values []string
+	switch {
+	case rule.Header != "":
+		values = r.Header.Values(rule.Header)
+	case rule.Cookie != "":
+		for _, cookie := range r.Cookies() {
+			if cookie.Name == rule.Cookie {
+				values = append(values, cookie.Value)
+			}
+		}
+	case rule.Query != "":
+		values = r.URL.Query()[rule.Query]
+	}
+
+	for _, value := range values {
+		if rule.Value == "" || value == rule.Value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/urfave/negroni/v3"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/handlers"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("RoutingRules", func() {
+	var (
+		handler *negroni.Negroni
+		logger  *test_util.TestLogger
+		req     *http.Request
+		pool    *route.EndpointPool
+		rules   []config.RoutingRule
+
+		matchedRule *config.RoutingRule
+		nextCalled  bool
+	)
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+		pool = route.NewPool(&route.PoolOpts{Logger: logger.Logger})
+		rules = []config.RoutingRule{
+			{Header: "X-Canary", Value: "true", Tag: "canary"},
+			{Cookie: "beta", Value: "1", Tag: "track", TagValue: "beta"},
+			{Query: "preview", Tag: "preview"},
+		}
+		req = test_util.NewRequest("GET", "example.com", "/", nil)
+		matchedRule = nil
+		nextCalled = false
+	})
+
+	JustBeforeEach(func() {
+		handler = negroni.New()
+		handler.Use(handlers.NewRequestInfo())
+		handler.UseFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+			reqInfo, err := handlers.ContextRequestInfo(r)
+			Expect(err).NotTo(HaveOccurred())
+			reqInfo.RoutePool = pool
+			next(rw, r)
+		})
+		handler.Use(handlers.NewRoutingRules(rules, logger.Logger))
+		handler.UseHandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			reqInfo, err := handlers.ContextRequestInfo(r)
+			Expect(err).NotTo(HaveOccurred())
+			matchedRule = reqInfo.RoutingRule
+			nextCalled = true
+		})
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		Expect(nextCalled).To(BeTrue())
+	})
+
+	Context("when no rule matches", func() {
+		BeforeEach(func() {
+			req.Header.Set("X-Canary", "false")
+		})
+
+		It("does not set a routing rule", func() {
+			Expect(matchedRule).To(BeNil())
+		})
+	})
+
+	Context("when a header matches", func() {
+		BeforeEach(func() {
+			req.Header.Set("X-Canary", "true")
+		})
+
+		It("sets the matching rule", func() {
+			Expect(matchedRule).To(Equal(&rules[0]))
+		})
+	})
+
+	Context("when a cookie matches", func() {
+		BeforeEach(func() {
+			req.AddCookie(&http.Cookie{Name: "beta", Value: "1"})
+		})
+
+		It("sets the matching rule", func() {
+			Expect(matchedRule).To(Equal(&rules[1]))
+		})
+	})
+
+	Context("when a query parameter is present and the rule matches any value", func() {
+		BeforeEach(func() {
+			req.URL.RawQuery = "preview=anything"
+		})
+
+		It("sets the matching rule", func() {
+			Expect(matchedRule).To(Equal(&rules[2]))
+		})
+	})
+
+	Context("when several rules match", func() {
+		BeforeEach(func() {
+			req.URL.RawQuery = "preview=yes"
+			req.Header.Set("X-Canary", "true")
+		})
+
+		It("sets the first matching rule", func() {
+			Expect(matchedRule).To(Equal(&rules[0]))
+		})
+	})
+
+	Context("when the route has registered routing rules", func() {
+		BeforeEach(func() {
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{
+				Host: "1.1.1.1",
+				Port: 1111,
+				RoutingRules: []config.RoutingRule{
+					{Header: "X-Canary", Value: "true", Tag: "route-canary"},
+				},
+			}))
+			req.Header.Set("X-Canary", "true")
+		})
+
+		It("prefers the rules of the route", func() {
+			Expect(matchedRule).NotTo(BeNil())
+			Expect(matchedRule.Tag).To(Equal("route-canary"))
+		})
+	})
+})
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/base64"
+
+	"code.cloudfoundry.org/gorouter/common/secure"
+	"code.cloudfoundry.org/gorouter/config"
+)
+
+// StickySessionCodec encrypts the ID of the instance a client is pinned to
+// before it is written into the __VCAP_ID__ cookie, so that clients can
+// neither learn instance IDs nor choose an instance themselves. Values
+// encrypted with a decrypt-only secret are still accepted, which allows
+// rotating the secret. A nil StickySessionCodec writes and reads plain
+// instance IDs.
+type StickySessionCodec struct {
+	// cryptos holds the crypto used for encryption first, followed by the
+	// ones which are only used for decryption
+	cryptos []*secure.AesGCM
+}
+
+// NewStickySessionCodec creates a codec from the given configuration. It
+// returns nil if sticky session cookies are not encrypted.
+func NewStickySessionCodec(cfg config.StickySessionCookieEncryptionConfig) (*StickySessionCodec, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	codec := &StickySessionCodec{}
+	for _, secret := range append([]string{cfg.Secret}, cfg.DecryptOnlySecrets...) {
+		// generate secure encryption key using key derivation function (pbkdf2)
+		crypto, err := secure.NewAesGCM(secure.NewPbkdf2([]byte(secret), 16))
+		if err != nil {
+			return nil, err
+		}
+		codec.cryptos = append(codec.cryptos, crypto)
+	}
+	return codec, nil
+}
+
+// Encode returns the cookie value for the given instance ID.
+func (c *StickySessionCodec) Encode(instanceID string) (string, error) {
+	if c == nil {
+		return instanceID, nil
+	}
+	cipherText, nonce, err := c.cryptos[0].Encrypt([]byte(instanceID))
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(append(nonce, cipherText...)), nil
+}
+
+// Decode returns the instance ID of the given cookie value. It reports false
+// if the value was not encrypted with one of the secrets of the codec.
+func (c *StickySessionCodec) Decode(value string) (string, bool) {
+	if c == nil {
+		return value, true
+	}
+	instanceID, _, ok := c.decode(value)
+	return instanceID, ok
+}
+
+// IsCurrent reports whether the given cookie value was encrypted with the
+// secret used for encryption, rather than with a decrypt-only secret or none
+// at all. It always reports true for a nil codec.
+func (c *StickySessionCodec) IsCurrent(value string) bool {
+	if c == nil {
+		return true
+	}
+	_, i, ok := c.decode(value)
+	return ok && i == 0
+}
+
+// decode returns the instance ID of the given cookie value and the index of
+// the crypto which decrypted it.
+func (c *StickySessionCodec) decode(value string) (string, int, bool) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", 0, false
+	}
+	for i, crypto := range c.cryptos {
+		if len(b) < crypto.NonceSize() {
+			continue
+		}
+		instanceID, err := crypto.Decrypt(b[crypto.NonceSize():], b[:crypto.NonceSize()])
+		if err == nil {
+			return string(instanceID), i, true
+		}
+	}
+	return "", 0, false
+}
@@ -0,0 +1,190 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/handlers"
+)
+
+var _ = Describe("StickySessionCodec", func() {
+	var codec *handlers.StickySessionCodec
+
+	newCodec := func(secret string, decryptOnlySecrets ...string) *handlers.StickySessionCodec {
+		c, err := handlers.NewStickySessionCodec(config.StickySessionCookieEncryptionConfig{
+			Enabled:            true,
+			Secret:             secret,
+			DecryptOnlySecrets: decryptOnlySecrets,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return c
+	}
+
+	BeforeEach(func() {
+		codec = newCodec("new-secret", "old-secret")
+	})
+
+	It("returns no codec when encryption is disabled", func() {
+		c, err := handlers.NewStickySessionCodec(config.StickySessionCookieEncryptionConfig{Secret: "secret"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c).To(BeNil())
+	})
+
+	It("encrypts and decrypts instance IDs", func() {
+		value, err := codec.Encode("some-instance-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).NotTo(ContainSubstring("some-instance-id"))
+
+		instanceID, ok := codec.Decode(value)
+		Expect(ok).To(BeTrue())
+		Expect(instanceID).To(Equal("some-instance-id"))
+	})
+
+	It("produces a different value every time", func() {
+		first, err := codec.Encode("some-instance-id")
+		Expect(err).NotTo(HaveOccurred())
+		second, err := codec.Encode("some-instance-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first).NotTo(Equal(second))
+	})
+
+	It("decrypts values encrypted with a decrypt-only secret", func() {
+		value, err := newCodec("old-secret").Encode("some-instance-id")
+		Expect(err).NotTo(HaveOccurred())
+
+		instanceID, ok := codec.Decode(value)
+		Expect(ok).To(BeTrue())
+		Expect(instanceID).To(Equal("some-instance-id"))
+	})
+
+	It("rejects values encrypted with an unknown secret", func() {
+		value, err := newCodec("other-secret").Encode("some-instance-id")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, ok := codec.Decode(value)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("rejects values which were tampered with", func() {
+		value, err := codec.Encode("some-instance-id")
+		Expect(err).NotTo(HaveOccurred())
+		tampered := []byte(value)
+		tampered[len(tampered)-1] ^= 1
+
+		_, ok := codec.Decode(string(tampered))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("reports whether values were encrypted with the current secret", func() {
+		current, err := codec.Encode("some-instance-id")
+		Expect(err).NotTo(HaveOccurred())
+		old, err := newCodec("old-secret").Encode("some-instance-id")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(codec.IsCurrent(current)).To(BeTrue())
+		Expect(codec.IsCurrent(old)).To(BeFalse())
+		Expect(codec.IsCurrent("some-instance-id")).To(BeFalse())
+	})
+
+	It("rejects plain instance IDs", func() {
+		for _, value := range []string{"some-instance-id", "", "a"} {
+			_, ok := codec.Decode(value)
+			Expect(ok).To(BeFalse())
+		}
+	})
+
+	Context("when the codec is nil", func() {
+		It("uses plain instance IDs", func() {
+			var nilCodec *handlers.StickySessionCodec
+			value, err := nilCodec.Encode("some-instance-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(value).To(Equal("some-instance-id"))
+
+			instanceID, ok := nilCodec.Decode(value)
+			Expect(ok).To(BeTrue())
+			Expect(instanceID).To(Equal("some-instance-id"))
+		})
+	})
+
+	Describe("GetStickySession", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			req = httptest.NewRequest("GET", "http://example.com", nil)
+			req.AddCookie(&http.Cookie{Name: "JSESSIONID", Value: "some-session"})
+		})
+
+		It("returns the decrypted instance ID", func() {
+			value, err := codec.Encode("some-instance-id")
+			Expect(err).NotTo(HaveOccurred())
+			req.AddCookie(&http.Cookie{Name: handlers.VcapCookieId, Value: value})
+
+			instanceID, mustBeSticky := handlers.GetStickySession(req, config.StringSet{"JSESSIONID": struct{}{}}, false, "", codec)
+			Expect(instanceID).To(Equal("some-instance-id"))
+			Expect(mustBeSticky).To(BeFalse())
+		})
+
+		It("ignores a cookie which fails validation", func() {
+			req.AddCookie(&http.Cookie{Name: handlers.VcapCookieId, Value: "some-instance-id"})
+
+			instanceID, mustBeSticky := handlers.GetStickySession(req, config.StringSet{"JSESSIONID": struct{}{}}, false, "", codec)
+			Expect(instanceID).To(BeEmpty())
+			Expect(mustBeSticky).To(BeFalse())
+		})
+
+		Context("with a sticky session header", func() {
+			BeforeEach(func() {
+				req = httptest.NewRequest("GET", "http://example.com", nil)
+			})
+
+			It("returns the instance ID of the header", func() {
+				value, err := codec.Encode("some-instance-id")
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Set("X-CF-Session-Affinity", value)
+
+				instanceID, mustBeSticky := handlers.GetStickySession(req, config.StringSet{"JSESSIONID": struct{}{}}, false, "X-CF-Session-Affinity", codec)
+				Expect(instanceID).To(Equal("some-instance-id"))
+				Expect(mustBeSticky).To(BeFalse())
+			})
+
+			It("ignores a header which fails validation", func() {
+				req.Header.Set("X-CF-Session-Affinity", "some-instance-id")
+
+				instanceID, _ := handlers.GetStickySession(req, config.StringSet{"JSESSIONID": struct{}{}}, false, "X-CF-Session-Affinity", codec)
+				Expect(instanceID).To(BeEmpty())
+			})
+
+			It("ignores the header when it is not configured", func() {
+				req.Header.Set("X-CF-Session-Affinity", "some-instance-id")
+
+				instanceID, _ := handlers.GetStickySession(req, config.StringSet{"JSESSIONID": struct{}{}}, false, "", nil)
+				Expect(instanceID).To(BeEmpty())
+			})
+
+			It("falls back to the header if the __VCAP_ID__ cookie fails validation", func() {
+				value, err := codec.Encode("header-instance-id")
+				Expect(err).NotTo(HaveOccurred())
+				req.AddCookie(&http.Cookie{Name: "JSESSIONID", Value: "some-session"})
+				req.AddCookie(&http.Cookie{Name: handlers.VcapCookieId, Value: "tampered"})
+				req.Header.Set("X-CF-Session-Affinity", value)
+
+				instanceID, mustBeSticky := handlers.GetStickySession(req, config.StringSet{"JSESSIONID": struct{}{}}, false, "X-CF-Session-Affinity", codec)
+				Expect(instanceID).To(Equal("header-instance-id"))
+				Expect(mustBeSticky).To(BeFalse())
+			})
+
+			It("prefers the __VCAP_ID__ cookie", func() {
+				req.AddCookie(&http.Cookie{Name: "JSESSIONID", Value: "some-session"})
+				req.AddCookie(&http.Cookie{Name: handlers.VcapCookieId, Value: "cookie-instance-id"})
+				req.Header.Set("X-CF-Session-Affinity", "header-instance-id")
+
+				instanceID, _ := handlers.GetStickySession(req, config.StringSet{"JSESSIONID": struct{}{}}, false, "X-CF-Session-Affinity", nil)
+				Expect(instanceID).To(Equal("cookie-instance-id"))
+			})
+		})
+	})
+})
@@ -0,0 +1,265 @@
+package healthchecker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"code.cloudfoundry.org/clock"
+
+	"code.cloudfoundry.org/gorouter/config"
+	log "code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+const userAgent = "gorouter-health-checker"
+
+// PoolSource provides the pools whose endpoints are health checked.
+type PoolSource interface {
+	EachPool(f func(pool *route.EndpointPool))
+}
+
+// HealthChecker periodically probes the endpoints of all pools and marks
+// those which fail their probes as unhealthy, so that the load balancing
+// algorithms skip them. An endpoint which is registered for several routes is
+// only probed once per interval for every distinct probe path.
+type HealthChecker struct {
+	logger    *slog.Logger
+	config    config.ActiveHealthCheckConfig
+	pools     PoolSource
+	tlsConfig *tls.Config
+	client    *http.Client
+	clock     clock.Clock
+
+	// states is only accessed by CheckAll, which never runs concurrently.
+	states map[target]*state
+}
+
+// target is a distinct probe. Endpoints with the same target share the
+// result of the probe.
+type target struct {
+	addr       string
+	tls        bool
+	serverName string
+	path       string
+}
+
+type state struct {
+	healthy   bool
+	successes int
+	failures  int
+}
+
+type member struct {
+	pool     *route.EndpointPool
+	endpoint *route.Endpoint
+}
+
+func NewHealthChecker(
+	logger *slog.Logger,
+	cfg *config.Config,
+	pools PoolSource,
+	tlsConfig *tls.Config,
+	clock clock.Clock,
+) *HealthChecker {
+	return &HealthChecker{
+		logger:    logger,
+		config:    cfg.ActiveHealthCheck,
+		pools:     pools,
+		tlsConfig: tlsConfig,
+		client: &http.Client{
+			Timeout: cfg.ActiveHealthCheck.Timeout,
+			Transport: &http.Transport{
+				DisableKeepAlives: true,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		clock:  clock,
+		states: make(map[target]*state),
+	}
+}
+
+func (h *HealthChecker) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ticker := h.clock.NewTicker(h.config.Interval)
+	h.logger.Info("health-checker-started",
+		slog.Duration("interval", h.config.Interval),
+		slog.Int("max-concurrency", h.config.MaxConcurrency))
+
+	close(ready)
+
+	checking := false
+	finished := make(chan struct{}, 1)
+	for {
+		select {
+		case <-ticker.C():
+			if checking {
+				h.logger.Info("health-check-cycle-still-running")
+				continue
+			}
+			checking = true
+			go func() {
+				h.CheckAll()
+				finished <- struct{}{}
+			}()
+		case <-finished:
+			checking = false
+		case <-signals:
+			h.logger.Info("stopping")
+			ticker.Stop()
+			return nil
+		}
+	}
+}
+
+// CheckAll probes the endpoints of all pools once and updates their health.
+// No more than the configured maximum number of probes are in flight at the
+// same time.
+func (h *HealthChecker) CheckAll() {
+	members := make(map[target][]member)
+	h.pools.EachPool(func(pool *route.EndpointPool) {
+		pool.Each(func(endpoint *route.Endpoint) {
+			t := h.target(endpoint)
+			members[t] = append(members[t], member{pool: pool, endpoint: endpoint})
+		})
+	})
+
+	targets := make([]target, 0, len(members))
+	for t := range members {
+		targets = append(targets, t)
+	}
+	results := h.probeAll(targets)
+
+	for i, t := range targets {
+		s := h.states[t]
+		if s == nil {
+			s = &state{healthy: true}
+			h.states[t] = s
+		}
+		s.update(results[i], h.config)
+
+		for _, m := range members[t] {
+			m.pool.SetEndpointHealthy(m.endpoint, s.healthy)
+		}
+	}
+
+	// forget endpoints which were unregistered
+	for t := range h.states {
+		if _, found := members[t]; !found {
+			delete(h.states, t)
+		}
+	}
+}
+
+func (h *HealthChecker) target(endpoint *route.Endpoint) target {
+	path := endpoint.HealthCheckPath
+	if path == "" {
+		path = h.config.Path
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	return target{
+		addr:       endpoint.CanonicalAddr(),
+		tls:        endpoint.IsTLS(),
+		serverName: endpoint.ServerCertDomainSAN,
+		path:       path,
+	}
+}
+
+func (h *HealthChecker) probeAll(targets []target) []bool {
+	results := make([]bool, len(targets))
+	work := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < min(h.config.MaxConcurrency, len(targets)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range work {
+				results[j] = h.probe(targets[j])
+			}
+		}()
+	}
+
+	for i := range targets {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	return results
+}
+
+// probe reports whether the target responds to a GET request on its probe
+// path with a 2xx or 3xx status code within the configured timeout.
+func (h *HealthChecker) probe(t target) bool {
+	scheme := "http"
+	client := h.client
+	if t.tls {
+		scheme = "https"
+		tlsConfig := &tls.Config{}
+		if h.tlsConfig != nil {
+			tlsConfig = h.tlsConfig.Clone()
+		}
+		tlsConfig.ServerName = t.serverName
+		client = &http.Client{
+			Timeout: h.client.Timeout,
+			Transport: &http.Transport{
+				DisableKeepAlives: true,
+				TLSClientConfig:   tlsConfig,
+			},
+			CheckRedirect: h.client.CheckRedirect,
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	url := fmt.Sprintf("%s://%s%s", scheme, t.addr, t.path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		h.logger.Error("health-check-request-failed", slog.String("url", url), log.ErrAttr(err))
+		return false
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	res, err := client.Do(req)
+	if err != nil {
+		h.logger.Debug("health-check-failed", slog.String("url", url), log.ErrAttr(err))
+		return false
+	}
+	res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
+		h.logger.Debug("health-check-failed", slog.String("url", url), slog.Int("status-code", res.StatusCode))
+		return false
+	}
+	return true
+}
+
+// update applies the result of a probe. The health of an endpoint only
+// changes after the configured number of consecutive probes agree.
+func (s *state) update(success bool, cfg config.ActiveHealthCheckConfig) {
+	if success {
+		s.successes++
+		s.failures = 0
+		if !s.healthy && s.successes >= cfg.HealthyThreshold {
+			s.healthy = true
+		}
+		return
+	}
+
+	s.failures++
+	s.successes = 0
+	if s.healthy && s.failures >= cfg.UnhealthyThreshold {
+		s.healthy = false
+	}
+}
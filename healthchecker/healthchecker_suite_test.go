@@ -0,0 +1,13 @@
+package healthchecker_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestHealthChecker(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "HealthChecker Suite")
+}
@@ -0,0 +1,275 @@
+package healthchecker_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+
+	"code.cloudfoundry.org/gorouter/config"
+	. "code.cloudfoundry.org/gorouter/healthchecker"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+type fakePoolSource struct {
+	pools []*route.EndpointPool
+}
+
+func (f *fakePoolSource) EachPool(fn func(pool *route.EndpointPool)) {
+	for _, pool := range f.pools {
+		fn(pool)
+	}
+}
+
+var _ = Describe("HealthChecker", func() {
+	var (
+		cfg       *config.Config
+		logger    *test_util.TestLogger
+		pool      *route.EndpointPool
+		source    *fakePoolSource
+		checker   *HealthChecker
+		clock     *fakeclock.FakeClock
+		tlsConfig *tls.Config
+
+		healthyServer, unhealthyServer *httptest.Server
+		healthy, unhealthy             *route.Endpoint
+
+		probedPaths     *sync.Map
+		probes          atomic.Int32
+		unhealthyStatus atomic.Int32
+		unhealthyDelay  atomic.Int64
+	)
+
+	endpointFor := func(server *httptest.Server, opts route.EndpointOpts) *route.Endpoint {
+		host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		port, err := strconv.Atoi(portStr)
+		Expect(err).NotTo(HaveOccurred())
+
+		opts.Host = host
+		opts.Port = uint16(port)
+		return route.NewEndpoint(&opts)
+	}
+
+	selected := func(requests int) map[*route.Endpoint]int {
+		counts := make(map[*route.Endpoint]int)
+		for i := 0; i < requests; i++ {
+			iter := pool.Endpoints(logger.Logger, "", false, config.AZ_PREF_NONE, "", "", nil)
+			counts[iter.Next(0)]++
+		}
+		return counts
+	}
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+		var err error
+		cfg, err = config.DefaultConfig()
+		Expect(err).ToNot(HaveOccurred())
+		cfg.ActiveHealthCheck.Enabled = true
+		cfg.ActiveHealthCheck.Path = "/health"
+		cfg.ActiveHealthCheck.UnhealthyThreshold = 1
+
+		probedPaths = &sync.Map{}
+		probes.Store(0)
+		unhealthyStatus.Store(http.StatusServiceUnavailable)
+		unhealthyDelay.Store(0)
+
+		healthyServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			probes.Add(1)
+			probedPaths.Store(r.URL.Path, true)
+		}))
+		unhealthyServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(time.Duration(unhealthyDelay.Load()))
+			w.WriteHeader(int(unhealthyStatus.Load()))
+		}))
+
+		pool = route.NewPool(&route.PoolOpts{
+			Logger:                 logger.Logger,
+			RetryAfterFailure:      time.Minute,
+			LoadBalancingAlgorithm: config.LOAD_BALANCE_RR,
+		})
+		healthy = endpointFor(healthyServer, route.EndpointOpts{})
+		unhealthy = endpointFor(unhealthyServer, route.EndpointOpts{})
+		pool.Put(healthy)
+		pool.Put(unhealthy)
+
+		source = &fakePoolSource{pools: []*route.EndpointPool{pool}}
+		clock = fakeclock.NewFakeClock(time.Now())
+		tlsConfig = nil
+	})
+
+	JustBeforeEach(func() {
+		checker = NewHealthChecker(logger.Logger, cfg, source, tlsConfig, clock)
+	})
+
+	AfterEach(func() {
+		healthyServer.Close()
+		unhealthyServer.Close()
+	})
+
+	Describe("CheckAll", func() {
+		It("skips endpoints which fail their health check", func() {
+			checker.CheckAll()
+
+			Expect(selected(10)).To(Equal(map[*route.Endpoint]int{healthy: 10}))
+			Expect(logger).To(gbytes.Say("endpoint-marked-unhealthy"))
+		})
+
+		It("selects endpoints again once they pass their health check", func() {
+			checker.CheckAll()
+			unhealthyStatus.Store(http.StatusOK)
+
+			checker.CheckAll()
+
+			Expect(selected(10)).To(HaveKey(unhealthy))
+			Expect(logger).To(gbytes.Say("endpoint-marked-healthy"))
+		})
+
+		It("treats endpoints which do not respond in time as unhealthy", func() {
+			cfg.ActiveHealthCheck.Timeout = 50 * time.Millisecond
+			unhealthyStatus.Store(http.StatusOK)
+			unhealthyDelay.Store(int64(200 * time.Millisecond))
+			checker = NewHealthChecker(logger.Logger, cfg, source, tlsConfig, clock)
+
+			checker.CheckAll()
+
+			Expect(selected(10)).NotTo(HaveKey(unhealthy))
+		})
+
+		It("uses the health check path of the endpoint", func() {
+			custom := endpointFor(healthyServer, route.EndpointOpts{HealthCheckPath: "/custom"})
+			otherPool := route.NewPool(&route.PoolOpts{Logger: logger.Logger})
+			otherPool.Put(custom)
+			source.pools = append(source.pools, otherPool)
+
+			checker.CheckAll()
+
+			_, found := probedPaths.Load("/custom")
+			Expect(found).To(BeTrue())
+			_, found = probedPaths.Load("/health")
+			Expect(found).To(BeTrue())
+		})
+
+		It("probes an endpoint which is registered for several routes only once", func() {
+			otherPool := route.NewPool(&route.PoolOpts{Logger: logger.Logger})
+			otherPool.Put(endpointFor(healthyServer, route.EndpointOpts{}))
+			source.pools = append(source.pools, otherPool)
+
+			checker.CheckAll()
+
+			Expect(probes.Load()).To(Equal(int32(1)))
+		})
+
+		It("does not run more probes at the same time than configured", func() {
+			cfg.ActiveHealthCheck.MaxConcurrency = 2
+
+			var inFlight, maxInFlight atomic.Int32
+			slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for {
+					m := maxInFlight.Load()
+					if n <= m || maxInFlight.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(20 * time.Millisecond)
+			}))
+			defer slowServer.Close()
+
+			// every pool probes the same endpoint on a different path
+			source.pools = nil
+			for _, path := range []string{"/a", "/b", "/c", "/d", "/e", "/f"} {
+				slowPool := route.NewPool(&route.PoolOpts{Logger: logger.Logger})
+				slowPool.Put(endpointFor(slowServer, route.EndpointOpts{HealthCheckPath: path}))
+				source.pools = append(source.pools, slowPool)
+			}
+			checker = NewHealthChecker(logger.Logger, cfg, source, tlsConfig, clock)
+
+			checker.CheckAll()
+
+			Expect(maxInFlight.Load()).To(BeNumerically("<=", 2))
+		})
+
+		Context("when the unhealthy threshold is greater than one", func() {
+			BeforeEach(func() {
+				cfg.ActiveHealthCheck.UnhealthyThreshold = 2
+			})
+
+			It("marks the endpoint unhealthy after consecutive failures only", func() {
+				checker.CheckAll()
+				Expect(selected(10)).To(HaveKey(unhealthy))
+
+				checker.CheckAll()
+				Expect(selected(10)).NotTo(HaveKey(unhealthy))
+			})
+		})
+
+		Context("when all endpoints are unhealthy", func() {
+			BeforeEach(func() {
+				Expect(pool.Remove(healthy)).To(BeTrue())
+			})
+
+			It("still selects them", func() {
+				checker.CheckAll()
+
+				Expect(selected(10)).To(HaveKey(unhealthy))
+			})
+		})
+
+		Context("when the endpoint uses TLS", func() {
+			var (
+				tlsServer   *httptest.Server
+				tlsEndpoint *route.Endpoint
+			)
+
+			BeforeEach(func() {
+				tlsServer = httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+				certPool := x509.NewCertPool()
+				certPool.AddCert(tlsServer.Certificate())
+				tlsConfig = &tls.Config{RootCAs: certPool}
+
+				tlsEndpoint = endpointFor(tlsServer, route.EndpointOpts{UseTLS: true, ServerCertDomainSAN: "example.com"})
+				pool.Put(tlsEndpoint)
+			})
+
+			AfterEach(func() {
+				tlsServer.Close()
+			})
+
+			It("probes the endpoint over TLS", func() {
+				checker.CheckAll()
+
+				Expect(selected(10)).To(HaveKey(tlsEndpoint))
+			})
+		})
+	})
+
+	Describe("Run", func() {
+		It("checks the endpoints every interval until it is signalled", func() {
+			process := ifrit.Invoke(checker)
+			Eventually(process.Ready()).Should(BeClosed())
+
+			Eventually(func() map[*route.Endpoint]int {
+				clock.Increment(cfg.ActiveHealthCheck.Interval)
+				return selected(10)
+			}).ShouldNot(HaveKey(unhealthy))
+
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+		})
+	})
+})
@@ -31,6 +31,7 @@ import (
 	"code.cloudfoundry.org/gorouter/common/secure"
 	"code.cloudfoundry.org/gorouter/config"
 	"code.cloudfoundry.org/gorouter/errorwriter"
+	"code.cloudfoundry.org/gorouter/healthchecker"
 	grlog "code.cloudfoundry.org/gorouter/logger"
 	"code.cloudfoundry.org/gorouter/mbus"
 	"code.cloudfoundry.org/gorouter/metrics"
@@ -40,6 +41,7 @@ import (
 	"code.cloudfoundry.org/gorouter/route_fetcher"
 	"code.cloudfoundry.org/gorouter/router"
 	"code.cloudfoundry.org/gorouter/routeservice"
+	"code.cloudfoundry.org/gorouter/static_routes"
 	rvarz "code.cloudfoundry.org/gorouter/varz"
 	routing_api "code.cloudfoundry.org/routing-api"
 	"code.cloudfoundry.org/routing-api/uaaclient"
@@ -138,6 +140,9 @@ func main() {
 	metricReporter := metrics.NewMultiMetricReporter(reporters...)
 
 	registry := rregistry.NewRouteRegistry(grlog.CreateLoggerWithSource(prefix, "registry"), c, metricReporter)
+	if err := registry.LoadSnapshot(); err != nil {
+		logger.Error("routing-table-snapshot-load-failed", grlog.ErrAttr(err))
+	}
 	varz := rvarz.NewVarz(registry)
 	compositeReporter := &metrics.CompositeReporter{VarzReporter: varz, MetricReporter: metricReporter}
 
@@ -237,9 +242,17 @@ func main() {
 
 	members := grouper.Members{}
 
-	if c.RoutingApiEnabled() {
-		routeFetcher := setupRouteFetcher(grlog.CreateLoggerWithSource(prefix, "route-fetcher"), c, registry, routingAPIClient)
-		members = append(members, grouper.Member{Name: "router-fetcher", Runner: routeFetcher})
+	members = append(members, setupRouteSources(prefix, c, registry, routingAPIClient)...)
+
+	if c.ActiveHealthCheck.Enabled {
+		healthChecker := healthchecker.NewHealthChecker(
+			grlog.CreateLoggerWithSource(prefix, "health-checker"),
+			c,
+			registry,
+			backendTLSConfig,
+			clock.NewClock(),
+		)
+		members = append(members, grouper.Member{Name: "health-checker", Runner: healthChecker})
 	}
 
 	subscriber := mbus.NewSubscriber(natsClient, registry, c, natsReconnected, grlog.CreateLoggerWithSource(prefix, "subscriber"))
@@ -394,6 +407,24 @@ func setupRoutingAPIClient(logger *slog.Logger, c *config.Config) (routing_api.C
 	return client, nil
 }
 
+// setupRouteSources returns the members running the route sources enabled by
+// the config, in the order in which they are started. NATS is always a route
+// source and is started separately, after the file descriptor monitor.
+func setupRouteSources(prefix string, c *config.Config, registry rregistry.Registry, routingAPIClient routing_api.Client) grouper.Members {
+	members := grouper.Members{}
+
+	if c.RoutingApiEnabled() {
+		routeFetcher := setupRouteFetcher(grlog.CreateLoggerWithSource(prefix, "route-fetcher"), c, registry, routingAPIClient)
+		members = append(members, grouper.Member{Name: "router-fetcher", Runner: routeFetcher})
+	}
+	if c.StaticRoutes.Enabled() {
+		staticRoutes := static_routes.NewStaticRoutes(grlog.CreateLoggerWithSource(prefix, "static-routes"), registry, c, clock.NewClock())
+		members = append(members, grouper.Member{Name: "static-routes", Runner: staticRoutes})
+	}
+
+	return members
+}
+
 func setupRouteFetcher(logger *slog.Logger, c *config.Config, registry rregistry.Registry, routingAPIClient routing_api.Client) *route_fetcher.RouteFetcher {
 	cl := clock.NewClock()
 
@@ -18,6 +18,7 @@ import (
 	log "code.cloudfoundry.org/gorouter/logger"
 	"code.cloudfoundry.org/gorouter/registry"
 	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/routesource"
 	"code.cloudfoundry.org/routing-api/models"
 )
 
@@ -41,10 +42,26 @@ type RegistryMessage struct {
 }
 
 type RegistryMessageOpts struct {
-	LoadBalancingAlgorithm string `json:"loadbalancing"`
+	LoadBalancingAlgorithm     string               `json:"loadbalancing"`
+	Weight                     int                  `json:"weight"`
+	HashKey                    string               `json:"hash_key"`
+	SlowStartDurationInSeconds int                  `json:"slow_start_duration_in_seconds"`
+	HealthCheckPath            string               `json:"health_check_path"`
+	RequestTimeout             string               `json:"request_timeout"`
+	DialTimeout                string               `json:"dial_timeout"`
+	MaxAttempts                int                  `json:"max_attempts"`
+	TrafficWeight              int                  `json:"traffic_weight"`
+	RoutingRules               []config.RoutingRule `json:"routing_rules"`
+	ShadowRoute                string               `json:"shadow_route"`
+	ShadowPercentage           float64              `json:"shadow_percentage"`
+	RetryStatusCodes           []int                `json:"retry_status_codes"`
+	HedgeDelay                 string               `json:"hedge_delay"`
 }
 
-func (rm *RegistryMessage) makeEndpoint(http2Enabled bool) (*route.Endpoint, error) {
+// MakeEndpoint returns the endpoint described by the registry message. Invalid
+// options are left out of the endpoint and passed to invalidOption, so that an
+// endpoint can be registered and unregistered whatever its options.
+func (rm *RegistryMessage) MakeEndpoint(http2Enabled bool, invalidOption func(error)) (*route.Endpoint, error) {
 	port, useTLS, err := rm.port()
 	if err != nil {
 		return nil, err
@@ -59,26 +76,88 @@ func (rm *RegistryMessage) makeEndpoint(http2Enabled bool) (*route.Endpoint, err
 		protocol = "http1"
 	}
 
+	durationOption := func(name, value string) time.Duration {
+		d, err := parseDurationOption(name, value)
+		if err != nil {
+			invalidOption(err)
+		}
+		return d
+	}
+
+	requestTimeout := durationOption("request_timeout", rm.Options.RequestTimeout)
+	dialTimeout := durationOption("dial_timeout", rm.Options.DialTimeout)
+	// an invalid hedge delay disables hedging for the endpoint
+	hedgeDelay := durationOption("hedge_delay", rm.Options.HedgeDelay)
+	// an invalid routing rule disables routing by rules for the endpoint, and
+	// an invalid shadow percentage disables its shadow route
+	routingRules := rm.Options.RoutingRules
+	for _, rule := range routingRules {
+		if err := rule.Validate(); err != nil {
+			invalidOption(fmt.Errorf("invalid routing_rules option: %s", err))
+			routingRules = nil
+			break
+		}
+	}
+	shadowRoute, shadowPercentage := rm.Options.ShadowRoute, rm.Options.ShadowPercentage
+	if shadowPercentage < 0 || shadowPercentage > 100 {
+		invalidOption(fmt.Errorf("invalid shadow_percentage option: %v", shadowPercentage))
+		shadowRoute, shadowPercentage = "", 0
+	}
+	var retryStatusCodes []int
+	for _, code := range rm.Options.RetryStatusCodes {
+		if code < 100 || code > 599 {
+			invalidOption(fmt.Errorf("invalid retry_status_codes option: %d", code))
+			continue
+		}
+		retryStatusCodes = append(retryStatusCodes, code)
+	}
+
 	return route.NewEndpoint(&route.EndpointOpts{
-		AppId:                   rm.App,
-		AvailabilityZone:        rm.AvailabilityZone,
-		Host:                    rm.Host,
-		Port:                    port,
-		Protocol:                protocol,
-		ServerCertDomainSAN:     rm.ServerCertDomainSAN,
-		PrivateInstanceId:       rm.PrivateInstanceID,
-		PrivateInstanceIndex:    rm.PrivateInstanceIndex,
-		Tags:                    rm.Tags,
-		StaleThresholdInSeconds: rm.StaleThresholdInSeconds,
-		RouteServiceUrl:         rm.RouteServiceURL,
-		ModificationTag:         models.ModificationTag{},
-		IsolationSegment:        rm.IsolationSegment,
-		UseTLS:                  useTLS,
-		UpdatedAt:               updatedAt,
-		LoadBalancingAlgorithm:  rm.Options.LoadBalancingAlgorithm,
+		AppId:                      rm.App,
+		AvailabilityZone:           rm.AvailabilityZone,
+		Host:                       rm.Host,
+		Port:                       port,
+		Protocol:                   protocol,
+		ServerCertDomainSAN:        rm.ServerCertDomainSAN,
+		PrivateInstanceId:          rm.PrivateInstanceID,
+		PrivateInstanceIndex:       rm.PrivateInstanceIndex,
+		Tags:                       rm.Tags,
+		StaleThresholdInSeconds:    rm.StaleThresholdInSeconds,
+		RouteServiceUrl:            rm.RouteServiceURL,
+		ModificationTag:            models.ModificationTag{},
+		IsolationSegment:           rm.IsolationSegment,
+		UseTLS:                     useTLS,
+		UpdatedAt:                  updatedAt,
+		LoadBalancingAlgorithm:     rm.Options.LoadBalancingAlgorithm,
+		Weight:                     rm.Options.Weight,
+		HashKey:                    rm.Options.HashKey,
+		SlowStartDurationInSeconds: rm.Options.SlowStartDurationInSeconds,
+		HealthCheckPath:            rm.Options.HealthCheckPath,
+		RequestTimeout:             requestTimeout,
+		DialTimeout:                dialTimeout,
+		MaxAttempts:                rm.Options.MaxAttempts,
+		TrafficWeight:              rm.Options.TrafficWeight,
+		RoutingRules:               routingRules,
+		ShadowRoute:                shadowRoute,
+		ShadowPercentage:           shadowPercentage,
+		RetryStatusCodes:           retryStatusCodes,
+		HedgeDelay:                 hedgeDelay,
 	}), nil
 }
 
+// parseDurationOption parses an optional duration such as "30s" from the
+// options of a registry message. An empty value results in zero.
+func parseDurationOption(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s option: %q", name, value)
+	}
+	return d, nil
+}
+
 // ValidateMessage checks to ensure the registry message is valid
 func (rm *RegistryMessage) ValidateMessage() bool {
 	return rm.RouteServiceURL == "" || strings.HasPrefix(rm.RouteServiceURL, "https")
@@ -95,7 +174,7 @@ func (rm *RegistryMessage) port() (uint16, bool, error) {
 // Subscriber subscribes to NATS for all router.* messages and handles them
 type Subscriber struct {
 	mbusClient       Client
-	routeRegistry    registry.Registry
+	routes           *routesource.Handler
 	subscription     *nats.Subscription
 	reconnected      <-chan Signal
 	natsPendingLimit int
@@ -126,8 +205,8 @@ func NewSubscriber(
 	}
 
 	return &Subscriber{
-		mbusClient:    mbusClient,
-		routeRegistry: routeRegistry,
+		mbusClient: mbusClient,
+		routes:     routesource.NewHandler("nats", routeRegistry),
 		params: startMessageParams{
 			id:                               fmt.Sprintf("%d-%s", c.Index, guid),
 			minimumRegisterIntervalInSeconds: int(c.StartResponseDelayInterval.Seconds()),
@@ -140,6 +219,13 @@ func NewSubscriber(
 	}
 }
 
+var _ routesource.RouteSource = &Subscriber{}
+
+// Name identifies NATS as the route source of the subscriber
+func (s *Subscriber) Name() string {
+	return s.routes.Source()
+}
+
 // Run manages the lifecycle of the subscriber process
 func (s *Subscriber) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 	s.logger.Info("subscriber-starting")
@@ -239,7 +325,7 @@ func (s *Subscriber) subscribeRoutes() (*nats.Subscription, error) {
 }
 
 func (s *Subscriber) registerEndpoint(msg *RegistryMessage) {
-	endpoint, err := msg.makeEndpoint(s.http2Enabled)
+	endpoint, err := msg.MakeEndpoint(s.http2Enabled, s.invalidOption(msg))
 	if err != nil {
 		s.logger.Error("Unable to register route",
 			log.ErrAttr(err),
@@ -249,12 +335,12 @@ func (s *Subscriber) registerEndpoint(msg *RegistryMessage) {
 	}
 
 	for _, uri := range msg.Uris {
-		s.routeRegistry.Register(uri, endpoint)
+		s.routes.Handle(routesource.Event{Type: routesource.RegisterEvent, Uri: uri, Endpoint: endpoint})
 	}
 }
 
 func (s *Subscriber) unregisterEndpoint(msg *RegistryMessage) {
-	endpoint, err := msg.makeEndpoint(s.http2Enabled)
+	endpoint, err := msg.MakeEndpoint(s.http2Enabled, s.invalidOption(msg))
 	if err != nil {
 		s.logger.Error("Unable to unregister route",
 			log.ErrAttr(err),
@@ -263,7 +349,18 @@ func (s *Subscriber) unregisterEndpoint(msg *RegistryMessage) {
 		return
 	}
 	for _, uri := range msg.Uris {
-		s.routeRegistry.Unregister(uri, endpoint)
+		s.routes.Handle(routesource.Event{Type: routesource.UnregisterEvent, Uri: uri, Endpoint: endpoint})
+	}
+}
+
+// invalidOption logs an option of msg which is ignored because it is invalid.
+func (s *Subscriber) invalidOption(msg *RegistryMessage) func(error) {
+	return func(err error) {
+		s.logger.Warn("invalid-route-option-ignored",
+			log.ErrAttr(err),
+			slog.Any("uris", msg.Uris),
+			slog.String("host", msg.Host),
+		)
 	}
 }
 
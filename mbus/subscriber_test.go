@@ -10,6 +10,7 @@ import (
 	"github.com/nats-io/nats.go"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	"github.com/tedsuo/ifrit"
 
 	"code.cloudfoundry.org/gorouter/common"
@@ -372,6 +373,7 @@ var _ = Describe("Subscriber", func() {
 			Eventually(registry.RegisterCallCount).Should(Equal(1))
 			_, originalEndpoint := registry.RegisterArgsForCall(0)
 			expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+				Source:           "nats",
 				Host:             "host",
 				AppId:            "app",
 				Protocol:         "http1",
@@ -405,6 +407,7 @@ var _ = Describe("Subscriber", func() {
 			Eventually(registry.RegisterCallCount).Should(Equal(1))
 			_, originalEndpoint := registry.RegisterArgsForCall(0)
 			expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+				Source:   "nats",
 				Host:     "host",
 				AppId:    "app",
 				Protocol: "http1",
@@ -436,6 +439,7 @@ var _ = Describe("Subscriber", func() {
 			Eventually(registry.RegisterCallCount).Should(Equal(1))
 			_, originalEndpoint := registry.RegisterArgsForCall(0)
 			expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+				Source:   "nats",
 				Host:     "host",
 				AppId:    "app",
 				Protocol: "http1",
@@ -468,6 +472,7 @@ var _ = Describe("Subscriber", func() {
 			Eventually(registry.RegisterCallCount).Should(Equal(1))
 			_, originalEndpoint := registry.RegisterArgsForCall(0)
 			expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+				Source:   "nats",
 				Host:     "host",
 				AppId:    "app",
 				Protocol: "http2",
@@ -501,6 +506,7 @@ var _ = Describe("Subscriber", func() {
 				Eventually(registry.RegisterCallCount).Should(Equal(2))
 				_, originalEndpoint := registry.RegisterArgsForCall(0)
 				expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+					Source:                 "nats",
 					Host:                   "host",
 					AppId:                  "app",
 					Protocol:               "http2",
@@ -511,6 +517,506 @@ var _ = Describe("Subscriber", func() {
 			})
 		})
 
+		Context("when the message contains a weight option", func() {
+			JustBeforeEach(func() {
+				sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, logger.Logger)
+				process = ifrit.Invoke(sub)
+				Eventually(process.Ready()).Should(BeClosed())
+			})
+
+			It("endpoint is constructed with the weight", func() {
+				var msg = mbus.RegistryMessage{
+					Host:     "host",
+					App:      "app",
+					Protocol: "http2",
+					Uris:     []route.Uri{"test.example.com"},
+					Options: mbus.RegistryMessageOpts{
+						LoadBalancingAlgorithm: "weighted-round-robin",
+						Weight:                 5,
+					},
+				}
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(registry.RegisterCallCount).Should(Equal(2))
+				_, originalEndpoint := registry.RegisterArgsForCall(0)
+				expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+					Source:                 "nats",
+					Host:                   "host",
+					AppId:                  "app",
+					Protocol:               "http2",
+					LoadBalancingAlgorithm: "weighted-round-robin",
+					Weight:                 5,
+				})
+
+				Expect(originalEndpoint).To(Equal(expectedEndpoint))
+			})
+		})
+
+		Context("when the message contains a hash key option", func() {
+			JustBeforeEach(func() {
+				sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, logger.Logger)
+				process = ifrit.Invoke(sub)
+				Eventually(process.Ready()).Should(BeClosed())
+			})
+
+			It("endpoint is constructed with the hash key", func() {
+				var msg = mbus.RegistryMessage{
+					Host:     "host",
+					App:      "app",
+					Protocol: "http2",
+					Uris:     []route.Uri{"test.example.com"},
+					Options: mbus.RegistryMessageOpts{
+						LoadBalancingAlgorithm: "consistent-hash",
+						HashKey:                "header:X-Tenant-Id",
+					},
+				}
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(registry.RegisterCallCount).Should(Equal(2))
+				_, originalEndpoint := registry.RegisterArgsForCall(0)
+				expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+					Source:                 "nats",
+					Host:                   "host",
+					AppId:                  "app",
+					Protocol:               "http2",
+					LoadBalancingAlgorithm: "consistent-hash",
+					HashKey:                "header:X-Tenant-Id",
+				})
+
+				Expect(originalEndpoint).To(Equal(expectedEndpoint))
+			})
+		})
+
+		Context("when the message contains a slow start option", func() {
+			JustBeforeEach(func() {
+				sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, logger.Logger)
+				process = ifrit.Invoke(sub)
+				Eventually(process.Ready()).Should(BeClosed())
+			})
+
+			It("endpoint is constructed with the slow start duration", func() {
+				var msg = mbus.RegistryMessage{
+					Host:     "host",
+					App:      "app",
+					Protocol: "http2",
+					Uris:     []route.Uri{"test.example.com"},
+					Options: mbus.RegistryMessageOpts{
+						SlowStartDurationInSeconds: 60,
+					},
+				}
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(registry.RegisterCallCount).Should(Equal(2))
+				_, originalEndpoint := registry.RegisterArgsForCall(0)
+				expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+					Source:                     "nats",
+					Host:                       "host",
+					AppId:                      "app",
+					Protocol:                   "http2",
+					SlowStartDurationInSeconds: 60,
+				})
+
+				Expect(originalEndpoint).To(Equal(expectedEndpoint))
+			})
+		})
+
+		Context("when the message contains a health check path option", func() {
+			JustBeforeEach(func() {
+				sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, logger.Logger)
+				process = ifrit.Invoke(sub)
+				Eventually(process.Ready()).Should(BeClosed())
+			})
+
+			It("endpoint is constructed with the health check path", func() {
+				var msg = mbus.RegistryMessage{
+					Host:     "host",
+					App:      "app",
+					Protocol: "http2",
+					Uris:     []route.Uri{"test.example.com"},
+					Options: mbus.RegistryMessageOpts{
+						HealthCheckPath: "/healthz",
+					},
+				}
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(registry.RegisterCallCount).Should(Equal(2))
+				_, originalEndpoint := registry.RegisterArgsForCall(0)
+				expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+					Source:          "nats",
+					Host:            "host",
+					AppId:           "app",
+					Protocol:        "http2",
+					HealthCheckPath: "/healthz",
+				})
+
+				Expect(originalEndpoint).To(Equal(expectedEndpoint))
+			})
+		})
+
+		Context("when the message contains timeout and attempt options", func() {
+			JustBeforeEach(func() {
+				sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, logger.Logger)
+				process = ifrit.Invoke(sub)
+				Eventually(process.Ready()).Should(BeClosed())
+			})
+
+			It("endpoint is constructed with the timeouts and attempts", func() {
+				var msg = mbus.RegistryMessage{
+					Host:     "host",
+					App:      "app",
+					Protocol: "http2",
+					Uris:     []route.Uri{"test.example.com"},
+					Options: mbus.RegistryMessageOpts{
+						RequestTimeout: "5m",
+						DialTimeout:    "500ms",
+						MaxAttempts:    5,
+					},
+				}
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(registry.RegisterCallCount).Should(Equal(2))
+				_, originalEndpoint := registry.RegisterArgsForCall(0)
+				expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+					Source:         "nats",
+					Host:           "host",
+					AppId:          "app",
+					Protocol:       "http2",
+					RequestTimeout: 5 * time.Minute,
+					DialTimeout:    500 * time.Millisecond,
+					MaxAttempts:    5,
+				})
+
+				Expect(originalEndpoint).To(Equal(expectedEndpoint))
+			})
+
+			It("registers the route without a timeout which is invalid", func() {
+				var msg = mbus.RegistryMessage{
+					Host:     "host",
+					App:      "app",
+					Protocol: "http2",
+					Uris:     []route.Uri{"test.example.com"},
+					Options: mbus.RegistryMessageOpts{
+						RequestTimeout: "forever",
+						DialTimeout:    "500ms",
+					},
+				}
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(registry.RegisterCallCount).Should(Equal(2))
+				_, endpoint := registry.RegisterArgsForCall(0)
+				Expect(endpoint.RequestTimeout).To(BeZero())
+				Expect(endpoint.DialTimeout).To(Equal(500 * time.Millisecond))
+				Expect(logger).To(gbytes.Say(`invalid-route-option-ignored.*invalid request_timeout option`))
+			})
+		})
+
+		Context("when the message contains a traffic weight option", func() {
+			JustBeforeEach(func() {
+				sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, logger.Logger)
+				process = ifrit.Invoke(sub)
+				Eventually(process.Ready()).Should(BeClosed())
+			})
+
+			It("endpoint is constructed with the traffic weight", func() {
+				var msg = mbus.RegistryMessage{
+					Host:     "host",
+					App:      "app",
+					Protocol: "http2",
+					Uris:     []route.Uri{"test.example.com"},
+					Options: mbus.RegistryMessageOpts{
+						TrafficWeight: 90,
+					},
+				}
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(registry.RegisterCallCount).Should(Equal(2))
+				_, originalEndpoint := registry.RegisterArgsForCall(0)
+				expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+					Source:        "nats",
+					Host:          "host",
+					AppId:         "app",
+					Protocol:      "http2",
+					TrafficWeight: 90,
+				})
+
+				Expect(originalEndpoint).To(Equal(expectedEndpoint))
+			})
+		})
+
+		Context("when the message contains routing rules", func() {
+			JustBeforeEach(func() {
+				sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, logger.Logger)
+				process = ifrit.Invoke(sub)
+				Eventually(process.Ready()).Should(BeClosed())
+			})
+
+			It("endpoint is constructed with the routing rules", func() {
+				var msg = mbus.RegistryMessage{
+					Host:     "host",
+					App:      "app",
+					Protocol: "http2",
+					Uris:     []route.Uri{"test.example.com"},
+					Options: mbus.RegistryMessageOpts{
+						RoutingRules: []config.RoutingRule{
+							{Header: "X-Canary", Value: "true", Tag: "canary"},
+						},
+					},
+				}
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(registry.RegisterCallCount).Should(Equal(2))
+				_, originalEndpoint := registry.RegisterArgsForCall(0)
+				expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+					Source:   "nats",
+					Host:     "host",
+					AppId:    "app",
+					Protocol: "http2",
+					RoutingRules: []config.RoutingRule{
+						{Header: "X-Canary", Value: "true", Tag: "canary"},
+					},
+				})
+
+				Expect(originalEndpoint).To(Equal(expectedEndpoint))
+			})
+
+			It("registers the route without routing rules when a rule is invalid", func() {
+				var msg = mbus.RegistryMessage{
+					Host:     "host",
+					App:      "app",
+					Protocol: "http2",
+					Uris:     []route.Uri{"test.example.com"},
+					Options: mbus.RegistryMessageOpts{
+						RoutingRules: []config.RoutingRule{
+							{Header: "X-Canary", Cookie: "canary", Tag: "canary"},
+						},
+					},
+				}
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(registry.RegisterCallCount).Should(Equal(2))
+				_, endpoint := registry.RegisterArgsForCall(0)
+				Expect(endpoint.RoutingRules).To(BeEmpty())
+				Expect(logger).To(gbytes.Say(`invalid-route-option-ignored.*invalid routing_rules option`))
+			})
+		})
+
+		Context("when the message contains shadow options", func() {
+			JustBeforeEach(func() {
+				sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, logger.Logger)
+				process = ifrit.Invoke(sub)
+				Eventually(process.Ready()).Should(BeClosed())
+			})
+
+			It("endpoint is constructed with the shadow route and percentage", func() {
+				var msg = mbus.RegistryMessage{
+					Host:     "host",
+					App:      "app",
+					Protocol: "http2",
+					Uris:     []route.Uri{"test.example.com"},
+					Options: mbus.RegistryMessageOpts{
+						ShadowRoute:      "test-v2.example.com",
+						ShadowPercentage: 10,
+					},
+				}
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(registry.RegisterCallCount).Should(Equal(2))
+				_, originalEndpoint := registry.RegisterArgsForCall(0)
+				expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+					Source:           "nats",
+					Host:             "host",
+					AppId:            "app",
+					Protocol:         "http2",
+					ShadowRoute:      "test-v2.example.com",
+					ShadowPercentage: 10,
+				})
+
+				Expect(originalEndpoint).To(Equal(expectedEndpoint))
+			})
+
+			It("registers the route without a shadow route when the shadow percentage is invalid", func() {
+				var msg = mbus.RegistryMessage{
+					Host:     "host",
+					App:      "app",
+					Protocol: "http2",
+					Uris:     []route.Uri{"test.example.com"},
+					Options: mbus.RegistryMessageOpts{
+						ShadowRoute:      "test-v2.example.com",
+						ShadowPercentage: 150,
+					},
+				}
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(registry.RegisterCallCount).Should(Equal(2))
+				_, endpoint := registry.RegisterArgsForCall(0)
+				Expect(endpoint.ShadowRoute).To(BeEmpty())
+				Expect(endpoint.ShadowPercentage).To(BeZero())
+				Expect(logger).To(gbytes.Say(`invalid-route-option-ignored.*invalid shadow_percentage option`))
+			})
+		})
+
+		Context("when the message contains a hedge delay", func() {
+			JustBeforeEach(func() {
+				sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, logger.Logger)
+				process = ifrit.Invoke(sub)
+				Eventually(process.Ready()).Should(BeClosed())
+			})
+
+			It("endpoint is constructed with the hedge delay", func() {
+				var msg = mbus.RegistryMessage{
+					Host:     "host",
+					App:      "app",
+					Protocol: "http2",
+					Uris:     []route.Uri{"test.example.com"},
+					Options: mbus.RegistryMessageOpts{
+						HedgeDelay: "50ms",
+					},
+				}
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(registry.RegisterCallCount).Should(Equal(2))
+				_, originalEndpoint := registry.RegisterArgsForCall(0)
+				expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+					Source:     "nats",
+					Host:       "host",
+					AppId:      "app",
+					Protocol:   "http2",
+					HedgeDelay: 50 * time.Millisecond,
+				})
+
+				Expect(originalEndpoint).To(Equal(expectedEndpoint))
+			})
+
+			It("registers the route without hedging when the hedge delay is invalid", func() {
+				var msg = mbus.RegistryMessage{
+					Host:     "host",
+					App:      "app",
+					Protocol: "http2",
+					Uris:     []route.Uri{"test.example.com"},
+					Options: mbus.RegistryMessageOpts{
+						HedgeDelay: "-1s",
+					},
+				}
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(registry.RegisterCallCount).Should(Equal(2))
+				_, endpoint := registry.RegisterArgsForCall(0)
+				Expect(endpoint.HedgeDelay).To(BeZero())
+				Expect(logger).To(gbytes.Say(`invalid-route-option-ignored.*invalid hedge_delay option`))
+			})
+		})
+
+		Context("when the message contains retry status codes", func() {
+			JustBeforeEach(func() {
+				sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, logger.Logger)
+				process = ifrit.Invoke(sub)
+				Eventually(process.Ready()).Should(BeClosed())
+			})
+
+			It("endpoint is constructed with the retry status codes", func() {
+				var msg = mbus.RegistryMessage{
+					Host:     "host",
+					App:      "app",
+					Protocol: "http2",
+					Uris:     []route.Uri{"test.example.com"},
+					Options: mbus.RegistryMessageOpts{
+						RetryStatusCodes: []int{502, 503},
+					},
+				}
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(registry.RegisterCallCount).Should(Equal(2))
+				_, originalEndpoint := registry.RegisterArgsForCall(0)
+				expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+					Source:           "nats",
+					Host:             "host",
+					AppId:            "app",
+					Protocol:         "http2",
+					RetryStatusCodes: []int{502, 503},
+				})
+
+				Expect(originalEndpoint).To(Equal(expectedEndpoint))
+			})
+
+			It("registers the route without the status codes which are invalid", func() {
+				var msg = mbus.RegistryMessage{
+					Host:     "host",
+					App:      "app",
+					Protocol: "http2",
+					Uris:     []route.Uri{"test.example.com"},
+					Options: mbus.RegistryMessageOpts{
+						RetryStatusCodes: []int{503, 1000},
+					},
+				}
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(registry.RegisterCallCount).Should(Equal(2))
+				_, endpoint := registry.RegisterArgsForCall(0)
+				Expect(endpoint.RetryStatusCodes).To(Equal([]int{503}))
+				Expect(logger).To(gbytes.Say(`invalid-route-option-ignored.*invalid retry_status_codes option: 1000`))
+			})
+		})
+
 		Context("when the message contains an empty load balancing algorithm option", func() {
 			JustBeforeEach(func() {
 				sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, logger.Logger)
@@ -534,6 +1040,7 @@ var _ = Describe("Subscriber", func() {
 				Eventually(registry.RegisterCallCount).Should(Equal(2))
 				_, originalEndpoint := registry.RegisterArgsForCall(0)
 				expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+					Source:   "nats",
 					Host:     "host",
 					AppId:    "app",
 					Protocol: "http2",
@@ -565,6 +1072,7 @@ var _ = Describe("Subscriber", func() {
 				Eventually(registry.RegisterCallCount).Should(Equal(1))
 				_, originalEndpoint := registry.RegisterArgsForCall(0)
 				expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+					Source:   "nats",
 					Host:     "host",
 					AppId:    "app",
 					Protocol: "http1",
@@ -603,6 +1111,7 @@ var _ = Describe("Subscriber", func() {
 			Eventually(registry.RegisterCallCount).Should(Equal(1))
 			_, originalEndpoint := registry.RegisterArgsForCall(0)
 			expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+				Source:                  "nats",
 				Host:                    "host",
 				AppId:                   "app",
 				Port:                    1999,
@@ -638,6 +1147,7 @@ var _ = Describe("Subscriber", func() {
 		Eventually(registry.RegisterCallCount).Should(Equal(1))
 		_, originalEndpoint := registry.RegisterArgsForCall(0)
 		expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+			Source:    "nats",
 			Host:      "host",
 			Port:      1111,
 			Protocol:  "http1",
@@ -676,6 +1186,7 @@ var _ = Describe("Subscriber", func() {
 			Eventually(registry.RegisterCallCount).Should(Equal(1))
 			_, originalEndpoint := registry.RegisterArgsForCall(0)
 			expectedEndpoint := route.NewEndpoint(&route.EndpointOpts{
+				Source:                  "nats",
 				Host:                    "host",
 				AppId:                   "app",
 				Port:                    1111,
@@ -838,6 +1349,30 @@ var _ = Describe("Subscriber", func() {
 				Expect(endpoint.IsolationSegment).To(Equal("abc-iso-seg"))
 			}
 		})
+
+		It("unregisters the route when an option is invalid", func() {
+			msg := mbus.RegistryMessage{
+				Host:    "host",
+				App:     "app",
+				Port:    1111,
+				Uris:    []route.Uri{"test.example.com"},
+				Options: mbus.RegistryMessageOpts{DialTimeout: "soon"},
+			}
+
+			data, err := json.Marshal(msg)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = natsClient.Publish("router.register", data)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(registry.RegisterCallCount).Should(Equal(1))
+
+			err = natsClient.Publish("router.unregister", data)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(registry.UnregisterCallCount).Should(Equal(1))
+			uri, endpoint := registry.UnregisterArgsForCall(0)
+			Expect(uri).To(Equal(route.Uri("test.example.com")))
+			Expect(endpoint.CanonicalAddr()).To(Equal("host:1111"))
+		})
 	})
 
 })
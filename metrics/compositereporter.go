@@ -32,6 +32,7 @@ type MetricReporter interface {
 	CaptureRoutingResponseLatency(b *route.Endpoint, statusCode int, t time.Time, d time.Duration)
 	CaptureGorouterTime(gorouterTime float64)
 	CaptureRouteServiceResponse(res *http.Response)
+	CaptureShadowResponse(res *http.Response)
 	CaptureWebSocketUpdate()
 	CaptureWebSocketFailure()
 	CaptureHTTPLatency(d time.Duration, sourceID string)
@@ -39,11 +40,21 @@ type MetricReporter interface {
 	CaptureRoutesPruned(prunedRoutes uint64)
 	CaptureLookupTime(t time.Duration)
 	CaptureRegistryMessage(msg ComponentTagged, action string)
+	CaptureRetryBudgetExhausted(scope string)
+	CaptureRetryBudgetDenied(route string)
+	CaptureRetryBudgetTokens(tokens float64)
+	CaptureMaxConnsQueueDepth(depth int)
+	CaptureMaxConnsQueueWaitTime(d time.Duration)
+	CaptureConcurrencyLimit(b *route.Endpoint, limit int64)
+	CaptureZoneSelection(zone string)
+	CaptureRouteSourceEvent(source string, event string)
 	CaptureRouteRegistrationLatency(t time.Duration)
 	CaptureUnregistryMessage(msg ComponentTagged)
 	CaptureFoundFileDescriptors(files int)
 	CaptureNATSBufferedMessages(messages int)
 	CaptureNATSDroppedMessages(messages int)
+	CaptureOutlierEjected()
+	CaptureOutlierReturned()
 	UnmuzzleRouteRegistrationLatency()
 }
 
@@ -120,6 +131,12 @@ func (m MultiMetricReporter) CaptureRouteServiceResponse(res *http.Response) {
 	}
 }
 
+func (m MultiMetricReporter) CaptureShadowResponse(res *http.Response) {
+	for _, r := range m {
+		r.CaptureShadowResponse(res)
+	}
+}
+
 func (m MultiMetricReporter) CaptureRoutingResponse(statusCode int) {
 	for _, r := range m {
 		r.CaptureRoutingResponse(statusCode)
@@ -216,6 +233,66 @@ func (m MultiMetricReporter) CaptureNATSDroppedMessages(messages int) {
 	}
 }
 
+func (m MultiMetricReporter) CaptureOutlierEjected() {
+	for _, r := range m {
+		r.CaptureOutlierEjected()
+	}
+}
+
+func (m MultiMetricReporter) CaptureOutlierReturned() {
+	for _, r := range m {
+		r.CaptureOutlierReturned()
+	}
+}
+
+func (m MultiMetricReporter) CaptureRetryBudgetExhausted(scope string) {
+	for _, r := range m {
+		r.CaptureRetryBudgetExhausted(scope)
+	}
+}
+
+func (m MultiMetricReporter) CaptureRetryBudgetDenied(route string) {
+	for _, r := range m {
+		r.CaptureRetryBudgetDenied(route)
+	}
+}
+
+func (m MultiMetricReporter) CaptureRetryBudgetTokens(tokens float64) {
+	for _, r := range m {
+		r.CaptureRetryBudgetTokens(tokens)
+	}
+}
+
+func (m MultiMetricReporter) CaptureMaxConnsQueueDepth(depth int) {
+	for _, r := range m {
+		r.CaptureMaxConnsQueueDepth(depth)
+	}
+}
+
+func (m MultiMetricReporter) CaptureMaxConnsQueueWaitTime(d time.Duration) {
+	for _, r := range m {
+		r.CaptureMaxConnsQueueWaitTime(d)
+	}
+}
+
+func (m MultiMetricReporter) CaptureConcurrencyLimit(b *route.Endpoint, limit int64) {
+	for _, r := range m {
+		r.CaptureConcurrencyLimit(b, limit)
+	}
+}
+
+func (m MultiMetricReporter) CaptureZoneSelection(zone string) {
+	for _, r := range m {
+		r.CaptureZoneSelection(zone)
+	}
+}
+
+func (m MultiMetricReporter) CaptureRouteSourceEvent(source string, event string) {
+	for _, r := range m {
+		r.CaptureRouteSourceEvent(source, event)
+	}
+}
+
 func (c *CompositeReporter) CaptureBadRequest() {
 	c.VarzReporter.CaptureBadRequest()
 	c.MetricReporter.CaptureBadRequest()
@@ -46,6 +46,69 @@ var _ = Describe("CompositeReporter", func() {
 		Expect(fakeMultiReporter.CaptureBackendExhaustedConnsCallCount()).To(Equal(1))
 	})
 
+	It("forwards CaptureOutlierEjected to the proxy reporter", func() {
+		composite.CaptureOutlierEjected()
+		Expect(fakeMultiReporter.CaptureOutlierEjectedCallCount()).To(Equal(1))
+	})
+
+	It("forwards CaptureRetryBudgetExhausted to the proxy reporter", func() {
+		composite.CaptureRetryBudgetExhausted("global")
+		Expect(fakeMultiReporter.CaptureRetryBudgetExhaustedCallCount()).To(Equal(1))
+		Expect(fakeMultiReporter.CaptureRetryBudgetExhaustedArgsForCall(0)).To(Equal("global"))
+	})
+
+	It("forwards CaptureRetryBudgetDenied to the proxy reporter", func() {
+		composite.CaptureRetryBudgetDenied("app.example.com/api")
+		Expect(fakeMultiReporter.CaptureRetryBudgetDeniedCallCount()).To(Equal(1))
+		Expect(fakeMultiReporter.CaptureRetryBudgetDeniedArgsForCall(0)).To(Equal("app.example.com/api"))
+	})
+
+	It("forwards CaptureRetryBudgetTokens to the proxy reporter", func() {
+		composite.CaptureRetryBudgetTokens(7)
+		Expect(fakeMultiReporter.CaptureRetryBudgetTokensCallCount()).To(Equal(1))
+		Expect(fakeMultiReporter.CaptureRetryBudgetTokensArgsForCall(0)).To(Equal(7.0))
+	})
+
+	It("forwards CaptureMaxConnsQueueDepth to the proxy reporter", func() {
+		composite.CaptureMaxConnsQueueDepth(3)
+		Expect(fakeMultiReporter.CaptureMaxConnsQueueDepthCallCount()).To(Equal(1))
+		Expect(fakeMultiReporter.CaptureMaxConnsQueueDepthArgsForCall(0)).To(Equal(3))
+	})
+
+	It("forwards CaptureMaxConnsQueueWaitTime to the proxy reporter", func() {
+		composite.CaptureMaxConnsQueueWaitTime(time.Second)
+		Expect(fakeMultiReporter.CaptureMaxConnsQueueWaitTimeCallCount()).To(Equal(1))
+		Expect(fakeMultiReporter.CaptureMaxConnsQueueWaitTimeArgsForCall(0)).To(Equal(time.Second))
+	})
+
+	It("forwards CaptureConcurrencyLimit to the proxy reporter", func() {
+		endpoint := route.NewEndpoint(&route.EndpointOpts{})
+		composite.CaptureConcurrencyLimit(endpoint, 12)
+		Expect(fakeMultiReporter.CaptureConcurrencyLimitCallCount()).To(Equal(1))
+		b, limit := fakeMultiReporter.CaptureConcurrencyLimitArgsForCall(0)
+		Expect(b).To(Equal(endpoint))
+		Expect(limit).To(Equal(int64(12)))
+	})
+
+	It("forwards CaptureZoneSelection to the proxy reporter", func() {
+		composite.CaptureZoneSelection("z1")
+		Expect(fakeMultiReporter.CaptureZoneSelectionCallCount()).To(Equal(1))
+		Expect(fakeMultiReporter.CaptureZoneSelectionArgsForCall(0)).To(Equal("z1"))
+	})
+
+	It("forwards CaptureRouteSourceEvent to the proxy reporter", func() {
+		composite.CaptureRouteSourceEvent("nats", "register")
+		Expect(fakeMultiReporter.CaptureRouteSourceEventCallCount()).To(Equal(1))
+		source, event := fakeMultiReporter.CaptureRouteSourceEventArgsForCall(0)
+		Expect(source).To(Equal("nats"))
+		Expect(event).To(Equal("register"))
+	})
+
+	It("forwards CaptureOutlierReturned to the proxy reporter", func() {
+		composite.CaptureOutlierReturned()
+		Expect(fakeMultiReporter.CaptureOutlierReturnedCallCount()).To(Equal(1))
+	})
+
 	It("forwards CaptureBackendInvalidID() to the proxy reporter", func() {
 		composite.CaptureBackendInvalidID()
 		Expect(fakeMultiReporter.CaptureBackendInvalidIDCallCount()).To(Equal(1))
@@ -108,6 +171,13 @@ var _ = Describe("CompositeReporter", func() {
 		Expect(callResponse).To(Equal(response))
 	})
 
+	It("forwards CaptureShadowResponse to proxy reporter", func() {
+		composite.CaptureShadowResponse(response)
+
+		Expect(fakeMultiReporter.CaptureShadowResponseCallCount()).To(Equal(1))
+		Expect(fakeMultiReporter.CaptureShadowResponseArgsForCall(0)).To(Equal(response))
+	})
+
 	It("forwards CaptureRoutingResponse to proxy reporter", func() {
 		composite.CaptureRoutingResponse(response.StatusCode)
 
@@ -35,6 +35,12 @@ type FakeMetricReporter struct {
 	captureBadRequestMutex       sync.RWMutex
 	captureBadRequestArgsForCall []struct {
 	}
+	CaptureConcurrencyLimitStub        func(*route.Endpoint, int64)
+	captureConcurrencyLimitMutex       sync.RWMutex
+	captureConcurrencyLimitArgsForCall []struct {
+		arg1 *route.Endpoint
+		arg2 int64
+	}
 	CaptureEmptyContentLengthHeaderStub        func()
 	captureEmptyContentLengthHeaderMutex       sync.RWMutex
 	captureEmptyContentLengthHeaderArgsForCall []struct {
@@ -60,6 +66,16 @@ type FakeMetricReporter struct {
 	captureLookupTimeArgsForCall []struct {
 		arg1 time.Duration
 	}
+	CaptureMaxConnsQueueDepthStub        func(int)
+	captureMaxConnsQueueDepthMutex       sync.RWMutex
+	captureMaxConnsQueueDepthArgsForCall []struct {
+		arg1 int
+	}
+	CaptureMaxConnsQueueWaitTimeStub        func(time.Duration)
+	captureMaxConnsQueueWaitTimeMutex       sync.RWMutex
+	captureMaxConnsQueueWaitTimeArgsForCall []struct {
+		arg1 time.Duration
+	}
 	CaptureNATSBufferedMessagesStub        func(int)
 	captureNATSBufferedMessagesMutex       sync.RWMutex
 	captureNATSBufferedMessagesArgsForCall []struct {
@@ -70,12 +86,35 @@ type FakeMetricReporter struct {
 	captureNATSDroppedMessagesArgsForCall []struct {
 		arg1 int
 	}
+	CaptureOutlierEjectedStub        func()
+	captureOutlierEjectedMutex       sync.RWMutex
+	captureOutlierEjectedArgsForCall []struct {
+	}
+	CaptureOutlierReturnedStub        func()
+	captureOutlierReturnedMutex       sync.RWMutex
+	captureOutlierReturnedArgsForCall []struct {
+	}
 	CaptureRegistryMessageStub        func(metrics.ComponentTagged, string)
 	captureRegistryMessageMutex       sync.RWMutex
 	captureRegistryMessageArgsForCall []struct {
 		arg1 metrics.ComponentTagged
 		arg2 string
 	}
+	CaptureRetryBudgetDeniedStub        func(string)
+	captureRetryBudgetDeniedMutex       sync.RWMutex
+	captureRetryBudgetDeniedArgsForCall []struct {
+		arg1 string
+	}
+	CaptureRetryBudgetExhaustedStub        func(string)
+	captureRetryBudgetExhaustedMutex       sync.RWMutex
+	captureRetryBudgetExhaustedArgsForCall []struct {
+		arg1 string
+	}
+	CaptureRetryBudgetTokensStub        func(float64)
+	captureRetryBudgetTokensMutex       sync.RWMutex
+	captureRetryBudgetTokensArgsForCall []struct {
+		arg1 float64
+	}
 	CaptureRouteRegistrationLatencyStub        func(time.Duration)
 	captureRouteRegistrationLatencyMutex       sync.RWMutex
 	captureRouteRegistrationLatencyArgsForCall []struct {
@@ -86,6 +125,12 @@ type FakeMetricReporter struct {
 	captureRouteServiceResponseArgsForCall []struct {
 		arg1 *http.Response
 	}
+	CaptureRouteSourceEventStub        func(string, string)
+	captureRouteSourceEventMutex       sync.RWMutex
+	captureRouteSourceEventArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
 	CaptureRouteStatsStub        func(int, int64)
 	captureRouteStatsMutex       sync.RWMutex
 	captureRouteStatsArgsForCall []struct {
@@ -115,6 +160,11 @@ type FakeMetricReporter struct {
 		arg3 time.Time
 		arg4 time.Duration
 	}
+	CaptureShadowResponseStub        func(*http.Response)
+	captureShadowResponseMutex       sync.RWMutex
+	captureShadowResponseArgsForCall []struct {
+		arg1 *http.Response
+	}
 	CaptureUnregistryMessageStub        func(metrics.ComponentTagged)
 	captureUnregistryMessageMutex       sync.RWMutex
 	captureUnregistryMessageArgsForCall []struct {
@@ -128,6 +178,11 @@ type FakeMetricReporter struct {
 	captureWebSocketUpdateMutex       sync.RWMutex
 	captureWebSocketUpdateArgsForCall []struct {
 	}
+	CaptureZoneSelectionStub        func(string)
+	captureZoneSelectionMutex       sync.RWMutex
+	captureZoneSelectionArgsForCall []struct {
+		arg1 string
+	}
 	UnmuzzleRouteRegistrationLatencyStub        func()
 	unmuzzleRouteRegistrationLatencyMutex       sync.RWMutex
 	unmuzzleRouteRegistrationLatencyArgsForCall []struct {
@@ -280,6 +335,39 @@ func (fake *FakeMetricReporter) CaptureBadRequestCalls(stub func()) {
 	fake.CaptureBadRequestStub = stub
 }
 
+func (fake *FakeMetricReporter) CaptureConcurrencyLimit(arg1 *route.Endpoint, arg2 int64) {
+	fake.captureConcurrencyLimitMutex.Lock()
+	fake.captureConcurrencyLimitArgsForCall = append(fake.captureConcurrencyLimitArgsForCall, struct {
+		arg1 *route.Endpoint
+		arg2 int64
+	}{arg1, arg2})
+	stub := fake.CaptureConcurrencyLimitStub
+	fake.recordInvocation("CaptureConcurrencyLimit", []interface{}{arg1, arg2})
+	fake.captureConcurrencyLimitMutex.Unlock()
+	if stub != nil {
+		fake.CaptureConcurrencyLimitStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeMetricReporter) CaptureConcurrencyLimitCallCount() int {
+	fake.captureConcurrencyLimitMutex.RLock()
+	defer fake.captureConcurrencyLimitMutex.RUnlock()
+	return len(fake.captureConcurrencyLimitArgsForCall)
+}
+
+func (fake *FakeMetricReporter) CaptureConcurrencyLimitCalls(stub func(*route.Endpoint, int64)) {
+	fake.captureConcurrencyLimitMutex.Lock()
+	defer fake.captureConcurrencyLimitMutex.Unlock()
+	fake.CaptureConcurrencyLimitStub = stub
+}
+
+func (fake *FakeMetricReporter) CaptureConcurrencyLimitArgsForCall(i int) (*route.Endpoint, int64) {
+	fake.captureConcurrencyLimitMutex.RLock()
+	defer fake.captureConcurrencyLimitMutex.RUnlock()
+	argsForCall := fake.captureConcurrencyLimitArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
 func (fake *FakeMetricReporter) CaptureEmptyContentLengthHeader() {
 	fake.captureEmptyContentLengthHeaderMutex.Lock()
 	fake.captureEmptyContentLengthHeaderArgsForCall = append(fake.captureEmptyContentLengthHeaderArgsForCall, struct {
@@ -433,6 +521,70 @@ func (fake *FakeMetricReporter) CaptureLookupTimeArgsForCall(i int) time.Duratio
 	return argsForCall.arg1
 }
 
+func (fake *FakeMetricReporter) CaptureMaxConnsQueueDepth(arg1 int) {
+	fake.captureMaxConnsQueueDepthMutex.Lock()
+	fake.captureMaxConnsQueueDepthArgsForCall = append(fake.captureMaxConnsQueueDepthArgsForCall, struct {
+		arg1 int
+	}{arg1})
+	stub := fake.CaptureMaxConnsQueueDepthStub
+	fake.recordInvocation("CaptureMaxConnsQueueDepth", []interface{}{arg1})
+	fake.captureMaxConnsQueueDepthMutex.Unlock()
+	if stub != nil {
+		fake.CaptureMaxConnsQueueDepthStub(arg1)
+	}
+}
+
+func (fake *FakeMetricReporter) CaptureMaxConnsQueueDepthCallCount() int {
+	fake.captureMaxConnsQueueDepthMutex.RLock()
+	defer fake.captureMaxConnsQueueDepthMutex.RUnlock()
+	return len(fake.captureMaxConnsQueueDepthArgsForCall)
+}
+
+func (fake *FakeMetricReporter) CaptureMaxConnsQueueDepthCalls(stub func(int)) {
+	fake.captureMaxConnsQueueDepthMutex.Lock()
+	defer fake.captureMaxConnsQueueDepthMutex.Unlock()
+	fake.CaptureMaxConnsQueueDepthStub = stub
+}
+
+func (fake *FakeMetricReporter) CaptureMaxConnsQueueDepthArgsForCall(i int) int {
+	fake.captureMaxConnsQueueDepthMutex.RLock()
+	defer fake.captureMaxConnsQueueDepthMutex.RUnlock()
+	argsForCall := fake.captureMaxConnsQueueDepthArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeMetricReporter) CaptureMaxConnsQueueWaitTime(arg1 time.Duration) {
+	fake.captureMaxConnsQueueWaitTimeMutex.Lock()
+	fake.captureMaxConnsQueueWaitTimeArgsForCall = append(fake.captureMaxConnsQueueWaitTimeArgsForCall, struct {
+		arg1 time.Duration
+	}{arg1})
+	stub := fake.CaptureMaxConnsQueueWaitTimeStub
+	fake.recordInvocation("CaptureMaxConnsQueueWaitTime", []interface{}{arg1})
+	fake.captureMaxConnsQueueWaitTimeMutex.Unlock()
+	if stub != nil {
+		fake.CaptureMaxConnsQueueWaitTimeStub(arg1)
+	}
+}
+
+func (fake *FakeMetricReporter) CaptureMaxConnsQueueWaitTimeCallCount() int {
+	fake.captureMaxConnsQueueWaitTimeMutex.RLock()
+	defer fake.captureMaxConnsQueueWaitTimeMutex.RUnlock()
+	return len(fake.captureMaxConnsQueueWaitTimeArgsForCall)
+}
+
+func (fake *FakeMetricReporter) CaptureMaxConnsQueueWaitTimeCalls(stub func(time.Duration)) {
+	fake.captureMaxConnsQueueWaitTimeMutex.Lock()
+	defer fake.captureMaxConnsQueueWaitTimeMutex.Unlock()
+	fake.CaptureMaxConnsQueueWaitTimeStub = stub
+}
+
+func (fake *FakeMetricReporter) CaptureMaxConnsQueueWaitTimeArgsForCall(i int) time.Duration {
+	fake.captureMaxConnsQueueWaitTimeMutex.RLock()
+	defer fake.captureMaxConnsQueueWaitTimeMutex.RUnlock()
+	argsForCall := fake.captureMaxConnsQueueWaitTimeArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeMetricReporter) CaptureNATSBufferedMessages(arg1 int) {
 	fake.captureNATSBufferedMessagesMutex.Lock()
 	fake.captureNATSBufferedMessagesArgsForCall = append(fake.captureNATSBufferedMessagesArgsForCall, struct {
@@ -497,6 +649,54 @@ func (fake *FakeMetricReporter) CaptureNATSDroppedMessagesArgsForCall(i int) int
 	return argsForCall.arg1
 }
 
+func (fake *FakeMetricReporter) CaptureOutlierEjected() {
+	fake.captureOutlierEjectedMutex.Lock()
+	fake.captureOutlierEjectedArgsForCall = append(fake.captureOutlierEjectedArgsForCall, struct {
+	}{})
+	stub := fake.CaptureOutlierEjectedStub
+	fake.recordInvocation("CaptureOutlierEjected", []interface{}{})
+	fake.captureOutlierEjectedMutex.Unlock()
+	if stub != nil {
+		fake.CaptureOutlierEjectedStub()
+	}
+}
+
+func (fake *FakeMetricReporter) CaptureOutlierEjectedCallCount() int {
+	fake.captureOutlierEjectedMutex.RLock()
+	defer fake.captureOutlierEjectedMutex.RUnlock()
+	return len(fake.captureOutlierEjectedArgsForCall)
+}
+
+func (fake *FakeMetricReporter) CaptureOutlierEjectedCalls(stub func()) {
+	fake.captureOutlierEjectedMutex.Lock()
+	defer fake.captureOutlierEjectedMutex.Unlock()
+	fake.CaptureOutlierEjectedStub = stub
+}
+
+func (fake *FakeMetricReporter) CaptureOutlierReturned() {
+	fake.captureOutlierReturnedMutex.Lock()
+	fake.captureOutlierReturnedArgsForCall = append(fake.captureOutlierReturnedArgsForCall, struct {
+	}{})
+	stub := fake.CaptureOutlierReturnedStub
+	fake.recordInvocation("CaptureOutlierReturned", []interface{}{})
+	fake.captureOutlierReturnedMutex.Unlock()
+	if stub != nil {
+		fake.CaptureOutlierReturnedStub()
+	}
+}
+
+func (fake *FakeMetricReporter) CaptureOutlierReturnedCallCount() int {
+	fake.captureOutlierReturnedMutex.RLock()
+	defer fake.captureOutlierReturnedMutex.RUnlock()
+	return len(fake.captureOutlierReturnedArgsForCall)
+}
+
+func (fake *FakeMetricReporter) CaptureOutlierReturnedCalls(stub func()) {
+	fake.captureOutlierReturnedMutex.Lock()
+	defer fake.captureOutlierReturnedMutex.Unlock()
+	fake.CaptureOutlierReturnedStub = stub
+}
+
 func (fake *FakeMetricReporter) CaptureRegistryMessage(arg1 metrics.ComponentTagged, arg2 string) {
 	fake.captureRegistryMessageMutex.Lock()
 	fake.captureRegistryMessageArgsForCall = append(fake.captureRegistryMessageArgsForCall, struct {
@@ -530,6 +730,102 @@ func (fake *FakeMetricReporter) CaptureRegistryMessageArgsForCall(i int) (metric
 	return argsForCall.arg1, argsForCall.arg2
 }
 
+func (fake *FakeMetricReporter) CaptureRetryBudgetDenied(arg1 string) {
+	fake.captureRetryBudgetDeniedMutex.Lock()
+	fake.captureRetryBudgetDeniedArgsForCall = append(fake.captureRetryBudgetDeniedArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.CaptureRetryBudgetDeniedStub
+	fake.recordInvocation("CaptureRetryBudgetDenied", []interface{}{arg1})
+	fake.captureRetryBudgetDeniedMutex.Unlock()
+	if stub != nil {
+		fake.CaptureRetryBudgetDeniedStub(arg1)
+	}
+}
+
+func (fake *FakeMetricReporter) CaptureRetryBudgetDeniedCallCount() int {
+	fake.captureRetryBudgetDeniedMutex.RLock()
+	defer fake.captureRetryBudgetDeniedMutex.RUnlock()
+	return len(fake.captureRetryBudgetDeniedArgsForCall)
+}
+
+func (fake *FakeMetricReporter) CaptureRetryBudgetDeniedCalls(stub func(string)) {
+	fake.captureRetryBudgetDeniedMutex.Lock()
+	defer fake.captureRetryBudgetDeniedMutex.Unlock()
+	fake.CaptureRetryBudgetDeniedStub = stub
+}
+
+func (fake *FakeMetricReporter) CaptureRetryBudgetDeniedArgsForCall(i int) string {
+	fake.captureRetryBudgetDeniedMutex.RLock()
+	defer fake.captureRetryBudgetDeniedMutex.RUnlock()
+	argsForCall := fake.captureRetryBudgetDeniedArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeMetricReporter) CaptureRetryBudgetExhausted(arg1 string) {
+	fake.captureRetryBudgetExhaustedMutex.Lock()
+	fake.captureRetryBudgetExhaustedArgsForCall = append(fake.captureRetryBudgetExhaustedArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.CaptureRetryBudgetExhaustedStub
+	fake.recordInvocation("CaptureRetryBudgetExhausted", []interface{}{arg1})
+	fake.captureRetryBudgetExhaustedMutex.Unlock()
+	if stub != nil {
+		fake.CaptureRetryBudgetExhaustedStub(arg1)
+	}
+}
+
+func (fake *FakeMetricReporter) CaptureRetryBudgetExhaustedCallCount() int {
+	fake.captureRetryBudgetExhaustedMutex.RLock()
+	defer fake.captureRetryBudgetExhaustedMutex.RUnlock()
+	return len(fake.captureRetryBudgetExhaustedArgsForCall)
+}
+
+func (fake *FakeMetricReporter) CaptureRetryBudgetExhaustedCalls(stub func(string)) {
+	fake.captureRetryBudgetExhaustedMutex.Lock()
+	defer fake.captureRetryBudgetExhaustedMutex.Unlock()
+	fake.CaptureRetryBudgetExhaustedStub = stub
+}
+
+func (fake *FakeMetricReporter) CaptureRetryBudgetExhaustedArgsForCall(i int) string {
+	fake.captureRetryBudgetExhaustedMutex.RLock()
+	defer fake.captureRetryBudgetExhaustedMutex.RUnlock()
+	argsForCall := fake.captureRetryBudgetExhaustedArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeMetricReporter) CaptureRetryBudgetTokens(arg1 float64) {
+	fake.captureRetryBudgetTokensMutex.Lock()
+	fake.captureRetryBudgetTokensArgsForCall = append(fake.captureRetryBudgetTokensArgsForCall, struct {
+		arg1 float64
+	}{arg1})
+	stub := fake.CaptureRetryBudgetTokensStub
+	fake.recordInvocation("CaptureRetryBudgetTokens", []interface{}{arg1})
+	fake.captureRetryBudgetTokensMutex.Unlock()
+	if stub != nil {
+		fake.CaptureRetryBudgetTokensStub(arg1)
+	}
+}
+
+func (fake *FakeMetricReporter) CaptureRetryBudgetTokensCallCount() int {
+	fake.captureRetryBudgetTokensMutex.RLock()
+	defer fake.captureRetryBudgetTokensMutex.RUnlock()
+	return len(fake.captureRetryBudgetTokensArgsForCall)
+}
+
+func (fake *FakeMetricReporter) CaptureRetryBudgetTokensCalls(stub func(float64)) {
+	fake.captureRetryBudgetTokensMutex.Lock()
+	defer fake.captureRetryBudgetTokensMutex.Unlock()
+	fake.CaptureRetryBudgetTokensStub = stub
+}
+
+func (fake *FakeMetricReporter) CaptureRetryBudgetTokensArgsForCall(i int) float64 {
+	fake.captureRetryBudgetTokensMutex.RLock()
+	defer fake.captureRetryBudgetTokensMutex.RUnlock()
+	argsForCall := fake.captureRetryBudgetTokensArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeMetricReporter) CaptureRouteRegistrationLatency(arg1 time.Duration) {
 	fake.captureRouteRegistrationLatencyMutex.Lock()
 	fake.captureRouteRegistrationLatencyArgsForCall = append(fake.captureRouteRegistrationLatencyArgsForCall, struct {
@@ -594,6 +890,39 @@ func (fake *FakeMetricReporter) CaptureRouteServiceResponseArgsForCall(i int) *h
 	return argsForCall.arg1
 }
 
+func (fake *FakeMetricReporter) CaptureRouteSourceEvent(arg1 string, arg2 string) {
+	fake.captureRouteSourceEventMutex.Lock()
+	fake.captureRouteSourceEventArgsForCall = append(fake.captureRouteSourceEventArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.CaptureRouteSourceEventStub
+	fake.recordInvocation("CaptureRouteSourceEvent", []interface{}{arg1, arg2})
+	fake.captureRouteSourceEventMutex.Unlock()
+	if stub != nil {
+		fake.CaptureRouteSourceEventStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeMetricReporter) CaptureRouteSourceEventCallCount() int {
+	fake.captureRouteSourceEventMutex.RLock()
+	defer fake.captureRouteSourceEventMutex.RUnlock()
+	return len(fake.captureRouteSourceEventArgsForCall)
+}
+
+func (fake *FakeMetricReporter) CaptureRouteSourceEventCalls(stub func(string, string)) {
+	fake.captureRouteSourceEventMutex.Lock()
+	defer fake.captureRouteSourceEventMutex.Unlock()
+	fake.CaptureRouteSourceEventStub = stub
+}
+
+func (fake *FakeMetricReporter) CaptureRouteSourceEventArgsForCall(i int) (string, string) {
+	fake.captureRouteSourceEventMutex.RLock()
+	defer fake.captureRouteSourceEventMutex.RUnlock()
+	argsForCall := fake.captureRouteSourceEventArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
 func (fake *FakeMetricReporter) CaptureRouteStats(arg1 int, arg2 int64) {
 	fake.captureRouteStatsMutex.Lock()
 	fake.captureRouteStatsArgsForCall = append(fake.captureRouteStatsArgsForCall, struct {
@@ -758,6 +1087,38 @@ func (fake *FakeMetricReporter) CaptureRoutingResponseLatencyArgsForCall(i int)
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
+func (fake *FakeMetricReporter) CaptureShadowResponse(arg1 *http.Response) {
+	fake.captureShadowResponseMutex.Lock()
+	fake.captureShadowResponseArgsForCall = append(fake.captureShadowResponseArgsForCall, struct {
+		arg1 *http.Response
+	}{arg1})
+	stub := fake.CaptureShadowResponseStub
+	fake.recordInvocation("CaptureShadowResponse", []interface{}{arg1})
+	fake.captureShadowResponseMutex.Unlock()
+	if stub != nil {
+		fake.CaptureShadowResponseStub(arg1)
+	}
+}
+
+func (fake *FakeMetricReporter) CaptureShadowResponseCallCount() int {
+	fake.captureShadowResponseMutex.RLock()
+	defer fake.captureShadowResponseMutex.RUnlock()
+	return len(fake.captureShadowResponseArgsForCall)
+}
+
+func (fake *FakeMetricReporter) CaptureShadowResponseCalls(stub func(*http.Response)) {
+	fake.captureShadowResponseMutex.Lock()
+	defer fake.captureShadowResponseMutex.Unlock()
+	fake.CaptureShadowResponseStub = stub
+}
+
+func (fake *FakeMetricReporter) CaptureShadowResponseArgsForCall(i int) *http.Response {
+	fake.captureShadowResponseMutex.RLock()
+	defer fake.captureShadowResponseMutex.RUnlock()
+	argsForCall := fake.captureShadowResponseArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeMetricReporter) CaptureUnregistryMessage(arg1 metrics.ComponentTagged) {
 	fake.captureUnregistryMessageMutex.Lock()
 	fake.captureUnregistryMessageArgsForCall = append(fake.captureUnregistryMessageArgsForCall, struct {
@@ -838,6 +1199,38 @@ func (fake *FakeMetricReporter) CaptureWebSocketUpdateCalls(stub func()) {
 	fake.CaptureWebSocketUpdateStub = stub
 }
 
+func (fake *FakeMetricReporter) CaptureZoneSelection(arg1 string) {
+	fake.captureZoneSelectionMutex.Lock()
+	fake.captureZoneSelectionArgsForCall = append(fake.captureZoneSelectionArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.CaptureZoneSelectionStub
+	fake.recordInvocation("CaptureZoneSelection", []interface{}{arg1})
+	fake.captureZoneSelectionMutex.Unlock()
+	if stub != nil {
+		fake.CaptureZoneSelectionStub(arg1)
+	}
+}
+
+func (fake *FakeMetricReporter) CaptureZoneSelectionCallCount() int {
+	fake.captureZoneSelectionMutex.RLock()
+	defer fake.captureZoneSelectionMutex.RUnlock()
+	return len(fake.captureZoneSelectionArgsForCall)
+}
+
+func (fake *FakeMetricReporter) CaptureZoneSelectionCalls(stub func(string)) {
+	fake.captureZoneSelectionMutex.Lock()
+	defer fake.captureZoneSelectionMutex.Unlock()
+	fake.CaptureZoneSelectionStub = stub
+}
+
+func (fake *FakeMetricReporter) CaptureZoneSelectionArgsForCall(i int) string {
+	fake.captureZoneSelectionMutex.RLock()
+	defer fake.captureZoneSelectionMutex.RUnlock()
+	argsForCall := fake.captureZoneSelectionArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeMetricReporter) UnmuzzleRouteRegistrationLatency() {
 	fake.unmuzzleRouteRegistrationLatencyMutex.Lock()
 	fake.unmuzzleRouteRegistrationLatencyArgsForCall = append(fake.unmuzzleRouteRegistrationLatencyArgsForCall, struct {
@@ -877,6 +1270,8 @@ func (fake *FakeMetricReporter) Invocations() map[string][][]interface{} {
 	defer fake.captureBadGatewayMutex.RUnlock()
 	fake.captureBadRequestMutex.RLock()
 	defer fake.captureBadRequestMutex.RUnlock()
+	fake.captureConcurrencyLimitMutex.RLock()
+	defer fake.captureConcurrencyLimitMutex.RUnlock()
 	fake.captureEmptyContentLengthHeaderMutex.RLock()
 	defer fake.captureEmptyContentLengthHeaderMutex.RUnlock()
 	fake.captureFoundFileDescriptorsMutex.RLock()
@@ -887,16 +1282,32 @@ func (fake *FakeMetricReporter) Invocations() map[string][][]interface{} {
 	defer fake.captureHTTPLatencyMutex.RUnlock()
 	fake.captureLookupTimeMutex.RLock()
 	defer fake.captureLookupTimeMutex.RUnlock()
+	fake.captureMaxConnsQueueDepthMutex.RLock()
+	defer fake.captureMaxConnsQueueDepthMutex.RUnlock()
+	fake.captureMaxConnsQueueWaitTimeMutex.RLock()
+	defer fake.captureMaxConnsQueueWaitTimeMutex.RUnlock()
 	fake.captureNATSBufferedMessagesMutex.RLock()
 	defer fake.captureNATSBufferedMessagesMutex.RUnlock()
 	fake.captureNATSDroppedMessagesMutex.RLock()
 	defer fake.captureNATSDroppedMessagesMutex.RUnlock()
+	fake.captureOutlierEjectedMutex.RLock()
+	defer fake.captureOutlierEjectedMutex.RUnlock()
+	fake.captureOutlierReturnedMutex.RLock()
+	defer fake.captureOutlierReturnedMutex.RUnlock()
 	fake.captureRegistryMessageMutex.RLock()
 	defer fake.captureRegistryMessageMutex.RUnlock()
+	fake.captureRetryBudgetDeniedMutex.RLock()
+	defer fake.captureRetryBudgetDeniedMutex.RUnlock()
+	fake.captureRetryBudgetExhaustedMutex.RLock()
+	defer fake.captureRetryBudgetExhaustedMutex.RUnlock()
+	fake.captureRetryBudgetTokensMutex.RLock()
+	defer fake.captureRetryBudgetTokensMutex.RUnlock()
 	fake.captureRouteRegistrationLatencyMutex.RLock()
 	defer fake.captureRouteRegistrationLatencyMutex.RUnlock()
 	fake.captureRouteServiceResponseMutex.RLock()
 	defer fake.captureRouteServiceResponseMutex.RUnlock()
+	fake.captureRouteSourceEventMutex.RLock()
+	defer fake.captureRouteSourceEventMutex.RUnlock()
 	fake.captureRouteStatsMutex.RLock()
 	defer fake.captureRouteStatsMutex.RUnlock()
 	fake.captureRoutesPrunedMutex.RLock()
@@ -907,12 +1318,16 @@ func (fake *FakeMetricReporter) Invocations() map[string][][]interface{} {
 	defer fake.captureRoutingResponseMutex.RUnlock()
 	fake.captureRoutingResponseLatencyMutex.RLock()
 	defer fake.captureRoutingResponseLatencyMutex.RUnlock()
+	fake.captureShadowResponseMutex.RLock()
+	defer fake.captureShadowResponseMutex.RUnlock()
 	fake.captureUnregistryMessageMutex.RLock()
 	defer fake.captureUnregistryMessageMutex.RUnlock()
 	fake.captureWebSocketFailureMutex.RLock()
 	defer fake.captureWebSocketFailureMutex.RUnlock()
 	fake.captureWebSocketUpdateMutex.RLock()
 	defer fake.captureWebSocketUpdateMutex.RUnlock()
+	fake.captureZoneSelectionMutex.RLock()
+	defer fake.captureZoneSelectionMutex.RUnlock()
 	fake.unmuzzleRouteRegistrationLatencyMutex.RLock()
 	defer fake.unmuzzleRouteRegistrationLatencyMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
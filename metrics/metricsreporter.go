@@ -72,6 +72,15 @@ func (m *Metrics) CaptureRouteServiceResponse(res *http.Response) {
 	m.Batcher.BatchIncrementCounter("responses.route_services")
 }
 
+func (m *Metrics) CaptureShadowResponse(res *http.Response) {
+	var statusCode int
+	if res != nil {
+		statusCode = res.StatusCode
+	}
+	m.Batcher.BatchIncrementCounter(fmt.Sprintf("responses.shadow.%s", getResponseCounterName(statusCode)))
+	m.Batcher.BatchIncrementCounter("responses.shadow")
+}
+
 func (m *Metrics) CaptureRoutingResponse(statusCode int) {
 	m.Batcher.BatchIncrementCounter(fmt.Sprintf("responses.%s", getResponseCounterName(statusCode)))
 	m.Batcher.BatchIncrementCounter("responses")
@@ -188,6 +197,52 @@ func (m *Metrics) CaptureNATSDroppedMessages(messages int) {
 	m.Sender.SendValue("total_dropped_messages", float64(messages), "message")
 }
 
+func (m *Metrics) CaptureOutlierEjected() {
+	m.Batcher.BatchIncrementCounter("outlier_ejections")
+}
+
+func (m *Metrics) CaptureOutlierReturned() {
+	m.Batcher.BatchIncrementCounter("outlier_returns")
+}
+
+func (m *Metrics) CaptureRetryBudgetExhausted(scope string) {
+	m.Batcher.BatchIncrementCounter(fmt.Sprintf("retry_budget_exhausted.%s", scope))
+}
+
+// CaptureRetryBudgetDenied is only reported to Prometheus, where the route is
+// a label rather than part of the metric name.
+func (m *Metrics) CaptureRetryBudgetDenied(_ string) {
+}
+
+func (m *Metrics) CaptureRetryBudgetTokens(tokens float64) {
+	m.Sender.SendValue("retry_budget_tokens", tokens, "token")
+}
+
+func (m *Metrics) CaptureMaxConnsQueueDepth(depth int) {
+	m.Sender.SendValue("max_conns_queue_depth", float64(depth), "request")
+}
+
+func (m *Metrics) CaptureMaxConnsQueueWaitTime(d time.Duration) {
+	m.Sender.SendValue("max_conns_queue_wait_time", float64(d)/float64(time.Millisecond), "ms")
+}
+
+func (m *Metrics) CaptureConcurrencyLimit(b *route.Endpoint, limit int64) {
+	m.Sender.SendValue("concurrency_limit", float64(limit), "request")
+
+	componentName, ok := b.Tags["component"]
+	if ok && len(componentName) > 0 {
+		m.Sender.SendValue(fmt.Sprintf("concurrency_limit.%s", componentName), float64(limit), "request")
+	}
+}
+
+func (m *Metrics) CaptureZoneSelection(zone string) {
+	m.Batcher.BatchIncrementCounter(fmt.Sprintf("az_selections.%s", zone))
+}
+
+func (m *Metrics) CaptureRouteSourceEvent(source string, event string) {
+	m.Batcher.BatchIncrementCounter(fmt.Sprintf("route_source_events.%s.%s", source, event))
+}
+
 // CaptureHTTPLatency observes histogram of HTTP latency metric
 // Empty implementation here is to fulfil interface
 func (m *Metrics) CaptureHTTPLatency(_ time.Duration, _ string) {
@@ -79,6 +79,91 @@ var _ = Describe("MetricsReporter", func() {
 		Expect(batcher.BatchIncrementCounterArgsForCall(1)).To(Equal("backend_exhausted_conns"))
 	})
 
+	It("increments the outlier_ejections metric", func() {
+		metricReporter.CaptureOutlierEjected()
+
+		Expect(batcher.BatchIncrementCounterCallCount()).To(Equal(1))
+		Expect(batcher.BatchIncrementCounterArgsForCall(0)).To(Equal("outlier_ejections"))
+	})
+
+	It("increments the retry_budget_exhausted metric of the scope", func() {
+		metricReporter.CaptureRetryBudgetExhausted("route")
+
+		Expect(batcher.BatchIncrementCounterCallCount()).To(Equal(1))
+		Expect(batcher.BatchIncrementCounterArgsForCall(0)).To(Equal("retry_budget_exhausted.route"))
+	})
+
+	It("sends the retry_budget_tokens metric", func() {
+		metricReporter.CaptureRetryBudgetTokens(42.5)
+
+		Expect(sender.SendValueCallCount()).To(Equal(1))
+		name, value, unit := sender.SendValueArgsForCall(0)
+		Expect(name).To(Equal("retry_budget_tokens"))
+		Expect(value).To(Equal(42.5))
+		Expect(unit).To(Equal("token"))
+	})
+
+	It("sends the max_conns_queue_depth metric", func() {
+		metricReporter.CaptureMaxConnsQueueDepth(4)
+
+		Expect(sender.SendValueCallCount()).To(Equal(1))
+		name, value, unit := sender.SendValueArgsForCall(0)
+		Expect(name).To(Equal("max_conns_queue_depth"))
+		Expect(value).To(Equal(4.0))
+		Expect(unit).To(Equal("request"))
+	})
+
+	It("sends the max_conns_queue_wait_time metric in milliseconds", func() {
+		metricReporter.CaptureMaxConnsQueueWaitTime(1500 * time.Millisecond)
+
+		Expect(sender.SendValueCallCount()).To(Equal(1))
+		name, value, unit := sender.SendValueArgsForCall(0)
+		Expect(name).To(Equal("max_conns_queue_wait_time"))
+		Expect(value).To(Equal(1500.0))
+		Expect(unit).To(Equal("ms"))
+	})
+
+	It("sends the concurrency_limit metric", func() {
+		metricReporter.CaptureConcurrencyLimit(endpoint, 25)
+
+		Expect(sender.SendValueCallCount()).To(Equal(1))
+		name, value, unit := sender.SendValueArgsForCall(0)
+		Expect(name).To(Equal("concurrency_limit"))
+		Expect(value).To(Equal(25.0))
+		Expect(unit).To(Equal("request"))
+	})
+
+	It("sends the concurrency_limit metric of the component", func() {
+		endpoint.Tags = map[string]string{"component": "CloudController"}
+		metricReporter.CaptureConcurrencyLimit(endpoint, 25)
+
+		Expect(sender.SendValueCallCount()).To(Equal(2))
+		name, value, _ := sender.SendValueArgsForCall(1)
+		Expect(name).To(Equal("concurrency_limit.CloudController"))
+		Expect(value).To(Equal(25.0))
+	})
+
+	It("increments the az_selections metric of the zone", func() {
+		metricReporter.CaptureZoneSelection("z1")
+
+		Expect(batcher.BatchIncrementCounterCallCount()).To(Equal(1))
+		Expect(batcher.BatchIncrementCounterArgsForCall(0)).To(Equal("az_selections.z1"))
+	})
+
+	It("increments the route_source_events metric of the source and event", func() {
+		metricReporter.CaptureRouteSourceEvent("nats", "register")
+
+		Expect(batcher.BatchIncrementCounterCallCount()).To(Equal(1))
+		Expect(batcher.BatchIncrementCounterArgsForCall(0)).To(Equal("route_source_events.nats.register"))
+	})
+
+	It("increments the outlier_returns metric", func() {
+		metricReporter.CaptureOutlierReturned()
+
+		Expect(batcher.BatchIncrementCounterCallCount()).To(Equal(1))
+		Expect(batcher.BatchIncrementCounterArgsForCall(0)).To(Equal("outlier_returns"))
+	})
+
 	It("increments the backend_invalid_id metric", func() {
 		metricReporter.CaptureBackendInvalidID()
 
@@ -259,6 +344,25 @@ var _ = Describe("MetricsReporter", func() {
 		})
 	})
 
+	Context("increments the response metrics for shadow requests", func() {
+		It("increments the response metrics by status group", func() {
+			response := http.Response{
+				StatusCode: 503,
+			}
+
+			metricReporter.CaptureShadowResponse(&response)
+			Expect(batcher.BatchIncrementCounterCallCount()).To(Equal(2))
+			Expect(batcher.BatchIncrementCounterArgsForCall(0)).To(Equal("responses.shadow.5xx"))
+			Expect(batcher.BatchIncrementCounterArgsForCall(1)).To(Equal("responses.shadow"))
+		})
+
+		It("increments the XXX response metrics with null response", func() {
+			metricReporter.CaptureShadowResponse(nil)
+			Expect(batcher.BatchIncrementCounterCallCount()).To(Equal(2))
+			Expect(batcher.BatchIncrementCounterArgsForCall(0)).To(Equal("responses.shadow.xxx"))
+		})
+	})
+
 	Context("increments the response metrics", func() {
 		It("increments the 2XX response metrics", func() {
 			metricReporter.CaptureRoutingResponse(200)
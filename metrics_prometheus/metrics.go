@@ -35,11 +35,22 @@ type Metrics struct {
 	WebsocketFailures           mr.Counter
 	Responses                   mr.CounterVec
 	RouteServicesResponses      mr.CounterVec
+	ShadowResponses             mr.CounterVec
 	RoutingResponseLatency      mr.HistogramVec
 	FoundFileDescriptors        mr.Gauge
 	NATSBufferedMessages        mr.Gauge
 	NATSDroppedMessages         mr.Gauge
 	HTTPLatency                 mr.HistogramVec
+	OutlierEjections            mr.Counter
+	OutlierReturns              mr.Counter
+	RetryBudgetExhausted        mr.CounterVec
+	RetryBudgetDenied           mr.CounterVec
+	RetryBudgetTokens           mr.Gauge
+	MaxConnsQueueDepth          mr.Gauge
+	MaxConnsQueueWaitTime       mr.Histogram
+	ConcurrencyLimit            mr.HistogramVec
+	ZoneSelections              mr.CounterVec
+	RouteSourceEvents           mr.CounterVec
 	perRequestMetricsReporting  bool
 }
 
@@ -81,11 +92,22 @@ func NewMetrics(registry *mr.Registry, perRequestMetricsReporting bool, meterCon
 		WebsocketFailures:           registry.NewCounter("websocket_failures", "websocket failure"),
 		Responses:                   registry.NewCounterVec("responses", "number of responses", []string{"status_group"}),
 		RouteServicesResponses:      registry.NewCounterVec("responses_route_services", "number of responses for route services", []string{"status_group"}),
+		ShadowResponses:             registry.NewCounterVec("responses_shadow", "number of responses for copies of requests sent to shadow routes", []string{"status_group"}),
 		RoutingResponseLatency:      registry.NewHistogramVec("latency", "routing response latency in ms", []string{"component"}, meterConfig.RoutingResponseLatencyHistogramBuckets),
 		FoundFileDescriptors:        registry.NewGauge("file_descriptors", "number of file descriptors found"),
 		NATSBufferedMessages:        registry.NewGauge("buffered_messages", "number of buffered messages in NATS"),
 		NATSDroppedMessages:         registry.NewGauge("total_dropped_messages", "number of total dropped messages in NATS"),
 		HTTPLatency:                 registry.NewHistogramVec("http_latency_seconds", "the latency of http requests from gorouter and back in sec", []string{"source_id"}, meterConfig.HTTPLatencyHistogramBuckets),
+		OutlierEjections:            registry.NewCounter("outlier_ejections", "number of endpoints ejected by outlier detection"),
+		OutlierReturns:              registry.NewCounter("outlier_returns", "number of endpoints returned to their pool after an ejection"),
+		RetryBudgetExhausted:        registry.NewCounterVec("retry_budget_exhausted", "number of retries denied because the retry budget of a route or the global retry budget was exhausted", []string{"scope"}),
+		RetryBudgetDenied:           registry.NewCounterVec("retry_budget_denied", "number of retries of requests to a route denied by the retry budget of the route or the global retry budget", []string{"route"}),
+		RetryBudgetTokens:           registry.NewGauge("retry_budget_tokens", "number of retries left in the global retry budget"),
+		MaxConnsQueueDepth:          registry.NewGauge("max_conns_queue_depth", "number of requests waiting for a backend connection across all routes"),
+		MaxConnsQueueWaitTime:       registry.NewHistogram("max_conns_queue_wait_time_seconds", "time requests waited for a backend connection in sec", meterConfig.MaxConnsQueueWaitTimeHistogramBuckets),
+		ConcurrencyLimit:            registry.NewHistogramVec("concurrency_limit", "adaptive concurrency limits of endpoints when they change", []string{"component"}, meterConfig.ConcurrencyLimitHistogramBuckets),
+		ZoneSelections:              registry.NewCounterVec("az_selections", "number of requests sent to endpoints in each availability zone", []string{"zone"}),
+		RouteSourceEvents:           registry.NewCounterVec("route_source_events", "number of route registrations and unregistrations from each route source", []string{"source", "event"}),
 		perRequestMetricsReporting:  perRequestMetricsReporting,
 	}
 }
@@ -137,6 +159,46 @@ func (metrics *Metrics) CaptureBackendExhaustedConns() {
 	metrics.BackendExhaustedConns.Add(1)
 }
 
+func (metrics *Metrics) CaptureOutlierEjected() {
+	metrics.OutlierEjections.Add(1)
+}
+
+func (metrics *Metrics) CaptureOutlierReturned() {
+	metrics.OutlierReturns.Add(1)
+}
+
+func (metrics *Metrics) CaptureRetryBudgetExhausted(scope string) {
+	metrics.RetryBudgetExhausted.Add(1, []string{scope})
+}
+
+func (metrics *Metrics) CaptureRetryBudgetDenied(route string) {
+	metrics.RetryBudgetDenied.Add(1, []string{route})
+}
+
+func (metrics *Metrics) CaptureRetryBudgetTokens(tokens float64) {
+	metrics.RetryBudgetTokens.Set(tokens)
+}
+
+func (metrics *Metrics) CaptureMaxConnsQueueDepth(depth int) {
+	metrics.MaxConnsQueueDepth.Set(float64(depth))
+}
+
+func (metrics *Metrics) CaptureMaxConnsQueueWaitTime(d time.Duration) {
+	metrics.MaxConnsQueueWaitTime.Observe(d.Seconds())
+}
+
+func (metrics *Metrics) CaptureConcurrencyLimit(b *route.Endpoint, limit int64) {
+	metrics.ConcurrencyLimit.Observe(float64(limit), []string{b.Component()})
+}
+
+func (metrics *Metrics) CaptureZoneSelection(zone string) {
+	metrics.ZoneSelections.Add(1, []string{zone})
+}
+
+func (metrics *Metrics) CaptureRouteSourceEvent(source string, event string) {
+	metrics.RouteSourceEvents.Add(1, []string{source, event})
+}
+
 func (metrics *Metrics) CaptureBadGateway() {
 	metrics.BadGateway.Add(1)
 }
@@ -185,6 +247,14 @@ func (metrics *Metrics) CaptureRouteServiceResponse(res *http.Response) {
 	metrics.RouteServicesResponses.Add(1, []string{statusGroupName(statusCode)})
 }
 
+func (metrics *Metrics) CaptureShadowResponse(res *http.Response) {
+	var statusCode int
+	if res != nil {
+		statusCode = res.StatusCode
+	}
+	metrics.ShadowResponses.Add(1, []string{statusGroupName(statusCode)})
+}
+
 func (metrics *Metrics) CaptureWebSocketUpdate() {
 	metrics.WebsocketUpgrades.Add(1)
 }
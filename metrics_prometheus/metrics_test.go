@@ -183,6 +183,68 @@ var _ = Describe("Metrics", func() {
 			m.CaptureBackendExhaustedConns()
 			Expect(getMetrics(r.Port())).To(ContainSubstring("backend_exhausted_conns 2"))
 		})
+
+		It("increments the outlier ejections metric", func() {
+			m.CaptureOutlierEjected()
+			Expect(getMetrics(r.Port())).To(ContainSubstring("outlier_ejections 1"))
+		})
+
+		It("increments the outlier returns metric", func() {
+			m.CaptureOutlierReturned()
+			Expect(getMetrics(r.Port())).To(ContainSubstring("outlier_returns 1"))
+		})
+
+		It("increments the retry budget exhausted metric of the scope", func() {
+			m.CaptureRetryBudgetExhausted("route")
+			m.CaptureRetryBudgetExhausted("route")
+			m.CaptureRetryBudgetExhausted("global")
+			Expect(getMetrics(r.Port())).To(ContainSubstring("retry_budget_exhausted{scope=\"route\"} 2"))
+			Expect(getMetrics(r.Port())).To(ContainSubstring("retry_budget_exhausted{scope=\"global\"} 1"))
+		})
+
+		It("increments the retry budget denied metric of the route", func() {
+			m.CaptureRetryBudgetDenied("app.example.com/api")
+			Expect(getMetrics(r.Port())).To(ContainSubstring("retry_budget_denied{route=\"app.example.com/api\"} 1"))
+		})
+
+		It("sets the retry budget tokens metric", func() {
+			m.CaptureRetryBudgetTokens(12)
+			Expect(getMetrics(r.Port())).To(ContainSubstring("retry_budget_tokens 12"))
+		})
+
+		It("sets the max conns queue depth metric", func() {
+			m.CaptureMaxConnsQueueDepth(5)
+			Expect(getMetrics(r.Port())).To(ContainSubstring("max_conns_queue_depth 5"))
+		})
+
+		It("observes the max conns queue wait time metric", func() {
+			m.CaptureMaxConnsQueueWaitTime(300 * time.Millisecond)
+			m.CaptureMaxConnsQueueWaitTime(2 * time.Second)
+			Expect(getMetrics(r.Port())).To(ContainSubstring("max_conns_queue_wait_time_seconds_bucket{le=\"0.5\"} 1"))
+			Expect(getMetrics(r.Port())).To(ContainSubstring("max_conns_queue_wait_time_seconds_count 2"))
+		})
+
+		It("observes the concurrency limit metric of the component", func() {
+			endpoint := route.NewEndpoint(&route.EndpointOpts{Tags: map[string]string{"component": "uaa"}})
+			m.CaptureConcurrencyLimit(endpoint, 8)
+			Expect(getMetrics(r.Port())).To(ContainSubstring("concurrency_limit_bucket{component=\"uaa\",le=\"10\"} 1"))
+		})
+
+		It("increments the az selections metric of the zone", func() {
+			m.CaptureZoneSelection("z1")
+			m.CaptureZoneSelection("z1")
+			m.CaptureZoneSelection("z2")
+			Expect(getMetrics(r.Port())).To(ContainSubstring("az_selections{zone=\"z1\"} 2"))
+			Expect(getMetrics(r.Port())).To(ContainSubstring("az_selections{zone=\"z2\"} 1"))
+		})
+
+		It("increments the route source events metric of the source and event", func() {
+			m.CaptureRouteSourceEvent("nats", "register")
+			m.CaptureRouteSourceEvent("nats", "register")
+			m.CaptureRouteSourceEvent("routing-api", "unregister")
+			Expect(getMetrics(r.Port())).To(ContainSubstring("route_source_events{event=\"register\",source=\"nats\"} 2"))
+			Expect(getMetrics(r.Port())).To(ContainSubstring("route_source_events{event=\"unregister\",source=\"routing-api\"} 1"))
+		})
 	})
 	Context("websocket metrics", func() {
 		BeforeEach(func() {
@@ -376,6 +438,28 @@ var _ = Describe("Metrics", func() {
 		})
 	})
 
+	Context("increments the response metrics for shadow requests", func() {
+		BeforeEach(func() {
+			var perRequestMetricsReporting = true
+			var config = config.PrometheusConfig{Port: 0, Meters: getMetersConfig()}
+			r = NewMetricsRegistry(config)
+			m = NewMetrics(r, perRequestMetricsReporting, config.Meters)
+		})
+
+		It("increments the response metrics by status group", func() {
+			m.CaptureShadowResponse(&http.Response{StatusCode: 200})
+			Expect(getMetrics(r.Port())).To(ContainSubstring("responses_shadow{status_group=\"2xx\"} 1"))
+
+			m.CaptureShadowResponse(&http.Response{StatusCode: 502})
+			Expect(getMetrics(r.Port())).To(ContainSubstring("responses_shadow{status_group=\"5xx\"} 1"))
+		})
+
+		It("increments the XXX response metrics with null response", func() {
+			m.CaptureShadowResponse(nil)
+			Expect(getMetrics(r.Port())).To(ContainSubstring("responses_shadow{status_group=\"xxx\"} 1"))
+		})
+	})
+
 	Context("increments the route response metrics", func() {
 		var endpoint *route.Endpoint
 
@@ -464,6 +548,8 @@ func getMetersConfig() config.MetersConfig {
 		RouteRegistrationLatencyHistogramBuckets: []float64{0.2, 0.4, 0.6, 0.8, 1, 1.2, 1.4, 1.6, 1.8, 2},
 		RoutingResponseLatencyHistogramBuckets:   []float64{0.2, 0.4, 0.6, 0.8, 1, 1.2, 1.4, 1.6, 1.8, 2},
 		HTTPLatencyHistogramBuckets:              []float64{0.1, 0.2, 0.4, 0.8, 1.6, 3.2, 6.4, 12.8, 25.6},
+		MaxConnsQueueWaitTimeHistogramBuckets:    []float64{0.1, 0.5, 1, 5},
+		ConcurrencyLimitHistogramBuckets:         []float64{1, 5, 10, 50},
 	}
 }
 
@@ -81,7 +81,7 @@ func NewProxy(
 
 	roundTripperFactory := &round_tripper.FactoryImpl{
 		BackendTemplate: &http.Transport{
-			DialContext:            dialer.DialContext,
+			DialContext:            round_tripper.DialContext(dialer),
 			DisableKeepAlives:      cfg.DisableKeepAlives,
 			MaxIdleConns:           cfg.MaxIdleConns,
 			IdleConnTimeout:        90 * time.Second, // setting the value to golang default transport
@@ -106,6 +106,11 @@ func NewProxy(
 		IsInstrumented: cfg.SendHttpStartStopClientEvent,
 	}
 
+	stickySessionCodec, err := handlers.NewStickySessionCodec(cfg.StickySessionCookieEncryption)
+	if err != nil {
+		log.Fatal(logger, "failed-to-create-sticky-session-codec", log.ErrAttr(err))
+	}
+
 	prt := round_tripper.NewProxyRoundTripper(
 		roundTripperFactory,
 		fails.RetriableClassifiers,
@@ -116,6 +121,7 @@ func NewProxy(
 			ErrorSpecs:     round_tripper.DefaultErrorSpecs,
 		},
 		routeServicesTransport,
+		stickySessionCodec,
 		cfg,
 	)
 
@@ -174,8 +180,9 @@ func NewProxy(
 	n.Use(handlers.NewHTTPRewriteHandler(cfg.HTTPRewrite, headersToAlwaysRemove))
 	n.Use(handlers.NewProxyHealthcheck(cfg.HealthCheckUserAgent, p.health))
 	n.Use(handlers.NewProtocolCheck(logger, errorWriter, cfg.EnableHTTP2))
-	n.Use(handlers.NewLookup(registry, reporter, logger, errorWriter, cfg.EmptyPoolResponseCode503))
-	n.Use(handlers.NewMaxRequestSize(cfg, logger))
+	n.Use(handlers.NewLookup(registry, reporter, logger, errorWriter, cfg.EmptyPoolResponseCode503, cfg.FallbackBackend))
+	n.Use(handlers.NewRoutingRules(cfg.RoutingRules, logger))
+	n.Use(handlers.NewMaxRequestSize(cfg, stickySessionCodec, logger))
 	n.Use(handlers.NewClientCert(
 		SkipSanitize(routeServiceHandler.(*handlers.RouteService)),
 		ForceDeleteXFCCHeader(routeServiceHandler.(*handlers.RouteService), cfg.ForwardedClientCert, logger),
@@ -190,6 +197,7 @@ func NewProxy(
 		SanitizeForwardedProto:   p.config.SanitizeForwardedProto,
 	})
 	n.Use(routeServiceHandler)
+	n.Use(newShadow(registry, roundTripperFactory, accessLogger, reporter, headersToLog, cfg, logger))
 	n.Use(p)
 	n.Use(handlers.NewProxyPicker(rproxy, expect100ContinueRProxy))
 
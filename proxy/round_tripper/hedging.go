@@ -0,0 +1,168 @@
+package round_tripper
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/handlers"
+	log "code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+// newHedgingBudget creates the budget which limits the hedged requests of all
+// routes.
+func newHedgingBudget(cfg config.HedgingConfig) *route.RetryBudget {
+	return route.NewRetryBudget(&config.RetryBudgetConfig{
+		Enabled:             true,
+		RetryRatio:          cfg.Ratio,
+		MinRetriesPerSecond: cfg.MinPerSecond,
+		MaxTokens:           cfg.MaxTokens,
+	})
+}
+
+// hedgeDelay returns the time after which the request is also sent to a second
+// endpoint of the pool, or zero if it is not hedged. Only requests without a
+// body which only read data are hedged, and only for routes which enable it.
+func hedgeDelay(request *http.Request, pool *route.EndpointPool) time.Duration {
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		return 0
+	}
+	if (request.Body != nil && request.Body != http.NoBody) || handlers.IsWebSocketUpgrade(request) {
+		return 0
+	}
+	return pool.HedgeDelay()
+}
+
+type attemptResult struct {
+	index    int
+	res      *http.Response
+	err      error
+	endpoint *route.Endpoint
+}
+
+// hedgedRoundTrip sends the request to endpoint and, if it has not responded
+// within delay and the hedging budget allows it, a second time to another
+// endpoint. The first response is returned together with the endpoint which
+// sent it, and the other attempt is canceled. If all attempts fail, the error
+// of the last one is returned. The endpoints of all attempts are added to
+// triedEndpoints, and those of failed attempts are marked failed in the pool,
+// as the iterator only knows the endpoint it returned last.
+func (rt *roundTripper) hedgedRoundTrip(
+	request *http.Request,
+	endpoint *route.Endpoint,
+	iter route.EndpointIterator,
+	pool *route.EndpointPool,
+	timeout time.Duration,
+	delay time.Duration,
+	triedEndpoints map[string]bool,
+	logger *slog.Logger,
+) (*http.Response, *route.Endpoint, error) {
+	results := make(chan attemptResult, 2)
+	var cancels []context.CancelFunc
+
+	send := func(endpoint *route.Endpoint) {
+		triedEndpoints[endpoint.CanonicalAddr()] = true
+		ctx, cancel := context.WithCancel(request.Context())
+		cancels = append(cancels, cancel)
+		req := request.Clone(ctx)
+		if endpoint.IsTLS() {
+			req.URL.Scheme = "https"
+		} else {
+			req.URL.Scheme = "http"
+		}
+
+		index := len(cancels) - 1
+		go func() {
+			res, err := rt.backendRoundTrip(req, endpoint, iter, pool, timeout, logger)
+			results <- attemptResult{index: index, res: res, err: err, endpoint: endpoint}
+		}()
+	}
+
+	send(endpoint)
+	pending := 1
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	hedge := timer.C
+
+	var result attemptResult
+	for pending > 0 {
+		select {
+		case <-hedge:
+			hedge = nil
+			if hedgeEndpoint := rt.selectHedgeEndpoint(iter, endpoint, logger); hedgeEndpoint != nil {
+				logger.Debug("hedged-request-sent", slog.Group("hedge-endpoint", hedgeEndpoint.ToLogData()...))
+				send(hedgeEndpoint)
+				pending++
+			}
+		case result = <-results:
+			pending--
+			if result.err != nil {
+				pool.EndpointFailed(result.endpoint, result.err)
+				if pending > 0 {
+					logger.Debug("hedged-attempt-failed", log.ErrAttr(result.err))
+					continue
+				}
+			}
+
+			for i, cancel := range cancels {
+				if i != result.index {
+					cancel()
+				}
+			}
+			go discardResults(results, pending)
+			pending = 0
+		}
+	}
+
+	if result.err != nil {
+		cancels[result.index]()
+		return nil, result.endpoint, result.err
+	}
+	// the context of the winning attempt is canceled once its response has
+	// been read
+	if result.res.Body == nil {
+		cancels[result.index]()
+	} else {
+		result.res.Body = &cancelOnClose{ReadCloser: result.res.Body, cancel: cancels[result.index]}
+	}
+	return result.res, result.endpoint, nil
+}
+
+// selectHedgeEndpoint returns an endpoint other than the one a request was sent
+// to, or nil if there is none or the hedging budget is exhausted.
+func (rt *roundTripper) selectHedgeEndpoint(iter route.EndpointIterator, endpoint *route.Endpoint, logger *slog.Logger) *route.Endpoint {
+	hedgeEndpoint := iter.Next(1)
+	if hedgeEndpoint == nil || hedgeEndpoint.CanonicalAddr() == endpoint.CanonicalAddr() {
+		return nil
+	}
+	if !rt.hedgingBudget.Withdraw() {
+		logger.Info("hedging-budget-exhausted")
+		return nil
+	}
+	return hedgeEndpoint
+}
+
+// discardResults discards the responses of the attempts which are still
+// pending once another attempt has responded.
+func discardResults(results <-chan attemptResult, pending int) {
+	for ; pending > 0; pending-- {
+		if result := <-results; result.res != nil {
+			discardResponse(result.res)
+		}
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
@@ -73,8 +73,13 @@ func NewProxyRoundTripper(
 	combinedReporter metrics.MetricReporter,
 	errHandler errorHandler,
 	routeServicesTransport http.RoundTripper,
+	stickySessionCodec *handlers.StickySessionCodec,
 	cfg *config.Config,
 ) ProxyRoundTripper {
+	var retryBudget *route.RetryBudget
+	if cfg.RetryBudget.Enabled {
+		retryBudget = route.NewRetryBudget(&cfg.RetryBudget)
+	}
 
 	return &roundTripper{
 		logger:                 logger,
@@ -83,7 +88,10 @@ func NewProxyRoundTripper(
 		retriableClassifier:    retriableClassifiers,
 		errorHandler:           errHandler,
 		routeServicesTransport: routeServicesTransport,
+		stickySessionCodec:     stickySessionCodec,
 		config:                 cfg,
+		retryBudget:            retryBudget,
+		hedgingBudget:          newHedgingBudget(cfg.Hedging),
 	}
 }
 
@@ -94,7 +102,14 @@ type roundTripper struct {
 	retriableClassifier    fails.Classifier
 	errorHandler           errorHandler
 	routeServicesTransport http.RoundTripper
+	stickySessionCodec     *handlers.StickySessionCodec
 	config                 *config.Config
+
+	// retryBudget limits the retries of requests to backends of all routes
+	retryBudget *route.RetryBudget
+
+	// hedgingBudget limits the hedged requests of all routes
+	hedgingBudget *route.RetryBudget
 }
 
 func (rt *roundTripper) RoundTrip(originalRequest *http.Request) (*http.Response, error) {
@@ -125,9 +140,10 @@ func (rt *roundTripper) RoundTrip(originalRequest *http.Request) (*http.Response
 		return nil, errors.New("ProxyResponseWriter not set on context")
 	}
 
-	stickyEndpointID, mustBeSticky := handlers.GetStickySession(request, rt.config.StickySessionCookieNames, rt.config.StickySessionsForAuthNegotiate)
+	stickyEndpointID, mustBeSticky := handlers.GetStickySession(request, rt.config.StickySessionCookieNames, rt.config.StickySessionsForAuthNegotiate, rt.config.StickySessionHeader, rt.stickySessionCodec)
+	renewStickySession := handlers.StickySessionNeedsRenewal(request, rt.config.StickySessionCookieNames, rt.stickySessionCodec)
 	numberOfEndpoints := reqInfo.RoutePool.NumEndpoints()
-	iter := reqInfo.RoutePool.Endpoints(rt.logger, stickyEndpointID, mustBeSticky, rt.config.LoadBalanceAZPreference, rt.config.Zone)
+	iter := reqInfo.RoutePool.Endpoints(rt.logger, stickyEndpointID, mustBeSticky, rt.config.LoadBalanceAZPreference, rt.config.Zone, reqInfo.RoutePool.RequestHashKey(request), reqInfo.RoutingRule)
 
 	// The selectEndpointErr needs to be tracked separately. If we get an error
 	// while selecting an endpoint we might just have run out of routes. In
@@ -136,13 +152,32 @@ func (rt *roundTripper) RoundTrip(originalRequest *http.Request) (*http.Response
 	// endpoint which would result in a 404 Not Found.
 	var selectEndpointErr error
 	var maxAttempts int
+	var requestTimeout time.Duration
+	var hedgeAfter time.Duration
 	if reqInfo.RouteServiceURL == nil {
-		maxAttempts = max(rt.config.Backends.MaxAttempts, 1)
+		maxAttempts = rt.backendMaxAttempts(reqInfo.RoutePool)
+		requestTimeout = rt.backendRequestTimeout(reqInfo.RoutePool)
+		if dialTimeout := rt.backendDialTimeout(reqInfo.RoutePool); dialTimeout > 0 {
+			request = request.WithContext(withDialTimeout(request.Context(), dialTimeout))
+		}
+		reqInfo.RoutePool.RetryBudget().Deposit()
+		rt.retryBudget.Deposit()
+		if hedgeAfter = hedgeDelay(originalRequest, reqInfo.RoutePool); hedgeAfter > 0 {
+			rt.hedgingBudget.Deposit()
+		}
 	} else {
 		maxAttempts = rt.config.RouteServiceConfig.MaxAttempts
+		requestTimeout = rt.config.EndpointTimeout
 	}
 	triedEndpoints := map[string]bool{}
 
+	// retriedRes is the response of the previous attempt if it is retried
+	// because of its status code. It is discarded once another backend has
+	// been selected, and returned otherwise, together with retriedEndpoint
+	// which sent it.
+	var retriedRes *http.Response
+	var retriedEndpoint *route.Endpoint
+
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		logger := rt.logger
 
@@ -169,6 +204,13 @@ func (rt *roundTripper) RoundTrip(originalRequest *http.Request) (*http.Response
 				logger.Error("select-endpoint-failed", slog.String("host", reqInfo.RoutePool.Host()), log.ErrAttr(selectEndpointErr))
 				break
 			}
+			if retriedRes != nil {
+				discardResponse(retriedRes)
+				retriedRes = nil
+				retriedEndpoint = nil
+				reqInfo.FailedAttempts++
+				reqInfo.LastFailedAttemptFinishedAt = time.Now()
+			}
 			logger = logger.With(slog.Group("route-endpoint", endpoint.ToLogData()...))
 			triedEndpoints[endpoint.CanonicalAddr()] = true
 			reqInfo.RouteEndpoint = endpoint
@@ -179,7 +221,13 @@ func (rt *roundTripper) RoundTrip(originalRequest *http.Request) (*http.Response
 			} else {
 				request.URL.Scheme = "http"
 			}
-			res, err = rt.backendRoundTrip(request, endpoint, iter, logger)
+			hedged := attempt == 1 && hedgeAfter > 0
+			if hedged {
+				res, endpoint, err = rt.hedgedRoundTrip(request, endpoint, iter, reqInfo.RoutePool, requestTimeout, hedgeAfter, triedEndpoints, logger)
+				reqInfo.RouteEndpoint = endpoint
+			} else {
+				res, err = rt.backendRoundTrip(request, endpoint, iter, reqInfo.RoutePool, requestTimeout, logger)
+			}
 
 			logger = logger.With(
 				slog.Int("attempt", attempt),
@@ -204,14 +252,22 @@ func (rt *roundTripper) RoundTrip(originalRequest *http.Request) (*http.Response
 					slog.Bool("retriable", retriable),
 				)
 
-				iter.EndpointFailed(err)
+				// the endpoints of failed hedged attempts are already marked
+				if !hedged {
+					iter.EndpointFailed(err)
+				}
 
 				if retriable {
+					if attempt < maxAttempts && !rt.retryAllowed(reqInfo.RoutePool, logger) {
+						break
+					}
 					continue
 				}
 			}
 
 			if res != nil && err == nil {
+				reqInfo.RoutePool.EndpointResponded(endpoint, res.StatusCode)
+
 				err = checkResponseHeaders(rt.config.MaxResponseHeaders, res.Header)
 				if err != nil {
 					logger.Error("backend-too-many-response-headers",
@@ -220,6 +276,15 @@ func (rt *roundTripper) RoundTrip(originalRequest *http.Request) (*http.Response
 					)
 					break
 				}
+
+				if attempt < maxAttempts && rt.retriableStatus(request, res, reqInfo.RoutePool) && rt.retryAllowed(reqInfo.RoutePool, logger) {
+					logger.Error("backend-endpoint-responded-with-retriable-status",
+						slog.Int("status-code", res.StatusCode),
+					)
+					retriedRes = res
+					retriedEndpoint = endpoint
+					continue
+				}
 			}
 
 			break
@@ -244,7 +309,7 @@ func (rt *roundTripper) RoundTrip(originalRequest *http.Request) (*http.Response
 				roundTripper = rt.routeServicesTransport
 			}
 
-			res, err = rt.timedRoundTrip(roundTripper, request, logger)
+			res, err = rt.timedRoundTrip(roundTripper, request, requestTimeout, logger)
 
 			logger = logger.With(
 				slog.Int("attempt", attempt),
@@ -300,6 +365,13 @@ func (rt *roundTripper) RoundTrip(originalRequest *http.Request) (*http.Response
 		}
 	}
 
+	// no further endpoint was selected, so the response which was to be
+	// retried is returned as the response of the endpoint which sent it
+	if retriedRes != nil {
+		endpoint = retriedEndpoint
+		reqInfo.RouteEndpoint = retriedEndpoint
+	}
+
 	// if the client disconnects before response is sent then return context.Canceled (499) instead of the gateway error
 	if err != nil && errors.Is(originalRequest.Context().Err(), context.Canceled) && !errors.Is(err, context.Canceled) {
 		rt.logger.Error("gateway-error-and-original-request-context-cancelled", log.ErrAttr(err))
@@ -311,8 +383,9 @@ func (rt *roundTripper) RoundTrip(originalRequest *http.Request) (*http.Response
 
 	// If we have an error from the round trip, we prefer it over errors
 	// returned from selecting the endpoint, see declaration of
-	// selectEndpointErr for details.
-	if err == nil {
+	// selectEndpointErr for details. The same applies to a response which was
+	// to be retried.
+	if err == nil && retriedRes == nil {
 		err = selectEndpointErr
 	}
 
@@ -348,10 +421,14 @@ func (rt *roundTripper) RoundTrip(originalRequest *http.Request) (*http.Response
 
 	if res != nil && endpoint.PrivateInstanceId != "" && !requestSentToRouteService(request) {
 		setupStickySession(
-			res, endpoint, stickyEndpointID, rt.config.SecureCookies,
+			res, endpoint, stickyEndpointID, renewStickySession, rt.config.SecureCookies,
 			reqInfo.RoutePool.ContextPath(), rt.config.StickySessionCookieNames,
 			rt.config.StickySessionsForAuthNegotiate,
+			rt.stickySessionCodec,
 		)
+		if rt.config.StickySessionHeader != "" {
+			setupStickySessionHeader(res, request, endpoint, rt.config.StickySessionHeader, rt.stickySessionCodec)
+		}
 	}
 
 	return res, nil
@@ -367,7 +444,7 @@ func (rt *roundTripper) CancelRequest(request *http.Request) {
 	tr.CancelRequest(request)
 }
 
-func (rt *roundTripper) backendRoundTrip(request *http.Request, endpoint *route.Endpoint, iter route.EndpointIterator, logger *slog.Logger) (*http.Response, error) {
+func (rt *roundTripper) backendRoundTrip(request *http.Request, endpoint *route.Endpoint, iter route.EndpointIterator, pool *route.EndpointPool, timeout time.Duration, logger *slog.Logger) (*http.Response, error) {
 	request.URL.Host = endpoint.CanonicalAddr()
 	request.Header.Set("X-CF-ApplicationID", endpoint.ApplicationId)
 	request.Header.Set("X-CF-InstanceIndex", endpoint.PrivateInstanceIndex)
@@ -377,20 +454,31 @@ func (rt *roundTripper) backendRoundTrip(request *http.Request, endpoint *route.
 	iter.PreRequest(endpoint)
 
 	rt.combinedReporter.CaptureRoutingRequest(endpoint)
+	if rt.config.LoadBalanceAZPreference != config.AZ_PREF_NONE {
+		rt.combinedReporter.CaptureZoneSelection(endpoint.AvailabilityZone)
+	}
 	tr := GetRoundTripper(endpoint, rt.roundTripperFactory, false, rt.config.EnableHTTP2)
-	res, err := rt.timedRoundTrip(tr, request, logger)
+	startedAt := time.Now()
+	res, err := rt.timedRoundTrip(tr, request, timeout, logger)
+
+	// requests canceled by the client say nothing about the endpoint
+	if !errors.Is(err, context.Canceled) {
+		latency := time.Since(startedAt)
+		endpoint.Stats.Latency.Observe(latency)
+		pool.EndpointFinished(endpoint, latency, err != nil)
+	}
 
 	// decrement connection stats
 	iter.PostRequest(endpoint)
 	return res, err
 }
 
-func (rt *roundTripper) timedRoundTrip(tr http.RoundTripper, request *http.Request, logger *slog.Logger) (*http.Response, error) {
-	if rt.config.EndpointTimeout <= 0 || handlers.IsWebSocketUpgrade(request) {
+func (rt *roundTripper) timedRoundTrip(tr http.RoundTripper, request *http.Request, timeout time.Duration, logger *slog.Logger) (*http.Response, error) {
+	if timeout <= 0 || handlers.IsWebSocketUpgrade(request) {
 		return tr.RoundTrip(request)
 	}
 
-	reqCtx, cancel := context.WithTimeout(request.Context(), rt.config.EndpointTimeout)
+	reqCtx, cancel := context.WithTimeout(request.Context(), timeout)
 	request = request.WithContext(reqCtx)
 
 	// unfortunately if the cancel function above is not called that
@@ -453,14 +541,18 @@ func setupStickySession(
 	response *http.Response,
 	endpoint *route.Endpoint,
 	originalEndpointId string,
+	renewVCAPID bool,
 	secureCookies bool,
 	path string,
 	stickySessionCookieNames config.StringSet,
 	authNegotiateSticky bool,
+	stickySessionCodec *handlers.StickySessionCodec,
 ) {
 
-	requestContainsStickySessionCookies := originalEndpointId != ""
-	requestNotSentToRequestedApp := originalEndpointId != endpoint.PrivateInstanceId
+	// a __VCAP_ID__ which is not encrypted with the current secret is replaced
+	// even if the request was sent to its instance
+	requestContainsStickySessionCookies := originalEndpointId != "" || renewVCAPID
+	requestNotSentToRequestedApp := originalEndpointId != endpoint.PrivateInstanceId || renewVCAPID
 	responseContainsAuthNegotiateHeader := strings.HasPrefix(strings.ToLower(response.Header.Get("WWW-Authenticate")), "negotiate")
 	shouldSetVCAPID := ((authNegotiateSticky && responseContainsAuthNegotiateHeader) || requestContainsStickySessionCookies) && requestNotSentToRequestedApp
 
@@ -505,9 +597,14 @@ func setupStickySession(
 			secure = true
 		}
 
+		value, err := stickySessionCodec.Encode(endpoint.PrivateInstanceId)
+		if err != nil {
+			return
+		}
+
 		vcapIDCookie := http.Cookie{
 			Name:        VcapCookieId,
-			Value:       endpoint.PrivateInstanceId,
+			Value:       value,
 			Path:        path,
 			MaxAge:      maxAge,
 			HttpOnly:    true,
@@ -523,6 +620,43 @@ func setupStickySession(
 	}
 }
 
+// setupStickySessionHeader returns the token of the instance which served the
+// request in the sticky session header of the response, so that clients
+// which do not keep cookies can send it with their next requests. Unless the
+// token is encrypted, it is only returned to requests which carry the header,
+// to not expose instance IDs to every client. A token set by the backend is
+// left alone.
+func setupStickySessionHeader(
+	response *http.Response,
+	request *http.Request,
+	endpoint *route.Endpoint,
+	header string,
+	stickySessionCodec *handlers.StickySessionCodec,
+) {
+	if response.Header.Get(header) != "" {
+		return
+	}
+
+	value := request.Header.Get(header)
+	if value == "" && stickySessionCodec == nil {
+		return
+	}
+
+	// the token of the request is reused if it still points to the instance
+	if instanceID, ok := stickySessionCodec.Decode(value); value == "" || !ok || instanceID != endpoint.PrivateInstanceId {
+		var err error
+		value, err = stickySessionCodec.Encode(endpoint.PrivateInstanceId)
+		if err != nil {
+			return
+		}
+	}
+
+	if response.Header == nil {
+		response.Header = http.Header{}
+	}
+	response.Header.Set(header, value)
+}
+
 func requestSentToRouteService(request *http.Request) bool {
 	sigHeader := request.Header.Get(routeservice.HeaderKeySignature)
 	rsUrl := request.Header.Get(routeservice.HeaderKeyForwardedURL)
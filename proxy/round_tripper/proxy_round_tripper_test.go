@@ -2,6 +2,7 @@ package round_tripper_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -11,6 +12,7 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -91,6 +93,7 @@ var _ = Describe("ProxyRoundTripper", func() {
 			routeServicesTransport *sharedfakes.RoundTripper
 			retriableClassifier    *errorClassifierFakes.Classifier
 			errorHandler           *roundtripperfakes.ErrorHandler
+			stickySessionCodec     *handlers.StickySessionCodec
 			cfg                    *config.Config
 
 			reqInfo *handlers.RequestInfo
@@ -139,6 +142,7 @@ var _ = Describe("ProxyRoundTripper", func() {
 			retriableClassifier = &errorClassifierFakes.Classifier{}
 			retriableClassifier.ClassifyReturns(false)
 			routeServicesTransport = &sharedfakes.RoundTripper{}
+			stickySessionCodec = nil
 
 			cfg, err = config.DefaultConfig()
 			Expect(err).ToNot(HaveOccurred())
@@ -170,6 +174,7 @@ var _ = Describe("ProxyRoundTripper", func() {
 				combinedReporter,
 				errorHandler,
 				routeServicesTransport,
+				stickySessionCodec,
 				cfg,
 			)
 		})
@@ -222,6 +227,527 @@ var _ = Describe("ProxyRoundTripper", func() {
 					Expect(outreq.Header.Get("X-CF-InstanceID")).To(Equal("instanceID1"))
 					Expect(outreq.Header.Get("X-CF-InstanceIndex")).To(Equal("1"))
 				})
+
+				It("records the response time of the endpoint", func() {
+					transport.RoundTripStub = func(*http.Request) (*http.Response, error) {
+						time.Sleep(5 * time.Millisecond)
+						return resp.Result(), nil
+					}
+					_, err := proxyRoundTripper.RoundTrip(req)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(endpoint.Stats.Latency.Value()).To(BeNumerically(">=", 4*time.Millisecond))
+				})
+			})
+
+			Context("when outlier detection is enabled", func() {
+				BeforeEach(func() {
+					routePool = route.NewPool(&route.PoolOpts{
+						Logger:                 logger.Logger,
+						RetryAfterFailure:      1 * time.Second,
+						Host:                   "myapp.com",
+						LoadBalancingAlgorithm: config.LOAD_BALANCE_RR,
+						OutlierDetection: &config.OutlierDetectionConfig{
+							Enabled:            true,
+							Consecutive5xx:     1,
+							Interval:           10 * time.Second,
+							BaseEjectionTime:   time.Minute,
+							MaxEjectionTime:    time.Minute,
+							MaxEjectionPercent: 50,
+						},
+					})
+					reqInfo.RoutePool = routePool
+					numEndpoints = 2
+					transport.RoundTripReturns(&http.Response{StatusCode: http.StatusInternalServerError}, nil)
+				})
+
+				It("ejects an endpoint which responds with a server error", func() {
+					_, err := proxyRoundTripper.RoundTrip(req)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(logger).To(gbytes.Say("outlier-endpoint-ejected"))
+					ejected := transport.RoundTripArgsForCall(0).URL.Host
+
+					for i := 0; i < 3; i++ {
+						_, err = proxyRoundTripper.RoundTrip(req)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(transport.RoundTripArgsForCall(i + 1).URL.Host).NotTo(Equal(ejected))
+					}
+				})
+			})
+
+			Context("when adaptive concurrency is enabled", func() {
+				BeforeEach(func() {
+					routePool = route.NewPool(&route.PoolOpts{
+						Logger:                 logger.Logger,
+						RetryAfterFailure:      1 * time.Second,
+						Host:                   "myapp.com",
+						LoadBalancingAlgorithm: config.LOAD_BALANCE_RR,
+						AdaptiveConcurrency: &config.AdaptiveConcurrencyConfig{
+							Enabled:          true,
+							InitialLimit:     10,
+							MinLimit:         1,
+							MaxLimit:         100,
+							LatencyTolerance: 2,
+							BackoffRatio:     0.5,
+						},
+					})
+					reqInfo.RoutePool = routePool
+				})
+
+				It("lowers the limit of an endpoint whose request fails", func() {
+					transport.RoundTripReturns(nil, dialError)
+					_, err := proxyRoundTripper.RoundTrip(req)
+					Expect(err).To(HaveOccurred())
+					Expect(routePool.ConcurrencyLimit(endpoint)).To(Equal(int64(5)))
+				})
+
+				It("keeps the limit of an endpoint which responds", func() {
+					transport.RoundTripReturns(&http.Response{StatusCode: http.StatusOK}, nil)
+					_, err := proxyRoundTripper.RoundTrip(req)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(routePool.ConcurrencyLimit(endpoint)).To(Equal(int64(10)))
+				})
+
+				It("keeps the limit of an endpoint when the client cancels the request", func() {
+					transport.RoundTripReturns(nil, context.Canceled)
+					_, err := proxyRoundTripper.RoundTrip(req)
+					Expect(err).To(HaveOccurred())
+					Expect(routePool.ConcurrencyLimit(endpoint)).To(Equal(int64(10)))
+				})
+			})
+
+			Context("when an AZ preference is set", func() {
+				BeforeEach(func() {
+					cfg.LoadBalanceAZPreference = config.AZ_PREF_ZONE_AWARE
+					cfg.Zone = AZ
+				})
+
+				It("captures the zone of the selected endpoint", func() {
+					transport.RoundTripReturns(&http.Response{StatusCode: http.StatusOK}, nil)
+					_, err := proxyRoundTripper.RoundTrip(req)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(combinedReporter.CaptureZoneSelectionCallCount()).To(Equal(1))
+					Expect(combinedReporter.CaptureZoneSelectionArgsForCall(0)).To(Equal(AZ))
+				})
+			})
+
+			Context("when no AZ preference is set", func() {
+				It("does not capture the zone of the selected endpoint", func() {
+					transport.RoundTripReturns(&http.Response{StatusCode: http.StatusOK}, nil)
+					_, err := proxyRoundTripper.RoundTrip(req)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(combinedReporter.CaptureZoneSelectionCallCount()).To(BeZero())
+				})
+			})
+
+			Context("when the route sets its own max attempts and request timeout", func() {
+				var reqCh chan *http.Request
+
+				BeforeEach(func() {
+					numEndpoints = 5
+					cfg.Backends.MaxAttempts = 2
+					routePool.Put(route.NewEndpoint(&route.EndpointOpts{
+						Host:           "10.0.0.1",
+						Port:           9090,
+						MaxAttempts:    4,
+						RequestTimeout: time.Hour,
+					}))
+
+					reqCh = make(chan *http.Request, 10)
+					transport.RoundTripStub = func(req *http.Request) (*http.Response, error) {
+						reqCh <- req
+						return nil, &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+					}
+					retriableClassifier.ClassifyReturns(true)
+				})
+
+				It("uses the max attempts of the route", func() {
+					_, err := proxyRoundTripper.RoundTrip(req)
+					Expect(err).To(MatchError(ContainSubstring("connection refused")))
+					Expect(transport.RoundTripCallCount()).To(Equal(4))
+				})
+
+				It("uses the request timeout of the route", func() {
+					proxyRoundTripper.RoundTrip(req)
+					var request *http.Request
+					Eventually(reqCh).Should(Receive(&request))
+
+					deadline, deadlineSet := request.Context().Deadline()
+					Expect(deadlineSet).To(BeTrue())
+					Expect(deadline).To(BeTemporally(">", time.Now().Add(59*time.Minute)))
+				})
+
+				Context("when the operator limits the values of routes", func() {
+					BeforeEach(func() {
+						cfg.Backends.MaxRouteAttempts = 3
+						cfg.Backends.MaxRequestTimeout = time.Minute
+					})
+
+					It("limits the max attempts of the route", func() {
+						_, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).To(MatchError(ContainSubstring("connection refused")))
+						Expect(transport.RoundTripCallCount()).To(Equal(3))
+					})
+
+					It("limits the request timeout of the route", func() {
+						proxyRoundTripper.RoundTrip(req)
+						var request *http.Request
+						Eventually(reqCh).Should(Receive(&request))
+
+						deadline, deadlineSet := request.Context().Deadline()
+						Expect(deadlineSet).To(BeTrue())
+						Expect(deadline).To(BeTemporally("<=", time.Now().Add(time.Minute)))
+					})
+				})
+			})
+
+			Context("when backends respond with a retriable status code", func() {
+				var bodies []*testBody
+
+				respond := func(statusCode int) *http.Response {
+					body := &testBody{}
+					body.WriteString("some-body")
+					bodies = append(bodies, body)
+					return &http.Response{StatusCode: statusCode, Body: body}
+				}
+
+				BeforeEach(func() {
+					numEndpoints = 3
+					bodies = nil
+					req.Body = nil
+					cfg.Backends.RetryStatusCodes = []int{http.StatusServiceUnavailable}
+					transport.RoundTripStub = func(*http.Request) (*http.Response, error) {
+						if transport.RoundTripCallCount() < 3 {
+							return respond(http.StatusServiceUnavailable), nil
+						}
+						return respond(http.StatusTeapot), nil
+					}
+				})
+
+				It("retries idempotent requests on the next endpoint", func() {
+					res, err := proxyRoundTripper.RoundTrip(req)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(res.StatusCode).To(Equal(http.StatusTeapot))
+					Expect(transport.RoundTripCallCount()).To(Equal(3))
+					Expect(transport.RoundTripArgsForCall(0).URL.Host).NotTo(Equal(transport.RoundTripArgsForCall(1).URL.Host))
+					Expect(logger).To(gbytes.Say("backend-endpoint-responded-with-retriable-status"))
+				})
+
+				It("drains and closes the discarded responses", func() {
+					res, err := proxyRoundTripper.RoundTrip(req)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(bodies).To(HaveLen(3))
+					for _, body := range bodies[:2] {
+						Expect(body.Len()).To(BeZero())
+						Expect(body.closeCount).To(Equal(1))
+					}
+					Expect(bodies[2].closeCount).To(BeZero())
+					Expect(res.Body).To(Equal(bodies[2]))
+				})
+
+				It("counts the discarded responses as failed attempts", func() {
+					_, err := proxyRoundTripper.RoundTrip(req)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(reqInfo.FailedAttempts).To(Equal(2))
+					Expect(reqInfo.LastFailedAttemptFinishedAt).NotTo(BeZero())
+					Expect(reqInfo.RoundTripSuccessful).To(BeTrue())
+				})
+
+				Context("when all attempts respond with the status code", func() {
+					BeforeEach(func() {
+						cfg.Backends.MaxAttempts = 2
+					})
+
+					It("returns the response of the last attempt", func() {
+						res, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(res.StatusCode).To(Equal(http.StatusServiceUnavailable))
+						Expect(transport.RoundTripCallCount()).To(Equal(2))
+						Expect(res.Body).To(Equal(bodies[1]))
+						Expect(bodies[1].closeCount).To(BeZero())
+						Expect(reqInfo.FailedAttempts).To(Equal(1))
+						Expect(errorHandler.HandleErrorCallCount()).To(BeZero())
+					})
+				})
+
+				Context("when there are no further endpoints", func() {
+					BeforeEach(func() {
+						numEndpoints = 1
+					})
+
+					It("returns the response of the last endpoint", func() {
+						res, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(res.StatusCode).To(Equal(http.StatusServiceUnavailable))
+						Expect(transport.RoundTripCallCount()).To(Equal(1))
+						Expect(bodies[0].closeCount).To(BeZero())
+						Expect(reqInfo.FailedAttempts).To(BeZero())
+					})
+				})
+
+				Context("when the next endpoint has already been tried", func() {
+					var hosts []string
+
+					BeforeEach(func() {
+						numEndpoints = 2
+						hosts = nil
+						transport.RoundTripStub = func(req *http.Request) (*http.Response, error) {
+							hosts = append(hosts, req.URL.Host)
+							res := respond(http.StatusServiceUnavailable)
+							res.Header = http.Header{}
+							res.Header.Add("Set-Cookie", (&http.Cookie{Name: StickyCookieKey, Value: "session"}).String())
+							return res, nil
+						}
+					})
+
+					It("returns the response together with the endpoint which sent it", func() {
+						res, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(transport.RoundTripCallCount()).To(Equal(2))
+						Expect(res.Body).To(Equal(bodies[1]))
+
+						Expect(reqInfo.RouteEndpoint.CanonicalAddr()).To(Equal(hosts[1]))
+						instanceID := "instanceID" + strings.Split(hosts[1], ".")[0]
+						var vcapID string
+						for _, cookie := range res.Cookies() {
+							if cookie.Name == round_tripper.VcapCookieId {
+								vcapID = cookie.Value
+							}
+						}
+						Expect(vcapID).To(Equal(instanceID))
+					})
+				})
+
+				Context("when the request is not idempotent", func() {
+					BeforeEach(func() {
+						req.Method = "POST"
+						req.Body = reqBody
+					})
+
+					It("does not retry", func() {
+						res, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(res.StatusCode).To(Equal(http.StatusServiceUnavailable))
+						Expect(transport.RoundTripCallCount()).To(Equal(1))
+					})
+				})
+
+				Context("when the route sets its own status codes", func() {
+					BeforeEach(func() {
+						routePool.Put(route.NewEndpoint(&route.EndpointOpts{
+							Host:             "10.0.0.1",
+							Port:             9090,
+							RetryStatusCodes: []int{http.StatusBadGateway},
+						}))
+					})
+
+					It("only retries on the status codes of the route", func() {
+						res, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(res.StatusCode).To(Equal(http.StatusServiceUnavailable))
+						Expect(transport.RoundTripCallCount()).To(Equal(1))
+					})
+				})
+			})
+
+			Context("when the route hedges requests", func() {
+				var (
+					slow     atomic.Bool
+					canceled chan string
+				)
+
+				BeforeEach(func() {
+					numEndpoints = 3
+					req.Body = nil
+					routePool.Put(route.NewEndpoint(&route.EndpointOpts{
+						Host:       "10.0.0.1",
+						Port:       9090,
+						HedgeDelay: 20 * time.Millisecond,
+					}))
+
+					slow.Store(true)
+					canceled = make(chan string, 1)
+					transport.RoundTripStub = func(req *http.Request) (*http.Response, error) {
+						if slow.CompareAndSwap(true, false) {
+							select {
+							case <-req.Context().Done():
+								canceled <- req.URL.Host
+								return nil, req.Context().Err()
+							case <-time.After(time.Second):
+								return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("slow"))}, nil
+							}
+						}
+						return &http.Response{StatusCode: http.StatusTeapot, Body: io.NopCloser(strings.NewReader("fast"))}, nil
+					}
+				})
+
+				It("sends the request to a second endpoint if the first has not responded in time", func() {
+					res, err := proxyRoundTripper.RoundTrip(req)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(res.StatusCode).To(Equal(http.StatusTeapot))
+					Expect(transport.RoundTripCallCount()).To(Equal(2))
+
+					firstHost := transport.RoundTripArgsForCall(0).URL.Host
+					secondHost := transport.RoundTripArgsForCall(1).URL.Host
+					Expect(secondHost).NotTo(Equal(firstHost))
+					Expect(reqInfo.RouteEndpoint.CanonicalAddr()).To(Equal(secondHost))
+
+					body, err := io.ReadAll(res.Body)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(body)).To(Equal("fast"))
+					Expect(res.Body.Close()).To(Succeed())
+				})
+
+				It("cancels the slower attempt", func() {
+					_, err := proxyRoundTripper.RoundTrip(req)
+					Expect(err).NotTo(HaveOccurred())
+					Eventually(canceled).Should(Receive(Equal(transport.RoundTripArgsForCall(0).URL.Host)))
+				})
+
+				It("does not hedge requests which are answered in time", func() {
+					slow.Store(false)
+					res, err := proxyRoundTripper.RoundTrip(req)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(res.StatusCode).To(Equal(http.StatusTeapot))
+					Consistently(transport.RoundTripCallCount).Should(Equal(1))
+				})
+
+				Context("when the request has a body", func() {
+					BeforeEach(func() {
+						req.Method = "POST"
+						req.Body = reqBody
+					})
+
+					It("does not hedge the request", func() {
+						res, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(res.StatusCode).To(Equal(http.StatusOK))
+						Expect(transport.RoundTripCallCount()).To(Equal(1))
+					})
+				})
+
+				Context("when all attempts fail", func() {
+					BeforeEach(func() {
+						transport.RoundTripStub = func(req *http.Request) (*http.Response, error) {
+							if slow.CompareAndSwap(true, false) {
+								time.Sleep(50 * time.Millisecond)
+							}
+							return nil, &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+						}
+					})
+
+					It("marks the endpoints of both attempts as failed", func() {
+						_, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).To(MatchError(ContainSubstring("connection refused")))
+						Expect(transport.RoundTripCallCount()).To(Equal(2))
+
+						failedHosts := []string{
+							transport.RoundTripArgsForCall(0).URL.Host,
+							transport.RoundTripArgsForCall(1).URL.Host,
+						}
+						iter := routePool.Endpoints(logger.Logger, "", false, config.AZ_PREF_NONE, "", "", nil)
+						for i := 0; i < 8; i++ {
+							Expect(failedHosts).NotTo(ContainElement(iter.Next(i).CanonicalAddr()))
+						}
+					})
+				})
+
+				Context("when the hedging budget is exhausted", func() {
+					BeforeEach(func() {
+						cfg.Hedging = config.HedgingConfig{MaxTokens: 1}
+					})
+
+					It("does not hedge further requests", func() {
+						res, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(res.StatusCode).To(Equal(http.StatusTeapot))
+						Expect(transport.RoundTripCallCount()).To(Equal(2))
+
+						slow.Store(true)
+						res, err = proxyRoundTripper.RoundTrip(req)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(res.StatusCode).To(Equal(http.StatusOK))
+						Expect(transport.RoundTripCallCount()).To(Equal(3))
+						Expect(logger).To(gbytes.Say("hedging-budget-exhausted"))
+					})
+				})
+			})
+
+			Context("when retries are limited by a retry budget", func() {
+				BeforeEach(func() {
+					numEndpoints = 5
+					cfg.Backends.MaxAttempts = 5
+					transport.RoundTripReturns(nil, &net.OpError{Op: "dial", Err: errors.New("connection refused")})
+					retriableClassifier.ClassifyReturns(true)
+				})
+
+				Context("when the budget of the route is exhausted", func() {
+					BeforeEach(func() {
+						routePool = route.NewPool(&route.PoolOpts{
+							Logger:                 logger.Logger,
+							RetryAfterFailure:      1 * time.Second,
+							Host:                   "myapp.com",
+							LoadBalancingAlgorithm: config.LOAD_BALANCE_RR,
+							RetryBudget: &config.RetryBudgetConfig{
+								Enabled:    true,
+								RetryRatio: 0.1,
+								MaxTokens:  2,
+							},
+						})
+						reqInfo.RoutePool = routePool
+					})
+
+					It("stops retrying and returns the last error", func() {
+						_, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).To(MatchError(ContainSubstring("connection refused")))
+						Expect(transport.RoundTripCallCount()).To(Equal(3))
+						Expect(errorHandler.HandleErrorCallCount()).To(Equal(1))
+						Expect(reqInfo.FailedAttempts).To(Equal(3))
+
+						Expect(combinedReporter.CaptureRetryBudgetExhaustedCallCount()).To(Equal(1))
+						Expect(combinedReporter.CaptureRetryBudgetExhaustedArgsForCall(0)).To(Equal("route"))
+						Expect(combinedReporter.CaptureRetryBudgetDeniedCallCount()).To(Equal(1))
+						Expect(combinedReporter.CaptureRetryBudgetDeniedArgsForCall(0)).To(Equal("myapp.com"))
+						Expect(logger).To(gbytes.Say("retry-budget-exhausted"))
+					})
+				})
+
+				Context("when the global budget is exhausted", func() {
+					BeforeEach(func() {
+						cfg.RetryBudget = config.RetryBudgetConfig{
+							Enabled:    true,
+							RetryRatio: 0.1,
+							MaxTokens:  1,
+						}
+					})
+
+					It("stops retrying and returns the last error", func() {
+						_, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).To(MatchError(ContainSubstring("connection refused")))
+						Expect(transport.RoundTripCallCount()).To(Equal(2))
+
+						Expect(combinedReporter.CaptureRetryBudgetTokensCallCount()).To(Equal(1))
+						Expect(combinedReporter.CaptureRetryBudgetExhaustedCallCount()).To(Equal(1))
+						Expect(combinedReporter.CaptureRetryBudgetExhaustedArgsForCall(0)).To(Equal("global"))
+						Expect(combinedReporter.CaptureRetryBudgetDeniedCallCount()).To(Equal(1))
+					})
+				})
+
+				Context("when the budget is not exhausted", func() {
+					BeforeEach(func() {
+						cfg.RetryBudget = config.RetryBudgetConfig{
+							Enabled:    true,
+							RetryRatio: 0.1,
+							MaxTokens:  10,
+						}
+					})
+
+					It("retries up to the max attempts", func() {
+						_, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).To(MatchError(ContainSubstring("connection refused")))
+						Expect(transport.RoundTripCallCount()).To(Equal(5))
+						Expect(combinedReporter.CaptureRetryBudgetExhaustedCallCount()).To(Equal(0))
+					})
+				})
 			})
 
 			Context("when some backends fail", func() {
@@ -274,7 +800,7 @@ var _ = Describe("ProxyRoundTripper", func() {
 					res, err := proxyRoundTripper.RoundTrip(req)
 					Expect(err).NotTo(HaveOccurred())
 
-					iter := routePool.Endpoints(logger.Logger, "", false, AZPreference, AZ)
+					iter := routePool.Endpoints(logger.Logger, "", false, AZPreference, AZ, "", nil)
 					ep1 := iter.Next(0)
 					ep2 := iter.Next(1)
 					Expect(ep1.PrivateInstanceId).To(Equal(ep2.PrivateInstanceId))
@@ -608,7 +1134,7 @@ var _ = Describe("ProxyRoundTripper", func() {
 					_, err := proxyRoundTripper.RoundTrip(req)
 					Expect(err).To(MatchError(ContainSubstring("tls: handshake failure")))
 
-					iter := routePool.Endpoints(logger.Logger, "", false, AZPreference, AZ)
+					iter := routePool.Endpoints(logger.Logger, "", false, AZPreference, AZ, "", nil)
 					ep1 := iter.Next(0)
 					ep2 := iter.Next(1)
 					Expect(ep1).To(Equal(ep2))
@@ -1209,6 +1735,227 @@ var _ = Describe("ProxyRoundTripper", func() {
 					Expect(removed).To(BeTrue())
 				})
 
+				Context("when a sticky session header is configured", func() {
+					const header = "X-CF-Session-Affinity"
+
+					hostOf := map[string]string{"id-1": "1.1.1.1:9091", "id-2": "1.1.1.1:9092"}
+
+					BeforeEach(func() {
+						cfg.StickySessionHeader = header
+						transport.RoundTripStub = responseContainsNoCookies
+					})
+
+					It("does not return the instance to requests without the header", func() {
+						resp, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(resp.Header.Get(header)).To(BeEmpty())
+						Expect(resp.Cookies()).To(BeEmpty())
+					})
+
+					It("sends requests with the header to its instance", func() {
+						req.Header.Set(header, "id-2")
+						for i := 0; i < 3; i++ {
+							resp, err := proxyRoundTripper.RoundTrip(req)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(transport.RoundTripArgsForCall(i).URL.Host).To(Equal("1.1.1.1:9092"))
+							Expect(resp.Header.Get(header)).To(Equal("id-2"))
+						}
+					})
+
+					It("chooses a new instance if the instance of the header is gone", func() {
+						req.Header.Set(header, "id-9")
+						resp, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).ToNot(HaveOccurred())
+
+						instanceID := resp.Header.Get(header)
+						Expect(instanceID).To(SatisfyAny(Equal("id-1"), Equal("id-2")))
+						Expect(transport.RoundTripArgsForCall(0).URL.Host).To(Equal(hostOf[instanceID]))
+					})
+
+					It("leaves a header set by the backend alone", func() {
+						req.Header.Set(header, "id-2")
+						transport.RoundTripStub = func(req *http.Request) (*http.Response, error) {
+							return &http.Response{StatusCode: http.StatusTeapot, Header: http.Header{header: []string{"set-by-backend"}}}, nil
+						}
+						resp, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(resp.Header.Get(header)).To(Equal("set-by-backend"))
+					})
+
+					Context("when sticky sessions are encrypted", func() {
+						BeforeEach(func() {
+							var err error
+							stickySessionCodec, err = handlers.NewStickySessionCodec(config.StickySessionCookieEncryptionConfig{Enabled: true, Secret: "secret"})
+							Expect(err).ToNot(HaveOccurred())
+						})
+
+						It("returns an encrypted token which sticks to the instance", func() {
+							resp, err := proxyRoundTripper.RoundTrip(req)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(resp.Cookies()).To(BeEmpty())
+							token := resp.Header.Get(header)
+							instanceID, ok := stickySessionCodec.Decode(token)
+							Expect(ok).To(BeTrue())
+
+							req.Header.Set(header, token)
+							for i := 1; i < 4; i++ {
+								resp, err = proxyRoundTripper.RoundTrip(req)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(transport.RoundTripArgsForCall(i).URL.Host).To(Equal(hostOf[instanceID]))
+								Expect(resp.Header.Get(header)).To(Equal(token))
+							}
+						})
+
+						It("ignores plain instance IDs", func() {
+							req.Header.Set(header, "id-2")
+							hosts := map[string]bool{}
+							for i := 0; i < 4; i++ {
+								_, err := proxyRoundTripper.RoundTrip(req)
+								Expect(err).ToNot(HaveOccurred())
+								hosts[transport.RoundTripArgsForCall(i).URL.Host] = true
+							}
+							Expect(hosts).To(HaveLen(2))
+						})
+					})
+				})
+
+				Context("when no sticky session header is configured", func() {
+					BeforeEach(func() {
+						transport.RoundTripStub = responseContainsNoCookies
+					})
+
+					It("does not return the instance in a header", func() {
+						req.Header.Set("X-CF-Session-Affinity", "id-2")
+						resp, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(resp.Header).NotTo(HaveKey("X-Cf-Session-Affinity"))
+					})
+				})
+
+				Context("when sticky session cookies are encrypted", func() {
+					encode := func(secret, instanceID string) string {
+						codec, err := handlers.NewStickySessionCodec(config.StickySessionCookieEncryptionConfig{Enabled: true, Secret: secret})
+						Expect(err).ToNot(HaveOccurred())
+						value, err := codec.Encode(instanceID)
+						Expect(err).ToNot(HaveOccurred())
+						return value
+					}
+
+					addStickyCookies := func(vcapID string) {
+						req.AddCookie(&http.Cookie{Name: StickyCookieKey, Value: "some-session"})
+						req.AddCookie(&http.Cookie{Name: round_tripper.VcapCookieId, Value: vcapID})
+					}
+
+					BeforeEach(func() {
+						var err error
+						stickySessionCodec, err = handlers.NewStickySessionCodec(config.StickySessionCookieEncryptionConfig{
+							Enabled:            true,
+							Secret:             "new-secret",
+							DecryptOnlySecrets: []string{"old-secret"},
+						})
+						Expect(err).ToNot(HaveOccurred())
+						transport.RoundTripStub = responseContainsJSESSIONID
+					})
+
+					It("encrypts the instance ID in the VCAP_ID", func() {
+						resp, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).ToNot(HaveOccurred())
+
+						cookies := resp.Cookies()
+						Expect(cookies).To(HaveLen(2))
+						Expect(cookies[1].Name).To(Equal(round_tripper.VcapCookieId))
+						Expect(cookies[1].Value).NotTo(ContainSubstring("id-"))
+
+						instanceID, ok := stickySessionCodec.Decode(cookies[1].Value)
+						Expect(ok).To(BeTrue())
+						Expect(instanceID).To(SatisfyAny(Equal("id-1"), Equal("id-2")))
+					})
+
+					It("sends requests with an encrypted VCAP_ID to its instance", func() {
+						addStickyCookies(encode("new-secret", "id-2"))
+						for i := 0; i < 3; i++ {
+							_, err := proxyRoundTripper.RoundTrip(req)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(transport.RoundTripArgsForCall(i).URL.Host).To(Equal("1.1.1.1:9092"))
+						}
+					})
+
+					It("accepts a VCAP_ID encrypted with a decrypt-only secret", func() {
+						addStickyCookies(encode("old-secret", "id-2"))
+						for i := 0; i < 3; i++ {
+							_, err := proxyRoundTripper.RoundTrip(req)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(transport.RoundTripArgsForCall(i).URL.Host).To(Equal("1.1.1.1:9092"))
+						}
+					})
+
+					It("ignores a VCAP_ID which fails validation", func() {
+						addStickyCookies("id-2")
+						hosts := map[string]bool{}
+						for i := 0; i < 4; i++ {
+							resp, err := proxyRoundTripper.RoundTrip(req)
+							Expect(err).ToNot(HaveOccurred())
+							hosts[transport.RoundTripArgsForCall(i).URL.Host] = true
+
+							cookies := resp.Cookies()
+							Expect(cookies).To(HaveLen(2))
+							_, ok := stickySessionCodec.Decode(cookies[1].Value)
+							Expect(ok).To(BeTrue())
+						}
+						Expect(hosts).To(HaveLen(2))
+					})
+
+					It("re-encrypts a VCAP_ID encrypted with a decrypt-only secret", func() {
+						transport.RoundTripStub = responseContainsNoCookies
+						addStickyCookies(encode("old-secret", "id-2"))
+
+						resp, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(transport.RoundTripArgsForCall(0).URL.Host).To(Equal("1.1.1.1:9092"))
+
+						cookies := resp.Cookies()
+						Expect(cookies).To(HaveLen(1))
+						Expect(cookies[0].Name).To(Equal(round_tripper.VcapCookieId))
+						Expect(stickySessionCodec.IsCurrent(cookies[0].Value)).To(BeTrue())
+						instanceID, ok := stickySessionCodec.Decode(cookies[0].Value)
+						Expect(ok).To(BeTrue())
+						Expect(instanceID).To(Equal("id-2"))
+					})
+
+					It("replaces a plain VCAP_ID even if the app does not set its session cookie", func() {
+						transport.RoundTripStub = responseContainsNoCookies
+						addStickyCookies("id-2")
+
+						resp, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).ToNot(HaveOccurred())
+
+						cookies := resp.Cookies()
+						Expect(cookies).To(HaveLen(1))
+						Expect(cookies[0].Name).To(Equal(round_tripper.VcapCookieId))
+						Expect(stickySessionCodec.IsCurrent(cookies[0].Value)).To(BeTrue())
+					})
+
+					It("does not replace a VCAP_ID encrypted with the current secret", func() {
+						transport.RoundTripStub = responseContainsNoCookies
+						addStickyCookies(encode("new-secret", "id-2"))
+
+						resp, err := proxyRoundTripper.RoundTrip(req)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(resp.Cookies()).To(BeEmpty())
+					})
+
+					It("ignores a VCAP_ID encrypted with an unknown secret", func() {
+						addStickyCookies(encode("other-secret", "id-2"))
+						hosts := map[string]bool{}
+						for i := 0; i < 4; i++ {
+							_, err := proxyRoundTripper.RoundTrip(req)
+							Expect(err).ToNot(HaveOccurred())
+							hosts[transport.RoundTripArgsForCall(i).URL.Host] = true
+						}
+						Expect(hosts).To(HaveLen(2))
+					})
+				})
+
 				Context("when there are no cookies on the request", func() {
 					Context("when there is a JSESSIONID set on the response", func() {
 						BeforeEach(func() {
@@ -0,0 +1,37 @@
+package round_tripper
+
+import (
+	"log/slog"
+	"strings"
+
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+// retryAllowed takes a retry of a request to the pool from the retry budget
+// of the route and from the global retry budget. It reports false, leaving
+// both budgets unchanged, if either is exhausted.
+func (rt *roundTripper) retryAllowed(pool *route.EndpointPool, logger *slog.Logger) bool {
+	if !pool.RetryBudget().Withdraw() {
+		logger.Info("retry-budget-exhausted", slog.String("scope", "route"))
+		rt.combinedReporter.CaptureRetryBudgetExhausted("route")
+		rt.combinedReporter.CaptureRetryBudgetDenied(retryBudgetRoute(pool))
+		return false
+	}
+	if !rt.retryBudget.Withdraw() {
+		pool.RetryBudget().Refund()
+		logger.Info("retry-budget-exhausted", slog.String("scope", "global"))
+		rt.combinedReporter.CaptureRetryBudgetExhausted("global")
+		rt.combinedReporter.CaptureRetryBudgetDenied(retryBudgetRoute(pool))
+		return false
+	}
+	if rt.retryBudget != nil {
+		rt.combinedReporter.CaptureRetryBudgetTokens(rt.retryBudget.Tokens())
+	}
+	return true
+}
+
+// retryBudgetRoute returns the route of the pool as it is reported in the
+// retry_budget_denied metric.
+func retryBudgetRoute(pool *route.EndpointPool) string {
+	return strings.TrimSuffix(pool.Host()+pool.ContextPath(), "/")
+}
@@ -0,0 +1,35 @@
+package round_tripper
+
+import (
+	"io"
+	"net/http"
+	"slices"
+
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+// maxDrainedResponseBytes is the amount of the body of a retried response
+// which is read before it is closed, so that the connection to the backend
+// may be reused.
+const maxDrainedResponseBytes = 64 * 1024
+
+// retriableStatus reports whether a request is retried on the given response
+// of a backend of the pool. Only idempotent requests are retried on the
+// status codes configured for the route or, if it has none, globally.
+func (rt *roundTripper) retriableStatus(request *http.Request, res *http.Response, pool *route.EndpointPool) bool {
+	codes := pool.RetryStatusCodes()
+	if len(codes) == 0 {
+		codes = rt.config.Backends.RetryStatusCodes
+	}
+	return slices.Contains(codes, res.StatusCode) && isIdempotent(request)
+}
+
+// discardResponse drains and closes the body of a response which is not
+// returned to the client.
+func discardResponse(res *http.Response) {
+	if res.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxDrainedResponseBytes))
+	_ = res.Body.Close()
+}
@@ -0,0 +1,67 @@
+package round_tripper
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+type dialTimeoutKey struct{}
+
+// withDialTimeout returns a context which makes the dialer returned by
+// DialContext use the given timeout instead of its own.
+func withDialTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, dialTimeoutKey{}, timeout)
+}
+
+// DialContext returns a dial function for backend transports which honors
+// the dial timeout of the route a request is sent to.
+func DialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if timeout, ok := ctx.Value(dialTimeoutKey{}).(time.Duration); ok && timeout > 0 {
+			routeDialer := *dialer
+			routeDialer.Timeout = timeout
+			return routeDialer.DialContext(ctx, network, addr)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+// backendMaxAttempts returns the number of backends a request to the pool may
+// be sent to. Routes may set their own maximum up to the one allowed by the
+// operator.
+func (rt *roundTripper) backendMaxAttempts(pool *route.EndpointPool) int {
+	if attempts := pool.MaxAttempts(); attempts > 0 {
+		return limitRouteOption(attempts, rt.config.Backends.MaxRouteAttempts)
+	}
+	return max(rt.config.Backends.MaxAttempts, 1)
+}
+
+// backendRequestTimeout returns the time a backend of the pool may take to
+// respond. Routes may set their own timeout up to the one allowed by the
+// operator.
+func (rt *roundTripper) backendRequestTimeout(pool *route.EndpointPool) time.Duration {
+	if timeout := pool.RequestTimeout(); timeout > 0 {
+		return limitRouteOption(timeout, rt.config.Backends.MaxRequestTimeout)
+	}
+	return rt.config.EndpointTimeout
+}
+
+// backendDialTimeout returns the dial timeout set by the route of the pool, up
+// to the one allowed by the operator, or zero if the route does not set one.
+func (rt *roundTripper) backendDialTimeout(pool *route.EndpointPool) time.Duration {
+	if timeout := pool.DialTimeout(); timeout > 0 {
+		return limitRouteOption(timeout, rt.config.Backends.MaxDialTimeout)
+	}
+	return 0
+}
+
+// limitRouteOption returns value, limited to maximum if a maximum is set.
+func limitRouteOption[T int | time.Duration](value, maximum T) T {
+	if maximum > 0 {
+		return min(value, maximum)
+	}
+	return value
+}
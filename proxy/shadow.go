@@ -0,0 +1,363 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/accesslog"
+	"code.cloudfoundry.org/gorouter/accesslog/schema"
+	router_http "code.cloudfoundry.org/gorouter/common/http"
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/handlers"
+	log "code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/metrics"
+	"code.cloudfoundry.org/gorouter/proxy/round_tripper"
+	"code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+// shadow sends copies of a share of the requests of a route to the shadow
+// route registered for it. The copies are sent in the background and their
+// responses are discarded, so that they never affect the response to the
+// original request. Only shadow routes in the space of the route or allowed by
+// the operator receive copies, and never the credentials of the client.
+type shadow struct {
+	registry            registry.Registry
+	roundTripperFactory round_tripper.RoundTripperFactory
+	accessLogger        accesslog.AccessLogger
+	reporter            metrics.MetricReporter
+	headersToLog        []string
+	config              *config.Config
+	logger              *slog.Logger
+
+	// inFlight bounds the number of copies being sent at the same time
+	inFlight chan struct{}
+}
+
+func newShadow(
+	registry registry.Registry,
+	roundTripperFactory round_tripper.RoundTripperFactory,
+	accessLogger accesslog.AccessLogger,
+	reporter metrics.MetricReporter,
+	headersToLog []string,
+	cfg *config.Config,
+	logger *slog.Logger,
+) *shadow {
+	return &shadow{
+		registry:            registry,
+		roundTripperFactory: roundTripperFactory,
+		accessLogger:        accessLogger,
+		reporter:            reporter,
+		headersToLog:        headersToLog,
+		config:              cfg,
+		logger:              logger,
+		inFlight:            make(chan struct{}, cfg.Shadow.MaxConcurrency),
+	}
+}
+
+func (s *shadow) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	logger := handlers.LoggerWithTraceInfo(s.logger, r)
+	reqInfo, err := handlers.ContextRequestInfo(r)
+	if err != nil {
+		log.Panic(logger, "request-info-err", log.ErrAttr(err))
+		return
+	}
+
+	if reqInfo.RoutePool != nil && reqInfo.RouteServiceURL == nil {
+		s.mirror(r, reqInfo.RoutePool, logger)
+	}
+
+	next(rw, r)
+}
+
+// mirror starts sending a copy of the request to the shadow route of pool, if
+// the pool has one and the request is picked.
+func (s *shadow) mirror(r *http.Request, pool *route.EndpointPool, logger *slog.Logger) {
+	shadowRoute, percentage := pool.Shadow()
+	if shadowRoute == "" || percentage <= 0 {
+		return
+	}
+	if percentage < 100 && rand.Float64()*100 >= percentage {
+		return
+	}
+	// copying the body of such requests would interfere with the original
+	if handlers.IsWebSocketUpgrade(r) || r.Header.Get("Expect") == "100-continue" {
+		return
+	}
+
+	shadowPool := s.registry.Lookup(route.Uri(shadowRoute))
+	if shadowPool == nil || shadowPool.IsEmpty() {
+		logger.Debug("shadow-route-not-found", slog.String("shadow-route", shadowRoute))
+		return
+	}
+	if !s.allowed(shadowRoute, pool, shadowPool) {
+		logger.Info("shadow-route-not-allowed", slog.String("shadow-route", shadowRoute))
+		return
+	}
+	// the copies would bypass the route service of the shadow route
+	if shadowPool.RouteServiceUrl() != "" {
+		logger.Info("shadow-route-has-route-service", slog.String("shadow-route", shadowRoute))
+		return
+	}
+
+	if r.ContentLength > s.config.Shadow.MaxBodyBytes {
+		logger.Debug("shadow-request-body-too-large", slog.Int64("max-body-bytes", s.config.Shadow.MaxBodyBytes))
+		return
+	}
+
+	select {
+	case s.inFlight <- struct{}{}:
+	default:
+		logger.Debug("shadow-requests-limit-reached", slog.Int("max-concurrency", s.config.Shadow.MaxConcurrency))
+		return
+	}
+
+	// the original request is modified while it is proxied, so it is copied
+	// before continuing
+	req := r.Clone(context.Background())
+	req.RequestURI = ""
+	req.Host = strings.SplitN(shadowRoute, "/", 2)[0]
+	req.TransferEncoding = nil
+	s.prepareHeaders(req, r.RemoteAddr)
+	req.Header.Set(router_http.CfShadow, "true")
+
+	body := s.captureBody(r)
+
+	go func() {
+		defer func() { <-s.inFlight }()
+
+		b, ok := body.wait(s.config.Shadow.Timeout)
+		if !ok {
+			logger.Debug("shadow-request-body-not-captured", slog.Int64("max-body-bytes", s.config.Shadow.MaxBodyBytes))
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		req.ContentLength = int64(len(b))
+		s.send(req, shadowPool, logger)
+	}()
+}
+
+// allowed reports whether copies of the requests of pool may be sent to the
+// shadow route, which is the case if the operator allows the shadow route or
+// if it has an endpoint in the space of an endpoint of pool.
+func (s *shadow) allowed(shadowRoute string, pool, shadowPool *route.EndpointPool) bool {
+	if slices.ContainsFunc(s.config.Shadow.AllowedRoutes, func(allowed string) bool {
+		return strings.EqualFold(allowed, shadowRoute)
+	}) {
+		return true
+	}
+
+	spaces := map[string]bool{}
+	pool.Each(func(e *route.Endpoint) {
+		if space := e.Tags["space_id"]; space != "" {
+			spaces[space] = true
+		}
+	})
+	sameSpace := false
+	shadowPool.Each(func(e *route.Endpoint) {
+		if spaces[e.Tags["space_id"]] {
+			sameSpace = true
+		}
+	})
+	return sameSpace
+}
+
+// hopByHopHeaders are removed from the copies like the reverse proxy removes
+// them from the original requests.
+var hopByHopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// prepareHeaders removes the credentials and cookies of the client from a copy,
+// which is sent to another app, and sets the headers that the director and the
+// reverse proxy set on the original request, as the copy is taken before they
+// run.
+func (s *shadow) prepareHeaders(req *http.Request, remoteAddr string) {
+	req.Header.Del("Authorization")
+	req.Header.Del("Cookie")
+	req.Header.Del("X-Forwarded-Client-Cert")
+	if s.config.StickySessionHeader != "" {
+		req.Header.Del(s.config.StickySessionHeader)
+	}
+	req.Header.Del(router_http.CfAppInstance)
+
+	for _, connectionHeaders := range req.Header.Values("Connection") {
+		for _, h := range strings.Split(connectionHeaders, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				req.Header.Del(h)
+			}
+		}
+	}
+	for _, h := range hopByHopHeaders {
+		req.Header.Del(h)
+	}
+
+	setRequestXRequestStart(req)
+	if clientIP, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		if prior := req.Header.Values("X-Forwarded-For"); len(prior) > 0 {
+			clientIP = strings.Join(prior, ", ") + ", " + clientIP
+		}
+		req.Header.Set("X-Forwarded-For", clientIP)
+	}
+}
+
+// captureBody replaces the body of the request with one which keeps a copy of
+// the bytes read while the request is proxied, so that the request is never
+// delayed by the copy.
+func (s *shadow) captureBody(r *http.Request) *shadowBody {
+	b := &shadowBody{limit: s.config.Shadow.MaxBodyBytes, done: make(chan bool, 1)}
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		b.finish(true)
+		return b
+	}
+	b.ReadCloser = r.Body
+	r.Body = b
+	return b
+}
+
+// shadowBody copies the body of a request while it is read and hands the copy
+// over once the body has been read to its end. The copy is abandoned if the
+// body is larger than limit or closed before its end.
+type shadowBody struct {
+	io.ReadCloser
+	limit int64
+
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	tooLarge bool
+	finished bool
+	done     chan bool
+}
+
+func (b *shadowBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.tooLarge && !b.finished {
+		if int64(b.buf.Len()+n) > b.limit {
+			b.tooLarge = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF {
+		b.finishLocked(!b.tooLarge)
+	}
+	return n, err
+}
+
+func (b *shadowBody) Close() error {
+	b.finish(false)
+	return b.ReadCloser.Close()
+}
+
+func (b *shadowBody) finish(complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.finishLocked(complete)
+}
+
+// finishLocked hands over the copy of the body, or reports that it is not
+// complete. The lock must be held when calling this function.
+func (b *shadowBody) finishLocked(complete bool) {
+	if b.finished {
+		return
+	}
+	b.finished = true
+	b.done <- complete
+}
+
+// wait returns the copy of the body once the body has been read to its end.
+// It reports false if the copy was abandoned or the body was not read within
+// timeout.
+func (b *shadowBody) wait(timeout time.Duration) ([]byte, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case complete := <-b.done:
+		if !complete {
+			return nil, false
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return b.buf.Bytes(), true
+	case <-timer.C:
+		return nil, false
+	}
+}
+
+func (s *shadow) send(req *http.Request, pool *route.EndpointPool, logger *slog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Shadow.Timeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	alr := schema.AccessLogRecord{
+		Request:           req,
+		ExtraHeadersToLog: s.headersToLog,
+		ExtraFields:       s.config.Logging.ExtraAccessLogFields,
+		Shadow:            true,
+		ReceivedAt:        time.Now(),
+	}
+	defer func() {
+		alr.FinishedAt = time.Now()
+		s.accessLogger.Log(alr)
+	}()
+
+	iter := pool.Endpoints(s.logger, "", false, s.config.LoadBalanceAZPreference, s.config.Zone, pool.RequestHashKey(req), nil)
+	endpoint := iter.Next(0)
+	if endpoint == nil {
+		alr.RouterError = "no_endpoints"
+		s.reporter.CaptureShadowResponse(nil)
+		return
+	}
+	alr.RouteEndpoint = endpoint
+
+	req.URL.Host = endpoint.CanonicalAddr()
+	req.URL.Scheme = "http"
+	if endpoint.IsTLS() {
+		req.URL.Scheme = "https"
+	}
+
+	iter.PreRequest(endpoint)
+	defer iter.PostRequest(endpoint)
+
+	alr.AppRequestStartedAt = time.Now()
+	tr := round_tripper.GetRoundTripper(endpoint, s.roundTripperFactory, false, s.config.EnableHTTP2)
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		alr.AppRequestFinishedAt = time.Now()
+		alr.RouterError = "shadow_request_failed"
+		logger.Debug("shadow-request-failed", slog.String("shadow-route", req.Host), log.ErrAttr(err))
+		s.reporter.CaptureShadowResponse(nil)
+		return
+	}
+	defer res.Body.Close()
+
+	n, _ := io.Copy(io.Discard, res.Body)
+	alr.AppRequestFinishedAt = time.Now()
+	alr.StatusCode = res.StatusCode
+	alr.BodyBytesSent = int(n)
+	alr.RequestBytesReceived = int(req.ContentLength)
+	alr.RoundTripSuccessful = true
+	s.reporter.CaptureShadowResponse(res)
+}
@@ -0,0 +1,293 @@
+package proxy_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("Shadow", func() {
+	type shadowRequest struct {
+		host   string
+		body   string
+		header http.Header
+	}
+
+	var (
+		shadowRequests chan shadowRequest
+		primaryStarted chan struct{}
+		primaryBodies  chan string
+		listeners      []net.Listener
+	)
+
+	registerPrimary := func(percentage float64) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		listeners = append(listeners, ln)
+
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			primaryStarted <- struct{}{}
+			body, _ := io.ReadAll(req.Body)
+			primaryBodies <- string(body)
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("primary"))
+		})}
+		go server.Serve(ln)
+
+		r.Register(route.Uri("app"), route.NewEndpoint(&route.EndpointOpts{
+			Host:             "127.0.0.1",
+			Port:             uint16(ln.Addr().(*net.TCPAddr).Port),
+			Tags:             map[string]string{"space_id": "space-1"},
+			ShadowRoute:      "app-shadow",
+			ShadowPercentage: percentage,
+		}))
+	}
+
+	registerShadowIn := func(space string, routeServiceUrl string) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		listeners = append(listeners, ln)
+
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			shadowRequests <- shadowRequest{host: req.Host, body: string(body), header: req.Header}
+			w.WriteHeader(http.StatusInternalServerError)
+		})}
+		go server.Serve(ln)
+
+		r.Register(route.Uri("app-shadow"), route.NewEndpoint(&route.EndpointOpts{
+			AppId:           "shadow-app",
+			Host:            "127.0.0.1",
+			Port:            uint16(ln.Addr().(*net.TCPAddr).Port),
+			Tags:            map[string]string{"space_id": space},
+			RouteServiceUrl: routeServiceUrl,
+		}))
+	}
+
+	registerShadow := func() {
+		registerShadowIn("space-1", "")
+	}
+
+	sendRequestWithHeader := func(body string, header http.Header) *http.Response {
+		conn := dialProxy(proxyServer)
+		req := test_util.NewRequest("POST", "app", "/some/path?foo=bar", bytes.NewBufferString(body))
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		conn.WriteRequest(req)
+		resp, respBody := conn.ReadResponse()
+		Expect(respBody).To(Equal("primary"))
+		return resp
+	}
+
+	sendRequest := func(body string) *http.Response {
+		return sendRequestWithHeader(body, nil)
+	}
+
+	BeforeEach(func() {
+		shadowRequests = make(chan shadowRequest, 10)
+		primaryStarted = make(chan struct{}, 10)
+		primaryBodies = make(chan string, 10)
+		listeners = nil
+	})
+
+	AfterEach(func() {
+		for _, ln := range listeners {
+			ln.Close()
+		}
+	})
+
+	Context("when the route has a shadow route", func() {
+		JustBeforeEach(func() {
+			registerPrimary(100)
+			registerShadow()
+		})
+
+		It("sends a copy of the request to the shadow route and discards its response", func() {
+			resp := sendRequest("some-body")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(primaryBodies).To(Receive(Equal("some-body")))
+
+			var shadowReq shadowRequest
+			Eventually(shadowRequests).Should(Receive(&shadowReq))
+			Expect(shadowReq.host).To(Equal("app-shadow"))
+			Expect(shadowReq.body).To(Equal("some-body"))
+			Expect(shadowReq.header.Get("X-Cf-Shadow")).To(Equal("true"))
+
+			Eventually(fakeReporter.CaptureShadowResponseCallCount).Should(Equal(1))
+			Expect(fakeReporter.CaptureShadowResponseArgsForCall(0).StatusCode).To(Equal(http.StatusInternalServerError))
+		})
+
+		It("removes the credentials of the client and hop-by-hop headers from the copy", func() {
+			sendRequestWithHeader("some-body", http.Header{
+				"Authorization": {"Bearer secret"},
+				"Cookie":        {"__VCAP_ID__=instance-1; session=secret"},
+				"Connection":    {"X-Private"},
+				"X-Private":     {"secret"},
+				"X-Custom":      {"kept"},
+			})
+
+			var shadowReq shadowRequest
+			Eventually(shadowRequests).Should(Receive(&shadowReq))
+			Expect(shadowReq.header).NotTo(HaveKey("Authorization"))
+			Expect(shadowReq.header).NotTo(HaveKey("Cookie"))
+			Expect(shadowReq.header).NotTo(HaveKey("X-Private"))
+			Expect(shadowReq.header.Get("X-Custom")).To(Equal("kept"))
+			Expect(shadowReq.header.Get("X-Forwarded-For")).To(Equal("127.0.0.1"))
+			Expect(shadowReq.header.Get("X-Request-Start")).NotTo(BeEmpty())
+		})
+
+		It("marks the copy in the access log", func() {
+			sendRequest("some-body")
+			Eventually(shadowRequests).Should(Receive())
+
+			Eventually(func() (string, error) {
+				b, err := os.ReadFile(f.Name())
+				return string(b), err
+			}).Should(ContainSubstring(`app_id:"shadow-app"`))
+
+			b, err := os.ReadFile(f.Name())
+			Expect(err).NotTo(HaveOccurred())
+			for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+				if strings.HasPrefix(line, "app-shadow - [") {
+					Expect(line).To(ContainSubstring(`"POST /some/path?foo=bar HTTP/1.1" 500`))
+					Expect(line).To(ContainSubstring(`shadow:"true"`))
+				} else {
+					Expect(line).NotTo(ContainSubstring(`shadow:`))
+				}
+			}
+		})
+
+		It("does not delay a request whose body is streamed", func() {
+			conn := dialProxy(proxyServer)
+			Expect(conn.WriteLines([]string{
+				"POST /some/path HTTP/1.1",
+				"Host: app",
+				"Transfer-Encoding: chunked",
+				"",
+				"5",
+				"some-",
+			})).To(Succeed())
+			Eventually(primaryStarted).Should(Receive())
+			Consistently(shadowRequests).ShouldNot(Receive())
+
+			Expect(conn.WriteLines([]string{"4", "body", "0", ""})).To(Succeed())
+			resp, respBody := conn.ReadResponse()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(respBody).To(Equal("primary"))
+			Expect(primaryBodies).To(Receive(Equal("some-body")))
+
+			var shadowReq shadowRequest
+			Eventually(shadowRequests).Should(Receive(&shadowReq))
+			Expect(shadowReq.body).To(Equal("some-body"))
+		})
+
+		Context("when the request body is larger than the maximum", func() {
+			BeforeEach(func() {
+				conf.Shadow.MaxBodyBytes = 4
+			})
+
+			It("does not copy the request and proxies the whole body", func() {
+				resp := sendRequest("some-body")
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(primaryBodies).To(Receive(Equal("some-body")))
+				Consistently(shadowRequests).ShouldNot(Receive())
+			})
+
+			It("does not copy a streamed request and proxies the whole body", func() {
+				conn := dialProxy(proxyServer)
+				Expect(conn.WriteLines([]string{
+					"POST /some/path HTTP/1.1",
+					"Host: app",
+					"Transfer-Encoding: chunked",
+					"",
+					"9",
+					"some-body",
+					"0",
+					"",
+				})).To(Succeed())
+				resp, _ := conn.ReadResponse()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(primaryBodies).To(Receive(Equal("some-body")))
+				Consistently(shadowRequests).ShouldNot(Receive())
+			})
+		})
+	})
+
+	Context("when the shadow route is in another space", func() {
+		JustBeforeEach(func() {
+			registerPrimary(100)
+			registerShadowIn("space-2", "")
+		})
+
+		It("does not copy requests", func() {
+			sendRequest("some-body")
+			Consistently(shadowRequests).ShouldNot(Receive())
+		})
+
+		Context("when the operator allows the shadow route", func() {
+			BeforeEach(func() {
+				conf.Shadow.AllowedRoutes = []string{"app-shadow"}
+			})
+
+			It("copies requests", func() {
+				sendRequest("some-body")
+				Eventually(shadowRequests).Should(Receive())
+			})
+		})
+	})
+
+	Context("when the shadow route has a route service", func() {
+		JustBeforeEach(func() {
+			registerPrimary(100)
+			registerShadowIn("space-1", "https://rs.example.com")
+		})
+
+		It("does not copy requests", func() {
+			sendRequest("some-body")
+			Consistently(shadowRequests).ShouldNot(Receive())
+		})
+	})
+
+	Context("when the shadow route cannot be reached", func() {
+		JustBeforeEach(func() {
+			registerPrimary(100)
+			r.Register(route.Uri("app-shadow"), route.NewEndpoint(&route.EndpointOpts{
+				Host: "127.0.0.1",
+				Port: 1,
+				Tags: map[string]string{"space_id": "space-1"},
+			}))
+		})
+
+		It("does not affect the response to the request", func() {
+			resp := sendRequest("some-body")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(primaryBodies).To(Receive(Equal("some-body")))
+
+			Eventually(fakeReporter.CaptureShadowResponseCallCount).Should(Equal(1))
+			Expect(fakeReporter.CaptureShadowResponseArgsForCall(0)).To(BeNil())
+		})
+	})
+
+	Context("when the shadow percentage is zero", func() {
+		JustBeforeEach(func() {
+			registerPrimary(0)
+			registerShadow()
+		})
+
+		It("does not copy requests", func() {
+			sendRequest("some-body")
+			Consistently(shadowRequests).ShouldNot(Receive())
+			Expect(fakeReporter.CaptureShadowResponseCallCount()).To(BeZero())
+		})
+	})
+})
@@ -0,0 +1,144 @@
+package registry
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+// Types of the changes of the routing table.
+const (
+	RouteChangeAdded        = "added"
+	RouteChangeUpdated      = "updated"
+	RouteChangeUnregistered = "unregistered"
+	RouteChangePruned       = "pruned"
+)
+
+// changeSubscriptionBuffer is the number of changes a subscriber may fall
+// behind before it is dropped.
+const changeSubscriptionBuffer = 256
+
+// RouteChange is a change of an endpoint of a route. The app guid is not part
+// of the JSON of the endpoint, so it is kept next to it.
+type RouteChange struct {
+	Seq      uint64          `json:"seq"`
+	Time     time.Time       `json:"time"`
+	Type     string          `json:"type"`
+	Uri      route.Uri       `json:"uri"`
+	AppId    string          `json:"app_id,omitempty"`
+	Endpoint *route.Endpoint `json:"endpoint"`
+}
+
+// ChangeSubscription receives the changes of the routing table.
+type ChangeSubscription struct {
+	// Buffered are the buffered changes after the sequence number the
+	// subscription resumes from, oldest first.
+	Buffered []RouteChange
+	// Truncated is true if changes after the sequence number the subscription
+	// resumes from have already been dropped from the buffer.
+	Truncated bool
+	// Changes receives the changes after the buffered changes. It is closed
+	// when the subscriber falls too far behind or cancels the subscription.
+	Changes <-chan RouteChange
+
+	feed *changeFeed
+	ch   chan RouteChange
+}
+
+// Cancel stops sending changes to the subscription.
+func (s *ChangeSubscription) Cancel() {
+	s.feed.unsubscribe(s)
+}
+
+// changeFeed keeps the most recent changes of the routing table in a ring
+// buffer and sends new changes to its subscribers.
+type changeFeed struct {
+	sync.Mutex
+	ring        []RouteChange
+	lastSeq     uint64
+	subscribers map[*ChangeSubscription]struct{}
+}
+
+func newChangeFeed(size int) *changeFeed {
+	return &changeFeed{
+		ring:        make([]RouteChange, max(size, 0)),
+		subscribers: map[*ChangeSubscription]struct{}{},
+	}
+}
+
+func (f *changeFeed) publish(changeType string, uri route.Uri, endpoint *route.Endpoint) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.lastSeq++
+	c := RouteChange{
+		Seq:      f.lastSeq,
+		Time:     time.Now(),
+		Type:     changeType,
+		Uri:      uri,
+		AppId:    endpoint.ApplicationId,
+		Endpoint: endpoint,
+	}
+	if len(f.ring) > 0 {
+		f.ring[(c.Seq-1)%uint64(len(f.ring))] = c
+	}
+
+	for s := range f.subscribers {
+		select {
+		case s.ch <- c:
+		default:
+			// the subscriber can resume from the buffer after reconnecting
+			delete(f.subscribers, s)
+			close(s.ch)
+		}
+	}
+}
+
+func (f *changeFeed) subscribe(after uint64) *ChangeSubscription {
+	f.Lock()
+	defer f.Unlock()
+
+	ch := make(chan RouteChange, changeSubscriptionBuffer)
+	s := &ChangeSubscription{Changes: ch, feed: f, ch: ch}
+	f.subscribers[s] = struct{}{}
+
+	if after == 0 || after == f.lastSeq {
+		return s
+	}
+
+	// a sequence number ahead of the feed is from before a restart
+	if after > f.lastSeq {
+		after = 0
+		s.Truncated = true
+	}
+	oldest := uint64(1)
+	if f.lastSeq > uint64(len(f.ring)) {
+		oldest = f.lastSeq - uint64(len(f.ring)) + 1
+	}
+	if after+1 < oldest {
+		after = oldest - 1
+		s.Truncated = true
+	}
+	for seq := after + 1; seq <= f.lastSeq; seq++ {
+		s.Buffered = append(s.Buffered, f.ring[(seq-1)%uint64(len(f.ring))])
+	}
+	return s
+}
+
+func (f *changeFeed) unsubscribe(s *ChangeSubscription) {
+	f.Lock()
+	defer f.Unlock()
+
+	if _, ok := f.subscribers[s]; ok {
+		delete(f.subscribers, s)
+		close(s.ch)
+	}
+}
+
+// SubscribeChanges subscribes to the changes of the routing table, resuming
+// from the buffered changes after the sequence number after. Only new changes
+// are received if after is zero.
+func (r *RouteRegistry) SubscribeChanges(after uint64) *ChangeSubscription {
+	return r.changes.subscribe(after)
+}
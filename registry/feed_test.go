@@ -0,0 +1,197 @@
+package registry_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/metrics/fakes"
+	. "code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("SubscribeChanges", func() {
+	var (
+		r         *RouteRegistry
+		configObj *config.Config
+		endpoint  *route.Endpoint
+	)
+
+	types := func(changes []RouteChange) []string {
+		var result []string
+		for _, c := range changes {
+			result = append(result, c.Type)
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		var err error
+		configObj, err = config.DefaultConfig()
+		Expect(err).ToNot(HaveOccurred())
+		configObj.Status.Routes.ChangeFeedBufferSize = 3
+		endpoint = route.NewEndpoint(&route.EndpointOpts{AppId: "app-1", Host: "10.0.0.1", Port: 8080})
+	})
+
+	JustBeforeEach(func() {
+		r = NewRouteRegistry(test_util.NewTestLogger("test").Logger, configObj, new(fakes.FakeMetricReporter))
+	})
+
+	It("sends registered, updated and unregistered endpoints to subscribers", func() {
+		sub := r.SubscribeChanges(0)
+		defer sub.Cancel()
+		Expect(sub.Buffered).To(BeEmpty())
+		Expect(sub.Truncated).To(BeFalse())
+
+		r.Register("Foo.example.com", endpoint)
+		r.Register("foo.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-1", Host: "10.0.0.1", Port: 8080, PrivateInstanceId: "instance-1"}))
+		r.Unregister("foo.example.com", endpoint)
+
+		var c RouteChange
+		Eventually(sub.Changes).Should(Receive(&c))
+		Expect(c.Seq).To(Equal(uint64(1)))
+		Expect(c.Type).To(Equal(RouteChangeAdded))
+		Expect(c.Uri).To(Equal(route.Uri("foo.example.com")))
+		Expect(c.Endpoint.CanonicalAddr()).To(Equal("10.0.0.1:8080"))
+
+		Eventually(sub.Changes).Should(Receive(&c))
+		Expect(c.Seq).To(Equal(uint64(2)))
+		Expect(c.Type).To(Equal(RouteChangeUpdated))
+
+		Eventually(sub.Changes).Should(Receive(&c))
+		Expect(c.Seq).To(Equal(uint64(3)))
+		Expect(c.Type).To(Equal(RouteChangeUnregistered))
+	})
+
+	It("does not send endpoints which are registered again unchanged", func() {
+		r.Register("foo.example.com", endpoint)
+		sub := r.SubscribeChanges(0)
+		defer sub.Cancel()
+
+		r.Register("foo.example.com", endpoint)
+		Consistently(sub.Changes).ShouldNot(Receive())
+	})
+
+	It("does not send endpoints which another route source registers again unchanged", func() {
+		r.Register("foo.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-1", Host: "10.0.0.1", Port: 8080, Source: "nats"}))
+		sub := r.SubscribeChanges(0)
+		defer sub.Cancel()
+
+		r.Register("foo.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-1", Host: "10.0.0.1", Port: 8080, Source: "routing-api"}))
+		r.Register("foo.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-1", Host: "10.0.0.1", Port: 8080, Source: "nats"}))
+		Consistently(sub.Changes).ShouldNot(Receive())
+	})
+
+	It("sends the route key of unregistered endpoints", func() {
+		r.Register("foo.example.com", endpoint)
+		sub := r.SubscribeChanges(1)
+		defer sub.Cancel()
+
+		r.Unregister("Foo.Example.COM", endpoint)
+
+		var c RouteChange
+		Eventually(sub.Changes).Should(Receive(&c))
+		Expect(c.Type).To(Equal(RouteChangeUnregistered))
+		Expect(c.Uri).To(Equal(route.Uri("foo.example.com")))
+	})
+
+	It("resumes from the buffered changes after a sequence number", func() {
+		r.Register("foo.example.com", endpoint)
+		r.Register("bar.example.com", endpoint)
+		r.Unregister("foo.example.com", endpoint)
+
+		sub := r.SubscribeChanges(1)
+		defer sub.Cancel()
+		Expect(sub.Truncated).To(BeFalse())
+		Expect(sub.Buffered).To(HaveLen(2))
+		Expect(sub.Buffered[0].Seq).To(Equal(uint64(2)))
+		Expect(sub.Buffered[0].Uri).To(Equal(route.Uri("bar.example.com")))
+		Expect(types(sub.Buffered)).To(Equal([]string{RouteChangeAdded, RouteChangeUnregistered}))
+
+		r.Register("baz.example.com", endpoint)
+		var c RouteChange
+		Eventually(sub.Changes).Should(Receive(&c))
+		Expect(c.Seq).To(Equal(uint64(4)))
+	})
+
+	It("marks the subscription as truncated if the changes have been dropped from the buffer", func() {
+		for _, uri := range []route.Uri{"a.example.com", "b.example.com", "c.example.com", "d.example.com", "e.example.com"} {
+			r.Register(uri, endpoint)
+		}
+
+		sub := r.SubscribeChanges(1)
+		defer sub.Cancel()
+		Expect(sub.Truncated).To(BeTrue())
+		Expect(sub.Buffered).To(HaveLen(3))
+		Expect(sub.Buffered[0].Seq).To(Equal(uint64(3)))
+	})
+
+	It("marks the subscription as truncated for a sequence number ahead of the feed", func() {
+		r.Register("foo.example.com", endpoint)
+
+		sub := r.SubscribeChanges(42)
+		defer sub.Cancel()
+		Expect(sub.Truncated).To(BeTrue())
+		Expect(sub.Buffered).To(HaveLen(1))
+		Expect(sub.Buffered[0].Seq).To(Equal(uint64(1)))
+	})
+
+	It("drops subscribers which fall too far behind", func() {
+		sub := r.SubscribeChanges(0)
+		defer sub.Cancel()
+
+		for i := 0; i < 300; i++ {
+			r.Register("foo.example.com", route.NewEndpoint(&route.EndpointOpts{Host: "10.0.0.1", Port: uint16(i + 1)}))
+		}
+
+		received := 0
+		Eventually(func() bool {
+			select {
+			case _, ok := <-sub.Changes:
+				if ok {
+					received++
+				}
+				return !ok
+			default:
+				return false
+			}
+		}).Should(BeTrue())
+		Expect(received).To(BeNumerically("<", 300))
+	})
+
+	It("stops sending changes when the subscription is cancelled", func() {
+		sub := r.SubscribeChanges(0)
+		sub.Cancel()
+		Eventually(sub.Changes).Should(BeClosed())
+
+		r.Register("foo.example.com", endpoint)
+	})
+
+	Context("when endpoints are pruned", func() {
+		BeforeEach(func() {
+			configObj.PruneStaleDropletsInterval = 50 * time.Millisecond
+			configObj.DropletStaleThreshold = 24 * time.Millisecond
+		})
+
+		AfterEach(func() {
+			r.StopPruningCycle()
+		})
+
+		It("sends the pruned endpoints to subscribers", func() {
+			r.Register("foo.example.com", endpoint)
+			sub := r.SubscribeChanges(1)
+			defer sub.Cancel()
+
+			r.StartPruningCycle()
+
+			var c RouteChange
+			Eventually(sub.Changes).Should(Receive(&c))
+			Expect(c.Type).To(Equal(RouteChangePruned))
+			Expect(c.Uri).To(Equal(route.Uri("foo.example.com")))
+			Expect(c.Endpoint.CanonicalAddr()).To(Equal("10.0.0.1:8080"))
+		})
+	})
+})
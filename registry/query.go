@@ -0,0 +1,77 @@
+package registry
+
+import (
+	"encoding/json"
+	"net"
+	"slices"
+	"strings"
+
+	"code.cloudfoundry.org/gorouter/registry/container"
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+// RouteFilter selects routes and endpoints of the routing table. Empty fields
+// match everything.
+type RouteFilter struct {
+	// HostPrefix matches routes whose uri starts with it.
+	HostPrefix string
+	// AppId matches endpoints of the app with this guid.
+	AppId string
+	// IsolationSegment matches endpoints in this isolation segment.
+	IsolationSegment string
+	// Address matches endpoints with this host:port, or with this host if it
+	// has no port.
+	Address string
+}
+
+// IsEmpty returns true if the filter matches everything.
+func (f RouteFilter) IsEmpty() bool {
+	return f == RouteFilter{}
+}
+
+func (f RouteFilter) matchesEndpoint(e *route.Endpoint) bool {
+	if f.AppId != "" && e.ApplicationId != f.AppId {
+		return false
+	}
+	if f.IsolationSegment != "" && e.IsolationSegment != f.IsolationSegment {
+		return false
+	}
+	if f.Address != "" && e.CanonicalAddr() != f.Address {
+		host, _, err := net.SplitHostPort(e.CanonicalAddr())
+		if err != nil || strings.Contains(f.Address, ":") || host != f.Address {
+			return false
+		}
+	}
+	return true
+}
+
+// RouteEndpoints is a route with those of its endpoints which match a filter.
+type RouteEndpoints struct {
+	Uri       route.Uri
+	Endpoints []json.Marshaler
+}
+
+// Routes returns the routes matching the filter which have endpoints matching
+// the filter, ordered by uri.
+func (r *RouteRegistry) Routes(filter RouteFilter) []RouteEndpoints {
+	prefix := strings.ToLower(filter.HostPrefix)
+
+	var routes []RouteEndpoints
+	r.RLock()
+	r.byURI.EachNodeWithPool(func(t *container.Trie) {
+		uri := route.Uri(t.ToPath())
+		if !strings.HasPrefix(string(uri), prefix) {
+			return
+		}
+		endpoints := t.Pool.FilteredEndpoints(filter.matchesEndpoint)
+		if len(endpoints) > 0 {
+			routes = append(routes, RouteEndpoints{Uri: uri, Endpoints: endpoints})
+		}
+	})
+	r.RUnlock()
+
+	slices.SortFunc(routes, func(a, b RouteEndpoints) int {
+		return strings.Compare(string(a.Uri), string(b.Uri))
+	})
+	return routes
+}
@@ -0,0 +1,88 @@
+package registry_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/metrics/fakes"
+	. "code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("Routes", func() {
+	var r *RouteRegistry
+
+	uris := func(routes []RouteEndpoints) []route.Uri {
+		var result []route.Uri
+		for _, re := range routes {
+			result = append(result, re.Uri)
+		}
+		return result
+	}
+
+	addresses := func(re RouteEndpoints) []string {
+		var result []string
+		for _, e := range re.Endpoints {
+			data, err := json.Marshal(e)
+			Expect(err).ToNot(HaveOccurred())
+			var endpoint struct {
+				Address string `json:"address"`
+			}
+			Expect(json.Unmarshal(data, &endpoint)).To(Succeed())
+			result = append(result, endpoint.Address)
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		configObj, err := config.DefaultConfig()
+		Expect(err).ToNot(HaveOccurred())
+		r = NewRouteRegistry(test_util.NewTestLogger("test").Logger, configObj, new(fakes.FakeMetricReporter))
+
+		r.Register("foo.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-1", Host: "10.0.0.1", Port: 8080}))
+		r.Register("foo.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-2", Host: "10.0.0.2", Port: 8080, IsolationSegment: "iso"}))
+		r.Register("foo.example.com/api", route.NewEndpoint(&route.EndpointOpts{AppId: "app-2", Host: "10.0.0.2", Port: 9090, IsolationSegment: "iso"}))
+		r.Register("bar.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-3", Host: "10.0.0.3", Port: 8080}))
+	})
+
+	It("returns all routes ordered by uri for an empty filter", func() {
+		routes := r.Routes(RouteFilter{})
+		Expect(uris(routes)).To(Equal([]route.Uri{"bar.example.com", "foo.example.com", "foo.example.com/api"}))
+		Expect(addresses(routes[1])).To(ConsistOf("10.0.0.1:8080", "10.0.0.2:8080"))
+	})
+
+	It("filters routes by host prefix", func() {
+		routes := r.Routes(RouteFilter{HostPrefix: "FOO."})
+		Expect(uris(routes)).To(Equal([]route.Uri{"foo.example.com", "foo.example.com/api"}))
+	})
+
+	It("filters endpoints by app guid", func() {
+		routes := r.Routes(RouteFilter{AppId: "app-2"})
+		Expect(uris(routes)).To(Equal([]route.Uri{"foo.example.com", "foo.example.com/api"}))
+		Expect(addresses(routes[0])).To(Equal([]string{"10.0.0.2:8080"}))
+	})
+
+	It("filters endpoints by isolation segment", func() {
+		routes := r.Routes(RouteFilter{IsolationSegment: "iso", HostPrefix: "foo.example.com/"})
+		Expect(uris(routes)).To(Equal([]route.Uri{"foo.example.com/api"}))
+	})
+
+	It("filters endpoints by address", func() {
+		routes := r.Routes(RouteFilter{Address: "10.0.0.2:9090"})
+		Expect(uris(routes)).To(Equal([]route.Uri{"foo.example.com/api"}))
+	})
+
+	It("filters endpoints by host if the address has no port", func() {
+		routes := r.Routes(RouteFilter{Address: "10.0.0.2"})
+		Expect(uris(routes)).To(Equal([]route.Uri{"foo.example.com", "foo.example.com/api"}))
+		Expect(addresses(routes[0])).To(Equal([]string{"10.0.0.2:8080"}))
+	})
+
+	It("returns no routes if no endpoints match", func() {
+		Expect(r.Routes(RouteFilter{AppId: "unknown"})).To(BeEmpty())
+	})
+})
@@ -55,11 +55,26 @@ type RouteRegistry struct {
 	routingTableShardingMode string
 	isolationSegments        []string
 
-	maxConnsPerBackend int64
+	maxConnsPerBackend  int64
+	slowStartDuration   time.Duration
+	outlierDetection    *config.OutlierDetectionConfig
+	retryBudget         *config.RetryBudgetConfig
+	maxConnsQueue       *config.MaxConnsQueueConfig
+	adaptiveConcurrency *config.AdaptiveConcurrencyConfig
+	azFailoverPercent   int
+
+	// snapshot configures the snapshots of the routing table; nil if they
+	// are disabled
+	snapshot       *config.RoutingTableSnapshotConfig
+	snapshotTicker *time.Ticker
+
+	// changes streams the changes of the routing table to the routes listener
+	changes *changeFeed
 
 	EmptyPoolTimeout              time.Duration
 	EmptyPoolResponseCode503      bool
 	DefaultLoadBalancingAlgorithm string
+	DefaultHashKey                string
 }
 
 func NewRouteRegistry(logger *slog.Logger, c *config.Config, reporter metrics.MetricReporter) *RouteRegistry {
@@ -77,9 +92,33 @@ func NewRouteRegistry(logger *slog.Logger, c *config.Config, reporter metrics.Me
 	r.isolationSegments = c.IsolationSegments
 
 	r.maxConnsPerBackend = c.Backends.MaxConns
+	r.slowStartDuration = c.SlowStartDuration
+	if c.OutlierDetection.Enabled {
+		outlierDetection := c.OutlierDetection
+		r.outlierDetection = &outlierDetection
+	}
+	if c.RetryBudget.Enabled {
+		retryBudget := c.RetryBudget
+		r.retryBudget = &retryBudget
+	}
+	if c.MaxConnsQueue.Enabled {
+		maxConnsQueue := c.MaxConnsQueue
+		r.maxConnsQueue = &maxConnsQueue
+	}
+	if c.AdaptiveConcurrency.Enabled {
+		adaptiveConcurrency := c.AdaptiveConcurrency
+		r.adaptiveConcurrency = &adaptiveConcurrency
+	}
+	r.azFailoverPercent = c.LoadBalanceAZFailoverPercent
+	if c.RoutingTableSnapshot.Enabled {
+		snapshot := c.RoutingTableSnapshot
+		r.snapshot = &snapshot
+	}
+	r.changes = newChangeFeed(c.Status.Routes.ChangeFeedBufferSize)
 	r.EmptyPoolTimeout = c.EmptyPoolTimeout
 	r.EmptyPoolResponseCode503 = c.EmptyPoolResponseCode503
 	r.DefaultLoadBalancingAlgorithm = c.LoadBalance
+	r.DefaultHashKey = c.LoadBalanceHashKey
 	return r
 }
 
@@ -91,6 +130,9 @@ func (r *RouteRegistry) Register(uri route.Uri, endpoint *route.Endpoint) {
 	endpointAdded := r.register(uri, endpoint)
 
 	r.reporter.CaptureRegistryMessage(endpoint, endpointAdded.String())
+	if endpoint.Source != "" {
+		r.reporter.CaptureRouteSourceEvent(endpoint.Source, "register")
+	}
 
 	if endpointAdded == route.ADDED && !endpoint.UpdatedAt.IsZero() {
 		r.reporter.CaptureRouteRegistrationLatency(time.Since(endpoint.UpdatedAt))
@@ -132,10 +174,21 @@ func (r *RouteRegistry) register(uri route.Uri, endpoint *route.Endpoint) route.
 		endpoint.StaleThreshold = r.dropletStaleThreshold
 	}
 
+	// endpoints are registered again unchanged on every heartbeat
+	changed := !pool.Contains(endpoint)
 	endpointAdded := pool.Put(endpoint)
 	// Overwrites the load balancing algorithm of a pool by that of a specified endpoint, if that is valid.
 	r.SetTimeOfLastUpdate(t)
 
+	switch endpointAdded {
+	case route.ADDED:
+		r.changes.publish(RouteChangeAdded, routekey, endpoint)
+	case route.UPDATED:
+		if changed {
+			r.changes.publish(RouteChangeUpdated, routekey, endpoint)
+		}
+	}
+
 	return endpointAdded
 }
 
@@ -149,12 +202,21 @@ func (r *RouteRegistry) insertRouteKey(routekey route.Uri, uri route.Uri) *route
 	if pool == nil {
 		host, contextPath := splitHostAndContextPath(uri)
 		pool = route.NewPool(&route.PoolOpts{
-			Logger:                 r.logger,
-			RetryAfterFailure:      r.dropletStaleThreshold / 4,
-			Host:                   host,
-			ContextPath:            contextPath,
-			MaxConnsPerBackend:     r.maxConnsPerBackend,
-			LoadBalancingAlgorithm: r.DefaultLoadBalancingAlgorithm,
+			Logger:                   r.logger,
+			RetryAfterFailure:        r.dropletStaleThreshold / 4,
+			Host:                     host,
+			ContextPath:              contextPath,
+			MaxConnsPerBackend:       r.maxConnsPerBackend,
+			LoadBalancingAlgorithm:   r.DefaultLoadBalancingAlgorithm,
+			HashKey:                  r.DefaultHashKey,
+			SlowStartDuration:        r.slowStartDuration,
+			OutlierDetection:         r.outlierDetection,
+			OutlierReporter:          r.reporter,
+			RetryBudget:              r.retryBudget,
+			MaxConnsQueue:            r.maxConnsQueue,
+			AdaptiveConcurrency:      r.adaptiveConcurrency,
+			ConcurrencyLimitReporter: r.reporter,
+			AZFailoverPercent:        r.azFailoverPercent,
 		})
 		r.byURI.Insert(routekey, pool)
 		r.logger.Info("route-registered", slog.Any("uri", routekey))
@@ -172,6 +234,9 @@ func (r *RouteRegistry) Unregister(uri route.Uri, endpoint *route.Endpoint) {
 	r.unregister(uri, endpoint)
 
 	r.reporter.CaptureUnregistryMessage(endpoint)
+	if endpoint.Source != "" {
+		r.reporter.CaptureRouteSourceEvent(endpoint.Source, "unregister")
+	}
 
 }
 
@@ -179,30 +244,31 @@ func (r *RouteRegistry) unregister(uri route.Uri, endpoint *route.Endpoint) {
 	r.Lock()
 	defer r.Unlock()
 
-	uri = uri.RouteKey()
+	routekey := uri.RouteKey()
 
-	pool := r.byURI.Find(uri)
+	pool := r.byURI.Find(routekey)
 	if pool != nil {
 		endpointRemoved := pool.Remove(endpoint)
 		if endpointRemoved {
+			r.changes.publish(RouteChangeUnregistered, routekey, endpoint)
 			if r.logger.Enabled(context.Background(), slog.LevelInfo) {
-				r.logger.Info("endpoint-unregistered", buildSlogAttrs(uri, endpoint)...)
+				r.logger.Info("endpoint-unregistered", buildSlogAttrs(routekey, endpoint)...)
 			}
 		} else {
 			if r.logger.Enabled(context.Background(), slog.LevelInfo) {
-				r.logger.Info("endpoint-not-unregistered", buildSlogAttrs(uri, endpoint)...)
+				r.logger.Info("endpoint-not-unregistered", buildSlogAttrs(routekey, endpoint)...)
 			}
 		}
 
 		if pool.IsEmpty() {
 			if r.EmptyPoolResponseCode503 && r.EmptyPoolTimeout > 0 {
 				if time.Since(pool.LastUpdated()) > r.EmptyPoolTimeout {
-					r.byURI.Delete(uri)
-					r.logger.Info("route-unregistered", slog.Any("uri", uri))
+					r.byURI.Delete(routekey)
+					r.logger.Info("route-unregistered", slog.Any("uri", routekey))
 				}
 			} else {
-				r.byURI.Delete(uri)
-				r.logger.Info("route-unregistered", slog.Any("uri", uri))
+				r.byURI.Delete(routekey)
+				r.logger.Info("route-unregistered", slog.Any("uri", routekey))
 			}
 		}
 	}
@@ -268,6 +334,7 @@ func (r *RouteRegistry) LookupWithAppInstance(uri route.Uri, appID string, appIn
 				ContextPath:            p.ContextPath(),
 				MaxConnsPerBackend:     p.MaxConnsPerBackend(),
 				LoadBalancingAlgorithm: p.LoadBalancingAlgorithm,
+				HashKey:                p.HashKey,
 			})
 			surgicalPool.Put(e)
 		}
@@ -296,6 +363,7 @@ func (r *RouteRegistry) LookupWithProcessInstance(uri route.Uri, processID strin
 					ContextPath:            p.ContextPath(),
 					MaxConnsPerBackend:     p.MaxConnsPerBackend(),
 					LoadBalancingAlgorithm: p.LoadBalancingAlgorithm,
+					HashKey:                p.HashKey,
 				})
 			}
 			surgicalPool.Put(e)
@@ -408,6 +476,7 @@ func (r *RouteRegistry) pruneStaleDroplets() {
 			addresses := []string{}
 			for _, e := range endpoints {
 				addresses = append(addresses, e.CanonicalAddr())
+				r.changes.publish(RouteChangePruned, route.Uri(t.ToPath()), e)
 			}
 			isolationSegment := endpoints[0].IsolationSegment
 			if isolationSegment == "" {
@@ -429,6 +498,21 @@ func (r *RouteRegistry) SuspendPruning(f func() bool) {
 	r.suspendPruning = f
 }
 
+// EachPool calls f for every pool in the registry. f is called without holding
+// the registry lock, so it may block without delaying route updates.
+func (r *RouteRegistry) EachPool(f func(pool *route.EndpointPool)) {
+	r.RLock()
+	var pools []*route.EndpointPool
+	r.byURI.EachNodeWithPool(func(t *container.Trie) {
+		pools = append(pools, t.Pool)
+	})
+	r.RUnlock()
+
+	for _, pool := range pools {
+		f(pool)
+	}
+}
+
 // bulk update to mark pool / endpoints as updated
 func (r *RouteRegistry) freshenRoutes() {
 	now := time.Now()
@@ -469,5 +553,6 @@ func buildSlogAttrs(uri route.Uri, endpoint *route.Endpoint) []any {
 		slog.Any("modification_tag", log.StructValue(endpoint.ModificationTag)),
 		isoSegField,
 		slog.Bool("isTLS", endpoint.IsTLS()),
+		slog.String("source", endpoint.Source),
 	}
 }
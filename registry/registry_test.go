@@ -3,6 +3,7 @@ package registry_test
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -75,6 +76,18 @@ var _ = Describe("RouteRegistry", func() {
 			Expect(reporter.CaptureRegistryMessageCallCount()).To(Equal(1))
 		})
 
+		It("emits route source metrics for endpoints of a route source", func() {
+			r.Register("foo", fooEndpoint)
+			Expect(reporter.CaptureRouteSourceEventCallCount()).To(BeZero())
+
+			fooEndpoint.Source = "nats"
+			r.Register("foo", fooEndpoint)
+			Expect(reporter.CaptureRouteSourceEventCallCount()).To(Equal(1))
+			source, event := reporter.CaptureRouteSourceEventArgsForCall(0)
+			Expect(source).To(Equal("nats"))
+			Expect(event).To(Equal("register"))
+		})
+
 		Context("when the endpoint has an UpdatedAt timestamp", func() {
 			BeforeEach(func() {
 				fooEndpoint.UpdatedAt = time.Now().Add(-3 * time.Second)
@@ -407,7 +420,7 @@ var _ = Describe("RouteRegistry", func() {
 					Expect(r.NumEndpoints()).To(Equal(1))
 
 					p := r.Lookup("foo.com")
-					Expect(p.Endpoints(logger.Logger, "", false, azPreference, az).Next(0).ModificationTag).To(Equal(modTag))
+					Expect(p.Endpoints(logger.Logger, "", false, azPreference, az, "", nil).Next(0).ModificationTag).To(Equal(modTag))
 				})
 			})
 
@@ -429,7 +442,7 @@ var _ = Describe("RouteRegistry", func() {
 						Expect(r.NumEndpoints()).To(Equal(1))
 
 						p := r.Lookup("foo.com")
-						Expect(p.Endpoints(logger.Logger, "", false, azPreference, az).Next(0).ModificationTag).To(Equal(modTag))
+						Expect(p.Endpoints(logger.Logger, "", false, azPreference, az, "", nil).Next(0).ModificationTag).To(Equal(modTag))
 					})
 
 					Context("updating an existing route with an older modification tag", func() {
@@ -449,7 +462,7 @@ var _ = Describe("RouteRegistry", func() {
 							Expect(r.NumEndpoints()).To(Equal(1))
 
 							p := r.Lookup("foo.com")
-							ep := p.Endpoints(logger.Logger, "", false, azPreference, az).Next(0)
+							ep := p.Endpoints(logger.Logger, "", false, azPreference, az, "", nil).Next(0)
 							Expect(ep.ModificationTag).To(Equal(modTag))
 							Expect(ep).To(Equal(endpoint2))
 						})
@@ -468,7 +481,7 @@ var _ = Describe("RouteRegistry", func() {
 						Expect(r.NumEndpoints()).To(Equal(1))
 
 						p := r.Lookup("foo.com")
-						Expect(p.Endpoints(logger.Logger, "", false, azPreference, az).Next(0).ModificationTag).To(Equal(modTag))
+						Expect(p.Endpoints(logger.Logger, "", false, azPreference, az, "", nil).Next(0).ModificationTag).To(Equal(modTag))
 					})
 				})
 			})
@@ -549,6 +562,111 @@ var _ = Describe("RouteRegistry", func() {
 				})
 			})
 		})
+
+		Context("Retry budget", func() {
+			It("does not limit the retries of a pool by default", func() {
+				r.Register("foo", fooEndpoint)
+				Expect(r.Lookup("foo").RetryBudget()).To(BeNil())
+			})
+
+			Context("when the retry budget is enabled", func() {
+				BeforeEach(func() {
+					configObj.RetryBudget.Enabled = true
+					configObj.RetryBudget.MaxTokens = 5
+					r = NewRouteRegistry(logger.Logger, configObj, reporter)
+				})
+
+				It("gives every pool its own full budget", func() {
+					r.Register("foo", fooEndpoint)
+					r.Register("bar", barEndpoint)
+					fooBudget := r.Lookup("foo").RetryBudget()
+					barBudget := r.Lookup("bar").RetryBudget()
+					Expect(fooBudget).NotTo(BeNil())
+					Expect(fooBudget).NotTo(BeIdenticalTo(barBudget))
+					Expect(fooBudget.Tokens()).To(BeNumerically("==", 5))
+				})
+			})
+		})
+
+		Context("Max conns queue", func() {
+			It("does not queue requests by default", func() {
+				r.Register("foo", fooEndpoint)
+				Expect(r.Lookup("foo").QueueTimeout()).To(BeZero())
+			})
+
+			Context("when the max conns queue is enabled", func() {
+				BeforeEach(func() {
+					configObj.MaxConnsQueue.Enabled = true
+					configObj.MaxConnsQueue.Timeout = 3 * time.Second
+					r = NewRouteRegistry(logger.Logger, configObj, reporter)
+				})
+
+				It("queues the requests of every pool", func() {
+					r.Register("foo", fooEndpoint)
+					Expect(r.Lookup("foo").QueueTimeout()).To(Equal(3 * time.Second))
+				})
+			})
+		})
+
+		Context("Adaptive concurrency", func() {
+			It("does not limit the requests to endpoints by default", func() {
+				r.Register("foo", fooEndpoint)
+				Expect(r.Lookup("foo").ConcurrencyLimit(fooEndpoint)).To(BeZero())
+			})
+
+			Context("when adaptive concurrency is enabled", func() {
+				BeforeEach(func() {
+					configObj.AdaptiveConcurrency.Enabled = true
+					configObj.AdaptiveConcurrency.InitialLimit = 7
+					r = NewRouteRegistry(logger.Logger, configObj, reporter)
+				})
+
+				It("limits the requests to every endpoint, starting at the initial limit", func() {
+					r.Register("foo", fooEndpoint)
+					Expect(r.Lookup("foo").ConcurrencyLimit(fooEndpoint)).To(Equal(int64(7)))
+				})
+			})
+		})
+
+		Context("AZ failover percent", func() {
+			var local1, local2, remote *route.Endpoint
+
+			BeforeEach(func() {
+				local1 = route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.1", Port: 1234, AvailabilityZone: "z1"})
+				local2 = route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.2", Port: 1234, AvailabilityZone: "z1"})
+				remote = route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.3", Port: 1234, AvailabilityZone: "z2"})
+			})
+
+			selectedZones := func() []string {
+				r.Register("foo", local1)
+				r.Register("foo", local2)
+				r.Register("foo", remote)
+				pool := r.Lookup("foo")
+				pool.EndpointFailed(local1, &net.OpError{Op: "dial"})
+
+				var zones []string
+				for i := 0; i < 10; i++ {
+					e := pool.Endpoints(logger.Logger, "", false, config.AZ_PREF_LOCAL_ONLY, "z1", "", nil).Next(0)
+					zones = append(zones, e.AvailabilityZone)
+				}
+				return zones
+			}
+
+			It("fails over once half of the local endpoints failed by default", func() {
+				Expect(selectedZones()).To(ContainElement("z2"))
+			})
+
+			Context("when the failover percent is configured", func() {
+				BeforeEach(func() {
+					configObj.LoadBalanceAZFailoverPercent = 100
+					r = NewRouteRegistry(logger.Logger, configObj, reporter)
+				})
+
+				It("passes it to every pool", func() {
+					Expect(selectedZones()).To(HaveEach("z1"))
+				})
+			})
+		})
 	})
 
 	Context("Unregister", func() {
@@ -574,6 +692,15 @@ var _ = Describe("RouteRegistry", func() {
 			})
 		})
 
+		It("emits route source metrics for endpoints of a route source", func() {
+			fooEndpoint.Source = "routing-api"
+			r.Unregister("foo", fooEndpoint)
+			Expect(reporter.CaptureRouteSourceEventCallCount()).To(Equal(1))
+			source, event := reporter.CaptureRouteSourceEventArgsForCall(0)
+			Expect(source).To(Equal("routing-api"))
+			Expect(event).To(Equal("unregister"))
+		})
+
 		It("handles unknown URIs", func() {
 			r.Unregister("bar", barEndpoint)
 			Expect(r.NumUris()).To(Equal(0))
@@ -811,7 +938,7 @@ var _ = Describe("RouteRegistry", func() {
 			Expect(r.NumUris()).To(Equal(1))
 
 			p1 := r.Lookup("foo/bar")
-			iter := p1.Endpoints(logger.Logger, "", false, azPreference, az)
+			iter := p1.Endpoints(logger.Logger, "", false, azPreference, az, "", nil)
 			Expect(iter.Next(0).CanonicalAddr()).To(Equal("192.168.1.1:1234"))
 
 			p2 := r.Lookup("foo")
@@ -907,7 +1034,7 @@ var _ = Describe("RouteRegistry", func() {
 			p2 := r.Lookup("FOO")
 			Expect(p1).To(Equal(p2))
 
-			iter := p1.Endpoints(logger.Logger, "", false, azPreference, az)
+			iter := p1.Endpoints(logger.Logger, "", false, azPreference, az, "", nil)
 			Expect(iter.Next(0).CanonicalAddr()).To(Equal("192.168.1.1:1234"))
 		})
 
@@ -926,7 +1053,7 @@ var _ = Describe("RouteRegistry", func() {
 
 			p := r.Lookup("bar")
 			Expect(p).ToNot(BeNil())
-			e := p.Endpoints(logger.Logger, "", false, azPreference, az).Next(0)
+			e := p.Endpoints(logger.Logger, "", false, azPreference, az, "", nil).Next(0)
 			Expect(e).ToNot(BeNil())
 			Expect(e.CanonicalAddr()).To(MatchRegexp("192.168.1.1:123[4|5]"))
 
@@ -941,13 +1068,13 @@ var _ = Describe("RouteRegistry", func() {
 
 			p := r.Lookup("foo.wild.card")
 			Expect(p).ToNot(BeNil())
-			e := p.Endpoints(logger.Logger, "", false, azPreference, az).Next(0)
+			e := p.Endpoints(logger.Logger, "", false, azPreference, az, "", nil).Next(0)
 			Expect(e).ToNot(BeNil())
 			Expect(e.CanonicalAddr()).To(Equal("192.168.1.2:1234"))
 
 			p = r.Lookup("foo.space.wild.card")
 			Expect(p).ToNot(BeNil())
-			e = p.Endpoints(logger.Logger, "", false, azPreference, az).Next(0)
+			e = p.Endpoints(logger.Logger, "", false, azPreference, az, "", nil).Next(0)
 			Expect(e).ToNot(BeNil())
 			Expect(e.CanonicalAddr()).To(Equal("192.168.1.2:1234"))
 		})
@@ -961,7 +1088,7 @@ var _ = Describe("RouteRegistry", func() {
 
 			p := r.Lookup("not.wild.card")
 			Expect(p).ToNot(BeNil())
-			e := p.Endpoints(logger.Logger, "", false, azPreference, az).Next(0)
+			e := p.Endpoints(logger.Logger, "", false, azPreference, az, "", nil).Next(0)
 			Expect(e).ToNot(BeNil())
 			Expect(e.CanonicalAddr()).To(Equal("192.168.1.1:1234"))
 		})
@@ -993,7 +1120,7 @@ var _ = Describe("RouteRegistry", func() {
 				p := r.Lookup("dora.app.com/env?foo=bar")
 
 				Expect(p).ToNot(BeNil())
-				iter := p.Endpoints(logger.Logger, "", false, azPreference, az)
+				iter := p.Endpoints(logger.Logger, "", false, azPreference, az, "", nil)
 				Expect(iter.Next(0).CanonicalAddr()).To(Equal("192.168.1.1:1234"))
 			})
 
@@ -1002,7 +1129,7 @@ var _ = Describe("RouteRegistry", func() {
 				p := r.Lookup("dora.app.com/env/abc?foo=bar&baz=bing")
 
 				Expect(p).ToNot(BeNil())
-				iter := p.Endpoints(logger.Logger, "", false, azPreference, az)
+				iter := p.Endpoints(logger.Logger, "", false, azPreference, az, "", nil)
 				Expect(iter.Next(0).CanonicalAddr()).To(Equal("192.168.1.1:1234"))
 			})
 		})
@@ -1022,7 +1149,7 @@ var _ = Describe("RouteRegistry", func() {
 			p1 := r.Lookup("foo/extra/paths")
 			Expect(p1).ToNot(BeNil())
 
-			iter := p1.Endpoints(logger.Logger, "", false, azPreference, az)
+			iter := p1.Endpoints(logger.Logger, "", false, azPreference, az, "", nil)
 			Expect(iter.Next(0).CanonicalAddr()).To(Equal("192.168.1.1:1234"))
 		})
 
@@ -1034,7 +1161,7 @@ var _ = Describe("RouteRegistry", func() {
 			p1 := r.Lookup("foo?fields=foo,bar")
 			Expect(p1).ToNot(BeNil())
 
-			iter := p1.Endpoints(logger.Logger, "", false, azPreference, az)
+			iter := p1.Endpoints(logger.Logger, "", false, azPreference, az, "", nil)
 			Expect(iter.Next(0).CanonicalAddr()).To(Equal("192.168.1.1:1234"))
 		})
 
@@ -1121,7 +1248,7 @@ var _ = Describe("RouteRegistry", func() {
 			Expect(r.NumEndpoints()).To(Equal(2))
 
 			p := r.LookupWithAppInstance("bar.com/foo", appId, appIndex)
-			e := p.Endpoints(logger.Logger, "", false, azPreference, az).Next(0)
+			e := p.Endpoints(logger.Logger, "", false, azPreference, az, "", nil).Next(0)
 
 			Expect(e).ToNot(BeNil())
 			Expect(e.CanonicalAddr()).To(MatchRegexp("192.168.1.1:1234"))
@@ -1142,7 +1269,7 @@ var _ = Describe("RouteRegistry", func() {
 			Expect(r.NumEndpoints()).To(Equal(2))
 
 			p := r.LookupWithAppInstance("bar.com/foo", appId, appIndex)
-			e := p.Endpoints(logger.Logger, "", false, azPreference, az).Next(0)
+			e := p.Endpoints(logger.Logger, "", false, azPreference, az, "", nil).Next(0)
 
 			Expect(e).ToNot(BeNil())
 			Expect(e.CanonicalAddr()).To(MatchRegexp("192.168.1.1:1234"))
@@ -1250,7 +1377,7 @@ var _ = Describe("RouteRegistry", func() {
 
 				p := r.LookupWithProcessInstance("bar.com/foo", processId, processIndex)
 				Expect(p.NumEndpoints()).To(Equal(2))
-				es := p.Endpoints(logger.Logger, "", false, azPreference, az)
+				es := p.Endpoints(logger.Logger, "", false, azPreference, az, "", nil)
 				e1 := es.Next(0)
 				Expect(e1).ToNot(BeNil())
 				e2 := es.Next(0)
@@ -1289,7 +1416,7 @@ var _ = Describe("RouteRegistry", func() {
 				Expect(r.NumEndpoints()).To(Equal(5))
 
 				p := r.LookupWithProcessInstance("bar.com/foo", processId, processIndex)
-				e := p.Endpoints(logger.Logger, "", false, azPreference, az).Next(0)
+				e := p.Endpoints(logger.Logger, "", false, azPreference, az, "", nil).Next(0)
 
 				Expect(e).ToNot(BeNil())
 				Expect(e.CanonicalAddr()).To(MatchRegexp("192.168.1.4:1237"))
@@ -1496,7 +1623,7 @@ var _ = Describe("RouteRegistry", func() {
 
 			p := r.Lookup("foo")
 			Expect(p).ToNot(BeNil())
-			Expect(p.Endpoints(logger.Logger, "", false, azPreference, az).Next(0)).To(Equal(endpoint))
+			Expect(p.Endpoints(logger.Logger, "", false, azPreference, az, "", nil).Next(0)).To(Equal(endpoint))
 
 			p = r.Lookup("bar")
 			Expect(p).To(BeNil())
@@ -1624,6 +1751,29 @@ var _ = Describe("RouteRegistry", func() {
 
 	})
 
+	Context("EachPool", func() {
+		It("calls the function for every pool", func() {
+			r.Register("foo", fooEndpoint)
+			r.Register("bar", barEndpoint)
+			r.Register("bar/baz", bar2Endpoint)
+
+			hosts := []string{}
+			r.EachPool(func(pool *route.EndpointPool) {
+				hosts = append(hosts, pool.Host()+pool.ContextPath())
+			})
+			Expect(hosts).To(ConsistOf("foo/", "bar/", "bar/baz"))
+		})
+
+		It("allows the function to update the registry", func() {
+			r.Register("foo", fooEndpoint)
+
+			r.EachPool(func(pool *route.EndpointPool) {
+				r.Register("bar", barEndpoint)
+			})
+			Expect(r.NumUris()).To(Equal(2))
+		})
+	})
+
 	Context("Varz data", func() {
 		It("NumUris", func() {
 			r.Register("bar", barEndpoint)
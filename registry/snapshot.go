@@ -0,0 +1,195 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/registry/container"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/routing-api/models"
+)
+
+// snapshotVersion is increased whenever the format of the snapshot changes
+// incompatibly. Snapshots of other versions are not loaded.
+const snapshotVersion = 1
+
+// snapshot is the routing table as written to the snapshot file.
+type snapshot struct {
+	Version   int             `json:"version"`
+	WrittenAt time.Time       `json:"written_at"`
+	Routes    []snapshotRoute `json:"routes"`
+}
+
+type snapshotRoute struct {
+	Uri       route.Uri             `json:"uri"`
+	Endpoints []*route.EndpointOpts `json:"endpoints"`
+}
+
+// WriteSnapshot writes all routes and their endpoints to the snapshot file. The
+// file is replaced atomically, so that it always holds a complete snapshot. An
+// empty routing table is not written, so that a router which lost its routes
+// does not overwrite the last good snapshot. Endpoints loaded from a snapshot
+// are only written once they have been registered again, so that endpoints
+// which are gone do not outlive repeated restarts.
+func (r *RouteRegistry) WriteSnapshot() error {
+	if r.snapshot == nil {
+		return nil
+	}
+
+	s := snapshot{Version: snapshotVersion, WrittenAt: time.Now()}
+	numEndpoints := 0
+	r.RLock()
+	r.byURI.EachNodeWithPool(func(t *container.Trie) {
+		sr := snapshotRoute{Uri: route.Uri(t.ToPath())}
+		t.Pool.Each(func(e *route.Endpoint) {
+			// static endpoints are loaded from their route file again
+			if !e.Static && !e.FromSnapshot {
+				sr.Endpoints = append(sr.Endpoints, e.Opts())
+			}
+		})
+		if len(sr.Endpoints) > 0 {
+			s.Routes = append(s.Routes, sr)
+			numEndpoints += len(sr.Endpoints)
+		}
+	})
+	r.RUnlock()
+
+	if len(s.Routes) == 0 {
+		r.logger.Debug("routing-table-snapshot-skipped-empty-table")
+		return nil
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	path := r.snapshot.Path
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	r.logger.Debug("routing-table-snapshot-written",
+		slog.Int("routes", len(s.Routes)),
+		slog.Int("endpoints", numEndpoints))
+	return nil
+}
+
+// LoadSnapshot registers the routes of the snapshot file, if there is one.
+// Endpoints which were already stale when the snapshot was written are not
+// loaded. The others are pruned after the stale threshold of the snapshot
+// config unless they are registered again, whether they use TLS or not, and
+// are replaced by any registration of the same address.
+func (r *RouteRegistry) LoadSnapshot() error {
+	if r.snapshot == nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(r.snapshot.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		r.logger.Info("routing-table-snapshot-not-found", slog.String("path", r.snapshot.Path))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid routing table snapshot: %w", err)
+	}
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("unsupported routing table snapshot version %d", s.Version)
+	}
+
+	age := time.Since(s.WrittenAt)
+	loaded, expired := 0, 0
+	for _, sr := range s.Routes {
+		for _, opts := range sr.Endpoints {
+			endpoint := route.NewEndpoint(opts)
+			if endpoint.StaleThreshold == 0 || endpoint.StaleThreshold > r.dropletStaleThreshold {
+				endpoint.StaleThreshold = r.dropletStaleThreshold
+			}
+			if age > endpoint.StaleThreshold {
+				expired++
+				continue
+			}
+			if !r.endpointInRouterShard(endpoint) {
+				continue
+			}
+
+			// a loaded endpoint only lives until it is confirmed by a
+			// registration, which must replace it even if it is unchanged
+			endpoint.StaleThreshold = min(endpoint.StaleThreshold, r.snapshot.StaleThreshold)
+			endpoint.ModificationTag = models.ModificationTag{}
+			endpoint.FromSnapshot = true
+
+			r.register(sr.Uri, endpoint)
+			loaded++
+		}
+	}
+
+	r.logger.Info("routing-table-snapshot-loaded",
+		slog.String("path", r.snapshot.Path),
+		slog.Duration("age", age),
+		slog.Int("endpoints", loaded),
+		slog.Int("expired-endpoints", expired))
+	return nil
+}
+
+// StartSnapshotCycle writes a snapshot of the routing table every snapshot
+// interval.
+func (r *RouteRegistry) StartSnapshotCycle() {
+	if r.snapshot == nil {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.snapshotTicker = time.NewTicker(r.snapshot.Interval)
+
+	go func(ticker *time.Ticker) {
+		for range ticker.C {
+			if err := r.WriteSnapshot(); err != nil {
+				r.logger.Error("routing-table-snapshot-write-failed", log.ErrAttr(err))
+			}
+		}
+	}(r.snapshotTicker)
+}
+
+// StopSnapshotCycle stops writing snapshots periodically and writes a final
+// snapshot of the routing table.
+func (r *RouteRegistry) StopSnapshotCycle() {
+	if r.snapshot == nil {
+		return
+	}
+
+	r.Lock()
+	if r.snapshotTicker != nil {
+		r.snapshotTicker.Stop()
+	}
+	r.Unlock()
+
+	if err := r.WriteSnapshot(); err != nil {
+		r.logger.Error("routing-table-snapshot-write-failed", log.ErrAttr(err))
+	}
+}
@@ -0,0 +1,232 @@
+package registry_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/metrics/fakes"
+	. "code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+	"code.cloudfoundry.org/routing-api/models"
+)
+
+var _ = Describe("Routing table snapshots", func() {
+	var (
+		r         *RouteRegistry
+		configObj *config.Config
+		logger    *test_util.TestLogger
+		reporter  *fakes.FakeMetricReporter
+		path      string
+		endpoint  *route.Endpoint
+	)
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+		reporter = new(fakes.FakeMetricReporter)
+
+		var err error
+		configObj, err = config.DefaultConfig()
+		Expect(err).ToNot(HaveOccurred())
+		path = filepath.Join(GinkgoT().TempDir(), "routes.json")
+		configObj.RoutingTableSnapshot = config.RoutingTableSnapshotConfig{
+			Enabled:        true,
+			Path:           path,
+			Interval:       time.Hour,
+			StaleThreshold: 30 * time.Second,
+		}
+		r = NewRouteRegistry(logger.Logger, configObj, reporter)
+
+		endpoint = route.NewEndpoint(&route.EndpointOpts{
+			AppId:                   "app-1",
+			AvailabilityZone:        "z1",
+			Host:                    "192.168.1.1",
+			Port:                    8080,
+			PrivateInstanceId:       "instance-1",
+			Tags:                    map[string]string{"component": "dora"},
+			StaleThresholdInSeconds: 120,
+			ModificationTag:         models.ModificationTag{Guid: "abc", Index: 3},
+		})
+	})
+
+	restart := func() *RouteRegistry {
+		return NewRouteRegistry(logger.Logger, configObj, reporter)
+	}
+
+	staleThreshold := func(r *RouteRegistry, uri route.Uri) time.Duration {
+		var threshold time.Duration
+		r.Lookup(uri).Each(func(e *route.Endpoint) { threshold = e.StaleThreshold })
+		return threshold
+	}
+
+	It("serves the routes of the snapshot after a restart", func() {
+		r.Register("foo.example.com/bar", endpoint)
+		Expect(r.WriteSnapshot()).To(Succeed())
+
+		restarted := restart()
+		Expect(restarted.LoadSnapshot()).To(Succeed())
+
+		pool := restarted.Lookup("foo.example.com/bar")
+		Expect(pool).NotTo(BeNil())
+		var loaded []*route.Endpoint
+		pool.Each(func(e *route.Endpoint) { loaded = append(loaded, e) })
+		Expect(loaded).To(HaveLen(1))
+		Expect(loaded[0].CanonicalAddr()).To(Equal("192.168.1.1:8080"))
+		Expect(loaded[0].ApplicationId).To(Equal("app-1"))
+		Expect(loaded[0].AvailabilityZone).To(Equal("z1"))
+		Expect(loaded[0].PrivateInstanceId).To(Equal("instance-1"))
+		Expect(loaded[0].Tags).To(Equal(map[string]string{"component": "dora"}))
+	})
+
+	It("shortens the stale threshold of loaded endpoints until they are registered again", func() {
+		r.Register("foo.example.com", endpoint)
+		Expect(r.WriteSnapshot()).To(Succeed())
+
+		restarted := restart()
+		Expect(restarted.LoadSnapshot()).To(Succeed())
+		Expect(staleThreshold(restarted, "foo.example.com")).To(Equal(30 * time.Second))
+
+		restarted.Register("foo.example.com", endpoint)
+		Expect(staleThreshold(restarted, "foo.example.com")).To(Equal(120 * time.Second))
+	})
+
+	It("does not write loaded endpoints until they are registered again", func() {
+		r.Register("foo.example.com", endpoint)
+		r.Register("bar.example.com", endpoint)
+		Expect(r.WriteSnapshot()).To(Succeed())
+
+		restarted := restart()
+		Expect(restarted.LoadSnapshot()).To(Succeed())
+		restarted.Register("foo.example.com", endpoint)
+		Expect(restarted.WriteSnapshot()).To(Succeed())
+
+		restartedAgain := restart()
+		Expect(restartedAgain.LoadSnapshot()).To(Succeed())
+		Expect(restartedAgain.Lookup("foo.example.com")).NotTo(BeNil())
+		Expect(restartedAgain.Lookup("bar.example.com")).To(BeNil())
+	})
+
+	It("does not load endpoints which were stale when the snapshot was written", func() {
+		r.Register("foo.example.com", endpoint)
+		Expect(r.WriteSnapshot()).To(Succeed())
+
+		data, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		var s map[string]any
+		Expect(json.Unmarshal(data, &s)).To(Succeed())
+		s["written_at"] = time.Now().Add(-10 * time.Minute)
+		data, err = json.Marshal(s)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(path, data, 0o644)).To(Succeed())
+
+		restarted := restart()
+		Expect(restarted.LoadSnapshot()).To(Succeed())
+		Expect(restarted.Lookup("foo.example.com")).To(BeNil())
+	})
+
+	It("does not overwrite the snapshot with an empty routing table", func() {
+		r.Register("foo.example.com", endpoint)
+		Expect(r.WriteSnapshot()).To(Succeed())
+
+		Expect(restart().WriteSnapshot()).To(Succeed())
+
+		restarted := restart()
+		Expect(restarted.LoadSnapshot()).To(Succeed())
+		Expect(restarted.Lookup("foo.example.com")).NotTo(BeNil())
+	})
+
+	It("does not write static endpoints", func() {
+		static := route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.2", Port: 8080, Static: true})
+		r.Register("foo.example.com", endpoint)
+		r.Register("static.example.com", static)
+		Expect(r.WriteSnapshot()).To(Succeed())
+
+		restarted := restart()
+		Expect(restarted.LoadSnapshot()).To(Succeed())
+		Expect(restarted.Lookup("foo.example.com")).NotTo(BeNil())
+		Expect(restarted.Lookup("static.example.com")).To(BeNil())
+	})
+
+	It("starts with an empty routing table if there is no snapshot", func() {
+		Expect(r.LoadSnapshot()).To(Succeed())
+		Expect(r.NumUris()).To(BeZero())
+	})
+
+	It("fails to load an invalid snapshot", func() {
+		Expect(os.WriteFile(path, []byte(`{"version": 99}`), 0o644)).To(Succeed())
+		Expect(r.LoadSnapshot()).To(MatchError("unsupported routing table snapshot version 99"))
+	})
+
+	It("writes a final snapshot when the snapshot cycle stops", func() {
+		r.StartSnapshotCycle()
+		r.Register("foo.example.com", endpoint)
+		r.StopSnapshotCycle()
+		Expect(path).To(BeAnExistingFile())
+	})
+
+	Context("when the endpoints use TLS", func() {
+		BeforeEach(func() {
+			configObj.PruneStaleDropletsInterval = 20 * time.Millisecond
+			configObj.RoutingTableSnapshot.StaleThreshold = 50 * time.Millisecond
+			endpoint = route.NewEndpoint(&route.EndpointOpts{
+				AppId:                   "app-1",
+				Host:                    "192.168.1.1",
+				Port:                    8443,
+				UseTLS:                  true,
+				ServerCertDomainSAN:     "instance-1",
+				PrivateInstanceId:       "instance-1",
+				StaleThresholdInSeconds: 120,
+			})
+		})
+
+		lookup := func(r *RouteRegistry) func() *route.EndpointPool {
+			return func() *route.EndpointPool { return r.Lookup("foo.example.com") }
+		}
+
+		It("prunes loaded endpoints which are not registered again", func() {
+			r.Register("foo.example.com", endpoint)
+			Expect(r.WriteSnapshot()).To(Succeed())
+
+			restarted := restart()
+			Expect(restarted.LoadSnapshot()).To(Succeed())
+			Expect(restarted.Lookup("foo.example.com")).NotTo(BeNil())
+
+			restarted.StartPruningCycle()
+			defer restarted.StopPruningCycle()
+			Eventually(lookup(restarted)).Should(BeNil())
+		})
+
+		It("does not prune loaded endpoints once they are registered again", func() {
+			r.Register("foo.example.com", endpoint)
+			Expect(r.WriteSnapshot()).To(Succeed())
+
+			restarted := restart()
+			Expect(restarted.LoadSnapshot()).To(Succeed())
+			restarted.Register("foo.example.com", endpoint)
+
+			restarted.StartPruningCycle()
+			defer restarted.StopPruningCycle()
+			Consistently(lookup(restarted), 200*time.Millisecond).ShouldNot(BeNil())
+		})
+	})
+
+	Context("when snapshots are disabled", func() {
+		BeforeEach(func() {
+			configObj.RoutingTableSnapshot.Enabled = false
+			r = NewRouteRegistry(logger.Logger, configObj, reporter)
+		})
+
+		It("neither writes nor loads snapshots", func() {
+			r.Register("foo.example.com", endpoint)
+			Expect(r.WriteSnapshot()).To(Succeed())
+			Expect(path).NotTo(BeAnExistingFile())
+			Expect(r.LoadSnapshot()).To(Succeed())
+		})
+	})
+})
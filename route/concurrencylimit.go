@@ -0,0 +1,131 @@
+package route
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/config"
+)
+
+// minLatencyWindow is the time after which the fastest response of an endpoint
+// is forgotten, so that its concurrency limit follows lasting changes of its
+// response times.
+const minLatencyWindow = time.Minute
+
+// ConcurrencyLimitReporter is notified when the adaptive concurrency limit of
+// an endpoint changes.
+type ConcurrencyLimitReporter interface {
+	CaptureConcurrencyLimit(b *Endpoint, limit int64)
+}
+
+// ConcurrencyLimiter adapts the number of requests in flight allowed for an
+// endpoint to its response times: the limit increases additively while the
+// endpoint responds quickly and decreases multiplicatively when its responses
+// slow down or fail. A nil ConcurrencyLimiter does not limit requests.
+type ConcurrencyLimiter struct {
+	sync.Mutex
+	cfg          *config.AdaptiveConcurrencyConfig
+	limit        float64
+	minLatency   time.Duration
+	minLatencyAt time.Time
+}
+
+// NewConcurrencyLimiter creates a limiter starting at the initial limit of the
+// given configuration.
+func NewConcurrencyLimiter(cfg *config.AdaptiveConcurrencyConfig) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		cfg:   cfg,
+		limit: float64(cfg.InitialLimit),
+	}
+}
+
+// Limit returns the number of requests in flight currently allowed, or zero if
+// the limiter is nil.
+func (l *ConcurrencyLimiter) Limit() int64 {
+	if l == nil {
+		return 0
+	}
+	l.Lock()
+	defer l.Unlock()
+	return int64(l.limit)
+}
+
+// Observe adjusts the limit to a request which took latency while inFlight
+// requests were sent to the endpoint, or which was dropped because it failed
+// or timed out. It returns the new limit and whether it changed.
+func (l *ConcurrencyLimiter) Observe(latency time.Duration, inFlight int64, dropped bool) (int64, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	before := int64(l.limit)
+	now := time.Now()
+	if !dropped && (l.minLatency == 0 || latency < l.minLatency || now.Sub(l.minLatencyAt) > minLatencyWindow) {
+		l.minLatency = latency
+		l.minLatencyAt = now
+	}
+
+	switch {
+	case dropped || float64(latency) > float64(l.minLatency)*l.cfg.LatencyTolerance:
+		l.limit = max(float64(l.cfg.MinLimit), l.limit*l.cfg.BackoffRatio)
+	case float64(inFlight)*2 >= l.limit:
+		// only grow the limit while the endpoint is using a good part of it
+		l.limit = min(float64(l.cfg.MaxLimit), l.limit+1)
+	}
+
+	after := int64(l.limit)
+	return after, after != before
+}
+
+// EndpointFinished adapts the concurrency limit of endpoint to a request which
+// took latency, or which was dropped because it failed or timed out. It must
+// be called before the request is no longer counted as in flight.
+func (p *EndpointPool) EndpointFinished(endpoint *Endpoint, latency time.Duration, dropped bool) {
+	if p.adaptiveConcurrency == nil {
+		return
+	}
+
+	p.Lock()
+	e := p.index[endpoint.CanonicalAddr()]
+	p.Unlock()
+	if e == nil || e.limiter == nil {
+		return
+	}
+
+	before := e.limiter.Limit()
+	limit, changed := e.limiter.Observe(latency, endpoint.Stats.NumberConnections.Count(), dropped)
+	if !changed {
+		return
+	}
+	if limit > before {
+		p.capacityAdded(e)
+	}
+	p.logger.Debug("endpoint-concurrency-limit-changed",
+		slog.String("endpoint", endpoint.CanonicalAddr()),
+		slog.Int64("limit", limit))
+	if p.concurrencyLimitReporter != nil {
+		p.concurrencyLimitReporter.CaptureConcurrencyLimit(endpoint, limit)
+	}
+}
+
+// ConcurrencyLimit returns the adaptive concurrency limit of endpoint, or zero
+// if the requests to it are not limited adaptively.
+func (p *EndpointPool) ConcurrencyLimit(endpoint *Endpoint) int64 {
+	p.Lock()
+	defer p.Unlock()
+
+	e := p.index[endpoint.CanonicalAddr()]
+	if e == nil {
+		return 0
+	}
+	return e.limiter.Limit()
+}
+
+// newConcurrencyLimiter returns the limiter of a new endpoint of the pool, or
+// nil if the requests to its endpoints are not limited adaptively.
+func (p *EndpointPool) newConcurrencyLimiter() *ConcurrencyLimiter {
+	if p.adaptiveConcurrency == nil {
+		return nil
+	}
+	return NewConcurrencyLimiter(p.adaptiveConcurrency)
+}
@@ -0,0 +1,187 @@
+package route_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+type fakeConcurrencyLimitReporter struct {
+	endpoints []*route.Endpoint
+	limits    []int64
+}
+
+func (f *fakeConcurrencyLimitReporter) CaptureConcurrencyLimit(b *route.Endpoint, limit int64) {
+	f.endpoints = append(f.endpoints, b)
+	f.limits = append(f.limits, limit)
+}
+
+var _ = Describe("ConcurrencyLimiter", func() {
+	var (
+		cfg     *config.AdaptiveConcurrencyConfig
+		limiter *route.ConcurrencyLimiter
+	)
+
+	BeforeEach(func() {
+		cfg = &config.AdaptiveConcurrencyConfig{
+			Enabled:          true,
+			InitialLimit:     10,
+			MinLimit:         2,
+			MaxLimit:         12,
+			LatencyTolerance: 2,
+			BackoffRatio:     0.5,
+		}
+	})
+
+	JustBeforeEach(func() {
+		limiter = route.NewConcurrencyLimiter(cfg)
+	})
+
+	It("starts at the initial limit", func() {
+		Expect(limiter.Limit()).To(Equal(int64(10)))
+	})
+
+	It("grows the limit while the endpoint is busy and responds quickly", func() {
+		limit, changed := limiter.Observe(10*time.Millisecond, 5, false)
+		Expect(changed).To(BeTrue())
+		Expect(limit).To(Equal(int64(11)))
+	})
+
+	It("does not grow the limit while the endpoint uses little of it", func() {
+		_, changed := limiter.Observe(10*time.Millisecond, 1, false)
+		Expect(changed).To(BeFalse())
+		Expect(limiter.Limit()).To(Equal(int64(10)))
+	})
+
+	It("does not grow the limit beyond the maximum", func() {
+		for i := 0; i < 10; i++ {
+			limiter.Observe(10*time.Millisecond, 10, false)
+		}
+		Expect(limiter.Limit()).To(Equal(int64(12)))
+	})
+
+	It("backs off when a response is much slower than the fastest one", func() {
+		limiter.Observe(10*time.Millisecond, 1, false)
+		limit, changed := limiter.Observe(30*time.Millisecond, 1, false)
+		Expect(changed).To(BeTrue())
+		Expect(limit).To(Equal(int64(5)))
+	})
+
+	It("tolerates responses which are somewhat slower than the fastest one", func() {
+		limiter.Observe(10*time.Millisecond, 1, false)
+		_, changed := limiter.Observe(15*time.Millisecond, 1, false)
+		Expect(changed).To(BeFalse())
+	})
+
+	It("backs off when a request is dropped, but not below the minimum", func() {
+		limiter.Observe(0, 1, true)
+		Expect(limiter.Limit()).To(Equal(int64(5)))
+		limiter.Observe(0, 1, true)
+		limiter.Observe(0, 1, true)
+		Expect(limiter.Limit()).To(Equal(int64(2)))
+	})
+
+	Context("when the limiter is nil", func() {
+		It("does not limit requests", func() {
+			var nilLimiter *route.ConcurrencyLimiter
+			Expect(nilLimiter.Limit()).To(BeZero())
+		})
+	})
+})
+
+var _ = Describe("EndpointPool adaptive concurrency", func() {
+	var (
+		pool     *route.EndpointPool
+		endpoint *route.Endpoint
+		reporter *fakeConcurrencyLimitReporter
+		cfg      *config.AdaptiveConcurrencyConfig
+	)
+
+	BeforeEach(func() {
+		reporter = &fakeConcurrencyLimitReporter{}
+		cfg = &config.AdaptiveConcurrencyConfig{
+			Enabled:          true,
+			InitialLimit:     2,
+			MinLimit:         1,
+			MaxLimit:         10,
+			LatencyTolerance: 2,
+			BackoffRatio:     0.5,
+		}
+	})
+
+	JustBeforeEach(func() {
+		pool = route.NewPool(&route.PoolOpts{
+			Logger:                   test_util.NewTestLogger("test").Logger,
+			AdaptiveConcurrency:      cfg,
+			ConcurrencyLimitReporter: reporter,
+		})
+		endpoint = route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111})
+		pool.Put(endpoint)
+	})
+
+	It("is overloaded once the requests in flight reach the limit", func() {
+		endpoint.Stats.NumberConnections.Increment()
+		Expect(pool.IsOverloaded()).To(BeFalse())
+		endpoint.Stats.NumberConnections.Increment()
+		Expect(pool.IsOverloaded()).To(BeTrue())
+	})
+
+	It("adapts the limit to finished requests and reports changes", func() {
+		endpoint.Stats.NumberConnections.Increment()
+		pool.EndpointFinished(endpoint, 10*time.Millisecond, false)
+		Expect(pool.ConcurrencyLimit(endpoint)).To(Equal(int64(3)))
+
+		pool.EndpointFinished(endpoint, 0, true)
+		Expect(pool.ConcurrencyLimit(endpoint)).To(Equal(int64(1)))
+
+		Expect(reporter.limits).To(Equal([]int64{3, 1}))
+		Expect(reporter.endpoints).To(HaveEach(endpoint))
+	})
+
+	It("includes the limit in the JSON of the pool", func() {
+		b, err := json.Marshal(pool)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(ContainSubstring(`"concurrency_limit":2`))
+	})
+
+	Context("when the pool also has a maximum number of connections per backend", func() {
+		JustBeforeEach(func() {
+			pool = route.NewPool(&route.PoolOpts{
+				Logger:              test_util.NewTestLogger("test").Logger,
+				MaxConnsPerBackend:  1,
+				AdaptiveConcurrency: cfg,
+			})
+			pool.Put(endpoint)
+		})
+
+		It("applies the lower limit", func() {
+			endpoint.Stats.NumberConnections.Increment()
+			Expect(pool.IsOverloaded()).To(BeTrue())
+		})
+	})
+
+	Context("when adaptive concurrency is disabled", func() {
+		BeforeEach(func() {
+			cfg = nil
+		})
+
+		It("does not limit requests", func() {
+			pool.EndpointFinished(endpoint, time.Second, true)
+			Expect(pool.ConcurrencyLimit(endpoint)).To(BeZero())
+			for i := 0; i < 100; i++ {
+				endpoint.Stats.NumberConnections.Increment()
+			}
+			Expect(pool.IsOverloaded()).To(BeFalse())
+
+			b, err := json.Marshal(pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(b)).NotTo(ContainSubstring("concurrency_limit"))
+		})
+	})
+})
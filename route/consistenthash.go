@@ -0,0 +1,316 @@
+package route
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/config"
+)
+
+// ringReplicas is the number of points each endpoint occupies on the hash
+// ring. More points spread the keys more evenly across endpoints.
+const ringReplicas = 100
+
+type ringEntry struct {
+	hash uint64
+	elem *endpointElem
+}
+
+// ConsistentHash maps a request key onto a hash ring of the pool's endpoints,
+// so that requests with the same key are sent to the same endpoint and adding
+// or removing an endpoint only remaps the keys of its neighbours on the ring.
+// Endpoints that have failed or are overloaded are skipped in favour of the
+// next endpoint on the ring.
+type ConsistentHash struct {
+	logger *slog.Logger
+	pool   *EndpointPool
+	lock   *sync.Mutex
+
+	initialEndpoint       string
+	mustBeSticky          bool
+	lastEndpoint          *Endpoint
+	locallyOptimistic     bool
+	localAvailabilityZone string
+	endpointFilter
+
+	hashKey string
+	tried   map[*endpointElem]struct{}
+}
+
+func NewConsistentHash(logger *slog.Logger, p *EndpointPool, initial string, mustBeSticky bool, locallyOptimistic bool, localAvailabilityZone string, hashKey string) EndpointIterator {
+	return &ConsistentHash{
+		logger:                logger,
+		pool:                  p,
+		lock:                  &sync.Mutex{},
+		initialEndpoint:       initial,
+		mustBeSticky:          mustBeSticky,
+		locallyOptimistic:     locallyOptimistic,
+		localAvailabilityZone: localAvailabilityZone,
+		hashKey:               hashKey,
+	}
+}
+
+func (r *ConsistentHash) Next(attempt int) *Endpoint {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var e *endpointElem
+	if r.initialEndpoint != "" {
+		e = r.pool.findById(r.initialEndpoint)
+		if e != nil && e.isOverloaded() {
+			if r.mustBeSticky {
+				if r.logger.Enabled(context.Background(), slog.LevelDebug) {
+					r.logger.Debug("endpoint-overloaded-but-request-must-be-sticky", e.endpoint.ToLogData()...)
+				}
+				return nil
+			}
+			e = nil
+		}
+
+		if e == nil && r.mustBeSticky {
+			r.logger.Debug("endpoint-missing-but-request-must-be-sticky", slog.String("requested-endpoint", r.initialEndpoint))
+			return nil
+		}
+
+		if !r.mustBeSticky {
+			r.logger.Debug("endpoint-missing-choosing-alternate", slog.String("requested-endpoint", r.initialEndpoint))
+			r.initialEndpoint = ""
+		}
+	}
+
+	if e != nil {
+		e.RLock()
+		defer e.RUnlock()
+		r.lastEndpoint = e.endpoint
+		return e.endpoint
+	}
+
+	e = r.next(attempt)
+	if e != nil {
+		e.RLock()
+		defer e.RUnlock()
+		r.lastEndpoint = e.endpoint
+		return e.endpoint
+	}
+
+	r.lastEndpoint = nil
+	return nil
+}
+
+func (r *ConsistentHash) next(attempt int) *endpointElem {
+	// Note: the iterator lock must be held when calling this function.
+	r.pool.Lock()
+	defer r.pool.Unlock()
+
+	if len(r.pool.endpoints) == 0 {
+		return nil
+	}
+
+	ring := r.pool.hashRing()
+
+	var start int
+	if r.hashKey == "" {
+		// requests without a key are spread randomly across the ring
+		start = r.pool.random.Intn(len(ring))
+	} else {
+		h := hashString(r.hashKey)
+		start, _ = slices.BinarySearchFunc(ring, h, func(e ringEntry, h uint64) int {
+			switch {
+			case e.hash < h:
+				return -1
+			case e.hash > h:
+				return 1
+			default:
+				return 0
+			}
+		})
+	}
+
+	for _, e := range r.pool.endpoints {
+		r.clearExpiredFailures(e)
+	}
+
+	zone, strict := r.preferredZone(r.pool, r.localAvailabilityZone, r.locallyOptimistic, attempt)
+	if subset := r.subset(attempt); subset != nil {
+		if e := r.walk(ring, start, zone, subset); e != nil {
+			return e
+		}
+		// could not find a valid endpoint in the preferred subset, consider all endpoints
+	}
+
+	if zone != "" && !strict {
+		if e := r.walk(ring, start, zone, nil); e != nil {
+			return e
+		}
+		// could not find a valid endpoint in the same AZ, consider all AZs
+		zone = ""
+	}
+
+	if e := r.walk(ring, start, zone, nil); e != nil {
+		return e
+	}
+
+	if r.allEndpointsAreOverloaded() {
+		return nil
+	}
+
+	// all endpoints were either tried or marked failed, so reset everything to available
+	r.pool.resetFailures()
+	clear(r.tried)
+
+	return r.walk(ring, start, zone, nil)
+}
+
+// walk returns the first eligible endpoint on the ring at or after start.
+// The pool lock must be held when calling this function.
+func (r *ConsistentHash) walk(ring []ringEntry, start int, zone string, subset *endpointSubset) *endpointElem {
+	for i := 0; i < len(ring); i++ {
+		e := ring[(start+i)%len(ring)].elem
+
+		if e.isFailed() || e.isOverloaded() || r.pool.isUnhealthy(e) {
+			continue
+		}
+		if _, found := r.tried[e]; found {
+			continue
+		}
+		if zone != "" && e.endpoint.AvailabilityZone != zone {
+			continue
+		}
+		if !subset.contains(e) {
+			continue
+		}
+
+		if r.tried == nil {
+			r.tried = make(map[*endpointElem]struct{}, 1)
+		}
+		r.tried[e] = struct{}{}
+		return e
+	}
+
+	return nil
+}
+
+func (r *ConsistentHash) clearExpiredFailures(e *endpointElem) {
+	r.pool.clearExpiredFailure(e, time.Now())
+}
+
+func (r *ConsistentHash) allEndpointsAreOverloaded() bool {
+	for _, e := range r.pool.endpoints {
+		if !e.isOverloaded() {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *ConsistentHash) EndpointFailed(err error) {
+	if r.lastEndpoint != nil {
+		r.pool.EndpointFailed(r.lastEndpoint, err)
+	}
+}
+
+func (r *ConsistentHash) PreRequest(e *Endpoint) {
+	e.Stats.NumberConnections.Increment()
+}
+
+func (r *ConsistentHash) PostRequest(e *Endpoint) {
+	e.Stats.NumberConnections.Decrement()
+	r.pool.connectionReleased()
+}
+
+// hashRing returns the hash ring of the pool, building it if endpoints were
+// added or removed since it was last built. The pool lock must be held when
+// calling this function.
+func (p *EndpointPool) hashRing() []ringEntry {
+	if p.ring != nil {
+		return p.ring
+	}
+
+	ring := make([]ringEntry, 0, len(p.endpoints)*ringReplicas)
+	for _, e := range p.endpoints {
+		addr := e.endpoint.CanonicalAddr()
+		for i := 0; i < ringReplicas; i++ {
+			ring = append(ring, ringEntry{hash: hashString(addr + "-" + strconv.Itoa(i)), elem: e})
+		}
+	}
+	slices.SortFunc(ring, func(a, b ringEntry) int {
+		switch {
+		case a.hash < b.hash:
+			return -1
+		case a.hash > b.hash:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	p.ring = ring
+	return ring
+}
+
+// RequestHashKey extracts the value the consistent-hash algorithm hashes to
+// select an endpoint for the request. It returns an empty string if the pool
+// does not use consistent hashing or the request does not contain the key.
+func (p *EndpointPool) RequestHashKey(req *http.Request) string {
+	p.Lock()
+	lbAlgo := p.LoadBalancingAlgorithm
+	hashKey := p.HashKey
+	p.Unlock()
+
+	if lbAlgo != config.LOAD_BALANCE_CH {
+		return ""
+	}
+
+	switch {
+	case hashKey == config.HASH_KEY_CLIENT_IP:
+		return clientIP(req)
+	case hashKey == config.HASH_KEY_PATH:
+		return req.URL.Path
+	case strings.HasPrefix(hashKey, config.HASH_KEY_HEADER_PREFIX):
+		return req.Header.Get(strings.TrimPrefix(hashKey, config.HASH_KEY_HEADER_PREFIX))
+	case strings.HasPrefix(hashKey, config.HASH_KEY_QUERY_PREFIX):
+		return req.URL.Query().Get(strings.TrimPrefix(hashKey, config.HASH_KEY_QUERY_PREFIX))
+	default:
+		return ""
+	}
+}
+
+// clientIP returns the left-most X-Forwarded-For address, which is the
+// original client when gorouter sits behind a load balancer, or the peer
+// address of the connection.
+func clientIP(req *http.Request) string {
+	if xff := req.Header.Get("X-Forwarded-For"); xff != "" {
+		first, _, _ := strings.Cut(xff, ",")
+		return strings.TrimSpace(first)
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// hashString hashes s with FNV-1a and finalises the result with the
+// SplitMix64 mixer, which spreads similar inputs such as "10.0.0.1:8080-1" and
+// "10.0.0.1:8080-2" evenly across the ring. The hash does not depend on a
+// random seed, so all router instances map a key onto the same endpoint.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
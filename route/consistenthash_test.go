@@ -0,0 +1,247 @@
+package route_test
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("ConsistentHash", func() {
+	var (
+		pool   *route.EndpointPool
+		logger *test_util.TestLogger
+	)
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+		pool = route.NewPool(&route.PoolOpts{
+			Logger:             logger.Logger,
+			RetryAfterFailure:  2 * time.Minute,
+			Host:               "",
+			ContextPath:        "",
+			MaxConnsPerBackend: 0,
+		})
+	})
+
+	Describe("Next", func() {
+		Context("when pool is empty", func() {
+			It("does not select an endpoint", func() {
+				iter := route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", "some-key")
+				Expect(iter.Next(0)).To(BeNil())
+			})
+		})
+
+		Context("when pool has endpoints", func() {
+			var endpoints []*route.Endpoint
+
+			BeforeEach(func() {
+				endpoints = make([]*route.Endpoint, 0)
+				for i := 0; i < 5; i++ {
+					e := route.NewEndpoint(&route.EndpointOpts{Host: fmt.Sprintf("10.0.1.%d", i), Port: 60000})
+					endpoints = append(endpoints, e)
+					pool.Put(e)
+				}
+			})
+
+			It("always selects the same endpoint for the same key", func() {
+				first := route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", "some-key").Next(0)
+				Expect(first).NotTo(BeNil())
+
+				for i := 0; i < 10; i++ {
+					iter := route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", "some-key")
+					Expect(iter.Next(0)).To(Equal(first))
+				}
+			})
+
+			It("selects the same endpoint in a pool with the same endpoints registered in a different order", func() {
+				otherPool := route.NewPool(&route.PoolOpts{
+					Logger:            logger.Logger,
+					RetryAfterFailure: 2 * time.Minute,
+				})
+				for i := len(endpoints) - 1; i >= 0; i-- {
+					otherPool.Put(endpoints[i])
+				}
+
+				for i := 0; i < 20; i++ {
+					key := fmt.Sprintf("key-%d", i)
+					expected := route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", key).Next(0)
+					actual := route.NewConsistentHash(logger.Logger, otherPool, "", false, false, "meow-az", key).Next(0)
+					Expect(actual).To(Equal(expected))
+				}
+			})
+
+			It("spreads different keys across all endpoints", func() {
+				counts := make(map[*route.Endpoint]int)
+				for i := 0; i < 1000; i++ {
+					iter := route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", fmt.Sprintf("key-%d", i))
+					counts[iter.Next(0)]++
+				}
+
+				Expect(counts).To(HaveLen(len(endpoints)))
+				for _, count := range counts {
+					Expect(count).To(BeNumerically(">", 100))
+				}
+			})
+
+			It("only remaps the keys of a removed endpoint", func() {
+				before := make(map[string]*route.Endpoint)
+				for i := 0; i < 100; i++ {
+					key := fmt.Sprintf("key-%d", i)
+					before[key] = route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", key).Next(0)
+				}
+
+				removed := endpoints[2]
+				Expect(pool.Remove(removed)).To(BeTrue())
+
+				for key, e := range before {
+					after := route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", key).Next(0)
+					Expect(after).NotTo(Equal(removed))
+					if e != removed {
+						Expect(after).To(Equal(e))
+					}
+				}
+			})
+
+			It("selects an endpoint when the key is empty", func() {
+				iter := route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", "")
+				Expect(iter.Next(0)).NotTo(BeNil())
+			})
+
+			It("selects the next endpoint on the ring when the endpoint has failed", func() {
+				iter := route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", "some-key")
+				first := iter.Next(0)
+				iter.EndpointFailed(&net.OpError{Op: "dial"})
+
+				second := iter.Next(1)
+				Expect(second).NotTo(BeNil())
+				Expect(second).NotTo(Equal(first))
+
+				iter = route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", "some-key")
+				Expect(iter.Next(0)).To(Equal(second))
+			})
+
+			It("does not return the same endpoint twice until all endpoints were tried", func() {
+				iter := route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", "some-key")
+				seen := make(map[*route.Endpoint]struct{})
+				for i := 0; i < len(endpoints); i++ {
+					e := iter.Next(i)
+					Expect(seen).NotTo(HaveKey(e))
+					seen[e] = struct{}{}
+				}
+				Expect(seen).To(HaveLen(len(endpoints)))
+			})
+
+			It("resets the failed endpoints when all endpoints have failed", func() {
+				iter := route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", "some-key")
+				for i := 0; i < len(endpoints); i++ {
+					Expect(iter.Next(i)).NotTo(BeNil())
+					iter.EndpointFailed(&net.OpError{Op: "dial"})
+				}
+				Expect(iter.Next(len(endpoints))).NotTo(BeNil())
+			})
+		})
+
+		Context("when some endpoints are overloaded", func() {
+			var epOne, epTwo *route.Endpoint
+
+			BeforeEach(func() {
+				pool = route.NewPool(&route.PoolOpts{
+					Logger:             logger.Logger,
+					RetryAfterFailure:  2 * time.Minute,
+					MaxConnsPerBackend: 1,
+				})
+				epOne = route.NewEndpoint(&route.EndpointOpts{Host: "5.5.5.5", Port: 5555, PrivateInstanceId: "private-label-1"})
+				pool.Put(epOne)
+				epTwo = route.NewEndpoint(&route.EndpointOpts{Host: "2.2.2.2", Port: 2222, PrivateInstanceId: "private-label-2"})
+				pool.Put(epTwo)
+			})
+
+			It("selects the next endpoint on the ring instead of an overloaded one", func() {
+				iter := route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", "some-key")
+				owner := iter.Next(0)
+				owner.Stats.NumberConnections.Increment()
+
+				iter = route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", "some-key")
+				e := iter.Next(0)
+				Expect(e).NotTo(BeNil())
+				Expect(e).NotTo(Equal(owner))
+			})
+
+			It("returns nil when all endpoints are overloaded", func() {
+				epOne.Stats.NumberConnections.Increment()
+				epTwo.Stats.NumberConnections.Increment()
+
+				iter := route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", "some-key")
+				Expect(iter.Next(0)).To(BeNil())
+			})
+
+			Context("when the initial endpoint is overloaded and must be sticky", func() {
+				It("returns nil", func() {
+					epOne.Stats.NumberConnections.Increment()
+
+					iter := route.NewConsistentHash(logger.Logger, pool, "private-label-1", true, false, "meow-az", "some-key")
+					Expect(iter.Next(0)).To(BeNil())
+					Expect(logger).Should(gbytes.Say("endpoint-overloaded-but-request-must-be-sticky"))
+				})
+			})
+
+			Context("when the initial endpoint is not overloaded", func() {
+				It("returns the initial endpoint regardless of the key", func() {
+					for i := 0; i < 10; i++ {
+						iter := route.NewConsistentHash(logger.Logger, pool, "private-label-2", false, false, "meow-az", fmt.Sprintf("key-%d", i))
+						Expect(iter.Next(0)).To(Equal(epTwo))
+					}
+				})
+			})
+		})
+
+		Describe("when in locally-optimistic mode", func() {
+			var localEndpoint *route.Endpoint
+
+			BeforeEach(func() {
+				localEndpoint = route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, AvailabilityZone: "meow-az"})
+				pool.Put(localEndpoint)
+				for i := 0; i < 5; i++ {
+					pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: fmt.Sprintf("2.2.2.%d", i), Port: 2222, AvailabilityZone: "other-az"}))
+				}
+			})
+
+			It("selects the local endpoint on the first attempt", func() {
+				for i := 0; i < 20; i++ {
+					iter := route.NewConsistentHash(logger.Logger, pool, "", false, true, "meow-az", fmt.Sprintf("key-%d", i))
+					Expect(iter.Next(0)).To(Equal(localEndpoint))
+				}
+			})
+
+			It("selects endpoints regardless of AZ on a retry", func() {
+				selected := make(map[*route.Endpoint]struct{})
+				for i := 0; i < 20; i++ {
+					iter := route.NewConsistentHash(logger.Logger, pool, "", false, true, "meow-az", fmt.Sprintf("key-%d", i))
+					selected[iter.Next(1)] = struct{}{}
+				}
+				Expect(len(selected)).To(BeNumerically(">", 1))
+			})
+		})
+	})
+
+	Context("PreRequest and PostRequest", func() {
+		It("tracks the number of connections", func() {
+			e := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678})
+			pool.Put(e)
+			iter := route.NewConsistentHash(logger.Logger, pool, "", false, false, "meow-az", "some-key")
+
+			iter.PreRequest(e)
+			Expect(e.Stats.NumberConnections.Count()).To(Equal(int64(1)))
+
+			iter.PostRequest(e)
+			Expect(e.Stats.NumberConnections.Count()).To(Equal(int64(0)))
+		})
+	})
+})
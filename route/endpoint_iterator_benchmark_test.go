@@ -57,7 +57,7 @@ func setupEndpointIterator(total int, azDistribution int, strategy string) route
 			}
 		}
 
-		e := route.NewEndpoint(&route.EndpointOpts{Host: ip, AvailabilityZone: az})
+		e := route.NewEndpoint(&route.EndpointOpts{Host: ip, AvailabilityZone: az, Weight: i%3 + 1})
 		endpoints = append(endpoints, e)
 	}
 
@@ -77,6 +77,22 @@ func setupEndpointIterator(total int, azDistribution int, strategy string) route
 		lb = route.NewLeastConnection(logger.Logger, pool, "", false, false, localAZ)
 	case "least-connection-locally-optimistic":
 		lb = route.NewLeastConnection(logger.Logger, pool, "", false, true, localAZ)
+	case "latency-ewma":
+		lb = route.NewLatencyEWMA(logger.Logger, pool, "", false, false, localAZ)
+	case "latency-ewma-locally-optimistic":
+		lb = route.NewLatencyEWMA(logger.Logger, pool, "", false, true, localAZ)
+	case "weighted-round-robin":
+		lb = route.NewWeightedRoundRobin(logger.Logger, pool, "", false, false, localAZ)
+	case "weighted-round-robin-locally-optimistic":
+		lb = route.NewWeightedRoundRobin(logger.Logger, pool, "", false, true, localAZ)
+	case "p2c":
+		lb = route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, localAZ)
+	case "p2c-locally-optimistic":
+		lb = route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, true, localAZ)
+	case "consistent-hash":
+		lb = route.NewConsistentHash(logger.Logger, pool, "", false, false, localAZ, "some-key")
+	case "consistent-hash-locally-optimistic":
+		lb = route.NewConsistentHash(logger.Logger, pool, "", false, true, localAZ, "some-key")
 	default:
 		panic("invalid load balancing strategy")
 	}
@@ -245,3 +261,138 @@ func BenchmarkLeastConn15Endpoints(b *testing.B) {
 	iter := setupEndpointIterator(numEndpoints, allEndpointsInNonLocalAZ, strategy)
 	testLoadBalance(iter, b)
 }
+
+// Weighted Round Robin, non-locally optimistic tests
+
+func BenchmarkWeightedRoundRobin1Endpoint(b *testing.B) {
+	numEndpoints := 1
+	strategy := "weighted-round-robin"
+	iter := setupEndpointIterator(numEndpoints, allEndpointsInNonLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+func BenchmarkWeightedRoundRobin5Endpoints(b *testing.B) {
+	numEndpoints := 5
+	strategy := "weighted-round-robin"
+	iter := setupEndpointIterator(numEndpoints, allEndpointsInNonLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+func BenchmarkWeightedRoundRobin15Endpoints(b *testing.B) {
+	numEndpoints := 15
+	strategy := "weighted-round-robin"
+	iter := setupEndpointIterator(numEndpoints, allEndpointsInNonLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+// Weighted Round Robin, locally optimistic tests
+
+func BenchmarkWeightedRoundRobinLocal15HalfLocalEndpoints(b *testing.B) {
+	numEndpoints := 15
+	strategy := "weighted-round-robin-locally-optimistic"
+	iter := setupEndpointIterator(numEndpoints, halfEndpointsInLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+// Latency EWMA, non-locally optimistic tests
+
+func BenchmarkLatencyEWMA1Endpoint(b *testing.B) {
+	numEndpoints := 1
+	strategy := "latency-ewma"
+	iter := setupEndpointIterator(numEndpoints, allEndpointsInNonLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+func BenchmarkLatencyEWMA5Endpoints(b *testing.B) {
+	numEndpoints := 5
+	strategy := "latency-ewma"
+	iter := setupEndpointIterator(numEndpoints, allEndpointsInNonLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+func BenchmarkLatencyEWMA15Endpoints(b *testing.B) {
+	numEndpoints := 15
+	strategy := "latency-ewma"
+	iter := setupEndpointIterator(numEndpoints, allEndpointsInNonLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+// Latency EWMA, locally optimistic tests
+
+func BenchmarkLatencyEWMALocal5HalfLocalEndpoints(b *testing.B) {
+	numEndpoints := 5
+	strategy := "latency-ewma-locally-optimistic"
+	iter := setupEndpointIterator(numEndpoints, halfEndpointsInLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+func BenchmarkLatencyEWMALocal15HalfLocalEndpoints(b *testing.B) {
+	numEndpoints := 15
+	strategy := "latency-ewma-locally-optimistic"
+	iter := setupEndpointIterator(numEndpoints, halfEndpointsInLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+// Consistent Hash, non-locally optimistic tests
+
+func BenchmarkConsistentHash1Endpoint(b *testing.B) {
+	numEndpoints := 1
+	strategy := "consistent-hash"
+	iter := setupEndpointIterator(numEndpoints, allEndpointsInNonLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+func BenchmarkConsistentHash5Endpoints(b *testing.B) {
+	numEndpoints := 5
+	strategy := "consistent-hash"
+	iter := setupEndpointIterator(numEndpoints, allEndpointsInNonLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+func BenchmarkConsistentHash15Endpoints(b *testing.B) {
+	numEndpoints := 15
+	strategy := "consistent-hash"
+	iter := setupEndpointIterator(numEndpoints, allEndpointsInNonLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+// Consistent Hash, locally optimistic tests
+
+func BenchmarkConsistentHashLocal15HalfLocalEndpoints(b *testing.B) {
+	numEndpoints := 15
+	strategy := "consistent-hash-locally-optimistic"
+	iter := setupEndpointIterator(numEndpoints, halfEndpointsInLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+// Power of Two Choices, non-locally optimistic tests
+
+func BenchmarkP2C1Endpoint(b *testing.B) {
+	numEndpoints := 1
+	strategy := "p2c"
+	iter := setupEndpointIterator(numEndpoints, allEndpointsInNonLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+func BenchmarkP2C5Endpoints(b *testing.B) {
+	numEndpoints := 5
+	strategy := "p2c"
+	iter := setupEndpointIterator(numEndpoints, allEndpointsInNonLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+func BenchmarkP2C15Endpoints(b *testing.B) {
+	numEndpoints := 15
+	strategy := "p2c"
+	iter := setupEndpointIterator(numEndpoints, allEndpointsInNonLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
+
+// Power of Two Choices, locally optimistic tests
+
+func BenchmarkP2CLocal15HalfLocalEndpoints(b *testing.B) {
+	numEndpoints := 15
+	strategy := "p2c-locally-optimistic"
+	iter := setupEndpointIterator(numEndpoints, halfEndpointsInLocalAZ, strategy)
+	testLoadBalance(iter, b)
+}
@@ -0,0 +1,85 @@
+package route
+
+import (
+	"log/slog"
+	"slices"
+
+	"code.cloudfoundry.org/gorouter/config"
+)
+
+// endpointFilter is embedded in the endpoint iterators. It holds the
+// endpoints an iterator prefers: those of the app picked by the traffic split
+// of the pool for the first attempt, those selected by the routing rule
+// matching the request for every attempt, and those in the zone selected by
+// the AZ preference of the router.
+type endpointFilter struct {
+	appGroup     string
+	rule         *config.RoutingRule
+	azPreference string
+}
+
+// filteredIterator is implemented by the iterators embedding endpointFilter.
+type filteredIterator interface {
+	filter() *endpointFilter
+}
+
+func (f *endpointFilter) filter() *endpointFilter {
+	return f
+}
+
+// subset returns the endpoints preferred for the given attempt, or nil if all
+// endpoints are equally preferred.
+func (f *endpointFilter) subset(attempt int) *endpointSubset {
+	s := endpointSubset{rule: f.rule}
+	if attempt == 0 {
+		s.appId = f.appGroup
+	}
+	if s.appId == "" && s.rule == nil {
+		return nil
+	}
+	return &s
+}
+
+// endpointSubset is a set of endpoints preferred by an iterator. If none of
+// them is available, the iterator considers all endpoints of the pool.
+type endpointSubset struct {
+	appId string
+	rule  *config.RoutingRule
+}
+
+// contains reports whether e belongs to the subset. A nil subset contains all
+// endpoints.
+func (s *endpointSubset) contains(e *endpointElem) bool {
+	if s == nil {
+		return true
+	}
+	if s.appId != "" && e.endpoint.ApplicationId != s.appId {
+		return false
+	}
+	if s.rule != nil && !e.endpoint.matchesRoutingRule(s.rule) {
+		return false
+	}
+	return true
+}
+
+// matchesRoutingRule reports whether the endpoint has the tag selected by rule.
+func (e *Endpoint) matchesRoutingRule(rule *config.RoutingRule) bool {
+	value, ok := e.Tags[rule.Tag]
+	return ok && (rule.TagValue == "" || value == rule.TagValue)
+}
+
+// RoutingRules returns the routing rules registered for the route.
+func (p *EndpointPool) RoutingRules() []config.RoutingRule {
+	p.Lock()
+	defer p.Unlock()
+	return p.routingRules
+}
+
+// setPoolRoutingRules overwrites the routing rules of a pool by those of a specified endpoint. If the endpoint has none, the rules are removed.
+func (p *EndpointPool) setPoolRoutingRules(endpoint *Endpoint) {
+	if !slices.Equal(endpoint.RoutingRules, p.routingRules) {
+		p.routingRules = endpoint.RoutingRules
+		p.logger.Debug("setting-pool-routing-rules-to-those-of-an-endpoint",
+			slog.Int("numRoutingRules", len(p.routingRules)))
+	}
+}
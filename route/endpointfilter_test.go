@@ -0,0 +1,129 @@
+package route_test
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("Routing rules", func() {
+	var (
+		pool      *route.EndpointPool
+		logger    *test_util.TestLogger
+		endpoints []*route.Endpoint
+		canary    *config.RoutingRule
+	)
+
+	setupPool := func(lbAlgo string, canaries int) {
+		pool = route.NewPool(&route.PoolOpts{
+			Logger:                 logger.Logger,
+			RetryAfterFailure:      2 * time.Minute,
+			LoadBalancingAlgorithm: lbAlgo,
+		})
+
+		endpoints = make([]*route.Endpoint, 0, 4)
+		for i := 0; i < 4; i++ {
+			tags := map[string]string{}
+			if i < canaries {
+				tags["track"] = "canary"
+			}
+			e := route.NewEndpoint(&route.EndpointOpts{Host: fmt.Sprintf("10.0.0.%d", i), Port: 8080, Tags: tags})
+			endpoints = append(endpoints, e)
+			pool.Put(e)
+		}
+	}
+
+	selections := func(requests int, rule *config.RoutingRule) map[*route.Endpoint]int {
+		counts := make(map[*route.Endpoint]int)
+		for i := 0; i < requests; i++ {
+			iter := pool.Endpoints(logger.Logger, "", false, config.AZ_PREF_NONE, "", fmt.Sprintf("key-%d", i), rule)
+			counts[iter.Next(0)]++
+		}
+		return counts
+	}
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+		canary = &config.RoutingRule{Header: "X-Canary", Tag: "track", TagValue: "canary"}
+	})
+
+	DescribeTable("load balancing algorithms prefer the endpoints selected by the routing rule",
+		func(lbAlgo string) {
+			setupPool(lbAlgo, 1)
+
+			Expect(selections(20, canary)).To(Equal(map[*route.Endpoint]int{endpoints[0]: 20}))
+		},
+		Entry("round-robin", config.LOAD_BALANCE_RR),
+		Entry("least-connection", config.LOAD_BALANCE_LC),
+		Entry("weighted-round-robin", config.LOAD_BALANCE_WRR),
+		Entry("latency-ewma", config.LOAD_BALANCE_EWMA),
+		Entry("consistent-hash", config.LOAD_BALANCE_CH),
+		Entry("p2c", config.LOAD_BALANCE_P2C),
+	)
+
+	DescribeTable("load balancing algorithms fall back to all endpoints when no endpoint is selected by the routing rule",
+		func(lbAlgo string) {
+			setupPool(lbAlgo, 0)
+
+			Expect(selections(20, canary)).NotTo(HaveKey(BeNil()))
+		},
+		Entry("round-robin", config.LOAD_BALANCE_RR),
+		Entry("least-connection", config.LOAD_BALANCE_LC),
+		Entry("weighted-round-robin", config.LOAD_BALANCE_WRR),
+		Entry("latency-ewma", config.LOAD_BALANCE_EWMA),
+		Entry("consistent-hash", config.LOAD_BALANCE_CH),
+		Entry("p2c", config.LOAD_BALANCE_P2C),
+	)
+
+	Context("with round-robin", func() {
+		BeforeEach(func() {
+			setupPool(config.LOAD_BALANCE_RR, 2)
+		})
+
+		It("uses all endpoints without a routing rule", func() {
+			Expect(selections(20, nil)).To(HaveLen(4))
+		})
+
+		It("matches any value of the tag when the rule has no tag value", func() {
+			Expect(selections(20, &config.RoutingRule{Header: "X-Canary", Tag: "track"})).To(HaveLen(2))
+		})
+
+		It("keeps retries on the selected endpoints while they are available", func() {
+			iter := pool.Endpoints(logger.Logger, "", false, config.AZ_PREF_NONE, "", "", canary)
+			first := iter.Next(0)
+			Expect(first.Tags).To(HaveKeyWithValue("track", "canary"))
+			iter.EndpointFailed(&net.OpError{Op: "dial"})
+
+			second := iter.Next(1)
+			Expect(second).NotTo(Equal(first))
+			Expect(second.Tags).To(HaveKeyWithValue("track", "canary"))
+			iter.EndpointFailed(&net.OpError{Op: "dial"})
+
+			Expect(iter.Next(2).Tags).NotTo(HaveKey("track"))
+		})
+	})
+
+	Context("when an endpoint registers routing rules", func() {
+		It("overwrites the routing rules of the pool", func() {
+			pool = route.NewPool(&route.PoolOpts{Logger: logger.Logger})
+			rules := []config.RoutingRule{{Cookie: "beta", Tag: "beta"}}
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, RoutingRules: rules}))
+			Expect(pool.RoutingRules()).To(Equal(rules))
+		})
+
+		It("removes the routing rules of the pool when the endpoint registers again without them", func() {
+			pool = route.NewPool(&route.PoolOpts{Logger: logger.Logger})
+			rules := []config.RoutingRule{{Cookie: "beta", Tag: "beta"}}
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, RoutingRules: rules}))
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111}))
+			Expect(pool.RoutingRules()).To(BeEmpty())
+		})
+	})
+})
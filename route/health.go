@@ -0,0 +1,50 @@
+package route
+
+import (
+	"log/slog"
+)
+
+// SetEndpointHealthy records the result of active health checks of endpoint.
+// Unhealthy endpoints are not selected by any load balancing algorithm until
+// they are marked healthy again.
+func (p *EndpointPool) SetEndpointHealthy(endpoint *Endpoint, healthy bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	e := p.index[endpoint.CanonicalAddr()]
+	if e == nil || e.endpoint != endpoint || e.unhealthy == !healthy {
+		return
+	}
+
+	p.setHealthy(e, healthy)
+	if healthy {
+		p.logger.Info("endpoint-marked-healthy", slog.Group("route-endpoint", endpoint.ToLogData()...))
+		p.capacityAdded(e)
+	} else {
+		p.logger.Info("endpoint-marked-unhealthy", slog.Group("route-endpoint", endpoint.ToLogData()...))
+	}
+}
+
+// setHealthy updates the health of e and the number of unhealthy endpoints in
+// the pool. The pool lock must be held when calling this function.
+func (p *EndpointPool) setHealthy(e *endpointElem, healthy bool) {
+	if e.unhealthy == !healthy {
+		return
+	}
+
+	e.unhealthy = !healthy
+	if healthy {
+		p.numUnhealthy--
+	} else {
+		p.numUnhealthy++
+	}
+	p.updateZone(e)
+}
+
+// isUnhealthy reports whether e failed its active health checks and must be
+// skipped. If all endpoints of the pool are unhealthy, none of them is
+// skipped, since a request to a possibly unhealthy endpoint is preferable to
+// no request at all. The pool lock must be held when calling this function.
+func (p *EndpointPool) isUnhealthy(e *endpointElem) bool {
+	return e.unhealthy && p.numUnhealthy < len(p.endpoints)
+}
@@ -0,0 +1,102 @@
+package route_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("Endpoint health", func() {
+	var (
+		pool      *route.EndpointPool
+		logger    *test_util.TestLogger
+		endpoints []*route.Endpoint
+	)
+
+	setupPool := func(lbAlgo string) {
+		pool = route.NewPool(&route.PoolOpts{
+			Logger:                 logger.Logger,
+			RetryAfterFailure:      2 * time.Minute,
+			LoadBalancingAlgorithm: lbAlgo,
+		})
+
+		endpoints = make([]*route.Endpoint, 0, 4)
+		for i := 0; i < 4; i++ {
+			e := route.NewEndpoint(&route.EndpointOpts{Host: fmt.Sprintf("10.0.0.%d", i), Port: 8080})
+			endpoints = append(endpoints, e)
+			pool.Put(e)
+		}
+	}
+
+	selections := func(requests int) map[*route.Endpoint]int {
+		counts := make(map[*route.Endpoint]int)
+		for i := 0; i < requests; i++ {
+			iter := pool.Endpoints(logger.Logger, "", false, config.AZ_PREF_NONE, "", fmt.Sprintf("key-%d", i), nil)
+			counts[iter.Next(0)]++
+		}
+		return counts
+	}
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+	})
+
+	DescribeTable("load balancing algorithms skip unhealthy endpoints",
+		func(lbAlgo string) {
+			setupPool(lbAlgo)
+			pool.SetEndpointHealthy(endpoints[0], false)
+
+			Expect(selections(50)).NotTo(HaveKey(endpoints[0]))
+		},
+		Entry("round-robin", config.LOAD_BALANCE_RR),
+		Entry("least-connection", config.LOAD_BALANCE_LC),
+		Entry("weighted-round-robin", config.LOAD_BALANCE_WRR),
+		Entry("latency-ewma", config.LOAD_BALANCE_EWMA),
+		Entry("consistent-hash", config.LOAD_BALANCE_CH),
+		Entry("p2c", config.LOAD_BALANCE_P2C),
+	)
+
+	Context("with round-robin", func() {
+		BeforeEach(func() {
+			setupPool(config.LOAD_BALANCE_RR)
+		})
+
+		It("selects an endpoint again once it is healthy", func() {
+			pool.SetEndpointHealthy(endpoints[0], false)
+			pool.SetEndpointHealthy(endpoints[0], true)
+
+			Expect(selections(8)).To(HaveKey(endpoints[0]))
+		})
+
+		It("selects unhealthy endpoints when all endpoints are unhealthy", func() {
+			for _, e := range endpoints {
+				pool.SetEndpointHealthy(e, false)
+			}
+
+			Expect(selections(8)).To(HaveLen(4))
+		})
+
+		It("ignores endpoints which are no longer in the pool", func() {
+			Expect(pool.Remove(endpoints[0])).To(BeTrue())
+			pool.SetEndpointHealthy(endpoints[0], false)
+			pool.SetEndpointHealthy(endpoints[1], false)
+			pool.SetEndpointHealthy(endpoints[2], false)
+
+			Expect(selections(6)).To(Equal(map[*route.Endpoint]int{endpoints[3]: 6}))
+		})
+
+		It("forgets the health of an endpoint which was removed", func() {
+			pool.SetEndpointHealthy(endpoints[0], false)
+			Expect(pool.Remove(endpoints[0])).To(BeTrue())
+			pool.Put(endpoints[0])
+
+			Expect(selections(8)).To(HaveKey(endpoints[0]))
+		})
+	})
+})
@@ -0,0 +1,24 @@
+package route
+
+import (
+	"log/slog"
+	"time"
+)
+
+// HedgeDelay returns the time after which a request of this route is also
+// sent to a second endpoint if the first has not responded, or zero if
+// requests of the route are not hedged.
+func (p *EndpointPool) HedgeDelay() time.Duration {
+	p.Lock()
+	defer p.Unlock()
+	return p.hedgeDelay
+}
+
+// setPoolHedgeDelay overwrites the hedge delay of a pool by that of a specified endpoint. If the endpoint has none, requests are no longer hedged.
+func (p *EndpointPool) setPoolHedgeDelay(endpoint *Endpoint) {
+	if endpoint.HedgeDelay != p.hedgeDelay {
+		p.hedgeDelay = endpoint.HedgeDelay
+		p.logger.Debug("setting-pool-hedge-delay-to-that-of-an-endpoint",
+			slog.Duration("poolHedgeDelay", p.hedgeDelay))
+	}
+}
@@ -0,0 +1,53 @@
+package route_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("HedgeDelay", func() {
+	var (
+		pool   *route.EndpointPool
+		logger *test_util.TestLogger
+	)
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+		pool = route.NewPool(&route.PoolOpts{Logger: logger.Logger})
+	})
+
+	It("does not hedge requests by default", func() {
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111}))
+		Expect(pool.HedgeDelay()).To(BeZero())
+	})
+
+	It("adopts the hedge delay of an endpoint", func() {
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, HedgeDelay: 50 * time.Millisecond}))
+		Expect(pool.HedgeDelay()).To(Equal(50 * time.Millisecond))
+	})
+
+	It("stops hedging requests when an endpoint registers again without a hedge delay", func() {
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, HedgeDelay: 50 * time.Millisecond}))
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111}))
+		Expect(pool.HedgeDelay()).To(BeZero())
+	})
+
+	It("treats endpoints with different hedge delays as different", func() {
+		endpoint := route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, HedgeDelay: 50 * time.Millisecond})
+		other := route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, HedgeDelay: 100 * time.Millisecond})
+		Expect(endpoint.Equal(other)).To(BeFalse())
+	})
+
+	It("includes the hedge delay of an endpoint in its JSON", func() {
+		endpoint := route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, HedgeDelay: 50 * time.Millisecond})
+		b, err := json.Marshal(endpoint)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(ContainSubstring(`"hedge_delay":"50ms"`))
+	})
+})
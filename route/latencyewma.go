@@ -0,0 +1,260 @@
+package route
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLatencyDecay is the time after which an observed response time
+	// has lost most of its influence on an endpoint's latency average.
+	DefaultLatencyDecay = 10 * time.Second
+
+	// unmeasuredLatencyPenalty is the cost of an endpoint which has requests in
+	// flight but has not reported a response time yet. It makes sure that new
+	// endpoints only receive one request at a time until their latency is known.
+	unmeasuredLatencyPenalty = float64(math.MaxInt64 >> 16)
+)
+
+// EWMA is a peak-sensitive exponentially weighted moving average of response
+// times. Samples above the current average replace it immediately, so that a
+// slow endpoint is penalised right away, while lower samples and idle time
+// decay the average with a time-based weight.
+type EWMA struct {
+	lock  sync.Mutex
+	value float64
+	stamp time.Time
+	decay time.Duration
+}
+
+func NewEWMA(decay time.Duration) *EWMA {
+	return &EWMA{decay: decay}
+}
+
+// Observe records the response time of a single request.
+func (a *EWMA) Observe(rtt time.Duration) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	now := time.Now()
+	sample := float64(rtt)
+	if sample > a.value {
+		a.value = sample
+	} else {
+		w := a.weight(now)
+		a.value = a.value*w + sample*(1-w)
+	}
+	a.stamp = now
+}
+
+// Value returns the current average. It decays towards zero while no new
+// samples are observed, so that endpoints which were slow in the past are
+// eventually tried again.
+func (a *EWMA) Value() time.Duration {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return time.Duration(a.value * a.weight(time.Now()))
+}
+
+func (a *EWMA) weight(now time.Time) float64 {
+	if a.stamp.IsZero() || a.decay <= 0 {
+		return 0
+	}
+	elapsed := max(now.Sub(a.stamp), 0)
+	return math.Exp(-float64(elapsed) / float64(a.decay))
+}
+
+// LatencyEWMA selects the endpoint with the lowest product of its average
+// response time and its number of in-flight requests, so that slow endpoints
+// receive less traffic even when they have few connections.
+type LatencyEWMA struct {
+	logger                *slog.Logger
+	pool                  *EndpointPool
+	initialEndpoint       string
+	mustBeSticky          bool
+	lastEndpoint          *Endpoint
+	randomize             *rand.Rand
+	locallyOptimistic     bool
+	localAvailabilityZone string
+	endpointFilter
+}
+
+func NewLatencyEWMA(logger *slog.Logger, p *EndpointPool, initial string, mustBeSticky bool, locallyOptimistic bool, localAvailabilityZone string) EndpointIterator {
+	return &LatencyEWMA{
+		logger:                logger,
+		pool:                  p,
+		initialEndpoint:       initial,
+		mustBeSticky:          mustBeSticky,
+		randomize:             rand.New(rand.NewSource(time.Now().UnixNano())),
+		locallyOptimistic:     locallyOptimistic,
+		localAvailabilityZone: localAvailabilityZone,
+	}
+}
+
+func (r *LatencyEWMA) Next(attempt int) *Endpoint {
+	var e *endpointElem
+	if r.initialEndpoint != "" {
+		e = r.pool.findById(r.initialEndpoint)
+		if e != nil && e.isOverloaded() {
+			if r.mustBeSticky {
+				if r.logger.Enabled(context.Background(), slog.LevelDebug) {
+					r.logger.Debug("endpoint-overloaded-but-request-must-be-sticky", e.endpoint.ToLogData()...)
+				}
+				return nil
+			}
+			e = nil
+		}
+
+		if e == nil && r.mustBeSticky {
+			r.logger.Debug("endpoint-missing-but-request-must-be-sticky", slog.String("requested-endpoint", r.initialEndpoint))
+			return nil
+		}
+
+		if !r.mustBeSticky {
+			r.logger.Debug("endpoint-missing-choosing-alternate", slog.String("requested-endpoint", r.initialEndpoint))
+			r.initialEndpoint = ""
+		}
+	}
+
+	if e != nil {
+		e.RLock()
+		defer e.RUnlock()
+		r.lastEndpoint = e.endpoint
+		return e.endpoint
+	}
+
+	e = r.next(attempt)
+	if e != nil {
+		e.RLock()
+		defer e.RUnlock()
+		r.lastEndpoint = e.endpoint
+		return e.endpoint
+	}
+
+	r.lastEndpoint = nil
+	return nil
+}
+
+// PreRequest and PostRequest only track the requests in flight. The response
+// times are observed by the proxy round tripper for each attempt, as hedged
+// attempts run concurrently on the same iterator.
+func (r *LatencyEWMA) PreRequest(e *Endpoint) {
+	e.Stats.NumberConnections.Increment()
+}
+
+func (r *LatencyEWMA) PostRequest(e *Endpoint) {
+	e.Stats.NumberConnections.Decrement()
+	r.pool.connectionReleased()
+}
+
+func (r *LatencyEWMA) next(attempt int) *endpointElem {
+	r.pool.Lock()
+	defer r.pool.Unlock()
+
+	if len(r.pool.endpoints) == 0 {
+		return nil
+	}
+
+	for _, e := range r.pool.endpoints {
+		r.clearExpiredFailures(e)
+	}
+
+	zone, strict := r.preferredZone(r.pool, r.localAvailabilityZone, r.locallyOptimistic, attempt)
+
+	if subset := r.subset(attempt); subset != nil {
+		selected, selectedLocal := r.selectLowestCost(zone, subset)
+		if zone != "" {
+			selected = selectedLocal
+		}
+		if selected != nil {
+			return selected
+		}
+		// could not find a valid endpoint in the preferred subset, consider all endpoints
+	}
+
+	selected, selectedLocal := r.selectLowestCost(zone, nil)
+	if selected == nil && !r.allEndpointsAreOverloaded() {
+		// all endpoints are marked failed so reset everything to available
+		r.pool.resetFailures()
+		selected, selectedLocal = r.selectLowestCost(zone, nil)
+	}
+
+	if zone != "" && (selectedLocal != nil || strict) {
+		return selectedLocal
+	}
+
+	return selected
+}
+
+// selectLowestCost returns the eligible endpoint with the lowest cost and, if
+// zone is not empty, the eligible endpoint in that AZ with the lowest
+// cost. Only endpoints in subset are eligible.
+// Endpoints are visited in random order so that ties are broken randomly.
+func (r *LatencyEWMA) selectLowestCost(zone string, subset *endpointSubset) (selected, selectedLocal *endpointElem) {
+	var selectedCost, selectedLocalCost float64
+
+	total := len(r.pool.endpoints)
+	randIndices := r.randomize.Perm(total)
+	for i := 0; i < total; i++ {
+		cur := r.pool.endpoints[randIndices[i]]
+
+		// Never select an endpoint that is overloaded or has failed
+		if cur.isFailed() || cur.isOverloaded() || r.pool.isUnhealthy(cur) {
+			continue
+		}
+		if !subset.contains(cur) {
+			continue
+		}
+
+		cost := latencyCost(cur.endpoint)
+
+		if selected == nil || cost < selectedCost {
+			selected = cur
+			selectedCost = cost
+		}
+
+		if zone != "" && cur.endpoint.AvailabilityZone == zone {
+			if selectedLocal == nil || cost < selectedLocalCost {
+				selectedLocal = cur
+				selectedLocalCost = cost
+			}
+		}
+	}
+
+	return selected, selectedLocal
+}
+
+func latencyCost(e *Endpoint) float64 {
+	inFlight := float64(e.Stats.NumberConnections.Count())
+	latency := float64(e.Stats.Latency.Value())
+
+	if latency == 0 && inFlight > 0 {
+		return unmeasuredLatencyPenalty + inFlight
+	}
+
+	return latency * (inFlight + 1)
+}
+
+func (r *LatencyEWMA) clearExpiredFailures(e *endpointElem) {
+	r.pool.clearExpiredFailure(e, time.Now())
+}
+
+func (r *LatencyEWMA) allEndpointsAreOverloaded() bool {
+	for _, e := range r.pool.endpoints {
+		if !e.isOverloaded() {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *LatencyEWMA) EndpointFailed(err error) {
+	if r.lastEndpoint != nil {
+		r.pool.EndpointFailed(r.lastEndpoint, err)
+	}
+}
@@ -0,0 +1,220 @@
+package route_test
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("EWMA", func() {
+	It("is zero before any sample is observed", func() {
+		a := route.NewEWMA(time.Minute)
+		Expect(a.Value()).To(BeZero())
+	})
+
+	It("jumps to samples above the current average", func() {
+		a := route.NewEWMA(time.Minute)
+		a.Observe(10 * time.Millisecond)
+		a.Observe(100 * time.Millisecond)
+		Expect(a.Value()).To(BeNumerically("~", 100*time.Millisecond, time.Millisecond))
+	})
+
+	It("decays slowly towards samples below the current average", func() {
+		a := route.NewEWMA(time.Minute)
+		a.Observe(100 * time.Millisecond)
+		a.Observe(10 * time.Millisecond)
+		Expect(a.Value()).To(BeNumerically(">", 90*time.Millisecond))
+		Expect(a.Value()).To(BeNumerically("<=", 100*time.Millisecond))
+	})
+
+	It("decays towards zero when no samples are observed", func() {
+		a := route.NewEWMA(10 * time.Millisecond)
+		a.Observe(100 * time.Millisecond)
+		Eventually(a.Value).Should(BeNumerically("<", time.Millisecond))
+	})
+})
+
+var _ = Describe("LatencyEWMA", func() {
+	var (
+		pool   *route.EndpointPool
+		logger *test_util.TestLogger
+	)
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+		pool = route.NewPool(&route.PoolOpts{
+			Logger:             logger.Logger,
+			RetryAfterFailure:  2 * time.Minute,
+			Host:               "",
+			ContextPath:        "",
+			MaxConnsPerBackend: 0,
+		})
+	})
+
+	Describe("Next", func() {
+		Context("when pool is empty", func() {
+			It("does not select an endpoint", func() {
+				iter := route.NewLatencyEWMA(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).To(BeNil())
+			})
+		})
+
+		Context("when pool has endpoints", func() {
+			var endpoints []*route.Endpoint
+
+			BeforeEach(func() {
+				endpoints = make([]*route.Endpoint, 0)
+				for i := 0; i < 3; i++ {
+					e := route.NewEndpoint(&route.EndpointOpts{Host: fmt.Sprintf("10.0.1.%d", i), Port: 60000})
+					endpoints = append(endpoints, e)
+					pool.Put(e)
+				}
+			})
+
+			It("selects an endpoint when no latencies are known", func() {
+				iter := route.NewLatencyEWMA(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).NotTo(BeNil())
+			})
+
+			It("selects the endpoint with the lowest latency", func() {
+				endpoints[0].Stats.Latency.Observe(300 * time.Millisecond)
+				endpoints[1].Stats.Latency.Observe(10 * time.Millisecond)
+				endpoints[2].Stats.Latency.Observe(100 * time.Millisecond)
+
+				for i := 0; i < 10; i++ {
+					iter := route.NewLatencyEWMA(logger.Logger, pool, "", false, false, "meow-az")
+					Expect(iter.Next(0)).To(Equal(endpoints[1]))
+				}
+			})
+
+			It("prefers a slower endpoint when the faster one has many requests in flight", func() {
+				endpoints[0].Stats.Latency.Observe(300 * time.Millisecond)
+				endpoints[1].Stats.Latency.Observe(10 * time.Millisecond)
+				endpoints[2].Stats.Latency.Observe(20 * time.Millisecond)
+				for i := 0; i < 5; i++ {
+					endpoints[1].Stats.NumberConnections.Increment()
+				}
+
+				iter := route.NewLatencyEWMA(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).To(Equal(endpoints[2]))
+			})
+
+			It("sends one request at a time to endpoints whose latency is unknown", func() {
+				endpoints[0].Stats.Latency.Observe(10 * time.Millisecond)
+				endpoints[1].Stats.Latency.Observe(10 * time.Millisecond)
+				endpoints[2].Stats.NumberConnections.Increment()
+
+				iter := route.NewLatencyEWMA(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).NotTo(Equal(endpoints[2]))
+			})
+
+			It("skips endpoints that have failed", func() {
+				endpoints[0].Stats.Latency.Observe(300 * time.Millisecond)
+				endpoints[1].Stats.Latency.Observe(10 * time.Millisecond)
+				endpoints[2].Stats.Latency.Observe(100 * time.Millisecond)
+
+				iter := route.NewLatencyEWMA(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).To(Equal(endpoints[1]))
+				iter.EndpointFailed(&net.OpError{Op: "dial"})
+				Expect(iter.Next(1)).To(Equal(endpoints[2]))
+			})
+		})
+
+		Context("when some endpoints are overloaded", func() {
+			var epOne, epTwo *route.Endpoint
+
+			BeforeEach(func() {
+				pool = route.NewPool(&route.PoolOpts{
+					Logger:             logger.Logger,
+					RetryAfterFailure:  2 * time.Minute,
+					MaxConnsPerBackend: 1,
+				})
+				epOne = route.NewEndpoint(&route.EndpointOpts{Host: "5.5.5.5", Port: 5555, PrivateInstanceId: "private-label-1"})
+				pool.Put(epOne)
+				epTwo = route.NewEndpoint(&route.EndpointOpts{Host: "2.2.2.2", Port: 2222, PrivateInstanceId: "private-label-2"})
+				pool.Put(epTwo)
+			})
+
+			It("never selects an overloaded endpoint", func() {
+				epOne.Stats.Latency.Observe(time.Millisecond)
+				epTwo.Stats.Latency.Observe(time.Second)
+				epOne.Stats.NumberConnections.Increment()
+
+				iter := route.NewLatencyEWMA(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).To(Equal(epTwo))
+			})
+
+			It("returns nil when all endpoints are overloaded", func() {
+				epOne.Stats.NumberConnections.Increment()
+				epTwo.Stats.NumberConnections.Increment()
+
+				iter := route.NewLatencyEWMA(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).To(BeNil())
+			})
+
+			Context("when the initial endpoint is overloaded and must be sticky", func() {
+				It("returns nil", func() {
+					epOne.Stats.NumberConnections.Increment()
+
+					iter := route.NewLatencyEWMA(logger.Logger, pool, "private-label-1", true, false, "meow-az")
+					Expect(iter.Next(0)).To(BeNil())
+					Expect(logger).Should(gbytes.Say("endpoint-overloaded-but-request-must-be-sticky"))
+				})
+			})
+
+			Context("when the initial endpoint is not overloaded", func() {
+				It("returns the initial endpoint regardless of its latency", func() {
+					epOne.Stats.Latency.Observe(time.Second)
+					epTwo.Stats.Latency.Observe(time.Millisecond)
+
+					iter := route.NewLatencyEWMA(logger.Logger, pool, "private-label-1", false, false, "meow-az")
+					Expect(iter.Next(0)).To(Equal(epOne))
+				})
+			})
+		})
+
+		Describe("when in locally-optimistic mode", func() {
+			var localEndpoint, otherEndpoint *route.Endpoint
+
+			BeforeEach(func() {
+				localEndpoint = route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, AvailabilityZone: "meow-az"})
+				otherEndpoint = route.NewEndpoint(&route.EndpointOpts{Host: "2.2.2.2", Port: 2222, AvailabilityZone: "other-az"})
+				pool.Put(localEndpoint)
+				pool.Put(otherEndpoint)
+				localEndpoint.Stats.Latency.Observe(100 * time.Millisecond)
+				otherEndpoint.Stats.Latency.Observe(time.Millisecond)
+			})
+
+			It("selects the local endpoint on the first attempt", func() {
+				iter := route.NewLatencyEWMA(logger.Logger, pool, "", false, true, "meow-az")
+				Expect(iter.Next(0)).To(Equal(localEndpoint))
+			})
+
+			It("selects the endpoint with the lowest cost regardless of AZ on a retry", func() {
+				iter := route.NewLatencyEWMA(logger.Logger, pool, "", false, true, "meow-az")
+				Expect(iter.Next(1)).To(Equal(otherEndpoint))
+			})
+		})
+	})
+
+	Context("PreRequest and PostRequest", func() {
+		It("tracks the number of connections", func() {
+			e := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678})
+			pool.Put(e)
+			iter := route.NewLatencyEWMA(logger.Logger, pool, "", false, false, "meow-az")
+
+			iter.PreRequest(e)
+			Expect(e.Stats.NumberConnections.Count()).To(Equal(int64(1)))
+
+			iter.PostRequest(e)
+			Expect(e.Stats.NumberConnections.Count()).To(Equal(int64(0)))
+		})
+	})
+})
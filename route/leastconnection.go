@@ -16,6 +16,7 @@ type LeastConnection struct {
 	randomize             *rand.Rand
 	locallyOptimistic     bool
 	localAvailabilityZone string
+	endpointFilter
 }
 
 func NewLeastConnection(logger *slog.Logger, p *EndpointPool, initial string, mustBeSticky bool, locallyOptimistic bool, localAvailabilityZone string) EndpointIterator {
@@ -80,14 +81,14 @@ func (r *LeastConnection) PreRequest(e *Endpoint) {
 
 func (r *LeastConnection) PostRequest(e *Endpoint) {
 	e.Stats.NumberConnections.Decrement()
+	r.pool.connectionReleased()
 }
 
 func (r *LeastConnection) next(attempt int) *endpointElem {
 	r.pool.Lock()
 	defer r.pool.Unlock()
 
-	var selected, selectedLocal *endpointElem
-	localDesired := r.locallyOptimistic && attempt == 0
+	zone, strict := r.preferredZone(r.pool, r.localAvailabilityZone, r.locallyOptimistic, attempt)
 
 	// none
 	total := len(r.pool.endpoints)
@@ -95,6 +96,21 @@ func (r *LeastConnection) next(attempt int) *endpointElem {
 		return nil
 	}
 
+	// least-connection ignores transport failures, but not endpoints which were
+	// ejected by outlier detection
+	now := time.Now()
+	ejected := 0
+	for _, e := range r.pool.endpoints {
+		r.pool.clearExpiredFailure(e, now)
+		if e.isEjected() {
+			ejected++
+		}
+	}
+	if ejected == total {
+		// all endpoints are ejected so reset everything to available
+		r.pool.resetFailures()
+	}
+
 	// single endpoint
 	if total == 1 {
 		e := r.pool.endpoints[0]
@@ -109,20 +125,48 @@ func (r *LeastConnection) next(attempt int) *endpointElem {
 	// select the least connection endpoint OR
 	// random one within the least connection endpoints
 
+	if subset := r.subset(attempt); subset != nil {
+		selected, selectedLocal := r.selectLeastLoaded(zone, subset, now)
+		if zone != "" {
+			selected = selectedLocal
+		}
+		if selected != nil {
+			return selected
+		}
+		// could not find a valid endpoint in the preferred subset, consider all endpoints
+	}
+
+	selected, selectedLocal := r.selectLeastLoaded(zone, nil, now)
+	if zone != "" && (selectedLocal != nil || strict) {
+		return selectedLocal
+	}
+
+	return selected
+}
+
+// selectLeastLoaded returns the eligible endpoint with the lowest load and, if
+// zone is not empty, the eligible endpoint in that AZ with the lowest
+// load. Only endpoints in subset are eligible.
+// The pool lock must be held when calling this function.
+func (r *LeastConnection) selectLeastLoaded(zone string, subset *endpointSubset, now time.Time) (selected, selectedLocal *endpointElem) {
+	total := len(r.pool.endpoints)
 	randIndices := r.randomize.Perm(total)
 	for i := 0; i < total; i++ {
 		randIdx := randIndices[i]
 		cur := r.pool.endpoints[randIdx]
-		curIsLocal := cur.endpoint.AvailabilityZone == r.localAvailabilityZone
+		curInZone := zone != "" && cur.endpoint.AvailabilityZone == zone
 
-		// Never select an endpoint that is overloaded
-		if cur.isOverloaded() {
+		// Never select an endpoint that is overloaded, ejected or unhealthy
+		if cur.isOverloaded() || cur.isEjected() || r.pool.isUnhealthy(cur) {
+			continue
+		}
+		if !subset.contains(cur) {
 			continue
 		}
 
 		// Initialize selectedLocal to the first non-overloaded local endpoint
-		if localDesired {
-			if curIsLocal && selectedLocal == nil {
+		if zone != "" {
+			if curInZone && selectedLocal == nil {
 				selectedLocal = cur
 			}
 		}
@@ -134,23 +178,19 @@ func (r *LeastConnection) next(attempt int) *endpointElem {
 		}
 
 		// If the current option is better than the selected option, select the current
-		if cur.endpoint.Stats.NumberConnections.Count() < selected.endpoint.Stats.NumberConnections.Count() {
+		if r.pool.slowStartLoad(cur, now) < r.pool.slowStartLoad(selected, now) {
 			selected = cur
 		}
 
-		if localDesired {
+		if zone != "" {
 			// If the current option is local and is better than the selectedLocal endpoint, then swap
-			if curIsLocal && cur.endpoint.Stats.NumberConnections.Count() < selectedLocal.endpoint.Stats.NumberConnections.Count() {
+			if curInZone && r.pool.slowStartLoad(cur, now) < r.pool.slowStartLoad(selectedLocal, now) {
 				selectedLocal = cur
 			}
 		}
 	}
 
-	if localDesired && selectedLocal != nil {
-		return selectedLocal
-	}
-
-	return selected
+	return selected, selectedLocal
 }
 
 func (r *LeastConnection) EndpointFailed(err error) {
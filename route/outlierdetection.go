@@ -0,0 +1,211 @@
+package route
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/config"
+)
+
+// OutlierReporter is notified when outlier detection ejects an endpoint from
+// its pool and when the endpoint is returned to the pool.
+type OutlierReporter interface {
+	CaptureOutlierEjected()
+	CaptureOutlierReturned()
+}
+
+// outlierStats tracks the responses of an endpoint for outlier detection.
+type outlierStats struct {
+	consecutive5xx int
+	windowStart    time.Time
+	requests       int
+	errors         int
+
+	// ejections is the number of consecutive ejections, which determines the
+	// length of the next ejection.
+	ejections int
+	// ejectedAt is zero unless the endpoint is currently ejected. It is kept
+	// apart from failedAt so that transport failures neither end nor extend
+	// an ejection.
+	ejectedAt  time.Time
+	ejectedFor time.Duration
+	returnedAt time.Time
+}
+
+// isEjected reports whether e is currently ejected by outlier detection. The
+// pool lock must be held when calling this function.
+func (e *endpointElem) isEjected() bool {
+	return !e.outlier.ejectedAt.IsZero()
+}
+
+// isFailed reports whether e is marked failed or is ejected, in which case the
+// load balancing algorithms do not select it. The pool lock must be held when
+// calling this function.
+func (e *endpointElem) isFailed() bool {
+	return e.failedAt != nil || e.isEjected()
+}
+
+// EndpointResponded records the status code of a response from endpoint and
+// ejects the endpoint from the pool if it responds with too many server
+// errors. An ejected endpoint is treated like a failed one by the load
+// balancing algorithms until its ejection time has passed.
+func (p *EndpointPool) EndpointResponded(endpoint *Endpoint, statusCode int) {
+	if p.outlierDetection == nil {
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	e := p.index[endpoint.CanonicalAddr()]
+	if e == nil {
+		return
+	}
+
+	od := p.outlierDetection
+	s := &e.outlier
+	now := time.Now()
+
+	if now.Sub(s.windowStart) > od.Interval {
+		s.windowStart = now
+		s.requests = 0
+		s.errors = 0
+	}
+	s.requests++
+
+	if statusCode < http.StatusInternalServerError {
+		s.consecutive5xx = 0
+		// forget earlier ejections once the endpoint has behaved for as long as
+		// its last ejection lasted
+		if s.ejections > 0 && !s.returnedAt.IsZero() && now.Sub(s.returnedAt) > p.ejectionTime(s.ejections-1) {
+			s.ejections = 0
+		}
+		return
+	}
+	s.consecutive5xx++
+	s.errors++
+
+	if e.isFailed() {
+		// responses to requests that were sent before the endpoint became ineligible
+		return
+	}
+
+	var reason string
+	switch {
+	case od.Consecutive5xx > 0 && s.consecutive5xx >= od.Consecutive5xx:
+		reason = "consecutive-5xx"
+	case od.ErrorRateThreshold > 0 && s.requests >= od.ErrorRateMinRequests &&
+		float64(s.errors)/float64(s.requests) >= od.ErrorRateThreshold:
+		reason = "error-rate"
+	default:
+		return
+	}
+
+	logger := p.logger.With(slog.Group("route-endpoint", endpoint.ToLogData()...))
+	if !p.canEject() {
+		logger.Info("outlier-ejection-skipped-max-ejection-percent-reached", slog.String("reason", reason))
+		return
+	}
+
+	s.ejectedFor = p.ejectionTime(s.ejections)
+	s.ejections++
+	s.consecutive5xx = 0
+	s.requests = 0
+	s.errors = 0
+	s.ejectedAt = now
+	p.updateZone(e)
+	p.scheduleReturn(e, s.ejectedFor)
+
+	logger.Info("outlier-endpoint-ejected",
+		slog.String("reason", reason),
+		slog.Duration("ejection-time", s.ejectedFor),
+		slog.Int("ejections", s.ejections))
+	if p.outlierReporter != nil {
+		p.outlierReporter.CaptureOutlierEjected()
+	}
+}
+
+// ejectionTime returns how long an endpoint is ejected after the given number
+// of previous consecutive ejections. The time doubles with every ejection up to
+// the configured maximum.
+func (p *EndpointPool) ejectionTime(previousEjections int) time.Duration {
+	od := p.outlierDetection
+	d := od.BaseEjectionTime
+	for i := 0; i < previousEjections && d < od.MaxEjectionTime; i++ {
+		d *= 2
+	}
+	return min(d, od.MaxEjectionTime)
+}
+
+// canEject reports whether one more endpoint may be ejected without exceeding
+// the maximum percentage of ejected endpoints. The pool lock must be held when
+// calling this function.
+func (p *EndpointPool) canEject() bool {
+	ejected := 0
+	for _, e := range p.endpoints {
+		if e.isEjected() {
+			ejected++
+		}
+	}
+	return (ejected+1)*100 <= len(p.endpoints)*p.outlierDetection.MaxEjectionPercent
+}
+
+// scheduleReturn returns e to the pool once its ejection time has passed, so
+// that ejections end and are reported even if no request is routed to the
+// pool.
+func (p *EndpointPool) scheduleReturn(e *endpointElem, ejectedFor time.Duration) {
+	time.AfterFunc(ejectedFor, func() {
+		p.Lock()
+		defer p.Unlock()
+		p.returnExpiredEjection(e, time.Now())
+	})
+}
+
+// returnExpiredEjection returns e to the pool if its ejection time has passed.
+// Timers of earlier ejections find the endpoint returned or ejected again and
+// leave it alone. The pool lock must be held when calling this function.
+func (p *EndpointPool) returnExpiredEjection(e *endpointElem, now time.Time) {
+	if e.isEjected() && now.Sub(e.outlier.ejectedAt) >= e.outlier.ejectedFor {
+		p.returnEjected(e, now)
+	}
+}
+
+// clearExpiredFailure makes e eligible again once it has been marked failed
+// for longer than the pool's retry interval, and returns it once its ejection
+// time has passed. The pool lock must be held when calling this function.
+func (p *EndpointPool) clearExpiredFailure(e *endpointElem, now time.Time) {
+	p.returnExpiredEjection(e, now)
+
+	if e.failedAt != nil && now.Sub(*e.failedAt) > p.retryAfterFailure {
+		e.failedAt = nil
+		p.updateZone(e)
+	}
+}
+
+// resetFailures makes all endpoints eligible again, including those that are
+// ejected. It is used when no eligible endpoint is left. The pool lock must be
+// held when calling this function.
+func (p *EndpointPool) resetFailures() {
+	now := time.Now()
+	for _, e := range p.endpoints {
+		if e.isEjected() {
+			p.returnEjected(e, now)
+		}
+		e.failedAt = nil
+		p.updateZone(e)
+	}
+}
+
+func (p *EndpointPool) returnEjected(e *endpointElem, now time.Time) {
+	e.outlier.ejectedAt = time.Time{}
+	e.outlier.ejectedFor = 0
+	e.outlier.returnedAt = now
+	p.updateZone(e)
+
+	p.logger.Info("outlier-endpoint-returned", slog.Group("route-endpoint", e.endpoint.ToLogData()...))
+	if p.outlierReporter != nil {
+		p.outlierReporter.CaptureOutlierReturned()
+	}
+	p.capacityAdded(e)
+}
@@ -0,0 +1,226 @@
+package route_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+// fakeOutlierReporter counts atomically, as endpoints are returned by timers.
+type fakeOutlierReporter struct {
+	ejected  atomic.Int64
+	returned atomic.Int64
+}
+
+func (f *fakeOutlierReporter) CaptureOutlierEjected()  { f.ejected.Add(1) }
+func (f *fakeOutlierReporter) CaptureOutlierReturned() { f.returned.Add(1) }
+
+var _ = Describe("Outlier detection", func() {
+	var (
+		pool      *route.EndpointPool
+		logger    *test_util.TestLogger
+		reporter  *fakeOutlierReporter
+		odConfig  *config.OutlierDetectionConfig
+		endpoints []*route.Endpoint
+	)
+
+	setupPool := func(lbAlgo string, numEndpoints int) {
+		pool = route.NewPool(&route.PoolOpts{
+			Logger:                 logger.Logger,
+			RetryAfterFailure:      2 * time.Minute,
+			LoadBalancingAlgorithm: lbAlgo,
+			OutlierDetection:       odConfig,
+			OutlierReporter:        reporter,
+		})
+
+		endpoints = make([]*route.Endpoint, 0, numEndpoints)
+		for i := 0; i < numEndpoints; i++ {
+			e := route.NewEndpoint(&route.EndpointOpts{Host: fmt.Sprintf("10.0.0.%d", i), Port: 8080})
+			endpoints = append(endpoints, e)
+			pool.Put(e)
+		}
+	}
+
+	respond := func(e *route.Endpoint, statusCode int, times int) {
+		for i := 0; i < times; i++ {
+			pool.EndpointResponded(e, statusCode)
+		}
+	}
+
+	selections := func(requests int) map[*route.Endpoint]int {
+		counts := make(map[*route.Endpoint]int)
+		for i := 0; i < requests; i++ {
+			iter := pool.Endpoints(logger.Logger, "", false, config.AZ_PREF_NONE, "", fmt.Sprintf("key-%d", i), nil)
+			counts[iter.Next(0)]++
+		}
+		return counts
+	}
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+		reporter = &fakeOutlierReporter{}
+		odConfig = &config.OutlierDetectionConfig{
+			Enabled:              true,
+			Consecutive5xx:       3,
+			ErrorRateMinRequests: 10,
+			Interval:             10 * time.Second,
+			BaseEjectionTime:     time.Minute,
+			MaxEjectionTime:      5 * time.Minute,
+			MaxEjectionPercent:   50,
+		}
+	})
+
+	Context("with consecutive server errors", func() {
+		BeforeEach(func() {
+			setupPool(config.LOAD_BALANCE_RR, 4)
+		})
+
+		It("ejects an endpoint after the configured number of consecutive server errors", func() {
+			respond(endpoints[0], http.StatusBadGateway, 3)
+
+			Expect(logger).To(gbytes.Say("outlier-endpoint-ejected"))
+			Expect(reporter.ejected.Load()).To(Equal(int64(1)))
+			Expect(selections(20)).NotTo(HaveKey(endpoints[0]))
+		})
+
+		It("does not eject an endpoint when a successful response breaks the streak", func() {
+			respond(endpoints[0], http.StatusInternalServerError, 2)
+			respond(endpoints[0], http.StatusOK, 1)
+			respond(endpoints[0], http.StatusInternalServerError, 2)
+
+			Expect(reporter.ejected.Load()).To(Equal(int64(0)))
+		})
+
+		It("does not count client errors", func() {
+			respond(endpoints[0], http.StatusNotFound, 10)
+
+			Expect(reporter.ejected.Load()).To(Equal(int64(0)))
+		})
+	})
+
+	Context("with an error rate threshold", func() {
+		BeforeEach(func() {
+			odConfig.Consecutive5xx = 0
+			odConfig.ErrorRateThreshold = 0.5
+			setupPool(config.LOAD_BALANCE_RR, 4)
+		})
+
+		It("ejects an endpoint whose error rate reaches the threshold", func() {
+			for i := 0; i < 5; i++ {
+				respond(endpoints[0], http.StatusOK, 1)
+				respond(endpoints[0], http.StatusServiceUnavailable, 1)
+			}
+
+			Expect(reporter.ejected.Load()).To(Equal(int64(1)))
+			Expect(logger).To(gbytes.Say("error-rate"))
+		})
+
+		It("does not eject an endpoint before the minimum number of requests", func() {
+			respond(endpoints[0], http.StatusServiceUnavailable, 9)
+
+			Expect(reporter.ejected.Load()).To(Equal(int64(0)))
+		})
+	})
+
+	Context("when the maximum ejection percentage is reached", func() {
+		BeforeEach(func() {
+			setupPool(config.LOAD_BALANCE_RR, 4)
+		})
+
+		It("does not eject more endpoints", func() {
+			for _, e := range endpoints {
+				respond(e, http.StatusInternalServerError, 3)
+			}
+
+			Expect(reporter.ejected.Load()).To(Equal(int64(2)))
+			Expect(logger).To(gbytes.Say("outlier-ejection-skipped-max-ejection-percent-reached"))
+		})
+	})
+
+	Context("when the ejection time has passed", func() {
+		BeforeEach(func() {
+			odConfig.BaseEjectionTime = 20 * time.Millisecond
+			odConfig.MaxEjectionTime = 40 * time.Millisecond
+			setupPool(config.LOAD_BALANCE_RR, 2)
+		})
+
+		It("returns the endpoint to the pool", func() {
+			respond(endpoints[0], http.StatusInternalServerError, 3)
+			Expect(selections(4)).NotTo(HaveKey(endpoints[0]))
+
+			time.Sleep(30 * time.Millisecond)
+
+			Expect(selections(4)).To(HaveKey(endpoints[0]))
+			Expect(reporter.returned.Load()).To(Equal(int64(1)))
+			Expect(logger).To(gbytes.Say("outlier-endpoint-returned"))
+		})
+
+		It("doubles the ejection time of an endpoint which is ejected again", func() {
+			respond(endpoints[0], http.StatusInternalServerError, 3)
+			time.Sleep(30 * time.Millisecond)
+			selections(2)
+
+			respond(endpoints[0], http.StatusInternalServerError, 3)
+			Expect(reporter.ejected.Load()).To(Equal(int64(2)))
+
+			time.Sleep(30 * time.Millisecond)
+			Expect(selections(4)).NotTo(HaveKey(endpoints[0]))
+		})
+
+		It("returns the endpoint when no request is routed to the pool", func() {
+			respond(endpoints[0], http.StatusInternalServerError, 3)
+
+			Eventually(reporter.returned.Load).Should(Equal(int64(1)))
+			Expect(logger).To(gbytes.Say("outlier-endpoint-returned"))
+		})
+
+		It("does not extend the ejection of an endpoint which also fails", func() {
+			respond(endpoints[0], http.StatusInternalServerError, 3)
+			pool.EndpointFailed(endpoints[0], &net.OpError{Op: "dial", Err: errors.New("connection refused")})
+
+			Eventually(reporter.returned.Load).Should(Equal(int64(1)))
+			// the failure is retried after the retry interval of the pool
+			Expect(selections(4)).NotTo(HaveKey(endpoints[0]))
+		})
+	})
+
+	Context("when outlier detection is disabled", func() {
+		BeforeEach(func() {
+			odConfig = nil
+			setupPool(config.LOAD_BALANCE_RR, 2)
+		})
+
+		It("never ejects an endpoint", func() {
+			respond(endpoints[0], http.StatusInternalServerError, 100)
+
+			Expect(reporter.ejected.Load()).To(Equal(int64(0)))
+			Expect(selections(4)).To(HaveKey(endpoints[0]))
+		})
+	})
+
+	DescribeTable("load balancing algorithms skip ejected endpoints",
+		func(lbAlgo string) {
+			setupPool(lbAlgo, 4)
+			respond(endpoints[0], http.StatusInternalServerError, 3)
+
+			Expect(selections(50)).NotTo(HaveKey(endpoints[0]))
+		},
+		Entry("round-robin", config.LOAD_BALANCE_RR),
+		Entry("least-connection", config.LOAD_BALANCE_LC),
+		Entry("weighted-round-robin", config.LOAD_BALANCE_WRR),
+		Entry("latency-ewma", config.LOAD_BALANCE_EWMA),
+		Entry("consistent-hash", config.LOAD_BALANCE_CH),
+		Entry("p2c", config.LOAD_BALANCE_P2C),
+	)
+})
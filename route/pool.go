@@ -6,8 +6,10 @@ import (
 	"log/slog"
 	"maps"
 	"math/rand"
+	"net"
 	"net/http"
 	"slices"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -58,11 +60,13 @@ func (c *Counter) Count() int64 {
 
 type Stats struct {
 	NumberConnections *Counter
+	Latency           *EWMA
 }
 
 func NewStats() *Stats {
 	return &Stats{
 		NumberConnections: &Counter{},
+		Latency:           NewEWMA(DefaultLatencyDecay),
 	}
 }
 
@@ -91,6 +95,27 @@ type Endpoint struct {
 	UpdatedAt              time.Time
 	RoundTripperInit       sync.Once
 	LoadBalancingAlgorithm string
+	Weight                 int
+	HashKey                string
+	SlowStartDuration      time.Duration
+	HealthCheckPath        string
+	RequestTimeout         time.Duration
+	DialTimeout            time.Duration
+	MaxAttempts            int
+	TrafficWeight          int
+	RoutingRules           []config.RoutingRule
+	ShadowRoute            string
+	ShadowPercentage       float64
+	RetryStatusCodes       []int
+	HedgeDelay             time.Duration
+	// Static endpoints come from a route file rather than from registrations,
+	// and are never pruned as stale.
+	Static bool
+	// FromSnapshot endpoints were loaded from a routing table snapshot and are
+	// pruned as stale, even if they use TLS, until they are registered again.
+	FromSnapshot bool
+	// Source is the name of the route source which registered the endpoint.
+	Source string
 }
 
 func (e *Endpoint) RoundTripper() ProxyRoundTripper {
@@ -116,6 +141,9 @@ func (e *Endpoint) SetRoundTripperIfNil(roundTripperCtor func() ProxyRoundTrippe
 	}
 }
 
+// Equal reports whether e2 has the same address and metadata as e. The route
+// source is left out, so that an endpoint registered by several sources does
+// not change whenever another of them registers it again.
 func (e *Endpoint) Equal(e2 *Endpoint) bool {
 	if e2 == nil {
 		return false
@@ -133,6 +161,21 @@ func (e *Endpoint) Equal(e2 *Endpoint) bool {
 		e.useTls == e2.useTls &&
 		e.UpdatedAt.Equal(e2.UpdatedAt) &&
 		e.LoadBalancingAlgorithm == e2.LoadBalancingAlgorithm &&
+		e.Weight == e2.Weight &&
+		e.HashKey == e2.HashKey &&
+		e.SlowStartDuration == e2.SlowStartDuration &&
+		e.HealthCheckPath == e2.HealthCheckPath &&
+		e.RequestTimeout == e2.RequestTimeout &&
+		e.DialTimeout == e2.DialTimeout &&
+		e.MaxAttempts == e2.MaxAttempts &&
+		e.TrafficWeight == e2.TrafficWeight &&
+		slices.Equal(e.RoutingRules, e2.RoutingRules) &&
+		e.ShadowRoute == e2.ShadowRoute &&
+		e.ShadowPercentage == e2.ShadowPercentage &&
+		slices.Equal(e.RetryStatusCodes, e2.RetryStatusCodes) &&
+		e.HedgeDelay == e2.HedgeDelay &&
+		e.Static == e2.Static &&
+		e.FromSnapshot == e2.FromSnapshot &&
 		maps.Equal(e.Tags, e2.Tags)
 
 }
@@ -141,6 +184,15 @@ func (e *Endpoint) ProcessId() string {
 	return e.Tags["process_id"]
 }
 
+// weight returns the relative share of traffic the endpoint should receive
+// from the weighted-round-robin algorithm. Endpoints without a weight count as 1.
+func (e *Endpoint) weight() int64 {
+	if e.Weight <= 0 {
+		return 1
+	}
+	return int64(e.Weight)
+}
+
 //go:generate counterfeiter -o fakes/fake_endpoint_iterator.go . EndpointIterator
 type EndpointIterator interface {
 	// Next MUST either return the next endpoint available or nil. It MUST NOT return the same endpoint.
@@ -159,6 +211,17 @@ type endpointElem struct {
 	updated            time.Time
 	failedAt           *time.Time
 	maxConnsPerBackend int64
+	currentWeight      int64
+	addedAt            time.Time
+	outlier            outlierStats
+	unhealthy          bool
+	limiter            *ConcurrencyLimiter
+
+	// zone counts the endpoint in its availability zone; nil once the
+	// endpoint is removed from the pool
+	zone *zoneCounts
+	// down is set if the endpoint is counted as failed in its zone
+	down bool
 }
 
 type EndpointPool struct {
@@ -173,30 +236,106 @@ type EndpointPool struct {
 	retryAfterFailure  time.Duration
 	NextIdx            int
 	maxConnsPerBackend int64
+	slowStartDuration  time.Duration
+
+	outlierDetection *config.OutlierDetectionConfig
+	outlierReporter  OutlierReporter
+	numUnhealthy     int
+
+	retryBudget *RetryBudget
+
+	// queue holds the requests waiting for an endpoint to drop below
+	// maxConnsPerBackend; nil if requests are not queued
+	queue *requestQueue
+
+	// adaptiveConcurrency limits the requests in flight to each endpoint to a
+	// limit adapted to its response times; nil if it is disabled
+	adaptiveConcurrency      *config.AdaptiveConcurrencyConfig
+	concurrencyLimitReporter ConcurrencyLimitReporter
+
+	// azFailoverPercent is the percentage of the endpoints in the local AZ
+	// which must be unhealthy before the local-only-with-failover AZ
+	// preference considers all AZs
+	azFailoverPercent int
+
+	// zones holds the number of endpoints and of failed endpoints in each
+	// availability zone of the pool
+	zones map[string]*zoneCounts
+
+	// per-route overrides of the global request timeout, dial timeout and
+	// maximum number of attempts; zero means the global value applies
+	requestTimeout time.Duration
+	dialTimeout    time.Duration
+	maxAttempts    int
+
+	// traffic weights of the apps of the route, as registered by their
+	// endpoints and as overridden through the routes API
+	appWeights         map[string]int
+	appWeightOverrides map[string]int
+
+	routingRules []config.RoutingRule
+
+	// shadowRoute receives copies of shadowPercentage percent of the
+	// requests of the route
+	shadowRoute      string
+	shadowPercentage float64
+
+	// retryStatusCodes overrides the global backend status codes on which
+	// idempotent requests are retried
+	retryStatusCodes []int
+
+	// hedgeDelay is the time after which a request of the route is also sent
+	// to a second endpoint; zero disables hedging
+	hedgeDelay time.Duration
 
 	random                 *rand.Rand
 	logger                 *slog.Logger
 	updatedAt              time.Time
 	LoadBalancingAlgorithm string
+	HashKey                string
+
+	// ring is the hash ring used by the consistent-hash algorithm. It is
+	// built lazily and reset whenever endpoints are added or removed.
+	ring []ringEntry
+
+	// appGroups holds the apps the traffic of the route is split between
+	// and their cumulative weights. It is built lazily and reset whenever
+	// endpoints are added or removed or the app weights change.
+	appGroups []appGroup
 }
 
 type EndpointOpts struct {
-	AppId                   string
-	AvailabilityZone        string
-	Host                    string
-	Port                    uint16
-	Protocol                string
-	ServerCertDomainSAN     string
-	PrivateInstanceId       string
-	PrivateInstanceIndex    string
-	Tags                    map[string]string
-	StaleThresholdInSeconds int
-	RouteServiceUrl         string
-	ModificationTag         models.ModificationTag
-	IsolationSegment        string
-	UseTLS                  bool
-	UpdatedAt               time.Time
-	LoadBalancingAlgorithm  string
+	AppId                      string
+	AvailabilityZone           string
+	Host                       string
+	Port                       uint16
+	Protocol                   string
+	ServerCertDomainSAN        string
+	PrivateInstanceId          string
+	PrivateInstanceIndex       string
+	Tags                       map[string]string
+	StaleThresholdInSeconds    int
+	RouteServiceUrl            string
+	ModificationTag            models.ModificationTag
+	IsolationSegment           string
+	UseTLS                     bool
+	UpdatedAt                  time.Time
+	LoadBalancingAlgorithm     string
+	Weight                     int
+	HashKey                    string
+	SlowStartDurationInSeconds int
+	HealthCheckPath            string
+	RequestTimeout             time.Duration
+	DialTimeout                time.Duration
+	MaxAttempts                int
+	TrafficWeight              int
+	RoutingRules               []config.RoutingRule
+	ShadowRoute                string
+	ShadowPercentage           float64
+	RetryStatusCodes           []int
+	HedgeDelay                 time.Duration
+	Static                     bool
+	Source                     string
 }
 
 func NewEndpoint(opts *EndpointOpts) *Endpoint {
@@ -217,6 +356,61 @@ func NewEndpoint(opts *EndpointOpts) *Endpoint {
 		IsolationSegment:       opts.IsolationSegment,
 		UpdatedAt:              opts.UpdatedAt,
 		LoadBalancingAlgorithm: opts.LoadBalancingAlgorithm,
+		Weight:                 opts.Weight,
+		HashKey:                opts.HashKey,
+		SlowStartDuration:      time.Duration(opts.SlowStartDurationInSeconds) * time.Second,
+		HealthCheckPath:        opts.HealthCheckPath,
+		RequestTimeout:         opts.RequestTimeout,
+		DialTimeout:            opts.DialTimeout,
+		MaxAttempts:            opts.MaxAttempts,
+		TrafficWeight:          opts.TrafficWeight,
+		RoutingRules:           opts.RoutingRules,
+		ShadowRoute:            opts.ShadowRoute,
+		ShadowPercentage:       opts.ShadowPercentage,
+		RetryStatusCodes:       opts.RetryStatusCodes,
+		HedgeDelay:             opts.HedgeDelay,
+		Static:                 opts.Static,
+		Source:                 opts.Source,
+	}
+}
+
+// Opts returns the options from which NewEndpoint creates an endpoint equal to
+// e. Stale thresholds and slow start durations are rounded down to seconds.
+func (e *Endpoint) Opts() *EndpointOpts {
+	host, port, _ := net.SplitHostPort(e.addr)
+	portNum, _ := strconv.ParseUint(port, 10, 16)
+	return &EndpointOpts{
+		AppId:                      e.ApplicationId,
+		AvailabilityZone:           e.AvailabilityZone,
+		Host:                       host,
+		Port:                       uint16(portNum),
+		Protocol:                   e.Protocol,
+		ServerCertDomainSAN:        e.ServerCertDomainSAN,
+		PrivateInstanceId:          e.PrivateInstanceId,
+		PrivateInstanceIndex:       e.PrivateInstanceIndex,
+		Tags:                       e.Tags,
+		StaleThresholdInSeconds:    int(e.StaleThreshold / time.Second),
+		RouteServiceUrl:            e.RouteServiceUrl,
+		ModificationTag:            e.ModificationTag,
+		IsolationSegment:           e.IsolationSegment,
+		UseTLS:                     e.useTls,
+		UpdatedAt:                  e.UpdatedAt,
+		LoadBalancingAlgorithm:     e.LoadBalancingAlgorithm,
+		Weight:                     e.Weight,
+		HashKey:                    e.HashKey,
+		SlowStartDurationInSeconds: int(e.SlowStartDuration / time.Second),
+		HealthCheckPath:            e.HealthCheckPath,
+		RequestTimeout:             e.RequestTimeout,
+		DialTimeout:                e.DialTimeout,
+		MaxAttempts:                e.MaxAttempts,
+		TrafficWeight:              e.TrafficWeight,
+		RoutingRules:               e.RoutingRules,
+		ShadowRoute:                e.ShadowRoute,
+		ShadowPercentage:           e.ShadowPercentage,
+		RetryStatusCodes:           e.RetryStatusCodes,
+		HedgeDelay:                 e.HedgeDelay,
+		Static:                     e.Static,
+		Source:                     e.Source,
 	}
 }
 
@@ -225,27 +419,56 @@ func (e *Endpoint) IsTLS() bool {
 }
 
 type PoolOpts struct {
-	RetryAfterFailure      time.Duration
-	Host                   string
-	ContextPath            string
-	MaxConnsPerBackend     int64
-	Logger                 *slog.Logger
-	LoadBalancingAlgorithm string
+	RetryAfterFailure        time.Duration
+	Host                     string
+	ContextPath              string
+	MaxConnsPerBackend       int64
+	Logger                   *slog.Logger
+	LoadBalancingAlgorithm   string
+	HashKey                  string
+	SlowStartDuration        time.Duration
+	OutlierDetection         *config.OutlierDetectionConfig
+	OutlierReporter          OutlierReporter
+	RetryBudget              *config.RetryBudgetConfig
+	MaxConnsQueue            *config.MaxConnsQueueConfig
+	AdaptiveConcurrency      *config.AdaptiveConcurrencyConfig
+	ConcurrencyLimitReporter ConcurrencyLimitReporter
+	AZFailoverPercent        int
 }
 
 func NewPool(opts *PoolOpts) *EndpointPool {
+	var retryBudget *RetryBudget
+	if opts.RetryBudget != nil {
+		retryBudget = NewRetryBudget(opts.RetryBudget)
+	}
+
+	var queue *requestQueue
+	if opts.MaxConnsQueue != nil {
+		queue = newRequestQueue(opts.MaxConnsQueue)
+	}
+
 	return &EndpointPool{
-		endpoints:              make([]*endpointElem, 0, 1),
-		index:                  make(map[string]*endpointElem),
-		retryAfterFailure:      opts.RetryAfterFailure,
-		NextIdx:                -1,
-		maxConnsPerBackend:     opts.MaxConnsPerBackend,
-		host:                   opts.Host,
-		contextPath:            opts.ContextPath,
-		random:                 rand.New(rand.NewSource(time.Now().UnixNano())),
-		logger:                 opts.Logger,
-		updatedAt:              time.Now(),
-		LoadBalancingAlgorithm: opts.LoadBalancingAlgorithm,
+		endpoints:                make([]*endpointElem, 0, 1),
+		index:                    make(map[string]*endpointElem),
+		retryAfterFailure:        opts.RetryAfterFailure,
+		NextIdx:                  -1,
+		maxConnsPerBackend:       opts.MaxConnsPerBackend,
+		slowStartDuration:        opts.SlowStartDuration,
+		outlierDetection:         opts.OutlierDetection,
+		outlierReporter:          opts.OutlierReporter,
+		retryBudget:              retryBudget,
+		queue:                    queue,
+		adaptiveConcurrency:      opts.AdaptiveConcurrency,
+		concurrencyLimitReporter: opts.ConcurrencyLimitReporter,
+		azFailoverPercent:        opts.AZFailoverPercent,
+		zones:                    make(map[string]*zoneCounts),
+		host:                     opts.Host,
+		contextPath:              opts.ContextPath,
+		random:                   rand.New(rand.NewSource(time.Now().UnixNano())),
+		logger:                   opts.Logger,
+		updatedAt:                time.Now(),
+		LoadBalancingAlgorithm:   opts.LoadBalancingAlgorithm,
+		HashKey:                  opts.HashKey,
 	}
 }
 
@@ -295,6 +518,18 @@ func (p *EndpointPool) Put(endpoint *Endpoint) PoolPutResult {
 			if oldEndpoint.PrivateInstanceId != endpoint.PrivateInstanceId {
 				delete(p.index, oldEndpoint.PrivateInstanceId)
 				p.index[endpoint.PrivateInstanceId] = e
+				// a new instance has not been health checked yet
+				p.setHealthy(e, true)
+			}
+
+			if oldEndpoint.AvailabilityZone != endpoint.AvailabilityZone {
+				p.removeFromZone(e)
+				p.addToZone(e)
+			}
+
+			if oldEndpoint.ApplicationId != endpoint.ApplicationId {
+				p.appGroups = nil
+				p.removeAppWeight(oldEndpoint.ApplicationId)
 			}
 
 			if oldEndpoint.ServerCertDomainSAN == endpoint.ServerCertDomainSAN {
@@ -307,16 +542,29 @@ func (p *EndpointPool) Put(endpoint *Endpoint) PoolPutResult {
 			endpoint:           endpoint,
 			index:              len(p.endpoints),
 			maxConnsPerBackend: p.maxConnsPerBackend,
+			addedAt:            time.Now(),
+			limiter:            p.newConcurrencyLimiter(),
 		}
 
 		p.endpoints = append(p.endpoints, e)
+		p.ring = nil
+		p.appGroups = nil
+		p.addToZone(e)
 
 		p.index[endpoint.CanonicalAddr()] = e
 		p.index[endpoint.PrivateInstanceId] = e
-
+		p.capacityAdded(e)
 	}
 	p.RouteSvcUrl = e.endpoint.RouteServiceUrl
 	p.setPoolLoadBalancingAlgorithm(e.endpoint)
+	p.setPoolHashKey(e.endpoint)
+	p.setPoolSlowStartDuration(e.endpoint)
+	p.setPoolTimeouts(e.endpoint)
+	p.setPoolAppWeight(e.endpoint)
+	p.setPoolRoutingRules(e.endpoint)
+	p.setPoolShadow(e.endpoint)
+	p.setPoolRetryStatusCodes(e.endpoint)
+	p.setPoolHedgeDelay(e.endpoint)
 	e.updated = time.Now()
 	// set the update time of the pool
 	p.Update()
@@ -342,7 +590,7 @@ func (p *EndpointPool) PruneEndpoints() []*Endpoint {
 	for i := 0; i < last; {
 		e := p.endpoints[i]
 
-		if e.endpoint.useTls {
+		if (e.endpoint.useTls && !e.endpoint.FromSnapshot) || e.endpoint.Static {
 			i++
 			continue
 		}
@@ -388,13 +636,37 @@ func (p *EndpointPool) removeEndpoint(e *endpointElem) {
 		es[j].index = j
 	}
 	p.endpoints = es
+	p.ring = nil
+	p.appGroups = nil
+	p.setHealthy(e, true)
+	p.removeFromZone(e)
 
 	delete(p.index, e.endpoint.CanonicalAddr())
 	delete(p.index, e.endpoint.PrivateInstanceId)
+	p.removeAppWeight(e.endpoint.ApplicationId)
 	p.Update()
 }
 
-func (p *EndpointPool) Endpoints(logger *slog.Logger, initial string, mustBeSticky bool, azPreference string, az string) EndpointIterator {
+// Endpoints returns an iterator over the endpoints of the pool using the pool's
+// load balancing algorithm. hashKey is the value extracted from the request by
+// RequestHashKey and is only used by the consistent-hash algorithm. If the
+// traffic of the route is split between apps, the first attempt prefers the
+// endpoints of an app picked according to the app weights. If rule is not nil,
+// all attempts prefer the endpoints with the tag selected by the rule.
+// azPreference selects how the endpoints in the availability zone az of the
+// router are preferred.
+func (p *EndpointPool) Endpoints(logger *slog.Logger, initial string, mustBeSticky bool, azPreference string, az string, hashKey string, rule *config.RoutingRule) EndpointIterator {
+	iter := p.newEndpointIterator(logger, initial, mustBeSticky, azPreference, az, hashKey)
+	if fi, ok := iter.(filteredIterator); ok {
+		f := fi.filter()
+		f.appGroup = p.pickAppGroup()
+		f.rule = rule
+		f.azPreference = azPreference
+	}
+	return iter
+}
+
+func (p *EndpointPool) newEndpointIterator(logger *slog.Logger, initial string, mustBeSticky bool, azPreference string, az string, hashKey string) EndpointIterator {
 	switch p.LoadBalancingAlgorithm {
 	case config.LOAD_BALANCE_LC:
 		logger.Debug("endpoint-iterator-with-least-connection-lb-algo")
@@ -402,6 +674,18 @@ func (p *EndpointPool) Endpoints(logger *slog.Logger, initial string, mustBeStic
 	case config.LOAD_BALANCE_RR:
 		logger.Debug("endpoint-iterator-with-round-robin-lb-algo")
 		return NewRoundRobin(logger, p, initial, mustBeSticky, azPreference == config.AZ_PREF_LOCAL, az)
+	case config.LOAD_BALANCE_EWMA:
+		logger.Debug("endpoint-iterator-with-latency-ewma-lb-algo")
+		return NewLatencyEWMA(logger, p, initial, mustBeSticky, azPreference == config.AZ_PREF_LOCAL, az)
+	case config.LOAD_BALANCE_WRR:
+		logger.Debug("endpoint-iterator-with-weighted-round-robin-lb-algo")
+		return NewWeightedRoundRobin(logger, p, initial, mustBeSticky, azPreference == config.AZ_PREF_LOCAL, az)
+	case config.LOAD_BALANCE_P2C:
+		logger.Debug("endpoint-iterator-with-p2c-lb-algo")
+		return NewPowerOfTwoChoices(logger, p, initial, mustBeSticky, azPreference == config.AZ_PREF_LOCAL, az)
+	case config.LOAD_BALANCE_CH:
+		logger.Debug("endpoint-iterator-with-consistent-hash-lb-algo")
+		return NewConsistentHash(logger, p, initial, mustBeSticky, azPreference == config.AZ_PREF_LOCAL, az, hashKey)
 	default:
 		logger.Error("invalid-pool-load-balancing-algorithm",
 			slog.String("poolLBAlgorithm", p.LoadBalancingAlgorithm),
@@ -423,6 +707,15 @@ func (p *EndpointPool) findById(id string) *endpointElem {
 	return p.index[id]
 }
 
+// Contains returns true if the pool has an endpoint at the address of the
+// endpoint which is equal to it.
+func (p *EndpointPool) Contains(endpoint *Endpoint) bool {
+	p.Lock()
+	defer p.Unlock()
+	e, found := p.index[endpoint.CanonicalAddr()]
+	return found && e.endpoint.Equal(endpoint)
+}
+
 func (p *EndpointPool) IsEmpty() bool {
 	p.Lock()
 	l := len(p.endpoints)
@@ -446,6 +739,8 @@ func (p *EndpointPool) NextIndex() int {
 	return next
 }
 
+// IsOverloaded reports whether all endpoints of the pool which are not skipped
+// as unhealthy are at their maximum number of connections.
 func (p *EndpointPool) IsOverloaded() bool {
 	if p.IsEmpty() {
 		return false
@@ -453,15 +748,9 @@ func (p *EndpointPool) IsOverloaded() bool {
 
 	p.Lock()
 	defer p.Unlock()
-	if p.maxConnsPerBackend == 0 {
-		return false
-	}
-
-	if p.maxConnsPerBackend > 0 {
-		for _, e := range p.endpoints {
-			if e.endpoint.Stats.NumberConnections.Count() < p.maxConnsPerBackend {
-				return false
-			}
+	for _, e := range p.endpoints {
+		if !e.isOverloaded() && !p.isUnhealthy(e) {
+			return false
 		}
 	}
 
@@ -494,6 +783,7 @@ func (p *EndpointPool) EndpointFailed(endpoint *Endpoint, err error) {
 	if fails.FailableClassifiers.Classify(err) {
 		logger.Error("endpoint-marked-as-ineligible")
 		e.failed()
+		p.updateZone(e)
 		return
 	}
 
@@ -509,15 +799,21 @@ func (p *EndpointPool) Each(f func(endpoint *Endpoint)) {
 
 func (p *EndpointPool) MarshalJSON() ([]byte, error) {
 	p.Lock()
-	endpoints := make([]*Endpoint, 0, len(p.endpoints))
-	for _, e := range p.endpoints {
-		endpoints = append(endpoints, e.endpoint)
-	}
+	endpoints := p.endpointsForJSON(nil)
 	p.Unlock()
 
 	return json.Marshal(endpoints)
 }
 
+// FilteredEndpoints returns the endpoints for which keep returns true, which
+// marshal to JSON like the endpoints of the pool.
+func (p *EndpointPool) FilteredEndpoints(keep func(*Endpoint) bool) []json.Marshaler {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.endpointsForJSON(keep)
+}
+
 // setPoolLoadBalancingAlgorithm overwrites the load balancing algorithm of a pool by that of a specified endpoint, if that is valid.
 func (p *EndpointPool) setPoolLoadBalancingAlgorithm(endpoint *Endpoint) {
 	if len(endpoint.LoadBalancingAlgorithm) > 0 && endpoint.LoadBalancingAlgorithm != p.LoadBalancingAlgorithm {
@@ -534,32 +830,81 @@ func (p *EndpointPool) setPoolLoadBalancingAlgorithm(endpoint *Endpoint) {
 	}
 }
 
+// setPoolHashKey overwrites the consistent-hash key of a pool by that of a specified endpoint, if that is valid.
+func (p *EndpointPool) setPoolHashKey(endpoint *Endpoint) {
+	if len(endpoint.HashKey) > 0 && endpoint.HashKey != p.HashKey {
+		if config.IsHashKeyValid(endpoint.HashKey) {
+			p.HashKey = endpoint.HashKey
+			p.logger.Debug("setting-pool-hash-key-to-that-of-an-endpoint",
+				slog.String("endpointHashKey", endpoint.HashKey),
+				slog.String("poolHashKey", p.HashKey))
+		} else {
+			p.logger.Error("invalid-endpoint-hash-key-provided-keeping-pool-hash-key",
+				slog.String("endpointHashKey", endpoint.HashKey),
+				slog.String("poolHashKey", p.HashKey))
+		}
+	}
+}
+
 func (e *endpointElem) failed() {
 	t := time.Now()
 	e.failedAt = &t
 }
 
+// isOverloaded reports whether the requests in flight to the endpoint have
+// reached the lower of the maximum number of connections per backend and its
+// adaptive concurrency limit.
 func (e *endpointElem) isOverloaded() bool {
-	if e.maxConnsPerBackend == 0 {
-		return false
+	return e.spareConns() == 0
+}
+
+// spareConns returns the number of requests e can take before it reaches its
+// maximum number of connections, or -1 if its connections are not limited.
+func (e *endpointElem) spareConns() int64 {
+	limit := e.maxConnsPerBackend
+	if adaptive := e.limiter.Limit(); adaptive > 0 && (limit == 0 || adaptive < limit) {
+		limit = adaptive
+	}
+	if limit == 0 {
+		return -1
 	}
 
-	return e.endpoint.Stats.NumberConnections.Count() >= e.maxConnsPerBackend
+	return max(0, limit-e.endpoint.Stats.NumberConnections.Count())
 }
 
 func (e *Endpoint) MarshalJSON() ([]byte, error) {
+	return e.marshalJSON(nil, 0)
+}
+
+func (e *Endpoint) marshalJSON(slowStartProgress *float64, concurrencyLimit int64) ([]byte, error) {
 	var jsonObj struct {
-		Address                string            `json:"address"`
-		AvailabilityZone       string            `json:"availability_zone"`
-		Protocol               string            `json:"protocol"`
-		TLS                    bool              `json:"tls"`
-		TTL                    int               `json:"ttl"`
-		RouteServiceUrl        string            `json:"route_service_url,omitempty"`
-		Tags                   map[string]string `json:"tags"`
-		IsolationSegment       string            `json:"isolation_segment,omitempty"`
-		PrivateInstanceId      string            `json:"private_instance_id,omitempty"`
-		ServerCertDomainSAN    string            `json:"server_cert_domain_san,omitempty"`
-		LoadBalancingAlgorithm string            `json:"load_balancing_algorithm,omitempty"`
+		Address                string               `json:"address"`
+		AvailabilityZone       string               `json:"availability_zone"`
+		Protocol               string               `json:"protocol"`
+		TLS                    bool                 `json:"tls"`
+		TTL                    int                  `json:"ttl"`
+		RouteServiceUrl        string               `json:"route_service_url,omitempty"`
+		Tags                   map[string]string    `json:"tags"`
+		IsolationSegment       string               `json:"isolation_segment,omitempty"`
+		PrivateInstanceId      string               `json:"private_instance_id,omitempty"`
+		ServerCertDomainSAN    string               `json:"server_cert_domain_san,omitempty"`
+		LoadBalancingAlgorithm string               `json:"load_balancing_algorithm,omitempty"`
+		Weight                 int                  `json:"weight,omitempty"`
+		HashKey                string               `json:"hash_key,omitempty"`
+		HealthCheckPath        string               `json:"health_check_path,omitempty"`
+		RequestTimeout         string               `json:"request_timeout,omitempty"`
+		DialTimeout            string               `json:"dial_timeout,omitempty"`
+		MaxAttempts            int                  `json:"max_attempts,omitempty"`
+		TrafficWeight          int                  `json:"traffic_weight,omitempty"`
+		RoutingRules           []config.RoutingRule `json:"routing_rules,omitempty"`
+		ShadowRoute            string               `json:"shadow_route,omitempty"`
+		ShadowPercentage       float64              `json:"shadow_percentage,omitempty"`
+		RetryStatusCodes       []int                `json:"retry_status_codes,omitempty"`
+		HedgeDelay             string               `json:"hedge_delay,omitempty"`
+		Static                 bool                 `json:"static,omitempty"`
+		Source                 string               `json:"source,omitempty"`
+		SlowStartProgress      *float64             `json:"slow_start_progress,omitempty"`
+		ConcurrencyLimit       int64                `json:"concurrency_limit,omitempty"`
 	}
 
 	jsonObj.Address = e.addr
@@ -573,6 +918,28 @@ func (e *Endpoint) MarshalJSON() ([]byte, error) {
 	jsonObj.PrivateInstanceId = e.PrivateInstanceId
 	jsonObj.ServerCertDomainSAN = e.ServerCertDomainSAN
 	jsonObj.LoadBalancingAlgorithm = e.LoadBalancingAlgorithm
+	jsonObj.Weight = e.Weight
+	jsonObj.HashKey = e.HashKey
+	jsonObj.HealthCheckPath = e.HealthCheckPath
+	if e.RequestTimeout > 0 {
+		jsonObj.RequestTimeout = e.RequestTimeout.String()
+	}
+	if e.DialTimeout > 0 {
+		jsonObj.DialTimeout = e.DialTimeout.String()
+	}
+	jsonObj.MaxAttempts = e.MaxAttempts
+	jsonObj.TrafficWeight = e.TrafficWeight
+	jsonObj.RoutingRules = e.RoutingRules
+	jsonObj.ShadowRoute = e.ShadowRoute
+	jsonObj.ShadowPercentage = e.ShadowPercentage
+	jsonObj.RetryStatusCodes = e.RetryStatusCodes
+	if e.HedgeDelay > 0 {
+		jsonObj.HedgeDelay = e.HedgeDelay.String()
+	}
+	jsonObj.Static = e.Static
+	jsonObj.Source = e.Source
+	jsonObj.SlowStartProgress = slowStartProgress
+	jsonObj.ConcurrencyLimit = concurrencyLimit
 	return json.Marshal(jsonObj)
 }
 
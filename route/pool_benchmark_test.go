@@ -1,10 +1,14 @@
 package route_test
 
 import (
+	"fmt"
+	"io"
+	"log/slog"
 	"sync"
 	"testing"
 	"time"
 
+	"code.cloudfoundry.org/gorouter/config"
 	"code.cloudfoundry.org/gorouter/route"
 )
 
@@ -61,3 +65,85 @@ func BenchmarkEndpointNotEquals(b *testing.B) {
 	}
 	b.ReportAllocs()
 }
+
+// The following benchmarks select endpoints from large pools the way the proxy
+// does: a new iterator is created for every request through the pool.
+// The parallel variants show how much the algorithms contend on the pool lock.
+
+func setupBenchmarkPool(total int, lbAlgo string) *route.EndpointPool {
+	pool := route.NewPool(&route.PoolOpts{
+		Logger:                 slog.New(slog.NewTextHandler(io.Discard, nil)),
+		RetryAfterFailure:      2 * time.Minute,
+		LoadBalancingAlgorithm: lbAlgo,
+	})
+	for i := 0; i < total; i++ {
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: fmt.Sprintf("10.0.%d.%d", i/256, i%256), Port: 8080}))
+	}
+	return pool
+}
+
+func benchmarkPoolSelection(b *testing.B, total int, lbAlgo string) {
+	pool := setupBenchmarkPool(total, lbAlgo)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		iter := pool.Endpoints(logger, "", false, config.AZ_PREF_NONE, "", "", nil)
+		e := iter.Next(0)
+		iter.PreRequest(e)
+		iter.PostRequest(e)
+	}
+}
+
+func benchmarkPoolSelectionParallel(b *testing.B, total int, lbAlgo string) {
+	pool := setupBenchmarkPool(total, lbAlgo)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			iter := pool.Endpoints(logger, "", false, config.AZ_PREF_NONE, "", "", nil)
+			e := iter.Next(0)
+			iter.PreRequest(e)
+			iter.PostRequest(e)
+		}
+	})
+}
+
+func BenchmarkPoolRoundRobin100Endpoints(b *testing.B) {
+	benchmarkPoolSelection(b, 100, config.LOAD_BALANCE_RR)
+}
+
+func BenchmarkPoolRoundRobin1000Endpoints(b *testing.B) {
+	benchmarkPoolSelection(b, 1000, config.LOAD_BALANCE_RR)
+}
+
+func BenchmarkPoolLeastConn100Endpoints(b *testing.B) {
+	benchmarkPoolSelection(b, 100, config.LOAD_BALANCE_LC)
+}
+
+func BenchmarkPoolLeastConn1000Endpoints(b *testing.B) {
+	benchmarkPoolSelection(b, 1000, config.LOAD_BALANCE_LC)
+}
+
+func BenchmarkPoolP2C100Endpoints(b *testing.B) {
+	benchmarkPoolSelection(b, 100, config.LOAD_BALANCE_P2C)
+}
+
+func BenchmarkPoolP2C1000Endpoints(b *testing.B) {
+	benchmarkPoolSelection(b, 1000, config.LOAD_BALANCE_P2C)
+}
+
+func BenchmarkPoolRoundRobin1000EndpointsParallel(b *testing.B) {
+	benchmarkPoolSelectionParallel(b, 1000, config.LOAD_BALANCE_RR)
+}
+
+func BenchmarkPoolLeastConn1000EndpointsParallel(b *testing.B) {
+	benchmarkPoolSelectionParallel(b, 1000, config.LOAD_BALANCE_LC)
+}
+
+func BenchmarkPoolP2C1000EndpointsParallel(b *testing.B) {
+	benchmarkPoolSelectionParallel(b, 1000, config.LOAD_BALANCE_P2C)
+}
@@ -182,7 +182,7 @@ var _ = Describe("EndpointPool", func() {
 				endpoint := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, ModificationTag: modTag2})
 
 				Expect(pool.Put(endpoint)).To(Equal(route.UPDATED))
-				Expect(pool.Endpoints(logger.Logger, "", false, azPreference, az).Next(0).ModificationTag).To(Equal(modTag2))
+				Expect(pool.Endpoints(logger.Logger, "", false, azPreference, az, "", nil).Next(0).ModificationTag).To(Equal(modTag2))
 			})
 
 			Context("when modification_tag is older", func() {
@@ -197,7 +197,7 @@ var _ = Describe("EndpointPool", func() {
 					endpoint := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, ModificationTag: olderModTag})
 
 					Expect(pool.Put(endpoint)).To(Equal(route.UNMODIFIED))
-					Expect(pool.Endpoints(logger.Logger, "", false, azPreference, az).Next(0).ModificationTag).To(Equal(modTag2))
+					Expect(pool.Endpoints(logger.Logger, "", false, azPreference, az, "", nil).Next(0).ModificationTag).To(Equal(modTag2))
 				})
 			})
 		})
@@ -246,7 +246,7 @@ var _ = Describe("EndpointPool", func() {
 				Logger:                 logger.Logger,
 				LoadBalancingAlgorithm: "wrong-lb-algo",
 			})
-			iterator := poolWithLBAlgo2.Endpoints(logger.Logger, "", false, "none", "zone")
+			iterator := poolWithLBAlgo2.Endpoints(logger.Logger, "", false, "none", "zone", "", nil)
 			Expect(iterator).To(BeAssignableToTypeOf(&route.RoundRobin{}))
 			Eventually(logger).Should(gbytes.Say(`invalid-pool-load-balancing-algorithm`))
 		})
@@ -256,7 +256,7 @@ var _ = Describe("EndpointPool", func() {
 				Logger:                 logger.Logger,
 				LoadBalancingAlgorithm: config.LOAD_BALANCE_LC,
 			})
-			iterator := poolWithLBAlgoLC.Endpoints(logger.Logger, "", false, "none", "az")
+			iterator := poolWithLBAlgoLC.Endpoints(logger.Logger, "", false, "none", "az", "", nil)
 			Expect(iterator).To(BeAssignableToTypeOf(&route.LeastConnection{}))
 			Eventually(logger).Should(gbytes.Say(`endpoint-iterator-with-least-connection-lb-algo`))
 		})
@@ -266,10 +266,50 @@ var _ = Describe("EndpointPool", func() {
 				Logger:                 logger.Logger,
 				LoadBalancingAlgorithm: config.LOAD_BALANCE_RR,
 			})
-			iterator := poolWithLBAlgoLC.Endpoints(logger.Logger, "", false, "none", "az")
+			iterator := poolWithLBAlgoLC.Endpoints(logger.Logger, "", false, "none", "az", "", nil)
 			Expect(iterator).To(BeAssignableToTypeOf(&route.RoundRobin{}))
 			Eventually(logger).Should(gbytes.Say(`endpoint-iterator-with-round-robin-lb-algo`))
 		})
+
+		It("is correctly propagated to the newly created endpoints LOAD_BALANCE_EWMA ", func() {
+			poolWithLBAlgoEWMA := route.NewPool(&route.PoolOpts{
+				Logger:                 logger.Logger,
+				LoadBalancingAlgorithm: config.LOAD_BALANCE_EWMA,
+			})
+			iterator := poolWithLBAlgoEWMA.Endpoints(logger.Logger, "", false, "none", "az", "", nil)
+			Expect(iterator).To(BeAssignableToTypeOf(&route.LatencyEWMA{}))
+			Eventually(logger).Should(gbytes.Say(`endpoint-iterator-with-latency-ewma-lb-algo`))
+		})
+
+		It("is correctly propagated to the newly created endpoints LOAD_BALANCE_WRR ", func() {
+			poolWithLBAlgoWRR := route.NewPool(&route.PoolOpts{
+				Logger:                 logger.Logger,
+				LoadBalancingAlgorithm: config.LOAD_BALANCE_WRR,
+			})
+			iterator := poolWithLBAlgoWRR.Endpoints(logger.Logger, "", false, "none", "az", "", nil)
+			Expect(iterator).To(BeAssignableToTypeOf(&route.WeightedRoundRobin{}))
+			Eventually(logger).Should(gbytes.Say(`endpoint-iterator-with-weighted-round-robin-lb-algo`))
+		})
+
+		It("is correctly propagated to the newly created endpoints LOAD_BALANCE_CH ", func() {
+			poolWithLBAlgoCH := route.NewPool(&route.PoolOpts{
+				Logger:                 logger.Logger,
+				LoadBalancingAlgorithm: config.LOAD_BALANCE_CH,
+			})
+			iterator := poolWithLBAlgoCH.Endpoints(logger.Logger, "", false, "none", "az", "some-key", nil)
+			Expect(iterator).To(BeAssignableToTypeOf(&route.ConsistentHash{}))
+			Eventually(logger).Should(gbytes.Say(`endpoint-iterator-with-consistent-hash-lb-algo`))
+		})
+
+		It("is correctly propagated to the newly created endpoints LOAD_BALANCE_P2C ", func() {
+			poolWithLBAlgoP2C := route.NewPool(&route.PoolOpts{
+				Logger:                 logger.Logger,
+				LoadBalancingAlgorithm: config.LOAD_BALANCE_P2C,
+			})
+			iterator := poolWithLBAlgoP2C.Endpoints(logger.Logger, "", false, "none", "az", "", nil)
+			Expect(iterator).To(BeAssignableToTypeOf(&route.PowerOfTwoChoices{}))
+			Eventually(logger).Should(gbytes.Say(`endpoint-iterator-with-p2c-lb-algo`))
+		})
 	})
 
 	Context("Load balancing algorithm of a newly added endpoint", func() {
@@ -362,6 +402,87 @@ var _ = Describe("EndpointPool", func() {
 		})
 	})
 
+	Context("Hash key of a newly added endpoint", func() {
+		It("is valid and will overwrite the hash key of a pool", func() {
+			pool := route.NewPool(&route.PoolOpts{
+				Logger:                 logger.Logger,
+				LoadBalancingAlgorithm: config.LOAD_BALANCE_CH,
+				HashKey:                config.HASH_KEY_CLIENT_IP,
+			})
+			endpoint := route.NewEndpoint(&route.EndpointOpts{
+				Host: "host-1", Port: 1234,
+				HashKey: "header:X-Tenant-Id",
+			})
+			pool.Put(endpoint)
+			Expect(pool.HashKey).To(Equal("header:X-Tenant-Id"))
+			Eventually(logger).Should(gbytes.Say(`setting-pool-hash-key-to-that-of-an-endpoint`))
+		})
+
+		It("is an invalid value and the hash key of a pool is kept", func() {
+			pool := route.NewPool(&route.PoolOpts{
+				Logger:                 logger.Logger,
+				LoadBalancingAlgorithm: config.LOAD_BALANCE_CH,
+				HashKey:                config.HASH_KEY_CLIENT_IP,
+			})
+			endpoint := route.NewEndpoint(&route.EndpointOpts{
+				Host: "host-1", Port: 1234,
+				HashKey: "cookie:session",
+			})
+			pool.Put(endpoint)
+			Expect(pool.HashKey).To(Equal(config.HASH_KEY_CLIENT_IP))
+			Eventually(logger).Should(gbytes.Say(`invalid-endpoint-hash-key-provided-keeping-pool-hash-key`))
+		})
+	})
+
+	Context("RequestHashKey", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			var err error
+			req, err = http.NewRequest("GET", "http://example.com/some/path?tenant=meow", nil)
+			Expect(err).NotTo(HaveOccurred())
+			req.RemoteAddr = "10.0.0.1:51234"
+			req.Header.Set("X-Tenant-Id", "purr")
+		})
+
+		poolWithHashKey := func(lbAlgo, hashKey string) *route.EndpointPool {
+			return route.NewPool(&route.PoolOpts{
+				Logger:                 logger.Logger,
+				LoadBalancingAlgorithm: lbAlgo,
+				HashKey:                hashKey,
+			})
+		}
+
+		It("returns an empty key when the pool does not use consistent hashing", func() {
+			Expect(poolWithHashKey(config.LOAD_BALANCE_RR, config.HASH_KEY_PATH).RequestHashKey(req)).To(BeEmpty())
+		})
+
+		It("returns the peer address for client_ip", func() {
+			Expect(poolWithHashKey(config.LOAD_BALANCE_CH, config.HASH_KEY_CLIENT_IP).RequestHashKey(req)).To(Equal("10.0.0.1"))
+		})
+
+		It("returns the left-most X-Forwarded-For address for client_ip", func() {
+			req.Header.Set("X-Forwarded-For", "192.168.0.7, 10.0.0.1")
+			Expect(poolWithHashKey(config.LOAD_BALANCE_CH, config.HASH_KEY_CLIENT_IP).RequestHashKey(req)).To(Equal("192.168.0.7"))
+		})
+
+		It("returns the path for path", func() {
+			Expect(poolWithHashKey(config.LOAD_BALANCE_CH, config.HASH_KEY_PATH).RequestHashKey(req)).To(Equal("/some/path"))
+		})
+
+		It("returns the header value for header:<name>", func() {
+			Expect(poolWithHashKey(config.LOAD_BALANCE_CH, "header:X-Tenant-Id").RequestHashKey(req)).To(Equal("purr"))
+		})
+
+		It("returns the query parameter for query:<name>", func() {
+			Expect(poolWithHashKey(config.LOAD_BALANCE_CH, "query:tenant").RequestHashKey(req)).To(Equal("meow"))
+		})
+
+		It("returns an empty key when the request does not contain the key", func() {
+			Expect(poolWithHashKey(config.LOAD_BALANCE_CH, "header:X-Missing").RequestHashKey(req)).To(BeEmpty())
+		})
+	})
+
 	Context("RouteServiceUrl", func() {
 		It("returns the route_service_url associated with the pool", func() {
 			endpoint := &route.Endpoint{}
@@ -432,7 +553,7 @@ var _ = Describe("EndpointPool", func() {
 					azPreference := "none"
 					connectionResetError := &net.OpError{Op: "read", Err: errors.New("read: connection reset by peer")}
 					pool.EndpointFailed(failedEndpoint, connectionResetError)
-					i := pool.Endpoints(logger.Logger, "", false, azPreference, az)
+					i := pool.Endpoints(logger.Logger, "", false, azPreference, az, "", nil)
 					epOne := i.Next(0)
 					epTwo := i.Next(1)
 					Expect(epOne).To(Equal(epTwo))
@@ -653,6 +774,21 @@ var _ = Describe("EndpointPool", func() {
 		})
 	})
 
+	Context("Contains", func() {
+		It("contains an equal endpoint", func() {
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, AppId: "app-1"}))
+
+			Expect(pool.Contains(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, AppId: "app-1"}))).To(BeTrue())
+		})
+
+		It("does not contain a changed endpoint at the same address", func() {
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, AppId: "app-1"}))
+
+			Expect(pool.Contains(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, AppId: "app-2"}))).To(BeFalse())
+			Expect(pool.Contains(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 1234, AppId: "app-1"}))).To(BeFalse())
+		})
+	})
+
 	Context("PruneEndpoints", func() {
 		defaultThreshold := 1 * time.Minute
 
@@ -670,6 +806,18 @@ var _ = Describe("EndpointPool", func() {
 			})
 		})
 
+		Context("when the pool contains static endpoints", func() {
+			It("does not prune the static endpoints", func() {
+				e1 := route.NewEndpoint(&route.EndpointOpts{Static: true, StaleThresholdInSeconds: 20})
+				pool.Put(e1)
+				pool.MarkUpdated(time.Now().Add(-25 * time.Second))
+
+				prunedEndpoints := pool.PruneEndpoints()
+				Expect(pool.IsEmpty()).To(Equal(false))
+				Expect(prunedEndpoints).To(BeEmpty())
+			})
+		})
+
 		Context("when an endpoint has passed the stale threshold", func() {
 			It("prunes the endpoint", func() {
 				e1 := route.NewEndpoint(&route.EndpointOpts{UseTLS: false, StaleThresholdInSeconds: 20})
@@ -884,6 +1032,54 @@ var _ = Describe("EndpointPool", func() {
 		})
 	})
 
+	Context("when endpoints have a weight", func() {
+		It("marshals json with the weight", func() {
+			e := route.NewEndpoint(&route.EndpointOpts{
+				Host:                    "1.2.3.4",
+				Port:                    5678,
+				Protocol:                "http1",
+				StaleThresholdInSeconds: -1,
+				Weight:                  3,
+			})
+			pool.Put(e)
+			json, err := pool.MarshalJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(json)).To(Equal(`[{"address":"1.2.3.4:5678","availability_zone":"","protocol":"http1","tls":false,"ttl":-1,"tags":null,"weight":3}]`))
+		})
+	})
+
+	Context("when endpoints have a source", func() {
+		It("marshals json with the source", func() {
+			e := route.NewEndpoint(&route.EndpointOpts{
+				Host:                    "1.2.3.4",
+				Port:                    5678,
+				Protocol:                "http1",
+				StaleThresholdInSeconds: -1,
+				Source:                  "nats",
+			})
+			pool.Put(e)
+			json, err := pool.MarshalJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(json)).To(Equal(`[{"address":"1.2.3.4:5678","availability_zone":"","protocol":"http1","tls":false,"ttl":-1,"tags":null,"source":"nats"}]`))
+		})
+	})
+
+	Context("when endpoints have a hash key", func() {
+		It("marshals json with the hash key", func() {
+			e := route.NewEndpoint(&route.EndpointOpts{
+				Host:                    "1.2.3.4",
+				Port:                    5678,
+				Protocol:                "http1",
+				StaleThresholdInSeconds: -1,
+				HashKey:                 "path",
+			})
+			pool.Put(e)
+			json, err := pool.MarshalJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(json)).To(Equal(`[{"address":"1.2.3.4:5678","availability_zone":"","protocol":"http1","tls":false,"ttl":-1,"tags":null,"hash_key":"path"}]`))
+		})
+	})
+
 	Context("when endpoints have empty tags", func() {
 		var e *route.Endpoint
 		BeforeEach(func() {
@@ -0,0 +1,219 @@
+package route
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"time"
+)
+
+// p2cMaxSamples is the number of random draws used to find two eligible
+// endpoints before falling back to scanning the pool.
+const p2cMaxSamples = 8
+
+// PowerOfTwoChoices samples two random eligible endpoints and selects the one
+// with fewer active connections. In contrast to round-robin it does not share
+// an index across requests, and in contrast to least-connection it does not
+// scan the whole pool, so the pool lock is only held for a constant time. It
+// uses the global random source, which does not need to be seeded for every
+// request and is safe for concurrent use without locking.
+type PowerOfTwoChoices struct {
+	logger                *slog.Logger
+	pool                  *EndpointPool
+	initialEndpoint       string
+	mustBeSticky          bool
+	lastEndpoint          *Endpoint
+	locallyOptimistic     bool
+	localAvailabilityZone string
+	tried                 map[*endpointElem]struct{}
+	endpointFilter
+}
+
+func NewPowerOfTwoChoices(logger *slog.Logger, p *EndpointPool, initial string, mustBeSticky bool, locallyOptimistic bool, localAvailabilityZone string) EndpointIterator {
+	return &PowerOfTwoChoices{
+		logger:                logger,
+		pool:                  p,
+		initialEndpoint:       initial,
+		mustBeSticky:          mustBeSticky,
+		locallyOptimistic:     locallyOptimistic,
+		localAvailabilityZone: localAvailabilityZone,
+	}
+}
+
+func (r *PowerOfTwoChoices) Next(attempt int) *Endpoint {
+	var e *endpointElem
+	if r.initialEndpoint != "" {
+		e = r.pool.findById(r.initialEndpoint)
+		if e != nil && e.isOverloaded() {
+			if r.mustBeSticky {
+				if r.logger.Enabled(context.Background(), slog.LevelDebug) {
+					r.logger.Debug("endpoint-overloaded-but-request-must-be-sticky", e.endpoint.ToLogData()...)
+				}
+				return nil
+			}
+			e = nil
+		}
+
+		if e == nil && r.mustBeSticky {
+			r.logger.Debug("endpoint-missing-but-request-must-be-sticky", slog.String("requested-endpoint", r.initialEndpoint))
+			return nil
+		}
+
+		if !r.mustBeSticky {
+			r.logger.Debug("endpoint-missing-choosing-alternate", slog.String("requested-endpoint", r.initialEndpoint))
+			r.initialEndpoint = ""
+		}
+	}
+
+	if e != nil {
+		e.RLock()
+		defer e.RUnlock()
+		r.lastEndpoint = e.endpoint
+		return e.endpoint
+	}
+
+	e = r.next(attempt)
+	if e != nil {
+		e.RLock()
+		defer e.RUnlock()
+		r.lastEndpoint = e.endpoint
+		return e.endpoint
+	}
+
+	r.lastEndpoint = nil
+	return nil
+}
+
+func (r *PowerOfTwoChoices) PreRequest(e *Endpoint) {
+	e.Stats.NumberConnections.Increment()
+}
+
+func (r *PowerOfTwoChoices) PostRequest(e *Endpoint) {
+	e.Stats.NumberConnections.Decrement()
+	r.pool.connectionReleased()
+}
+
+func (r *PowerOfTwoChoices) next(attempt int) *endpointElem {
+	r.pool.Lock()
+	defer r.pool.Unlock()
+
+	if len(r.pool.endpoints) == 0 {
+		return nil
+	}
+
+	zone, strict := r.preferredZone(r.pool, r.localAvailabilityZone, r.locallyOptimistic, attempt)
+	if subset := r.subset(attempt); subset != nil {
+		if e := r.choose(zone, subset); e != nil {
+			return e
+		}
+		// could not find a valid endpoint in the preferred subset, consider all endpoints
+	}
+
+	if zone != "" && !strict {
+		if e := r.choose(zone, nil); e != nil {
+			return e
+		}
+		// could not find a valid endpoint in the same AZ, consider all AZs
+		zone = ""
+	}
+
+	if e := r.choose(zone, nil); e != nil {
+		return e
+	}
+
+	if r.allEndpointsAreOverloaded() {
+		return nil
+	}
+
+	// all endpoints were either tried or marked failed, so reset everything to available
+	r.pool.resetFailures()
+	clear(r.tried)
+
+	return r.choose(zone, nil)
+}
+
+// choose samples two eligible endpoints and returns the one with fewer active
+// connections. The pool lock must be held when calling this function.
+func (r *PowerOfTwoChoices) choose(zone string, subset *endpointSubset) *endpointElem {
+	total := len(r.pool.endpoints)
+	now := time.Now()
+
+	var first, second *endpointElem
+	for i := 0; i < p2cMaxSamples && second == nil; i++ {
+		cur := r.pool.endpoints[rand.Intn(total)]
+		if cur == first || !r.isEligible(cur, zone, subset, now) {
+			continue
+		}
+		if first == nil {
+			first = cur
+		} else {
+			second = cur
+		}
+	}
+
+	if second == nil {
+		// random draws did not hit two eligible endpoints, e.g. because the pool
+		// is small or most of it is overloaded or has failed, so scan the pool instead
+		offset := rand.Intn(total)
+		for i := 0; i < total && second == nil; i++ {
+			cur := r.pool.endpoints[(offset+i)%total]
+			if cur == first || !r.isEligible(cur, zone, subset, now) {
+				continue
+			}
+			if first == nil {
+				first = cur
+			} else {
+				second = cur
+			}
+		}
+	}
+
+	if first == nil {
+		return nil
+	}
+
+	selected := first
+	if second != nil && second.endpoint.Stats.NumberConnections.Count() < first.endpoint.Stats.NumberConnections.Count() {
+		selected = second
+	}
+
+	if r.tried == nil {
+		r.tried = make(map[*endpointElem]struct{}, 1)
+	}
+	r.tried[selected] = struct{}{}
+	return selected
+}
+
+// isEligible reports whether e may be selected. Expired failures and ejections
+// are cleared. The pool lock must be held when calling this function.
+func (r *PowerOfTwoChoices) isEligible(e *endpointElem, zone string, subset *endpointSubset, now time.Time) bool {
+	r.pool.clearExpiredFailure(e, now)
+	if e.isFailed() || e.isOverloaded() || r.pool.isUnhealthy(e) {
+		return false
+	}
+	if _, found := r.tried[e]; found {
+		return false
+	}
+	if zone != "" && e.endpoint.AvailabilityZone != zone {
+		return false
+	}
+	if !subset.contains(e) {
+		return false
+	}
+	return true
+}
+
+func (r *PowerOfTwoChoices) allEndpointsAreOverloaded() bool {
+	for _, e := range r.pool.endpoints {
+		if !e.isOverloaded() {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *PowerOfTwoChoices) EndpointFailed(err error) {
+	if r.lastEndpoint != nil {
+		r.pool.EndpointFailed(r.lastEndpoint, err)
+	}
+}
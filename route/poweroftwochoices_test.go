@@ -0,0 +1,283 @@
+package route_test
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("PowerOfTwoChoices", func() {
+	var (
+		pool   *route.EndpointPool
+		logger *test_util.TestLogger
+	)
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+		pool = route.NewPool(&route.PoolOpts{
+			Logger:             logger.Logger,
+			RetryAfterFailure:  2 * time.Minute,
+			Host:               "",
+			ContextPath:        "",
+			MaxConnsPerBackend: 0,
+		})
+	})
+
+	Describe("Next", func() {
+		Context("when pool is empty", func() {
+			It("does not select an endpoint", func() {
+				iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).To(BeNil())
+			})
+		})
+
+		Context("when pool has one endpoint", func() {
+			It("selects the endpoint", func() {
+				e := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678})
+				pool.Put(e)
+
+				iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).To(Equal(e))
+			})
+		})
+
+		Context("when pool has two endpoints", func() {
+			var epOne, epTwo *route.Endpoint
+
+			BeforeEach(func() {
+				epOne = route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111})
+				epTwo = route.NewEndpoint(&route.EndpointOpts{Host: "2.2.2.2", Port: 2222})
+				pool.Put(epOne)
+				pool.Put(epTwo)
+			})
+
+			It("selects the endpoint with fewer connections", func() {
+				for i := 0; i < 3; i++ {
+					epOne.Stats.NumberConnections.Increment()
+				}
+
+				for i := 0; i < 10; i++ {
+					iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+					Expect(iter.Next(0)).To(Equal(epTwo))
+				}
+			})
+
+			It("does not select the endpoint that failed", func() {
+				iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+				failed := iter.Next(0)
+				iter.EndpointFailed(&net.OpError{Op: "dial"})
+
+				for i := 0; i < 10; i++ {
+					iter = route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+					Expect(iter.Next(0)).NotTo(Equal(failed))
+				}
+			})
+
+			It("selects failed endpoints again once the retry interval has passed", func() {
+				pool = route.NewPool(&route.PoolOpts{
+					Logger:            logger.Logger,
+					RetryAfterFailure: time.Millisecond,
+				})
+				pool.Put(epOne)
+				pool.Put(epTwo)
+
+				iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).NotTo(BeNil())
+				iter.EndpointFailed(&net.OpError{Op: "dial"})
+				Expect(iter.Next(1)).NotTo(BeNil())
+				iter.EndpointFailed(&net.OpError{Op: "dial"})
+
+				time.Sleep(5 * time.Millisecond)
+
+				selected := make(map[*route.Endpoint]struct{})
+				for i := 0; i < 50; i++ {
+					iter = route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+					selected[iter.Next(0)] = struct{}{}
+				}
+				Expect(selected).To(HaveLen(2))
+			})
+
+			It("resets the failed endpoints when all endpoints have failed", func() {
+				iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).NotTo(BeNil())
+				iter.EndpointFailed(&net.OpError{Op: "dial"})
+				Expect(iter.Next(1)).NotTo(BeNil())
+				iter.EndpointFailed(&net.OpError{Op: "dial"})
+
+				Expect(iter.Next(2)).NotTo(BeNil())
+			})
+		})
+
+		Context("when pool has many endpoints", func() {
+			var endpoints []*route.Endpoint
+
+			BeforeEach(func() {
+				endpoints = make([]*route.Endpoint, 0)
+				for i := 0; i < 10; i++ {
+					e := route.NewEndpoint(&route.EndpointOpts{Host: fmt.Sprintf("10.0.1.%d", i), Port: 60000})
+					endpoints = append(endpoints, e)
+					pool.Put(e)
+				}
+			})
+
+			It("spreads requests across all endpoints", func() {
+				selected := make(map[*route.Endpoint]struct{})
+				for i := 0; i < 200; i++ {
+					iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+					selected[iter.Next(0)] = struct{}{}
+				}
+				Expect(selected).To(HaveLen(len(endpoints)))
+			})
+
+			It("never selects the endpoint with the most connections", func() {
+				endpoints[4].Stats.NumberConnections.Increment()
+
+				for i := 0; i < 100; i++ {
+					iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+					Expect(iter.Next(0)).NotTo(Equal(endpoints[4]))
+				}
+			})
+
+			It("does not return the same endpoint twice until all endpoints were tried", func() {
+				iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+				seen := make(map[*route.Endpoint]struct{})
+				for i := 0; i < len(endpoints); i++ {
+					e := iter.Next(i)
+					Expect(e).NotTo(BeNil())
+					Expect(seen).NotTo(HaveKey(e))
+					seen[e] = struct{}{}
+				}
+			})
+
+			It("finds the only eligible endpoint when most endpoints have failed", func() {
+				iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+				for i := 0; i < len(endpoints)-1; i++ {
+					Expect(iter.Next(i)).NotTo(BeNil())
+					iter.EndpointFailed(&net.OpError{Op: "dial"})
+				}
+
+				remaining := iter.Next(len(endpoints) - 1)
+				Expect(remaining).NotTo(BeNil())
+
+				iter = route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).To(Equal(remaining))
+			})
+		})
+
+		Context("when some endpoints are overloaded", func() {
+			var epOne, epTwo *route.Endpoint
+
+			BeforeEach(func() {
+				pool = route.NewPool(&route.PoolOpts{
+					Logger:             logger.Logger,
+					RetryAfterFailure:  2 * time.Minute,
+					MaxConnsPerBackend: 1,
+				})
+				epOne = route.NewEndpoint(&route.EndpointOpts{Host: "5.5.5.5", Port: 5555, PrivateInstanceId: "private-label-1"})
+				pool.Put(epOne)
+				epTwo = route.NewEndpoint(&route.EndpointOpts{Host: "2.2.2.2", Port: 2222, PrivateInstanceId: "private-label-2"})
+				pool.Put(epTwo)
+			})
+
+			It("never selects an overloaded endpoint", func() {
+				epOne.Stats.NumberConnections.Increment()
+
+				for i := 0; i < 10; i++ {
+					iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+					Expect(iter.Next(0)).To(Equal(epTwo))
+				}
+			})
+
+			It("returns nil when all endpoints are overloaded", func() {
+				epOne.Stats.NumberConnections.Increment()
+				epTwo.Stats.NumberConnections.Increment()
+
+				iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).To(BeNil())
+			})
+
+			Context("when the initial endpoint is overloaded and must be sticky", func() {
+				It("returns nil", func() {
+					epOne.Stats.NumberConnections.Increment()
+
+					iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "private-label-1", true, false, "meow-az")
+					Expect(iter.Next(0)).To(BeNil())
+					Expect(logger).Should(gbytes.Say("endpoint-overloaded-but-request-must-be-sticky"))
+				})
+			})
+
+			Context("when the initial endpoint is overloaded and need not be sticky", func() {
+				It("returns another endpoint", func() {
+					epOne.Stats.NumberConnections.Increment()
+
+					iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "private-label-1", false, false, "meow-az")
+					Expect(iter.Next(0)).To(Equal(epTwo))
+				})
+			})
+		})
+
+		Describe("when in locally-optimistic mode", func() {
+			var localEndpoint *route.Endpoint
+
+			BeforeEach(func() {
+				localEndpoint = route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, AvailabilityZone: "meow-az"})
+				pool.Put(localEndpoint)
+				for i := 0; i < 5; i++ {
+					pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: fmt.Sprintf("2.2.2.%d", i), Port: 2222, AvailabilityZone: "other-az"}))
+				}
+			})
+
+			It("selects the local endpoint on the first attempt", func() {
+				for i := 0; i < 10; i++ {
+					iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, true, "meow-az")
+					Expect(iter.Next(0)).To(Equal(localEndpoint))
+				}
+			})
+
+			It("selects endpoints regardless of AZ on a retry", func() {
+				selected := make(map[*route.Endpoint]struct{})
+				for i := 0; i < 20; i++ {
+					iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, true, "meow-az")
+					selected[iter.Next(1)] = struct{}{}
+				}
+				Expect(len(selected)).To(BeNumerically(">", 1))
+			})
+
+			It("selects a non-local endpoint when the local endpoint is overloaded", func() {
+				pool = route.NewPool(&route.PoolOpts{
+					Logger:             logger.Logger,
+					RetryAfterFailure:  2 * time.Minute,
+					MaxConnsPerBackend: 1,
+				})
+				pool.Put(localEndpoint)
+				otherEndpoint := route.NewEndpoint(&route.EndpointOpts{Host: "3.3.3.3", Port: 3333, AvailabilityZone: "other-az"})
+				pool.Put(otherEndpoint)
+				localEndpoint.Stats.NumberConnections.Increment()
+
+				iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, true, "meow-az")
+				Expect(iter.Next(0)).To(Equal(otherEndpoint))
+			})
+		})
+	})
+
+	Context("PreRequest and PostRequest", func() {
+		It("tracks the number of connections", func() {
+			e := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678})
+			pool.Put(e)
+			iter := route.NewPowerOfTwoChoices(logger.Logger, pool, "", false, false, "meow-az")
+
+			iter.PreRequest(e)
+			Expect(e.Stats.NumberConnections.Count()).To(Equal(int64(1)))
+
+			iter.PostRequest(e)
+			Expect(e.Stats.NumberConnections.Count()).To(Equal(int64(0)))
+		})
+	})
+})
@@ -0,0 +1,169 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/config"
+)
+
+var (
+	// ErrQueueFull is returned by WaitForCapacity when the pool does not queue
+	// requests or the maximum number of requests already wait for it.
+	ErrQueueFull = errors.New("max conns queue is full")
+	// ErrQueueTimeout is returned by WaitForCapacity when no endpoint of the
+	// pool dropped below the maximum number of connections in time.
+	ErrQueueTimeout = errors.New("timed out waiting for a backend connection")
+)
+
+// requestQueue holds the requests which wait for an endpoint of a pool to drop
+// below the maximum number of connections. Every released connection
+// dispatches the request which waited longest, and an endpoint which can take
+// more requests for another reason dispatches as many as it can take.
+type requestQueue struct {
+	sync.Mutex
+	maxDepth int
+	timeout  time.Duration
+	waiters  []chan struct{}
+}
+
+func newRequestQueue(cfg *config.MaxConnsQueueConfig) *requestQueue {
+	return &requestQueue{
+		maxDepth: cfg.MaxDepth,
+		timeout:  cfg.Timeout,
+	}
+}
+
+// enqueue adds a waiter to the queue, at its front if the waiter was dispatched
+// before but lost the released connection to another request. It reports false
+// if the queue is full.
+func (q *requestQueue) enqueue(requeue bool) (chan struct{}, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	ready := make(chan struct{})
+	if requeue {
+		q.waiters = slices.Insert(q.waiters, 0, ready)
+		return ready, true
+	}
+	if len(q.waiters) >= q.maxDepth {
+		return nil, false
+	}
+	q.waiters = append(q.waiters, ready)
+	return ready, true
+}
+
+// remove takes a waiter which gave up off the queue. If it was dispatched in
+// the meantime, the next waiter is dispatched instead.
+func (q *requestQueue) remove(ready chan struct{}) {
+	q.Lock()
+	defer q.Unlock()
+
+	if i := slices.Index(q.waiters, ready); i >= 0 {
+		q.waiters = slices.Delete(q.waiters, i, i+1)
+		return
+	}
+	q.dispatch()
+}
+
+// release dispatches up to n waiters, or all of them if n is negative.
+func (q *requestQueue) release(n int64) {
+	q.Lock()
+	defer q.Unlock()
+	for ; n != 0 && len(q.waiters) > 0; n-- {
+		q.dispatch()
+	}
+}
+
+// dispatch wakes the waiter at the front of the queue. The lock must be held
+// when calling this function.
+func (q *requestQueue) dispatch() {
+	if len(q.waiters) == 0 {
+		return
+	}
+	close(q.waiters[0])
+	q.waiters = q.waiters[1:]
+}
+
+func (q *requestQueue) depth() int {
+	q.Lock()
+	defer q.Unlock()
+	return len(q.waiters)
+}
+
+// QueueTimeout returns the maximum time a request waits for an endpoint of the
+// pool, or zero if requests are not queued.
+func (p *EndpointPool) QueueTimeout() time.Duration {
+	if p.queue == nil {
+		return 0
+	}
+	return p.queue.timeout
+}
+
+// QueueDepth returns the number of requests waiting for an endpoint of the
+// pool.
+func (p *EndpointPool) QueueDepth() int {
+	if p.queue == nil {
+		return 0
+	}
+	return p.queue.depth()
+}
+
+// WaitForCapacity waits until an endpoint of an overloaded pool drops below the
+// maximum number of connections. It returns ErrQueueFull if the request cannot
+// be queued, ErrQueueTimeout if it waited for the queue timeout, or the error
+// of ctx if it is done first.
+func (p *EndpointPool) WaitForCapacity(ctx context.Context) error {
+	if p.queue == nil {
+		return ErrQueueFull
+	}
+
+	timer := time.NewTimer(p.queue.timeout)
+	defer timer.Stop()
+
+	requeue := false
+	for p.IsOverloaded() {
+		ready, ok := p.queue.enqueue(requeue)
+		if !ok {
+			return ErrQueueFull
+		}
+		// a connection may have been released before the request was queued
+		if !p.IsOverloaded() {
+			p.queue.remove(ready)
+			return nil
+		}
+
+		select {
+		case <-ready:
+		case <-timer.C:
+			p.queue.remove(ready)
+			return ErrQueueTimeout
+		case <-ctx.Done():
+			p.queue.remove(ready)
+			return ctx.Err()
+		}
+		requeue = true
+	}
+	return nil
+}
+
+// connectionReleased dispatches a request waiting for the pool, if any, once a
+// request to one of its endpoints has finished.
+func (p *EndpointPool) connectionReleased() {
+	if p.queue != nil {
+		p.queue.release(1)
+	}
+}
+
+// capacityAdded dispatches as many requests waiting for the pool as e can take
+// after it was registered, recovered, returned from ejection or its
+// concurrency limit rose. A dispatched request which still finds the pool
+// overloaded queues again at the front.
+func (p *EndpointPool) capacityAdded(e *endpointElem) {
+	if p.queue != nil {
+		p.queue.release(e.spareConns())
+	}
+}
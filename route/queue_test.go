@@ -0,0 +1,178 @@
+package route_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("WaitForCapacity", func() {
+	var (
+		pool     *route.EndpointPool
+		endpoint *route.Endpoint
+		iter     route.EndpointIterator
+		queueCfg *config.MaxConnsQueueConfig
+		maxConns int64
+		adaptive *config.AdaptiveConcurrencyConfig
+		logger   *test_util.TestLogger
+	)
+
+	wait := func(ctx context.Context) chan error {
+		done := make(chan error, 1)
+		go func() {
+			done <- pool.WaitForCapacity(ctx)
+		}()
+		Eventually(pool.QueueDepth).Should(BeNumerically(">", 0))
+		return done
+	}
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+		queueCfg = &config.MaxConnsQueueConfig{Enabled: true, MaxDepth: 2, Timeout: time.Minute}
+		maxConns = 1
+		adaptive = nil
+	})
+
+	JustBeforeEach(func() {
+		pool = route.NewPool(&route.PoolOpts{
+			Logger:              logger.Logger,
+			MaxConnsPerBackend:  maxConns,
+			MaxConnsQueue:       queueCfg,
+			AdaptiveConcurrency: adaptive,
+		})
+		endpoint = route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111})
+		pool.Put(endpoint)
+		iter = route.NewRoundRobin(logger.Logger, pool, "", false, false, "")
+	})
+
+	It("returns immediately if the pool is not overloaded", func() {
+		Expect(pool.WaitForCapacity(context.Background())).To(Succeed())
+		Expect(pool.QueueDepth()).To(BeZero())
+	})
+
+	Context("when all endpoints are at the maximum number of connections", func() {
+		JustBeforeEach(func() {
+			iter.PreRequest(endpoint)
+		})
+
+		It("dispatches a waiting request once a connection is released", func() {
+			done := wait(context.Background())
+			Consistently(done).ShouldNot(Receive())
+
+			iter.PostRequest(endpoint)
+			Eventually(done).Should(Receive(BeNil()))
+			Expect(pool.QueueDepth()).To(BeZero())
+		})
+
+		It("dispatches waiting requests in order", func() {
+			first := wait(context.Background())
+			second := make(chan error, 1)
+			go func() {
+				second <- pool.WaitForCapacity(context.Background())
+			}()
+			Eventually(pool.QueueDepth).Should(Equal(2))
+
+			iter.PostRequest(endpoint)
+			Eventually(first).Should(Receive(BeNil()))
+			Consistently(second).ShouldNot(Receive())
+		})
+
+		It("dispatches as many waiting requests as a registered endpoint can take", func() {
+			first := wait(context.Background())
+			second := make(chan error, 1)
+			go func() {
+				second <- pool.WaitForCapacity(context.Background())
+			}()
+			Eventually(pool.QueueDepth).Should(Equal(2))
+
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "2.2.2.2", Port: 2222}))
+			Eventually(first).Should(Receive(BeNil()))
+			Consistently(second).ShouldNot(Receive())
+		})
+
+		Context("when another endpoint is unhealthy", func() {
+			var unhealthy *route.Endpoint
+
+			JustBeforeEach(func() {
+				unhealthy = route.NewEndpoint(&route.EndpointOpts{Host: "2.2.2.2", Port: 2222})
+				pool.Put(unhealthy)
+				pool.SetEndpointHealthy(unhealthy, false)
+			})
+
+			It("dispatches a waiting request once the endpoint recovers", func() {
+				done := wait(context.Background())
+				Consistently(done).ShouldNot(Receive())
+
+				pool.SetEndpointHealthy(unhealthy, true)
+				Eventually(done).Should(Receive(BeNil()))
+			})
+		})
+
+		Context("when the concurrency limit of the endpoint is adaptive", func() {
+			BeforeEach(func() {
+				maxConns = 0
+				adaptive = &config.AdaptiveConcurrencyConfig{
+					Enabled:          true,
+					InitialLimit:     1,
+					MinLimit:         1,
+					MaxLimit:         10,
+					LatencyTolerance: 2,
+					BackoffRatio:     0.5,
+				}
+			})
+
+			It("dispatches a waiting request once the limit rises", func() {
+				done := wait(context.Background())
+				Consistently(done).ShouldNot(Receive())
+
+				pool.EndpointFinished(endpoint, 10*time.Millisecond, false)
+				Expect(pool.ConcurrencyLimit(endpoint)).To(Equal(int64(2)))
+				Eventually(done).Should(Receive(BeNil()))
+			})
+		})
+
+		It("rejects requests once the queue is full", func() {
+			wait(context.Background())
+			go pool.WaitForCapacity(context.Background())
+			Eventually(pool.QueueDepth).Should(Equal(2))
+
+			Expect(pool.WaitForCapacity(context.Background())).To(MatchError(route.ErrQueueFull))
+		})
+
+		It("stops waiting when the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			done := wait(ctx)
+			cancel()
+			Eventually(done).Should(Receive(MatchError(context.Canceled)))
+			Expect(pool.QueueDepth()).To(BeZero())
+		})
+
+		Context("when the timeout expires", func() {
+			BeforeEach(func() {
+				queueCfg.Timeout = 10 * time.Millisecond
+			})
+
+			It("returns an error", func() {
+				Expect(pool.WaitForCapacity(context.Background())).To(MatchError(route.ErrQueueTimeout))
+				Expect(pool.QueueDepth()).To(BeZero())
+			})
+		})
+
+		Context("when the pool does not queue requests", func() {
+			BeforeEach(func() {
+				queueCfg = nil
+			})
+
+			It("rejects requests", func() {
+				Expect(pool.QueueTimeout()).To(BeZero())
+				Expect(pool.WaitForCapacity(context.Background())).To(MatchError(route.ErrQueueFull))
+			})
+		})
+	})
+})
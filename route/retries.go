@@ -0,0 +1,23 @@
+package route
+
+import (
+	"log/slog"
+	"slices"
+)
+
+// RetryStatusCodes returns the backend status codes on which idempotent
+// requests of this route are retried, or nil if the global ones apply.
+func (p *EndpointPool) RetryStatusCodes() []int {
+	p.Lock()
+	defer p.Unlock()
+	return p.retryStatusCodes
+}
+
+// setPoolRetryStatusCodes overwrites the retry status codes of a pool by those of a specified endpoint. If the endpoint has none, the global ones apply again.
+func (p *EndpointPool) setPoolRetryStatusCodes(endpoint *Endpoint) {
+	if !slices.Equal(endpoint.RetryStatusCodes, p.retryStatusCodes) {
+		p.retryStatusCodes = endpoint.RetryStatusCodes
+		p.logger.Debug("setting-pool-retry-status-codes-to-that-of-an-endpoint",
+			slog.Any("poolRetryStatusCodes", p.retryStatusCodes))
+	}
+}
@@ -0,0 +1,52 @@
+package route_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("RetryStatusCodes", func() {
+	var (
+		pool   *route.EndpointPool
+		logger *test_util.TestLogger
+	)
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+		pool = route.NewPool(&route.PoolOpts{Logger: logger.Logger})
+	})
+
+	It("has no retry status codes by default", func() {
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111}))
+		Expect(pool.RetryStatusCodes()).To(BeEmpty())
+	})
+
+	It("adopts the retry status codes of an endpoint", func() {
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, RetryStatusCodes: []int{502, 503}}))
+		Expect(pool.RetryStatusCodes()).To(Equal([]int{502, 503}))
+	})
+
+	It("resets the retry status codes of the pool when an endpoint registers again without them", func() {
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, RetryStatusCodes: []int{503}}))
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111}))
+		Expect(pool.RetryStatusCodes()).To(BeEmpty())
+	})
+
+	It("treats endpoints with different retry status codes as different", func() {
+		endpoint := route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, RetryStatusCodes: []int{503}})
+		other := route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, RetryStatusCodes: []int{503, 504}})
+		Expect(endpoint.Equal(other)).To(BeFalse())
+	})
+
+	It("includes the retry status codes of an endpoint in its JSON", func() {
+		endpoint := route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, RetryStatusCodes: []int{503}})
+		b, err := json.Marshal(endpoint)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(ContainSubstring(`"retry_status_codes":[503]`))
+	})
+})
@@ -0,0 +1,98 @@
+package route
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/config"
+)
+
+// RetryBudget is a token bucket which limits retries to a ratio of the
+// requests plus a minimum rate. Every request deposits a fraction of a token,
+// tokens are added at the minimum rate over time and every retry withdraws a
+// token. A nil RetryBudget allows all retries.
+type RetryBudget struct {
+	sync.Mutex
+	ratio        float64
+	minPerSecond float64
+	maxTokens    float64
+	tokens       float64
+	refilledAt   time.Time
+}
+
+// NewRetryBudget creates a full retry budget from the given configuration.
+func NewRetryBudget(cfg *config.RetryBudgetConfig) *RetryBudget {
+	return &RetryBudget{
+		ratio:        cfg.RetryRatio,
+		minPerSecond: cfg.MinRetriesPerSecond,
+		maxTokens:    cfg.MaxTokens,
+		tokens:       cfg.MaxTokens,
+		refilledAt:   time.Now(),
+	}
+}
+
+// Deposit adds the share of a request to the budget.
+func (b *RetryBudget) Deposit() {
+	if b == nil {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+	b.refill()
+	b.add(b.ratio)
+}
+
+// Withdraw takes a retry from the budget. It reports false if the budget is
+// exhausted.
+func (b *RetryBudget) Withdraw() bool {
+	if b == nil {
+		return true
+	}
+	b.Lock()
+	defer b.Unlock()
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Refund returns a retry which was withdrawn but not used to the budget.
+func (b *RetryBudget) Refund() {
+	if b == nil {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+	b.add(1)
+}
+
+// Tokens returns the number of retries left in the budget.
+func (b *RetryBudget) Tokens() float64 {
+	if b == nil {
+		return 0
+	}
+	b.Lock()
+	defer b.Unlock()
+	b.refill()
+	return b.tokens
+}
+
+// refill adds the tokens accrued at the minimum rate since the last refill.
+// The lock must be held when calling this function.
+func (b *RetryBudget) refill() {
+	now := time.Now()
+	b.add(now.Sub(b.refilledAt).Seconds() * b.minPerSecond)
+	b.refilledAt = now
+}
+
+func (b *RetryBudget) add(tokens float64) {
+	b.tokens = min(b.tokens+tokens, b.maxTokens)
+}
+
+// RetryBudget returns the retry budget of the route, or nil if retries of the
+// route are not limited.
+func (p *EndpointPool) RetryBudget() *RetryBudget {
+	return p.retryBudget
+}
@@ -0,0 +1,85 @@
+package route_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+var _ = Describe("RetryBudget", func() {
+	var (
+		cfg    *config.RetryBudgetConfig
+		budget *route.RetryBudget
+	)
+
+	exhaust := func() {
+		for budget.Withdraw() {
+		}
+	}
+
+	BeforeEach(func() {
+		cfg = &config.RetryBudgetConfig{
+			Enabled:    true,
+			RetryRatio: 0.5,
+			MaxTokens:  3,
+		}
+	})
+
+	JustBeforeEach(func() {
+		budget = route.NewRetryBudget(cfg)
+	})
+
+	It("starts full", func() {
+		Expect(budget.Withdraw()).To(BeTrue())
+		Expect(budget.Withdraw()).To(BeTrue())
+		Expect(budget.Withdraw()).To(BeTrue())
+		Expect(budget.Withdraw()).To(BeFalse())
+	})
+
+	It("adds the retry ratio for every request", func() {
+		exhaust()
+
+		budget.Deposit()
+		Expect(budget.Withdraw()).To(BeFalse())
+
+		budget.Deposit()
+		budget.Deposit()
+		Expect(budget.Withdraw()).To(BeTrue())
+		Expect(budget.Withdraw()).To(BeFalse())
+	})
+
+	It("does not accumulate more than the maximum", func() {
+		for i := 0; i < 100; i++ {
+			budget.Deposit()
+		}
+		Expect(budget.Tokens()).To(BeNumerically("==", 3))
+	})
+
+	It("takes back refunded retries", func() {
+		exhaust()
+		budget.Refund()
+		Expect(budget.Withdraw()).To(BeTrue())
+	})
+
+	Context("with a minimum rate of retries", func() {
+		BeforeEach(func() {
+			cfg.RetryRatio = 0
+			cfg.MinRetriesPerSecond = 100
+		})
+
+		It("adds retries over time", func() {
+			exhaust()
+			Eventually(budget.Withdraw).Should(BeTrue())
+		})
+	})
+
+	Context("when the budget is nil", func() {
+		It("allows all retries", func() {
+			var nilBudget *route.RetryBudget
+			nilBudget.Deposit()
+			Expect(nilBudget.Withdraw()).To(BeTrue())
+		})
+	})
+})
@@ -17,6 +17,7 @@ type RoundRobin struct {
 	lastEndpoint          *Endpoint
 	locallyOptimistic     bool
 	localAvailabilityZone string
+	endpointFilter
 
 	nextIdx int
 }
@@ -86,7 +87,8 @@ func (r *RoundRobin) next(attempt int) *endpointElem {
 	r.pool.Lock()
 	defer r.pool.Unlock()
 
-	localDesired := r.locallyOptimistic && attempt == 0
+	zone, strict := r.preferredZone(r.pool, r.localAvailabilityZone, r.locallyOptimistic, attempt)
+	subset := r.subset(attempt)
 
 	poolSize := len(r.pool.endpoints)
 	if poolSize == 0 {
@@ -105,9 +107,16 @@ func (r *RoundRobin) next(attempt int) *endpointElem {
 	currentIndex := startingIndex
 	var nextIndex int
 
+	// An endpoint that is still ramping up is passed over in favour of the
+	// following endpoints, but is selected if no other endpoint is available.
+	now := time.Now()
+	var skipped *endpointElem
+	var skippedNextIndex int
+	reset := false
+
 	for {
 		e := r.pool.endpoints[currentIndex]
-		currentEndpointIsLocal := e.endpoint.AvailabilityZone == r.localAvailabilityZone
+		inPreferredZone := zone == "" || e.endpoint.AvailabilityZone == zone
 
 		// We tried using the actual modulo operator, but it has a 10x performance penalty
 		nextIndex = currentIndex + 1
@@ -117,28 +126,48 @@ func (r *RoundRobin) next(attempt int) *endpointElem {
 
 		r.clearExpiredFailures(e)
 
-		if !localDesired || (localDesired && currentEndpointIsLocal) {
-			if e.failedAt == nil && !e.isOverloaded() {
-				r.nextIdx = nextIndex
-				return e
+		if inPreferredZone {
+			if !e.isFailed() && !e.isOverloaded() && !r.pool.isUnhealthy(e) && subset.contains(e) {
+				if !r.pool.skipRampingEndpoint(e, now) {
+					r.nextIdx = nextIndex
+					return e
+				}
+				if skipped == nil {
+					skipped = e
+					skippedNextIndex = nextIndex
+				}
 			}
 		}
 
 		// If we've cycled through all of the indices and we WILL be back where we started.
 		if nextIndex == startingIndex {
-			if r.allEndpointsAreOverloaded() {
+			if skipped != nil {
+				r.nextIdx = skippedNextIndex
+				return skipped
+			}
+
+			if subset != nil {
+				// could not find a valid endpoint in the preferred subset, consider all endpoints
+				subset = nil
+				currentIndex = nextIndex
+				continue
+			}
+
+			if r.allEndpointsAreOverloaded() || reset {
+				// a second cycle found no endpoint either, e.g. because the
+				// endpoints of a strictly preferred zone are overloaded
 				return nil
 			}
 
 			// could not find a valid route in the same AZ
-			// start again but consider all AZs
-			localDesired = false
-
-			// all endpoints are marked failed so reset everything to available
-			for _, e2 := range r.pool.endpoints {
-				e2.failedAt = nil
+			// start again but consider all AZs, unless the AZ preference is strict
+			if !strict {
+				zone = ""
 			}
 
+			// all endpoints are marked failed so reset everything to available
+			r.pool.resetFailures()
+			reset = true
 		}
 
 		currentIndex = nextIndex
@@ -146,12 +175,7 @@ func (r *RoundRobin) next(attempt int) *endpointElem {
 }
 
 func (r *RoundRobin) clearExpiredFailures(e *endpointElem) {
-	if e.failedAt != nil {
-		curTime := time.Now()
-		if curTime.Sub(*e.failedAt) > r.pool.retryAfterFailure {
-			e.failedAt = nil
-		}
-	}
+	r.pool.clearExpiredFailure(e, time.Now())
 }
 
 func (r *RoundRobin) allEndpointsAreOverloaded() bool {
@@ -174,4 +198,5 @@ func (r *RoundRobin) PreRequest(e *Endpoint) {
 
 func (r *RoundRobin) PostRequest(e *Endpoint) {
 	e.Stats.NumberConnections.Decrement()
+	r.pool.connectionReleased()
 }
@@ -0,0 +1,25 @@
+package route
+
+import (
+	"log/slog"
+)
+
+// Shadow returns the route to which copies of requests for this route are
+// sent, and the percentage of requests copied. The route is empty if requests
+// are not copied.
+func (p *EndpointPool) Shadow() (string, float64) {
+	p.Lock()
+	defer p.Unlock()
+	return p.shadowRoute, p.shadowPercentage
+}
+
+// setPoolShadow overwrites the shadow route and percentage of a pool by those of a specified endpoint. If the endpoint has no shadow route, requests are no longer copied.
+func (p *EndpointPool) setPoolShadow(endpoint *Endpoint) {
+	if endpoint.ShadowRoute != p.shadowRoute || endpoint.ShadowPercentage != p.shadowPercentage {
+		p.shadowRoute = endpoint.ShadowRoute
+		p.shadowPercentage = endpoint.ShadowPercentage
+		p.logger.Debug("setting-pool-shadow-to-that-of-an-endpoint",
+			slog.String("poolShadowRoute", p.shadowRoute),
+			slog.Float64("poolShadowPercentage", p.shadowPercentage))
+	}
+}
@@ -0,0 +1,49 @@
+package route_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("Shadow", func() {
+	var (
+		pool   *route.EndpointPool
+		logger *test_util.TestLogger
+	)
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+		pool = route.NewPool(&route.PoolOpts{Logger: logger.Logger})
+	})
+
+	It("has no shadow route by default", func() {
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111}))
+		shadowRoute, percentage := pool.Shadow()
+		Expect(shadowRoute).To(BeEmpty())
+		Expect(percentage).To(BeZero())
+	})
+
+	It("adopts the shadow route and percentage of an endpoint", func() {
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, ShadowRoute: "v2.example.com", ShadowPercentage: 12.5}))
+		shadowRoute, percentage := pool.Shadow()
+		Expect(shadowRoute).To(Equal("v2.example.com"))
+		Expect(percentage).To(Equal(12.5))
+	})
+
+	It("removes the shadow route of the pool when an endpoint registers again without one", func() {
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, ShadowRoute: "v2.example.com", ShadowPercentage: 50}))
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111}))
+		shadowRoute, percentage := pool.Shadow()
+		Expect(shadowRoute).To(BeEmpty())
+		Expect(percentage).To(BeZero())
+	})
+
+	It("treats endpoints with different shadow percentages as different", func() {
+		endpoint := route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, ShadowRoute: "v2.example.com", ShadowPercentage: 10})
+		other := route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, ShadowRoute: "v2.example.com", ShadowPercentage: 20})
+		Expect(endpoint.Equal(other)).To(BeFalse())
+	})
+})
@@ -0,0 +1,108 @@
+package route
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+// slowStartMinFactor is the share of traffic, relative to a fully ramped-up
+// endpoint, that an endpoint receives right after it was added to a pool.
+const slowStartMinFactor = 0.1
+
+// slowStartProgress returns how far e is through its slow-start window, from 0
+// right after it was added to 1 once the window has passed. The pool lock must
+// be held when calling this function.
+func (p *EndpointPool) slowStartProgress(e *endpointElem, now time.Time) float64 {
+	if p.slowStartDuration <= 0 || e.addedAt.IsZero() {
+		return 1
+	}
+
+	elapsed := now.Sub(e.addedAt)
+	if elapsed >= p.slowStartDuration {
+		return 1
+	}
+	return max(float64(elapsed)/float64(p.slowStartDuration), 0)
+}
+
+// slowStartFactor returns the share of traffic e should receive relative to an
+// endpoint that is not ramping up. It rises linearly from slowStartMinFactor
+// to 1 over the slow-start window. The pool lock must be held when calling
+// this function.
+func (p *EndpointPool) slowStartFactor(e *endpointElem, now time.Time) float64 {
+	return slowStartMinFactor + (1-slowStartMinFactor)*p.slowStartProgress(e, now)
+}
+
+// slowStartLoad returns the number of connections e would have with one more
+// request, scaled up while e is ramping up, so that least-connection treats a
+// new endpoint as busier than it is. The pool lock must be held when calling
+// this function.
+func (p *EndpointPool) slowStartLoad(e *endpointElem, now time.Time) float64 {
+	return float64(e.endpoint.Stats.NumberConnections.Count()+1) / p.slowStartFactor(e, now)
+}
+
+// skipRampingEndpoint randomly decides to pass over e while it is ramping up,
+// so that it is only selected in proportion to its slow-start factor. The
+// pool lock must be held when calling this function.
+func (p *EndpointPool) skipRampingEndpoint(e *endpointElem, now time.Time) bool {
+	factor := p.slowStartFactor(e, now)
+	if factor >= 1 {
+		return false
+	}
+	return p.random.Float64() >= factor
+}
+
+// SlowStartDuration returns the time over which newly added endpoints ramp up
+// to a full share of traffic.
+func (p *EndpointPool) SlowStartDuration() time.Duration {
+	p.Lock()
+	defer p.Unlock()
+	return p.slowStartDuration
+}
+
+// setPoolSlowStartDuration overwrites the slow-start window of a pool by that of a specified endpoint, if it has one.
+func (p *EndpointPool) setPoolSlowStartDuration(endpoint *Endpoint) {
+	if endpoint.SlowStartDuration > 0 && endpoint.SlowStartDuration != p.slowStartDuration {
+		p.slowStartDuration = endpoint.SlowStartDuration
+		p.logger.Debug("setting-pool-slow-start-duration-to-that-of-an-endpoint",
+			slog.Duration("poolSlowStartDuration", p.slowStartDuration))
+	}
+}
+
+// endpointWithState is an endpoint marshalled with the state its pool keeps
+// for it: the slow-start progress of an endpoint which is still ramping up and
+// the adaptive concurrency limit of the endpoint.
+type endpointWithState struct {
+	endpoint          *Endpoint
+	slowStartProgress *float64
+	concurrencyLimit  int64
+}
+
+func (e endpointWithState) MarshalJSON() ([]byte, error) {
+	return e.endpoint.marshalJSON(e.slowStartProgress, e.concurrencyLimit)
+}
+
+// endpointsForJSON returns the endpoints of the pool for marshalling, with the
+// slow-start progress of those endpoints that are still ramping up and the
+// adaptive concurrency limits. Only the endpoints for which keep returns true
+// are returned, or all endpoints if keep is nil. The pool lock must be held
+// when calling this function.
+func (p *EndpointPool) endpointsForJSON(keep func(*Endpoint) bool) []json.Marshaler {
+	now := time.Now()
+	endpoints := make([]json.Marshaler, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		if keep != nil && !keep(e.endpoint) {
+			continue
+		}
+		state := endpointWithState{endpoint: e.endpoint, concurrencyLimit: e.limiter.Limit()}
+		if progress := p.slowStartProgress(e, now); progress < 1 {
+			state.slowStartProgress = &progress
+		}
+		if state.slowStartProgress == nil && state.concurrencyLimit == 0 {
+			endpoints = append(endpoints, e.endpoint)
+		} else {
+			endpoints = append(endpoints, state)
+		}
+	}
+	return endpoints
+}
@@ -0,0 +1,153 @@
+package route_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("Slow start", func() {
+	const slowStartDuration = 200 * time.Millisecond
+
+	var (
+		pool         *route.EndpointPool
+		logger       *test_util.TestLogger
+		epOne, epTwo *route.Endpoint
+		newEndpoint  *route.Endpoint
+	)
+
+	setupPool := func(lbAlgo string) {
+		pool = route.NewPool(&route.PoolOpts{
+			Logger:                 logger.Logger,
+			RetryAfterFailure:      2 * time.Minute,
+			LoadBalancingAlgorithm: lbAlgo,
+			SlowStartDuration:      slowStartDuration,
+		})
+
+		epOne = route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111})
+		epTwo = route.NewEndpoint(&route.EndpointOpts{Host: "2.2.2.2", Port: 2222})
+		pool.Put(epOne)
+		pool.Put(epTwo)
+
+		// let the first endpoints finish their ramp-up before adding a new one
+		time.Sleep(slowStartDuration + 50*time.Millisecond)
+
+		newEndpoint = route.NewEndpoint(&route.EndpointOpts{Host: "3.3.3.3", Port: 3333})
+		pool.Put(newEndpoint)
+	}
+
+	countSelections := func(requests int) map[*route.Endpoint]int {
+		counts := make(map[*route.Endpoint]int)
+		for i := 0; i < requests; i++ {
+			iter := pool.Endpoints(logger.Logger, "", false, config.AZ_PREF_NONE, "", "", nil)
+			counts[iter.Next(0)]++
+		}
+		return counts
+	}
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+	})
+
+	Context("with round-robin", func() {
+		BeforeEach(func() {
+			setupPool(config.LOAD_BALANCE_RR)
+		})
+
+		It("sends a reduced share of traffic to a new endpoint", func() {
+			counts := countSelections(300)
+			Expect(counts[newEndpoint]).To(BeNumerically("<", 50))
+			Expect(counts[epOne]).To(BeNumerically(">=", 100))
+			Expect(counts[epTwo]).To(BeNumerically(">=", 100))
+		})
+
+		It("sends a full share of traffic to the endpoint once the window has passed", func() {
+			time.Sleep(slowStartDuration)
+			counts := countSelections(300)
+			Expect(counts[newEndpoint]).To(Equal(100))
+		})
+
+		It("selects the new endpoint when it is the only one available", func() {
+			Expect(pool.Remove(epOne)).To(BeTrue())
+			Expect(pool.Remove(epTwo)).To(BeTrue())
+
+			iter := pool.Endpoints(logger.Logger, "", false, config.AZ_PREF_NONE, "", "", nil)
+			Expect(iter.Next(0)).To(Equal(newEndpoint))
+		})
+	})
+
+	Context("with least-connection", func() {
+		BeforeEach(func() {
+			setupPool(config.LOAD_BALANCE_LC)
+		})
+
+		It("treats a new endpoint as busier than it is", func() {
+			counts := countSelections(20)
+			Expect(counts[newEndpoint]).To(Equal(0))
+		})
+
+		It("selects the new endpoint when the other endpoints are busy enough", func() {
+			for i := 0; i < 20; i++ {
+				epOne.Stats.NumberConnections.Increment()
+				epTwo.Stats.NumberConnections.Increment()
+			}
+
+			iter := pool.Endpoints(logger.Logger, "", false, config.AZ_PREF_NONE, "", "", nil)
+			Expect(iter.Next(0)).To(Equal(newEndpoint))
+		})
+
+		It("selects the endpoint with the fewest connections once the window has passed", func() {
+			time.Sleep(slowStartDuration)
+			epOne.Stats.NumberConnections.Increment()
+			epTwo.Stats.NumberConnections.Increment()
+
+			iter := pool.Endpoints(logger.Logger, "", false, config.AZ_PREF_NONE, "", "", nil)
+			Expect(iter.Next(0)).To(Equal(newEndpoint))
+		})
+	})
+
+	Context("when marshalling the pool", func() {
+		BeforeEach(func() {
+			setupPool(config.LOAD_BALANCE_RR)
+		})
+
+		It("includes the slow-start progress of endpoints which are ramping up", func() {
+			data, err := pool.MarshalJSON()
+			Expect(err).NotTo(HaveOccurred())
+
+			var endpoints []map[string]any
+			Expect(json.Unmarshal(data, &endpoints)).To(Succeed())
+			Expect(endpoints).To(HaveLen(3))
+
+			Expect(endpoints[0]).NotTo(HaveKey("slow_start_progress"))
+			Expect(endpoints[1]).NotTo(HaveKey("slow_start_progress"))
+			Expect(endpoints[2]).To(HaveKeyWithValue("slow_start_progress", BeNumerically("<", 0.5)))
+		})
+	})
+
+	Context("when an endpoint has a slow-start duration", func() {
+		It("overwrites the slow-start duration of the pool", func() {
+			pool = route.NewPool(&route.PoolOpts{
+				Logger:            logger.Logger,
+				SlowStartDuration: time.Minute,
+			})
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, SlowStartDurationInSeconds: 90}))
+			Expect(pool.SlowStartDuration()).To(Equal(90 * time.Second))
+		})
+
+		It("keeps the slow-start duration of the pool when the endpoint has none", func() {
+			pool = route.NewPool(&route.PoolOpts{
+				Logger:            logger.Logger,
+				SlowStartDuration: time.Minute,
+			})
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111}))
+			Expect(pool.SlowStartDuration()).To(Equal(time.Minute))
+		})
+	})
+})
@@ -0,0 +1,49 @@
+package route
+
+import (
+	"log/slog"
+	"time"
+)
+
+// RequestTimeout returns the time a backend may take to respond to a request
+// of this route, or zero if the global endpoint timeout applies.
+func (p *EndpointPool) RequestTimeout() time.Duration {
+	p.Lock()
+	defer p.Unlock()
+	return p.requestTimeout
+}
+
+// DialTimeout returns the time allowed for connecting to a backend of this
+// route, or zero if the global endpoint dial timeout applies.
+func (p *EndpointPool) DialTimeout() time.Duration {
+	p.Lock()
+	defer p.Unlock()
+	return p.dialTimeout
+}
+
+// MaxAttempts returns the maximum number of backends a request of this route
+// is sent to, or zero if the global maximum applies.
+func (p *EndpointPool) MaxAttempts() int {
+	p.Lock()
+	defer p.Unlock()
+	return p.maxAttempts
+}
+
+// setPoolTimeouts overwrites the timeouts and maximum number of attempts of a pool by those of a specified endpoint. Those the endpoint does not have are reset to the global values.
+func (p *EndpointPool) setPoolTimeouts(endpoint *Endpoint) {
+	if endpoint.RequestTimeout != p.requestTimeout {
+		p.requestTimeout = endpoint.RequestTimeout
+		p.logger.Debug("setting-pool-request-timeout-to-that-of-an-endpoint",
+			slog.Duration("poolRequestTimeout", p.requestTimeout))
+	}
+	if endpoint.DialTimeout != p.dialTimeout {
+		p.dialTimeout = endpoint.DialTimeout
+		p.logger.Debug("setting-pool-dial-timeout-to-that-of-an-endpoint",
+			slog.Duration("poolDialTimeout", p.dialTimeout))
+	}
+	if endpoint.MaxAttempts != p.maxAttempts {
+		p.maxAttempts = endpoint.MaxAttempts
+		p.logger.Debug("setting-pool-max-attempts-to-that-of-an-endpoint",
+			slog.Int("poolMaxAttempts", p.maxAttempts))
+	}
+}
@@ -0,0 +1,57 @@
+package route_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("Route timeouts", func() {
+	var (
+		pool   *route.EndpointPool
+		logger *test_util.TestLogger
+	)
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+		pool = route.NewPool(&route.PoolOpts{Logger: logger.Logger})
+	})
+
+	It("has no timeouts or max attempts of its own by default", func() {
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111}))
+		Expect(pool.RequestTimeout()).To(BeZero())
+		Expect(pool.DialTimeout()).To(BeZero())
+		Expect(pool.MaxAttempts()).To(BeZero())
+	})
+
+	It("adopts the timeouts and max attempts of an endpoint", func() {
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{
+			Host:           "1.1.1.1",
+			Port:           1111,
+			RequestTimeout: 30 * time.Second,
+			DialTimeout:    2 * time.Second,
+			MaxAttempts:    5,
+		}))
+		Expect(pool.RequestTimeout()).To(Equal(30 * time.Second))
+		Expect(pool.DialTimeout()).To(Equal(2 * time.Second))
+		Expect(pool.MaxAttempts()).To(Equal(5))
+	})
+
+	It("resets the values of the pool when an endpoint registers again without them", func() {
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, RequestTimeout: 30 * time.Second, DialTimeout: 2 * time.Second, MaxAttempts: 5}))
+		pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, DialTimeout: 2 * time.Second}))
+		Expect(pool.RequestTimeout()).To(BeZero())
+		Expect(pool.DialTimeout()).To(Equal(2 * time.Second))
+		Expect(pool.MaxAttempts()).To(BeZero())
+	})
+
+	It("treats endpoints with different timeouts as different", func() {
+		endpoint := route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111})
+		other := route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, DialTimeout: time.Second})
+		Expect(endpoint.Equal(other)).To(BeFalse())
+	})
+})
@@ -0,0 +1,160 @@
+package route
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+// SetAppWeights overrides the traffic weights registered for the apps of the
+// route. Apps missing from weights keep their registered weight. An empty map
+// removes all overrides.
+func (p *EndpointPool) SetAppWeights(weights map[string]int) error {
+	for appId, weight := range weights {
+		if weight < 0 {
+			return fmt.Errorf("invalid traffic weight %d for app %q: must not be negative", weight, appId)
+		}
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if len(weights) == 0 {
+		p.appWeightOverrides = nil
+	} else {
+		p.appWeightOverrides = maps.Clone(weights)
+	}
+	p.appGroups = nil
+	p.logger.Info("setting-pool-app-weights",
+		slog.String("Host", p.host),
+		slog.String("Path", p.contextPath),
+		slog.Any("appWeights", p.appWeightOverrides))
+	return nil
+}
+
+// AppWeights returns the traffic weight of every app with endpoints in the
+// pool, or nil if the traffic of the route is not split.
+func (p *EndpointPool) AppWeights() map[string]int {
+	p.Lock()
+	defer p.Unlock()
+
+	if !p.isTrafficSplit() {
+		return nil
+	}
+
+	weights := make(map[string]int)
+	for _, e := range p.endpoints {
+		weights[e.endpoint.ApplicationId] = p.appWeight(e.endpoint.ApplicationId)
+	}
+	return weights
+}
+
+// pickAppGroup picks one of the apps with endpoints in the pool at random,
+// according to their traffic weights. It returns an empty string if the
+// traffic of the route is not split.
+func (p *EndpointPool) pickAppGroup() string {
+	p.Lock()
+	defer p.Unlock()
+
+	groups := p.appGroupWeights()
+	if len(groups) < 2 {
+		return ""
+	}
+	total := groups[len(groups)-1].cumulativeWeight
+	if total == 0 {
+		return ""
+	}
+
+	n := p.random.Intn(total)
+	i, _ := slices.BinarySearchFunc(groups, n, func(g appGroup, n int) int {
+		if g.cumulativeWeight <= n {
+			return -1
+		}
+		return 1
+	})
+	return groups[i].appId
+}
+
+// appGroup is an app with endpoints in the pool. cumulativeWeight is the sum
+// of its traffic weight and those of the apps before it.
+type appGroup struct {
+	appId            string
+	cumulativeWeight int
+}
+
+// appGroupWeights returns the apps with endpoints in the pool and their
+// cumulative traffic weights, or an empty slice if the traffic of the route
+// is not split. It is built lazily and reset whenever endpoints are added or
+// removed or the weights change. The pool lock must be held when calling this
+// function.
+func (p *EndpointPool) appGroupWeights() []appGroup {
+	if p.appGroups != nil {
+		return p.appGroups
+	}
+
+	groups := []appGroup{}
+	if p.isTrafficSplit() {
+		seen := make(map[string]bool)
+		total := 0
+		for _, e := range p.endpoints {
+			appId := e.endpoint.ApplicationId
+			if seen[appId] {
+				continue
+			}
+			seen[appId] = true
+			total += p.appWeight(appId)
+			groups = append(groups, appGroup{appId: appId, cumulativeWeight: total})
+		}
+	}
+
+	p.appGroups = groups
+	return groups
+}
+
+// isTrafficSplit reports whether weights are set for any app of the route.
+// The pool lock must be held when calling this function.
+func (p *EndpointPool) isTrafficSplit() bool {
+	return len(p.appWeights) > 0 || len(p.appWeightOverrides) > 0
+}
+
+// appWeight returns the traffic weight of an app. Apps without a weight
+// receive no traffic on the first attempt while the traffic is split. The
+// pool lock must be held when calling this function.
+func (p *EndpointPool) appWeight(appId string) int {
+	if weight, ok := p.appWeightOverrides[appId]; ok {
+		return weight
+	}
+	return p.appWeights[appId]
+}
+
+// setPoolAppWeight records the traffic weight registered by a specified
+// endpoint for its app, if it has one.
+func (p *EndpointPool) setPoolAppWeight(endpoint *Endpoint) {
+	if endpoint.TrafficWeight <= 0 || p.appWeights[endpoint.ApplicationId] == endpoint.TrafficWeight {
+		return
+	}
+	if p.appWeights == nil {
+		p.appWeights = make(map[string]int)
+	}
+	p.appWeights[endpoint.ApplicationId] = endpoint.TrafficWeight
+	p.appGroups = nil
+	p.logger.Debug("setting-pool-app-weight-to-that-of-an-endpoint",
+		slog.String("ApplicationId", endpoint.ApplicationId),
+		slog.Int("appWeight", endpoint.TrafficWeight))
+}
+
+// removeAppWeight forgets the registered traffic weight of an app once it
+// has no endpoints left in the pool. The pool lock must be held when calling
+// this function.
+func (p *EndpointPool) removeAppWeight(appId string) {
+	if _, ok := p.appWeights[appId]; !ok {
+		return
+	}
+	for _, e := range p.endpoints {
+		if e.endpoint.ApplicationId == appId {
+			return
+		}
+	}
+	delete(p.appWeights, appId)
+}
@@ -0,0 +1,173 @@
+package route_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("Traffic split", func() {
+	var (
+		pool   *route.EndpointPool
+		logger *test_util.TestLogger
+		blue   []*route.Endpoint
+		green  *route.Endpoint
+	)
+
+	setupPool := func(lbAlgo string, blueWeight, greenWeight int) {
+		pool = route.NewPool(&route.PoolOpts{
+			Logger:                 logger.Logger,
+			RetryAfterFailure:      2 * time.Minute,
+			LoadBalancingAlgorithm: lbAlgo,
+		})
+
+		blue = make([]*route.Endpoint, 0, 3)
+		for i := 0; i < 3; i++ {
+			e := route.NewEndpoint(&route.EndpointOpts{
+				AppId:             "blue",
+				Host:              fmt.Sprintf("10.0.0.%d", i),
+				Port:              8080,
+				PrivateInstanceId: fmt.Sprintf("blue-%d", i),
+				TrafficWeight:     blueWeight,
+			})
+			blue = append(blue, e)
+			pool.Put(e)
+		}
+		green = route.NewEndpoint(&route.EndpointOpts{
+			AppId:             "green",
+			Host:              "10.0.1.0",
+			Port:              8080,
+			PrivateInstanceId: "green-0",
+			TrafficWeight:     greenWeight,
+		})
+		pool.Put(green)
+	}
+
+	selectionsByApp := func(requests int) map[string]int {
+		counts := make(map[string]int)
+		for i := 0; i < requests; i++ {
+			iter := pool.Endpoints(logger.Logger, "", false, config.AZ_PREF_NONE, "", fmt.Sprintf("key-%d", i), nil)
+			counts[iter.Next(0).ApplicationId]++
+		}
+		return counts
+	}
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+	})
+
+	DescribeTable("load balancing algorithms split the traffic between apps by weight",
+		func(lbAlgo string) {
+			setupPool(lbAlgo, 90, 10)
+
+			counts := selectionsByApp(2000)
+			Expect(counts["green"]).To(BeNumerically("~", 200, 100))
+			Expect(counts["blue"]).To(BeNumerically("~", 1800, 100))
+		},
+		Entry("round-robin", config.LOAD_BALANCE_RR),
+		Entry("least-connection", config.LOAD_BALANCE_LC),
+		Entry("weighted-round-robin", config.LOAD_BALANCE_WRR),
+		Entry("latency-ewma", config.LOAD_BALANCE_EWMA),
+		Entry("consistent-hash", config.LOAD_BALANCE_CH),
+		Entry("p2c", config.LOAD_BALANCE_P2C),
+	)
+
+	DescribeTable("load balancing algorithms fall back to other apps when the picked app has no available endpoint",
+		func(lbAlgo string) {
+			setupPool(lbAlgo, 0, 100)
+			pool.SetEndpointHealthy(green, false)
+
+			Expect(selectionsByApp(20)).To(Equal(map[string]int{"blue": 20}))
+		},
+		Entry("round-robin", config.LOAD_BALANCE_RR),
+		Entry("least-connection", config.LOAD_BALANCE_LC),
+		Entry("weighted-round-robin", config.LOAD_BALANCE_WRR),
+		Entry("latency-ewma", config.LOAD_BALANCE_EWMA),
+		Entry("consistent-hash", config.LOAD_BALANCE_CH),
+		Entry("p2c", config.LOAD_BALANCE_P2C),
+	)
+
+	Context("with round-robin", func() {
+		BeforeEach(func() {
+			setupPool(config.LOAD_BALANCE_RR, 0, 100)
+		})
+
+		It("sends the first attempt to the picked app only", func() {
+			Expect(selectionsByApp(20)).To(Equal(map[string]int{"green": 20}))
+		})
+
+		It("considers the endpoints of all apps for retries", func() {
+			iter := pool.Endpoints(logger.Logger, "", false, config.AZ_PREF_NONE, "", "", nil)
+			Expect(iter.Next(0)).To(Equal(green))
+			Expect(iter.Next(1).ApplicationId).To(Equal("blue"))
+		})
+
+		It("keeps sticky sessions on their endpoint", func() {
+			iter := pool.Endpoints(logger.Logger, "blue-1", false, config.AZ_PREF_NONE, "", "", nil)
+			Expect(iter.Next(0)).To(Equal(blue[1]))
+		})
+	})
+
+	Describe("app weights", func() {
+		It("does not split the traffic when no app has a weight", func() {
+			setupPool(config.LOAD_BALANCE_RR, 0, 0)
+			Expect(pool.AppWeights()).To(BeNil())
+
+			counts := selectionsByApp(20)
+			Expect(counts["blue"]).To(Equal(15))
+			Expect(counts["green"]).To(Equal(5))
+		})
+
+		It("returns the registered weights", func() {
+			setupPool(config.LOAD_BALANCE_RR, 90, 10)
+			Expect(pool.AppWeights()).To(Equal(map[string]int{"blue": 90, "green": 10}))
+		})
+
+		It("overrides the registered weights", func() {
+			setupPool(config.LOAD_BALANCE_RR, 90, 10)
+			Expect(pool.SetAppWeights(map[string]int{"green": 100, "blue": 0})).To(Succeed())
+			Expect(pool.AppWeights()).To(Equal(map[string]int{"blue": 0, "green": 100}))
+			Expect(selectionsByApp(20)).To(Equal(map[string]int{"green": 20}))
+
+			Expect(pool.SetAppWeights(nil)).To(Succeed())
+			Expect(pool.AppWeights()).To(Equal(map[string]int{"blue": 90, "green": 10}))
+		})
+
+		It("picks the apps by their current weights as weights and apps change", func() {
+			setupPool(config.LOAD_BALANCE_RR, 0, 100)
+			Expect(selectionsByApp(20)).To(Equal(map[string]int{"green": 20}))
+
+			Expect(pool.SetAppWeights(map[string]int{"blue": 100, "green": 0})).To(Succeed())
+			Expect(selectionsByApp(20)).To(Equal(map[string]int{"blue": 20}))
+
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{AppId: "red", Host: "10.0.2.0", Port: 8080, TrafficWeight: 100}))
+			Expect(selectionsByApp(50)).To(HaveKey("red"))
+		})
+
+		It("keeps overrides when apps register their weights again", func() {
+			setupPool(config.LOAD_BALANCE_RR, 90, 10)
+			Expect(pool.SetAppWeights(map[string]int{"green": 50})).To(Succeed())
+			pool.Put(green)
+			Expect(pool.AppWeights()).To(Equal(map[string]int{"blue": 90, "green": 50}))
+		})
+
+		It("rejects negative weights", func() {
+			setupPool(config.LOAD_BALANCE_RR, 90, 10)
+			Expect(pool.SetAppWeights(map[string]int{"green": -1})).To(MatchError(ContainSubstring("must not be negative")))
+			Expect(pool.AppWeights()).To(Equal(map[string]int{"blue": 90, "green": 10}))
+		})
+
+		It("forgets the weight of an app once all its endpoints are removed", func() {
+			setupPool(config.LOAD_BALANCE_RR, 90, 10)
+			Expect(pool.Remove(green)).To(BeTrue())
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{AppId: "green", Host: "10.0.1.1", Port: 8080}))
+			Expect(pool.AppWeights()).To(Equal(map[string]int{"blue": 90, "green": 0}))
+		})
+	})
+})
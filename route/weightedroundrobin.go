@@ -0,0 +1,203 @@
+package route
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// WeightedRoundRobin distributes requests across the endpoints of a pool in
+// proportion to their Weight. It uses the smooth weighted round-robin
+// algorithm, which interleaves heavier endpoints with lighter ones instead of
+// sending them bursts of consecutive requests.
+type WeightedRoundRobin struct {
+	logger *slog.Logger
+	pool   *EndpointPool
+	lock   *sync.Mutex
+
+	initialEndpoint       string
+	mustBeSticky          bool
+	lastEndpoint          *Endpoint
+	locallyOptimistic     bool
+	localAvailabilityZone string
+	endpointFilter
+
+	// tried holds the endpoints already returned by this iterator, so that
+	// retries do not hit the same endpoint before all others have been used.
+	tried map[*endpointElem]struct{}
+}
+
+func NewWeightedRoundRobin(logger *slog.Logger, p *EndpointPool, initial string, mustBeSticky bool, locallyOptimistic bool, localAvailabilityZone string) EndpointIterator {
+	return &WeightedRoundRobin{
+		logger:                logger,
+		pool:                  p,
+		lock:                  &sync.Mutex{},
+		initialEndpoint:       initial,
+		mustBeSticky:          mustBeSticky,
+		locallyOptimistic:     locallyOptimistic,
+		localAvailabilityZone: localAvailabilityZone,
+	}
+}
+
+func (r *WeightedRoundRobin) Next(attempt int) *Endpoint {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var e *endpointElem
+	if r.initialEndpoint != "" {
+		e = r.pool.findById(r.initialEndpoint)
+		if e != nil && e.isOverloaded() {
+			if r.mustBeSticky {
+				if r.logger.Enabled(context.Background(), slog.LevelDebug) {
+					r.logger.Debug("endpoint-overloaded-but-request-must-be-sticky", e.endpoint.ToLogData()...)
+				}
+				return nil
+			}
+			e = nil
+		}
+
+		if e == nil && r.mustBeSticky {
+			r.logger.Debug("endpoint-missing-but-request-must-be-sticky", slog.String("requested-endpoint", r.initialEndpoint))
+			return nil
+		}
+
+		if !r.mustBeSticky {
+			r.logger.Debug("endpoint-missing-choosing-alternate", slog.String("requested-endpoint", r.initialEndpoint))
+			r.initialEndpoint = ""
+		}
+	}
+
+	if e != nil {
+		e.RLock()
+		defer e.RUnlock()
+		r.lastEndpoint = e.endpoint
+		return e.endpoint
+	}
+
+	e = r.next(attempt)
+	if e != nil {
+		e.RLock()
+		defer e.RUnlock()
+		r.lastEndpoint = e.endpoint
+		return e.endpoint
+	}
+
+	r.lastEndpoint = nil
+	return nil
+}
+
+func (r *WeightedRoundRobin) next(attempt int) *endpointElem {
+	// Note: the iterator lock must be held when calling this function.
+	r.pool.Lock()
+	defer r.pool.Unlock()
+
+	if len(r.pool.endpoints) == 0 {
+		return nil
+	}
+
+	for _, e := range r.pool.endpoints {
+		r.clearExpiredFailures(e)
+	}
+
+	zone, strict := r.preferredZone(r.pool, r.localAvailabilityZone, r.locallyOptimistic, attempt)
+	if subset := r.subset(attempt); subset != nil {
+		if e := r.pick(zone, subset); e != nil {
+			return e
+		}
+		// could not find a valid endpoint in the preferred subset, consider all endpoints
+	}
+
+	if zone != "" && !strict {
+		if e := r.pick(zone, nil); e != nil {
+			return e
+		}
+		// could not find a valid endpoint in the same AZ, consider all AZs
+		zone = ""
+	}
+
+	if e := r.pick(zone, nil); e != nil {
+		return e
+	}
+
+	if r.allEndpointsAreOverloaded() {
+		return nil
+	}
+
+	// all endpoints were either tried or marked failed, so reset everything to available
+	r.pool.resetFailures()
+	clear(r.tried)
+
+	return r.pick(zone, nil)
+}
+
+// pick selects the eligible endpoint with the highest current weight and
+// lowers its current weight by the total weight of all eligible endpoints.
+// The pool lock must be held when calling this function.
+func (r *WeightedRoundRobin) pick(zone string, subset *endpointSubset) *endpointElem {
+	var selected *endpointElem
+	var totalWeight int64
+
+	for _, e := range r.pool.endpoints {
+		if e.isFailed() || e.isOverloaded() || r.pool.isUnhealthy(e) {
+			continue
+		}
+		if _, found := r.tried[e]; found {
+			continue
+		}
+		if zone != "" && e.endpoint.AvailabilityZone != zone {
+			continue
+		}
+		if !subset.contains(e) {
+			continue
+		}
+
+		weight := e.endpoint.weight()
+		e.currentWeight += weight
+		totalWeight += weight
+
+		if selected == nil || e.currentWeight > selected.currentWeight {
+			selected = e
+		}
+	}
+
+	if selected == nil {
+		return nil
+	}
+
+	selected.currentWeight -= totalWeight
+	if r.tried == nil {
+		r.tried = make(map[*endpointElem]struct{}, 1)
+	}
+	r.tried[selected] = struct{}{}
+
+	return selected
+}
+
+func (r *WeightedRoundRobin) clearExpiredFailures(e *endpointElem) {
+	r.pool.clearExpiredFailure(e, time.Now())
+}
+
+func (r *WeightedRoundRobin) allEndpointsAreOverloaded() bool {
+	for _, e := range r.pool.endpoints {
+		if !e.isOverloaded() {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *WeightedRoundRobin) EndpointFailed(err error) {
+	if r.lastEndpoint != nil {
+		r.pool.EndpointFailed(r.lastEndpoint, err)
+	}
+}
+
+func (r *WeightedRoundRobin) PreRequest(e *Endpoint) {
+	e.Stats.NumberConnections.Increment()
+}
+
+func (r *WeightedRoundRobin) PostRequest(e *Endpoint) {
+	e.Stats.NumberConnections.Decrement()
+	r.pool.connectionReleased()
+}
@@ -0,0 +1,252 @@
+package route_test
+
+import (
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("WeightedRoundRobin", func() {
+	var (
+		pool   *route.EndpointPool
+		logger *test_util.TestLogger
+	)
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+		pool = route.NewPool(&route.PoolOpts{
+			Logger:             logger.Logger,
+			RetryAfterFailure:  2 * time.Minute,
+			Host:               "",
+			ContextPath:        "",
+			MaxConnsPerBackend: 0,
+		})
+	})
+
+	Describe("Next", func() {
+		It("returns nil when no endpoints exist", func() {
+			iter := route.NewWeightedRoundRobin(logger.Logger, pool, "", false, false, "meow-az")
+			Expect(iter.Next(0)).To(BeNil())
+		})
+
+		It("distributes requests in proportion to the endpoint weights", func() {
+			e1 := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, Weight: 5})
+			e2 := route.NewEndpoint(&route.EndpointOpts{Host: "5.6.7.8", Port: 1234, Weight: 3})
+			e3 := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.7.8", Port: 1234, Weight: 2})
+			pool.Put(e1)
+			pool.Put(e2)
+			pool.Put(e3)
+
+			counts := map[*route.Endpoint]int{}
+			for i := 0; i < 100; i++ {
+				iter := route.NewWeightedRoundRobin(logger.Logger, pool, "", false, false, "meow-az")
+				counts[iter.Next(0)]++
+			}
+
+			Expect(counts[e1]).To(Equal(50))
+			Expect(counts[e2]).To(Equal(30))
+			Expect(counts[e3]).To(Equal(20))
+		})
+
+		It("treats endpoints without a weight as having a weight of 1", func() {
+			e1 := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678})
+			e2 := route.NewEndpoint(&route.EndpointOpts{Host: "5.6.7.8", Port: 1234, Weight: 3})
+			pool.Put(e1)
+			pool.Put(e2)
+
+			counts := map[*route.Endpoint]int{}
+			for i := 0; i < 40; i++ {
+				iter := route.NewWeightedRoundRobin(logger.Logger, pool, "", false, false, "meow-az")
+				counts[iter.Next(0)]++
+			}
+
+			Expect(counts[e1]).To(Equal(10))
+			Expect(counts[e2]).To(Equal(30))
+		})
+
+		It("interleaves heavier endpoints with lighter ones", func() {
+			e1 := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, Weight: 2})
+			e2 := route.NewEndpoint(&route.EndpointOpts{Host: "5.6.7.8", Port: 1234, Weight: 1})
+			pool.Put(e1)
+			pool.Put(e2)
+
+			var picked []*route.Endpoint
+			for i := 0; i < 6; i++ {
+				iter := route.NewWeightedRoundRobin(logger.Logger, pool, "", false, false, "meow-az")
+				picked = append(picked, iter.Next(0))
+			}
+
+			Expect(picked).To(Equal([]*route.Endpoint{e1, e2, e1, e1, e2, e1}))
+		})
+
+		It("does not return the same endpoint twice before all endpoints have been tried", func() {
+			e1 := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, Weight: 10})
+			e2 := route.NewEndpoint(&route.EndpointOpts{Host: "5.6.7.8", Port: 1234, Weight: 1})
+			e3 := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.7.8", Port: 1234, Weight: 1})
+			pool.Put(e1)
+			pool.Put(e2)
+			pool.Put(e3)
+
+			iter := route.NewWeightedRoundRobin(logger.Logger, pool, "", false, false, "meow-az")
+			Expect([]*route.Endpoint{iter.Next(0), iter.Next(1), iter.Next(2)}).To(ConsistOf(e1, e2, e3))
+		})
+
+		Context("when an endpoint has failed", func() {
+			It("skips the failed endpoint until the failure expires", func() {
+				e1 := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, Weight: 10})
+				e2 := route.NewEndpoint(&route.EndpointOpts{Host: "5.6.7.8", Port: 1234, Weight: 1})
+				pool.Put(e1)
+				pool.Put(e2)
+
+				iter := route.NewWeightedRoundRobin(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).To(Equal(e1))
+				iter.EndpointFailed(&net.OpError{Op: "dial"})
+
+				for i := 0; i < 10; i++ {
+					iter = route.NewWeightedRoundRobin(logger.Logger, pool, "", false, false, "meow-az")
+					Expect(iter.Next(0)).To(Equal(e2))
+				}
+			})
+
+			It("resets the failures when all endpoints have failed", func() {
+				e1 := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, Weight: 10})
+				pool.Put(e1)
+
+				iter := route.NewWeightedRoundRobin(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).To(Equal(e1))
+				iter.EndpointFailed(&net.OpError{Op: "dial"})
+
+				Expect(iter.Next(1)).To(Equal(e1))
+			})
+		})
+
+		Context("when some endpoints are overloaded", func() {
+			var epOne, epTwo *route.Endpoint
+
+			BeforeEach(func() {
+				pool = route.NewPool(&route.PoolOpts{
+					Logger:             logger.Logger,
+					RetryAfterFailure:  2 * time.Minute,
+					MaxConnsPerBackend: 1,
+				})
+				epOne = route.NewEndpoint(&route.EndpointOpts{Host: "5.5.5.5", Port: 5555, PrivateInstanceId: "private-label-1", Weight: 10})
+				pool.Put(epOne)
+				epTwo = route.NewEndpoint(&route.EndpointOpts{Host: "2.2.2.2", Port: 2222, PrivateInstanceId: "private-label-2", Weight: 1})
+				pool.Put(epTwo)
+			})
+
+			It("returns an unencumbered endpoint", func() {
+				epOne.Stats.NumberConnections.Increment()
+
+				iter := route.NewWeightedRoundRobin(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).To(Equal(epTwo))
+				Expect(iter.Next(1)).To(Equal(epTwo))
+			})
+
+			It("returns nil when all endpoints are overloaded", func() {
+				epOne.Stats.NumberConnections.Increment()
+				epTwo.Stats.NumberConnections.Increment()
+
+				iter := route.NewWeightedRoundRobin(logger.Logger, pool, "", false, false, "meow-az")
+				Expect(iter.Next(0)).To(BeNil())
+			})
+
+			Context("when the initial endpoint is overloaded and must be sticky", func() {
+				It("returns nil", func() {
+					epTwo.Stats.NumberConnections.Increment()
+
+					iter := route.NewWeightedRoundRobin(logger.Logger, pool, "private-label-2", true, false, "meow-az")
+					Expect(iter.Next(0)).To(BeNil())
+					Expect(logger).Should(gbytes.Say("endpoint-overloaded-but-request-must-be-sticky"))
+				})
+			})
+		})
+
+		Context("with an initial endpoint", func() {
+			var epOne, epTwo *route.Endpoint
+
+			BeforeEach(func() {
+				epOne = route.NewEndpoint(&route.EndpointOpts{Host: "5.5.5.5", Port: 5555, PrivateInstanceId: "private-label-1", Weight: 10})
+				pool.Put(epOne)
+				epTwo = route.NewEndpoint(&route.EndpointOpts{Host: "2.2.2.2", Port: 2222, PrivateInstanceId: "private-label-2", Weight: 1})
+				pool.Put(epTwo)
+			})
+
+			It("returns the initial endpoint regardless of its weight", func() {
+				for i := 0; i < 10; i++ {
+					iter := route.NewWeightedRoundRobin(logger.Logger, pool, "private-label-2", false, false, "meow-az")
+					Expect(iter.Next(0)).To(Equal(epTwo))
+				}
+			})
+
+			It("chooses another endpoint when the initial endpoint is not found", func() {
+				iter := route.NewWeightedRoundRobin(logger.Logger, pool, "bogus", false, false, "meow-az")
+				Expect(iter.Next(0)).NotTo(BeNil())
+				Expect(logger).Should(gbytes.Say("endpoint-missing-choosing-alternate"))
+			})
+
+			It("returns nil when the initial endpoint is not found and must be sticky", func() {
+				iter := route.NewWeightedRoundRobin(logger.Logger, pool, "bogus", true, false, "meow-az")
+				Expect(iter.Next(0)).To(BeNil())
+				Expect(logger).Should(gbytes.Say("endpoint-missing-but-request-must-be-sticky"))
+			})
+		})
+
+		Context("when locally-optimistic", func() {
+			var localAZ, otherAZ *route.Endpoint
+
+			BeforeEach(func() {
+				localAZ = route.NewEndpoint(&route.EndpointOpts{Host: "1.1.1.1", Port: 1111, AvailabilityZone: "meow-az", Weight: 1})
+				otherAZ = route.NewEndpoint(&route.EndpointOpts{Host: "2.2.2.2", Port: 2222, AvailabilityZone: "other-az", Weight: 10})
+				pool.Put(localAZ)
+				pool.Put(otherAZ)
+			})
+
+			It("prefers endpoints in the local AZ on the first attempt", func() {
+				for i := 0; i < 10; i++ {
+					iter := route.NewWeightedRoundRobin(logger.Logger, pool, "", false, true, "meow-az")
+					Expect(iter.Next(0)).To(Equal(localAZ))
+				}
+			})
+
+			It("considers all AZs on subsequent attempts", func() {
+				iter := route.NewWeightedRoundRobin(logger.Logger, pool, "", false, true, "meow-az")
+				Expect(iter.Next(0)).To(Equal(localAZ))
+				Expect(iter.Next(1)).To(Equal(otherAZ))
+			})
+
+			It("falls back to other AZs when no local endpoint is available", func() {
+				localAZ.Stats.NumberConnections.Increment()
+				pool = route.NewPool(&route.PoolOpts{
+					Logger:             logger.Logger,
+					RetryAfterFailure:  2 * time.Minute,
+					MaxConnsPerBackend: 1,
+				})
+				pool.Put(localAZ)
+				pool.Put(otherAZ)
+
+				iter := route.NewWeightedRoundRobin(logger.Logger, pool, "", false, true, "meow-az")
+				Expect(iter.Next(0)).To(Equal(otherAZ))
+			})
+		})
+	})
+
+	Describe("PreRequest and PostRequest", func() {
+		It("increments and decrements the number of connections", func() {
+			e := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678})
+			pool.Put(e)
+
+			iter := route.NewWeightedRoundRobin(logger.Logger, pool, "", false, false, "meow-az")
+			iter.PreRequest(e)
+			Expect(e.Stats.NumberConnections.Count()).To(Equal(int64(1)))
+			iter.PostRequest(e)
+			Expect(e.Stats.NumberConnections.Count()).To(Equal(int64(0)))
+		})
+	})
+})
@@ -0,0 +1,134 @@
+package route
+
+import (
+	"code.cloudfoundry.org/gorouter/config"
+)
+
+// preferredZone returns the availability zone whose endpoints an iterator
+// prefers for the given attempt, or "" if all zones are equally preferred. If
+// strict is set, the iterator must not fall back to the endpoints of other
+// zones when none of the preferred endpoints is available. The pool lock must
+// be held when calling this function.
+func (f *endpointFilter) preferredZone(p *EndpointPool, localZone string, locallyOptimistic bool, attempt int) (zone string, strict bool) {
+	switch f.azPreference {
+	case config.AZ_PREF_LOCAL_ONLY:
+		if localZone == "" || p.localZoneFailedOver(localZone) {
+			return "", false
+		}
+		return localZone, true
+	case config.AZ_PREF_ZONE_AWARE:
+		if localZone == "" || attempt > 0 {
+			return "", false
+		}
+		return p.zoneAwareZone(localZone), false
+	}
+
+	if locallyOptimistic && attempt == 0 {
+		return localZone, false
+	}
+	return "", false
+}
+
+// zoneCounts holds the number of endpoints of a pool in an availability zone
+// and how many of them are failed, ejected or unhealthy. The counts are kept
+// up to date as endpoints change so that iterators need not scan the pool.
+type zoneCounts struct {
+	endpoints int
+	down      int
+}
+
+// addToZone counts e in the zone of its endpoint. The pool lock must be held
+// when calling this function.
+func (p *EndpointPool) addToZone(e *endpointElem) {
+	zone := e.endpoint.AvailabilityZone
+	z := p.zones[zone]
+	if z == nil {
+		z = &zoneCounts{}
+		p.zones[zone] = z
+	}
+	z.endpoints++
+	e.zone = z
+	e.down = e.isFailed() || e.unhealthy
+	if e.down {
+		z.down++
+	}
+}
+
+// removeFromZone stops counting e in its zone. The pool lock must be held
+// when calling this function.
+func (p *EndpointPool) removeFromZone(e *endpointElem) {
+	z := e.zone
+	if z == nil {
+		return
+	}
+	z.endpoints--
+	if e.down {
+		z.down--
+	}
+	if z.endpoints == 0 {
+		delete(p.zones, e.endpoint.AvailabilityZone)
+	}
+	e.zone = nil
+}
+
+// updateZone recounts e in its zone after it failed, was ejected or changed
+// its health, or became eligible again. The pool lock must be held when
+// calling this function.
+func (p *EndpointPool) updateZone(e *endpointElem) {
+	down := e.isFailed() || e.unhealthy
+	if e.zone == nil || e.down == down {
+		return
+	}
+	e.down = down
+	if down {
+		e.zone.down++
+	} else {
+		e.zone.down--
+	}
+}
+
+// localZoneFailedOver reports whether so many endpoints in the local zone
+// failed that the local-only-with-failover preference considers all zones. The
+// pool lock must be held when calling this function.
+func (p *EndpointPool) localZoneFailedOver(localZone string) bool {
+	z := p.zones[localZone]
+	return z == nil || z.down*100 >= p.azFailoverPercent*z.endpoints
+}
+
+// zoneAwareZone picks the zone a request is sent to so that, provided the
+// routers are spread evenly across the zones, each zone receives a share of
+// the requests proportional to its number of endpoints. A router keeps as
+// many requests in its own zone as the local endpoints can serve and spreads
+// the rest over the zones which have more than their share of endpoints.
+// It returns "" if the zones need not be balanced. The pool lock must be held
+// when calling this function.
+func (p *EndpointPool) zoneAwareZone(localZone string) string {
+	local := p.zones[localZone]
+	if local == nil || len(p.zones) == 1 {
+		return ""
+	}
+
+	total := float64(len(p.endpoints))
+	fairShare := 1 / float64(len(p.zones))
+	localShare := float64(local.endpoints) / total
+	if localShare >= fairShare || p.random.Float64() < localShare/fairShare {
+		return localZone
+	}
+
+	var residual float64
+	for _, z := range p.zones {
+		residual += max(0, float64(z.endpoints)/total-fairShare)
+	}
+	pick := p.random.Float64() * residual
+	for zone, z := range p.zones {
+		excess := float64(z.endpoints)/total - fairShare
+		if excess <= 0 {
+			continue
+		}
+		if pick < excess {
+			return zone
+		}
+		pick -= excess
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package route_test
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("AZ preferences", func() {
+	var (
+		logger *test_util.TestLogger
+		pool   *route.EndpointPool
+	)
+
+	newPool := func(algorithm string, failoverPercent int, zones ...string) []*route.Endpoint {
+		pool = route.NewPool(&route.PoolOpts{
+			Logger:                 logger.Logger,
+			RetryAfterFailure:      2 * time.Minute,
+			MaxConnsPerBackend:     1,
+			LoadBalancingAlgorithm: algorithm,
+			AZFailoverPercent:      failoverPercent,
+		})
+		var endpoints []*route.Endpoint
+		for i, zone := range zones {
+			e := route.NewEndpoint(&route.EndpointOpts{
+				Host:             fmt.Sprintf("10.0.0.%d", i+1),
+				Port:             8080,
+				AvailabilityZone: zone,
+			})
+			pool.Put(e)
+			endpoints = append(endpoints, e)
+		}
+		return endpoints
+	}
+
+	selectedZones := func(azPreference string, attempt int, n int) map[string]int {
+		zones := make(map[string]int)
+		for i := 0; i < n; i++ {
+			iter := pool.Endpoints(logger.Logger, "", false, azPreference, "z1", fmt.Sprintf("key-%d", i), nil)
+			e := iter.Next(attempt)
+			Expect(e).NotTo(BeNil())
+			zones[e.AvailabilityZone]++
+		}
+		return zones
+	}
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+	})
+
+	for _, algorithm := range config.LoadBalancingStrategies {
+		algorithm := algorithm
+		Context(fmt.Sprintf("with the %s algorithm", algorithm), func() {
+			Describe("local-only-with-failover", func() {
+				It("only selects local endpoints, also when retrying", func() {
+					newPool(algorithm, 50, "z1", "z1", "z2")
+					Expect(selectedZones(config.AZ_PREF_LOCAL_ONLY, 0, 20)).To(Equal(map[string]int{"z1": 20}))
+					Expect(selectedZones(config.AZ_PREF_LOCAL_ONLY, 2, 20)).To(Equal(map[string]int{"z1": 20}))
+				})
+
+				It("stays in the local zone while fewer local endpoints than the failover percentage failed", func() {
+					endpoints := newPool(algorithm, 75, "z1", "z1", "z2")
+					pool.EndpointFailed(endpoints[0], &net.OpError{Op: "dial"})
+					Expect(selectedZones(config.AZ_PREF_LOCAL_ONLY, 0, 20)).To(Equal(map[string]int{"z1": 20}))
+				})
+
+				It("does not select endpoints in other zones when the local endpoints are overloaded", func() {
+					endpoints := newPool(algorithm, 50, "z1", "z1", "z2")
+					endpoints[0].Stats.NumberConnections.Increment()
+					endpoints[1].Stats.NumberConnections.Increment()
+					iter := pool.Endpoints(logger.Logger, "", false, config.AZ_PREF_LOCAL_ONLY, "z1", "some-key", nil)
+					Expect(iter.Next(0)).To(BeNil())
+				})
+
+				It("fails over to all zones once the failover percentage of local endpoints failed", func() {
+					endpoints := newPool(algorithm, 50, "z1", "z1", "z2")
+					pool.EndpointFailed(endpoints[0], &net.OpError{Op: "dial"})
+					Expect(selectedZones(config.AZ_PREF_LOCAL_ONLY, 0, 50)).To(HaveKey("z2"))
+				})
+
+				It("fails over to all zones if there are no local endpoints", func() {
+					newPool(algorithm, 50, "z2", "z3")
+					Expect(selectedZones(config.AZ_PREF_LOCAL_ONLY, 0, 10)).NotTo(HaveKey("z1"))
+				})
+
+				It("fails over while the local endpoints are unhealthy and returns once they are healthy", func() {
+					endpoints := newPool(algorithm, 50, "z1", "z1", "z2")
+					pool.SetEndpointHealthy(endpoints[0], false)
+					Expect(selectedZones(config.AZ_PREF_LOCAL_ONLY, 0, 50)).To(HaveKey("z2"))
+
+					pool.SetEndpointHealthy(endpoints[0], true)
+					Expect(selectedZones(config.AZ_PREF_LOCAL_ONLY, 0, 20)).To(Equal(map[string]int{"z1": 20}))
+				})
+
+				It("fails over to all zones once the local endpoints are removed", func() {
+					endpoints := newPool(algorithm, 50, "z1", "z2")
+					Expect(pool.Remove(endpoints[0])).To(BeTrue())
+					Expect(selectedZones(config.AZ_PREF_LOCAL_ONLY, 0, 10)).To(Equal(map[string]int{"z2": 10}))
+				})
+			})
+
+			Describe("zone-aware", func() {
+				It("keeps all requests local if the local zone has at least its share of endpoints", func() {
+					newPool(algorithm, 50, "z1", "z1", "z1", "z2")
+					Expect(selectedZones(config.AZ_PREF_ZONE_AWARE, 0, 50)).To(Equal(map[string]int{"z1": 50}))
+				})
+
+				It("sends the requests the local endpoints cannot serve to the zones with more than their share", func() {
+					newPool(algorithm, 50, "z1", "z2", "z3", "z3")
+					zones := selectedZones(config.AZ_PREF_ZONE_AWARE, 0, 1000)
+					Expect(zones).NotTo(HaveKey("z2"))
+					Expect(zones["z1"]).To(BeNumerically("~", 750, 100))
+					Expect(zones["z3"]).To(BeNumerically("~", 250, 100))
+				})
+
+				It("balances the zones again when an endpoint moves to another zone", func() {
+					newPool(algorithm, 50, "z1", "z1", "z2", "z2")
+					pool.Put(route.NewEndpoint(&route.EndpointOpts{
+						Host:             "10.0.0.2",
+						Port:             8080,
+						AvailabilityZone: "z2",
+					}))
+					zones := selectedZones(config.AZ_PREF_ZONE_AWARE, 0, 1000)
+					Expect(zones["z1"]).To(BeNumerically("~", 500, 100))
+					Expect(zones["z2"]).To(BeNumerically("~", 500, 100))
+				})
+
+				It("spreads the requests in proportion to the endpoints of each zone", func() {
+					newPool(algorithm, 50, "z1", "z2", "z2", "z2")
+					zones := selectedZones(config.AZ_PREF_ZONE_AWARE, 0, 1000)
+					Expect(zones["z1"]).To(BeNumerically("~", 500, 100))
+					Expect(zones["z2"]).To(BeNumerically("~", 500, 100))
+				})
+			})
+		})
+	}
+})
@@ -16,18 +16,26 @@ import (
 	log "code.cloudfoundry.org/gorouter/logger"
 	"code.cloudfoundry.org/gorouter/registry"
 	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/routesource"
 	routing_api "code.cloudfoundry.org/routing-api"
 	"code.cloudfoundry.org/routing-api/models"
 	"code.cloudfoundry.org/routing-api/uaaclient"
 )
 
+// RouteFetcher registers the HTTP routes of the routing API. Routing API routes
+// have no weight, so their endpoints always get the default weight of 1 from
+// the weighted-round-robin algorithm; weights can only be set over NATS.
 type RouteFetcher struct {
-	UaaTokenFetcher           uaaclient.TokenFetcher
+	UaaTokenFetcher uaaclient.TokenFetcher
+	// Deprecated: RouteRegistry is no longer used. Routes are registered in the
+	// registry passed to NewRouteFetcher through the route source handler of
+	// the fetcher.
 	RouteRegistry             registry.Registry
 	FetchRoutesInterval       time.Duration
 	SubscriptionRetryInterval time.Duration
 
 	logger          *slog.Logger
+	routes          *routesource.Handler
 	endpoints       []models.Route
 	endpointsMutex  sync.Mutex
 	client          routing_api.Client
@@ -61,11 +69,19 @@ func NewRouteFetcher(
 
 		client:       client,
 		logger:       logger,
+		routes:       routesource.NewHandler("routing-api", routeRegistry),
 		eventChannel: make(chan routing_api.Event, 1024),
 		clock:        clock,
 	}
 }
 
+var _ routesource.RouteSource = &RouteFetcher{}
+
+// Name identifies the routing API as the route source of the fetcher.
+func (r *RouteFetcher) Name() string {
+	return r.routes.Source()
+}
+
 func (r *RouteFetcher) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 	r.startEventCycle()
 
@@ -190,9 +206,9 @@ func (r *RouteFetcher) HandleEvent(e routing_api.Event) {
 	})
 	switch e.Action {
 	case "Delete":
-		r.RouteRegistry.Unregister(uri, endpoint)
+		r.routes.Handle(routesource.Event{Type: routesource.UnregisterEvent, Uri: uri, Endpoint: endpoint})
 	case "Upsert":
-		r.RouteRegistry.Register(uri, endpoint)
+		r.routes.Handle(routesource.Event{Type: routesource.RegisterEvent, Uri: uri, Endpoint: endpoint})
 	}
 }
 
@@ -265,9 +281,10 @@ func (r *RouteFetcher) refreshEndpoints(validRoutes []models.Route) {
 	r.setEndpoints(validRoutes)
 
 	for _, aRoute := range validRoutes {
-		r.RouteRegistry.Register(
-			route.Uri(aRoute.Route),
-			route.NewEndpoint(&route.EndpointOpts{
+		r.routes.Handle(routesource.Event{
+			Type: routesource.RegisterEvent,
+			Uri:  route.Uri(aRoute.Route),
+			Endpoint: route.NewEndpoint(&route.EndpointOpts{
 				AppId:                   aRoute.LogGuid,
 				Host:                    aRoute.IP,
 				Port:                    uint16(aRoute.Port),
@@ -277,7 +294,7 @@ func (r *RouteFetcher) refreshEndpoints(validRoutes []models.Route) {
 				ModificationTag:         aRoute.ModificationTag,
 				UseTLS:                  false,
 			}),
-		)
+		})
 	}
 }
 
@@ -300,9 +317,10 @@ func (r *RouteFetcher) deleteEndpoints(validRoutes []models.Route) {
 	}
 
 	for _, aRoute := range diff {
-		r.RouteRegistry.Unregister(
-			route.Uri(aRoute.Route),
-			route.NewEndpoint(&route.EndpointOpts{
+		r.routes.Handle(routesource.Event{
+			Type: routesource.UnregisterEvent,
+			Uri:  route.Uri(aRoute.Route),
+			Endpoint: route.NewEndpoint(&route.EndpointOpts{
 				AppId:                   aRoute.LogGuid,
 				Host:                    aRoute.IP,
 				Port:                    uint16(aRoute.Port),
@@ -312,7 +330,7 @@ func (r *RouteFetcher) deleteEndpoints(validRoutes []models.Route) {
 				ModificationTag:         aRoute.ModificationTag,
 				UseTLS:                  false,
 			}),
-		)
+		})
 	}
 }
 
@@ -146,6 +146,7 @@ var _ = Describe("RouteFetcher", func() {
 				Expect(uri).To(Equal(route.Uri(expectedRoute.Route)))
 				Expect(endpoint).To(Equal(
 					route.NewEndpoint(&route.EndpointOpts{
+						Source:                  "routing-api",
 						AppId:                   expectedRoute.LogGuid,
 						Host:                    expectedRoute.IP,
 						Port:                    uint16(expectedRoute.Port),
@@ -231,6 +232,7 @@ var _ = Describe("RouteFetcher", func() {
 				Expect(uri).To(Equal(route.Uri(expectedRoute.Route)))
 				Expect(endpoint).To(Equal(
 					route.NewEndpoint(&route.EndpointOpts{
+						Source:                  "routing-api",
 						AppId:                   expectedRoute.LogGuid,
 						Host:                    expectedRoute.IP,
 						Port:                    uint16(expectedRoute.Port),
@@ -460,6 +462,7 @@ var _ = Describe("RouteFetcher", func() {
 				Expect(uri).To(Equal(route.Uri(eventRoute.Route)))
 				Expect(endpoint).To(Equal(
 					route.NewEndpoint(&route.EndpointOpts{
+						Source:                  "routing-api",
 						AppId:                   eventRoute.LogGuid,
 						Host:                    eventRoute.IP,
 						Port:                    uint16(eventRoute.Port),
@@ -468,7 +471,7 @@ var _ = Describe("RouteFetcher", func() {
 						RouteServiceUrl:         eventRoute.RouteServiceUrl,
 						ModificationTag:         eventRoute.ModificationTag,
 					})))
-
+				Expect(endpoint.Weight).To(BeZero())
 			})
 		})
 
@@ -494,6 +497,7 @@ var _ = Describe("RouteFetcher", func() {
 				Expect(uri).To(Equal(route.Uri(eventRoute.Route)))
 				Expect(endpoint).To(Equal(
 					route.NewEndpoint(&route.EndpointOpts{
+						Source:                  "routing-api",
 						AppId:                   eventRoute.LogGuid,
 						Host:                    eventRoute.IP,
 						Port:                    uint16(eventRoute.Port),
@@ -96,6 +96,9 @@ func NewRouter(
 	routesListener := &RoutesListener{
 		Config:        cfg,
 		RouteRegistry: r,
+		Pools:         r,
+		Routes:        r,
+		Changes:       r,
 	}
 	if err := routesListener.ListenAndServe(); err != nil {
 		return nil, err
@@ -197,6 +200,7 @@ const MAX_HEADER_BYTES = 1024 * 1024
 
 func (r *Router) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 	r.registry.StartPruningCycle()
+	r.registry.StartSnapshotCycle()
 
 	err := r.RegisterComponent()
 	if err != nil {
@@ -453,6 +457,7 @@ func (r *Router) Stop() {
 		r.healthTLSListener.Stop()
 	}
 	r.uptimeMonitor.Stop()
+	r.registry.StopSnapshotCycle()
 	r.logger.Info(
 		"gorouter.stopped",
 		slog.Duration("took", time.Since(stoppingAt)),
@@ -3,32 +3,78 @@ package router
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	common "code.cloudfoundry.org/gorouter/common/http"
 	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
 )
 
+// PoolLookup finds the pool of endpoints serving a route.
+type PoolLookup interface {
+	Lookup(uri route.Uri) *route.EndpointPool
+}
+
+// RouteQuery finds the routes and endpoints matching a filter.
+type RouteQuery interface {
+	Routes(filter registry.RouteFilter) []registry.RouteEndpoints
+}
+
+// ChangeFeed streams the changes of the routing table.
+type ChangeFeed interface {
+	SubscribeChanges(after uint64) *registry.ChangeSubscription
+}
+
+// changeFeedKeepAliveInterval is the interval at which comments are sent to
+// idle clients of the change feed, so that their connections are kept open.
+const changeFeedKeepAliveInterval = 15 * time.Second
+
 type RoutesListener struct {
 	Config        *config.Config
 	RouteRegistry json.Marshaler
+	// Pools is used to change the traffic split of routes and to match urls to
+	// routes. The traffic split and match endpoints are only served if it is
+	// set.
+	Pools PoolLookup
+	// Routes is used to filter the routes and to list them page by page. All
+	// routes are returned if it is not set.
+	Routes RouteQuery
+	// Changes is used to stream the changes of the routing table. The change
+	// feed is only served if it is set.
+	Changes ChangeFeed
 
 	listener net.Listener
 }
 
+type trafficSplit struct {
+	Route   string         `json:"route"`
+	Weights map[string]int `json:"weights"`
+}
+
+type routeMatch struct {
+	Route                  string              `json:"route"`
+	RouteServiceUrl        string              `json:"route_service_url,omitempty"`
+	LoadBalancingAlgorithm string              `json:"load_balancing_algorithm"`
+	Endpoints              *route.EndpointPool `json:"endpoints"`
+}
+
 func (rl *RoutesListener) ListenAndServe() error {
 	hs := http.NewServeMux()
-	hs.HandleFunc("/routes", func(w http.ResponseWriter, req *http.Request) {
-		w.Header().Set("Connection", "close")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-
-		enc := json.NewEncoder(w)
-		// #nosec G104 - ignore errors when writing HTTP responses so we don't spam our logs during a DoS
-		enc.Encode(rl.RouteRegistry)
-	})
+	hs.HandleFunc("/routes", rl.serveRoutes)
+	if rl.Pools != nil {
+		hs.HandleFunc("/routes/traffic_split", rl.serveTrafficSplit)
+		hs.HandleFunc("/routes/match", rl.serveMatch)
+	}
+	if rl.Changes != nil {
+		hs.HandleFunc("/routes/changes", rl.serveChanges)
+	}
 
 	f := func(user, password string) bool {
 		return user == rl.Config.Status.User && password == rl.Config.Status.Pass
@@ -54,6 +100,259 @@ func (rl *RoutesListener) ListenAndServe() error {
 	return nil
 }
 
+// serveRoutes returns the routes with their endpoints. The routes can be
+// filtered by the host_prefix, app_id, isolation_segment and address query
+// parameters, and listed page by page with the offset and limit query
+// parameters, in which case the number of matching routes is returned in the
+// X-Total-Count header.
+func (rl *RoutesListener) serveRoutes(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Connection", "close")
+
+	query := req.URL.Query()
+	filter := registry.RouteFilter{
+		HostPrefix:       query.Get("host_prefix"),
+		AppId:            query.Get("app_id"),
+		IsolationSegment: query.Get("isolation_segment"),
+		Address:          query.Get("address"),
+	}
+	paginated := query.Has("offset") || query.Has("limit")
+
+	if rl.Routes == nil || (filter.IsEmpty() && !paginated) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		// #nosec G104 - ignore errors when writing HTTP responses so we don't spam our logs during a DoS
+		json.NewEncoder(w).Encode(rl.RouteRegistry)
+		return
+	}
+
+	offset, err := nonNegativeQueryParam(query, "offset")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := nonNegativeQueryParam(query, "limit")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	routes := rl.Routes.Routes(filter)
+	total := len(routes)
+	routes = routes[min(offset, total):]
+	if limit > 0 && limit < len(routes) {
+		routes = routes[:limit]
+	}
+
+	byURI := make(map[route.Uri][]json.Marshaler, len(routes))
+	for _, r := range routes {
+		byURI[r.Uri] = r.Endpoints
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	w.WriteHeader(http.StatusOK)
+	// #nosec G104 - ignore errors when writing HTTP responses so we don't spam our logs during a DoS
+	json.NewEncoder(w).Encode(byURI)
+}
+
+// nonNegativeQueryParam returns the value of an optional integer query
+// parameter, or zero if it is not set.
+func nonNegativeQueryParam(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", name, value)
+	}
+	return n, nil
+}
+
+// serveMatch returns the route, route service and endpoints which a request
+// for the url query parameter would be routed to.
+func (rl *RoutesListener) serveMatch(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Connection", "close")
+
+	target := req.URL.Query().Get("url")
+	if target == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Hostname() == "" {
+		http.Error(w, fmt.Sprintf("invalid url %s", req.URL.Query().Get("url")), http.StatusBadRequest)
+		return
+	}
+
+	pool := rl.Pools.Lookup(route.Uri(u.Hostname() + u.EscapedPath()))
+	if pool == nil {
+		http.Error(w, fmt.Sprintf("no route matches %s", req.URL.Query().Get("url")), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// #nosec G104 - ignore errors when writing HTTP responses so we don't spam our logs during a DoS
+	json.NewEncoder(w).Encode(routeMatch{
+		Route:                  route.Uri(pool.Host() + pool.ContextPath()).String(),
+		RouteServiceUrl:        pool.RouteServiceUrl(),
+		LoadBalancingAlgorithm: pool.LoadBalancingAlgorithm,
+		Endpoints:              pool,
+	})
+}
+
+// serveChanges streams the changes of the routing table as server-sent events,
+// until the client disconnects or falls too far behind. Clients resume from
+// the buffered changes after the sequence number in the Last-Event-ID header
+// or the since query parameter. The changes can be filtered by the host and
+// app_id query parameters.
+func (rl *RoutesListener) serveChanges(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	since := req.Header.Get("Last-Event-ID")
+	if since == "" {
+		since = query.Get("since")
+	}
+	var after uint64
+	if since != "" {
+		var err error
+		after, err = strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid sequence number: %q", since), http.StatusBadRequest)
+			return
+		}
+	}
+	host := query.Get("host")
+	appID := query.Get("app_id")
+
+	// the write timeout of the listener would end the stream
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub := rl.Changes.SubscribeChanges(after)
+	defer sub.Cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if sub.Truncated {
+		// #nosec G104 - ignore errors when writing HTTP responses so we don't spam our logs during a DoS
+		io.WriteString(w, "event: truncated\ndata: {}\n\n")
+	}
+	for _, c := range sub.Buffered {
+		if changeMatches(c, host, appID) {
+			if err := writeChange(w, c); err != nil {
+				return
+			}
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(changeFeedKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case c, ok := <-sub.Changes:
+			if !ok {
+				return
+			}
+			if !changeMatches(c, host, appID) {
+				continue
+			}
+			if err := writeChange(w, c); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-req.Context().Done():
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// changeMatches returns true if the change is of a route of the host and of an
+// endpoint of the app, where empty values match everything.
+func changeMatches(c registry.RouteChange, host, appID string) bool {
+	if host != "" {
+		routeHost, _, _ := strings.Cut(string(c.Uri), "/")
+		if !strings.EqualFold(routeHost, host) {
+			return false
+		}
+	}
+	return appID == "" || c.AppId == appID
+}
+
+func writeChange(w io.Writer, c registry.RouteChange) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", c.Seq, c.Type, data)
+	return err
+}
+
+// serveTrafficSplit returns the traffic weights of the apps of a route on GET
+// and overrides them on PUT. An empty set of weights on PUT restores the
+// weights registered by the apps.
+func (rl *RoutesListener) serveTrafficSplit(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Connection", "close")
+
+	var split trafficSplit
+	switch req.Method {
+	case http.MethodGet:
+		split.Route = req.URL.Query().Get("route")
+	case http.MethodPut:
+		if err := json.NewDecoder(req.Body).Decode(&split); err != nil {
+			http.Error(w, fmt.Sprintf("invalid traffic split: %s", err), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if split.Route == "" {
+		http.Error(w, "route is required", http.StatusBadRequest)
+		return
+	}
+
+	pool := rl.Pools.Lookup(route.Uri(split.Route))
+	if pool == nil {
+		http.Error(w, fmt.Sprintf("route %s not found", split.Route), http.StatusNotFound)
+		return
+	}
+
+	if req.Method == http.MethodPut {
+		if err := pool.SetAppWeights(split.Weights); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// #nosec G104 - ignore errors when writing HTTP responses so we don't spam our logs during a DoS
+	json.NewEncoder(w).Encode(trafficSplit{
+		Route:   route.Uri(pool.Host() + pool.ContextPath()).String(),
+		Weights: pool.AppWeights(),
+	})
+}
+
 func (rl *RoutesListener) Stop() error {
 	if rl.listener != nil {
 		err := rl.listener.Close()
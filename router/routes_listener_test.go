@@ -1,19 +1,43 @@
 package router
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/metrics/fakes"
+	"code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
 	"code.cloudfoundry.org/gorouter/test_util"
 )
 
+type poolsByURI map[route.Uri]*route.EndpointPool
+
+func (p poolsByURI) Lookup(uri route.Uri) *route.EndpointPool {
+	return p[uri]
+}
+
+type routeQueryFunc func(filter registry.RouteFilter) []registry.RouteEndpoints
+
+func (f routeQueryFunc) Routes(filter registry.RouteFilter) []registry.RouteEndpoints {
+	return f(filter)
+}
+
+func newRouteRegistry() *registry.RouteRegistry {
+	cfg, err := config.DefaultConfig()
+	Expect(err).ToNot(HaveOccurred())
+	return registry.NewRouteRegistry(test_util.NewTestLogger("test").Logger, cfg, new(fakes.FakeMetricReporter))
+}
+
 type MarshalableValue struct {
 	Value map[string]string
 }
@@ -53,8 +77,6 @@ var _ = Describe("RoutesListener", func() {
 			Config:        cfg,
 			RouteRegistry: registry,
 		}
-		err := routesListener.ListenAndServe()
-		Expect(err).ToNot(HaveOccurred())
 	})
 
 	AfterEach(func() {
@@ -62,11 +84,26 @@ var _ = Describe("RoutesListener", func() {
 	})
 
 	JustBeforeEach(func() {
-		var err error
+		err := routesListener.ListenAndServe()
+		Expect(err).ToNot(HaveOccurred())
+
 		req, err = http.NewRequest("GET", fmt.Sprintf("http://%s:%d/routes", addr, port), nil)
 		Expect(err).ToNot(HaveOccurred())
 	})
 
+	doRequest := func(method, path, body string) (int, string) {
+		r, err := http.NewRequest(method, fmt.Sprintf("http://%s:%d%s", addr, port, path), strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		r.SetBasicAuth("test-user", "test-pass")
+
+		resp, err := http.DefaultClient.Do(r)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return resp.StatusCode, string(respBody)
+	}
+
 	It("returns the route list", func() {
 		req.SetBasicAuth("test-user", "test-pass")
 		resp, err := http.DefaultClient.Do(req)
@@ -132,4 +169,305 @@ var _ = Describe("RoutesListener", func() {
 			Expect(string(body)).To(Equal("401 Unauthorized\n"))
 		})
 	})
+
+	Context("filtering routes", func() {
+		var filters []registry.RouteFilter
+
+		endpoints := func(host string) []json.Marshaler {
+			return []json.Marshaler{route.NewEndpoint(&route.EndpointOpts{AppId: "app-1", Host: host, Port: 8080})}
+		}
+
+		BeforeEach(func() {
+			filters = nil
+			routesListener.Routes = routeQueryFunc(func(filter registry.RouteFilter) []registry.RouteEndpoints {
+				filters = append(filters, filter)
+				return []registry.RouteEndpoints{
+					{Uri: "a.example.com", Endpoints: endpoints("10.0.0.1")},
+					{Uri: "b.example.com", Endpoints: endpoints("10.0.0.2")},
+					{Uri: "c.example.com", Endpoints: endpoints("10.0.0.3")},
+				}
+			})
+		})
+
+		It("returns the route list without filters", func() {
+			status, body := doRequest("GET", "/routes", "")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(MatchJSON(`{"route1":"endpoint1"}`))
+			Expect(filters).To(BeEmpty())
+		})
+
+		It("returns the routes matching the filter", func() {
+			status, body := doRequest("GET", "/routes?host_prefix=a.&app_id=app-1&isolation_segment=iso&address=10.0.0.1", "")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(filters).To(Equal([]registry.RouteFilter{{
+				HostPrefix:       "a.",
+				AppId:            "app-1",
+				IsolationSegment: "iso",
+				Address:          "10.0.0.1",
+			}}))
+
+			var routes map[string][]map[string]any
+			Expect(json.Unmarshal([]byte(body), &routes)).To(Succeed())
+			Expect(routes).To(HaveLen(3))
+			Expect(routes["a.example.com"][0]["address"]).To(Equal("10.0.0.1:8080"))
+		})
+
+		It("returns a page of the routes", func() {
+			r, err := http.NewRequest("GET", fmt.Sprintf("http://%s:%d/routes?offset=1&limit=1", addr, port), nil)
+			Expect(err).ToNot(HaveOccurred())
+			r.SetBasicAuth("test-user", "test-pass")
+			resp, err := http.DefaultClient.Do(r)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("X-Total-Count")).To(Equal("3"))
+			var routes map[string]any
+			Expect(json.NewDecoder(resp.Body).Decode(&routes)).To(Succeed())
+			Expect(routes).To(HaveLen(1))
+			Expect(routes).To(HaveKey("b.example.com"))
+		})
+
+		It("returns no routes for an offset beyond the last route", func() {
+			status, body := doRequest("GET", "/routes?offset=5", "")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(MatchJSON(`{}`))
+		})
+
+		It("rejects an invalid limit", func() {
+			status, body := doRequest("GET", "/routes?limit=-1", "")
+			Expect(status).To(Equal(http.StatusBadRequest))
+			Expect(body).To(ContainSubstring(`invalid limit: "-1"`))
+		})
+	})
+
+	Context("matching urls", func() {
+		BeforeEach(func() {
+			pool := route.NewPool(&route.PoolOpts{
+				Logger:      test_util.NewTestLogger("test").Logger,
+				Host:        "app.example.com",
+				ContextPath: "/api",
+			})
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "10.0.0.1", Port: 8080, RouteServiceUrl: "https://rs.example.com"}))
+			routesListener.Pools = poolsByURI{"app.example.com/api": pool}
+		})
+
+		It("returns the route, route service and endpoints the url resolves to", func() {
+			status, body := doRequest("GET", "/routes/match?url="+url.QueryEscape("https://app.example.com:443/api"), "")
+			Expect(status).To(Equal(http.StatusOK))
+
+			var match map[string]any
+			Expect(json.Unmarshal([]byte(body), &match)).To(Succeed())
+			Expect(match["route"]).To(Equal("app.example.com/api"))
+			Expect(match["route_service_url"]).To(Equal("https://rs.example.com"))
+			Expect(match["endpoints"]).To(HaveLen(1))
+		})
+
+		It("accepts urls without a scheme", func() {
+			status, _ := doRequest("GET", "/routes/match?url=app.example.com/api", "")
+			Expect(status).To(Equal(http.StatusOK))
+		})
+
+		It("returns a 404 if no route matches", func() {
+			status, body := doRequest("GET", "/routes/match?url=unknown.example.com", "")
+			Expect(status).To(Equal(http.StatusNotFound))
+			Expect(body).To(ContainSubstring("no route matches unknown.example.com"))
+		})
+
+		It("requires a url", func() {
+			status, _ := doRequest("GET", "/routes/match", "")
+			Expect(status).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Context("traffic split", func() {
+		var pool *route.EndpointPool
+
+		BeforeEach(func() {
+			pool = route.NewPool(&route.PoolOpts{
+				Logger:      test_util.NewTestLogger("test").Logger,
+				Host:        "app.example.com",
+				ContextPath: "/",
+			})
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{AppId: "blue", Host: "10.0.0.1", Port: 8080, TrafficWeight: 90}))
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{AppId: "green", Host: "10.0.0.2", Port: 8080, TrafficWeight: 10}))
+			routesListener.Pools = poolsByURI{"app.example.com": pool}
+		})
+
+		It("returns the weights of a route", func() {
+			status, body := doRequest("GET", "/routes/traffic_split?route=app.example.com", "")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(MatchJSON(`{"route":"app.example.com","weights":{"blue":90,"green":10}}`))
+		})
+
+		It("overrides the weights of a route", func() {
+			status, body := doRequest("PUT", "/routes/traffic_split", `{"route":"app.example.com","weights":{"blue":50,"green":50}}`)
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(MatchJSON(`{"route":"app.example.com","weights":{"blue":50,"green":50}}`))
+			Expect(pool.AppWeights()).To(Equal(map[string]int{"blue": 50, "green": 50}))
+		})
+
+		It("rejects negative weights", func() {
+			status, body := doRequest("PUT", "/routes/traffic_split", `{"route":"app.example.com","weights":{"green":-10}}`)
+			Expect(status).To(Equal(http.StatusBadRequest))
+			Expect(body).To(ContainSubstring("must not be negative"))
+		})
+
+		It("rejects requests without a route", func() {
+			status, _ := doRequest("PUT", "/routes/traffic_split", `{"weights":{"green":10}}`)
+			Expect(status).To(Equal(http.StatusBadRequest))
+		})
+
+		It("returns a 404 for unknown routes", func() {
+			status, _ := doRequest("GET", "/routes/traffic_split?route=unknown.example.com", "")
+			Expect(status).To(Equal(http.StatusNotFound))
+		})
+
+		It("only allows GET and PUT", func() {
+			status, _ := doRequest("DELETE", "/routes/traffic_split", "")
+			Expect(status).To(Equal(http.StatusMethodNotAllowed))
+		})
+
+		Context("when no pools are set", func() {
+			BeforeEach(func() {
+				routesListener.Pools = nil
+			})
+
+			It("does not serve the traffic split", func() {
+				status, _ := doRequest("GET", "/routes/traffic_split?route=app.example.com", "")
+				Expect(status).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Context("streaming route changes", func() {
+		var routeRegistry *registry.RouteRegistry
+
+		openStream := func(path, lastEventID string) (*http.Response, *bufio.Reader) {
+			r, err := http.NewRequest("GET", fmt.Sprintf("http://%s:%d%s", addr, port, path), nil)
+			Expect(err).ToNot(HaveOccurred())
+			r.SetBasicAuth("test-user", "test-pass")
+			if lastEventID != "" {
+				r.Header.Set("Last-Event-ID", lastEventID)
+			}
+
+			resp, err := http.DefaultClient.Do(r)
+			Expect(err).ToNot(HaveOccurred())
+			DeferCleanup(resp.Body.Close)
+			return resp, bufio.NewReader(resp.Body)
+		}
+
+		// readEvent returns the lines of the next event, skipping comments
+		readEvent := func(events *bufio.Reader) []string {
+			var lines []string
+			for {
+				line, err := events.ReadString('\n')
+				Expect(err).ToNot(HaveOccurred())
+				line = strings.TrimSuffix(line, "\n")
+				switch {
+				case line == "" && len(lines) > 0:
+					return lines
+				case line == "" || strings.HasPrefix(line, ":"):
+				default:
+					lines = append(lines, line)
+				}
+			}
+		}
+
+		type change struct {
+			Seq      uint64 `json:"seq"`
+			Type     string `json:"type"`
+			Uri      string `json:"uri"`
+			AppId    string `json:"app_id"`
+			Endpoint struct {
+				Address string `json:"address"`
+			} `json:"endpoint"`
+		}
+
+		eventData := func(lines []string) change {
+			Expect(lines).To(HaveLen(3))
+			Expect(lines[2]).To(HavePrefix("data: "))
+			var c change
+			Expect(json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &c)).To(Succeed())
+			return c
+		}
+
+		BeforeEach(func() {
+			routeRegistry = newRouteRegistry()
+			routesListener.Changes = routeRegistry
+
+			routeRegistry.Register("foo.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-1", Host: "10.0.0.1", Port: 8080}))
+			routeRegistry.Register("bar.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-2", Host: "10.0.0.2", Port: 8080}))
+		})
+
+		It("streams the changes after the last event id", func() {
+			resp, events := openStream("/routes/changes", "1")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+			lines := readEvent(events)
+			Expect(lines[:2]).To(Equal([]string{"id: 2", "event: added"}))
+			c := eventData(lines)
+			Expect(c.Seq).To(Equal(uint64(2)))
+			Expect(c.Type).To(Equal("added"))
+			Expect(c.Uri).To(Equal("bar.example.com"))
+			Expect(c.AppId).To(Equal("app-2"))
+			Expect(c.Endpoint.Address).To(Equal("10.0.0.2:8080"))
+
+			routeRegistry.Unregister("foo.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-1", Host: "10.0.0.1", Port: 8080}))
+			lines = readEvent(events)
+			Expect(lines[:2]).To(Equal([]string{"id: 3", "event: unregistered"}))
+			Expect(eventData(lines).Uri).To(Equal("foo.example.com"))
+		})
+
+		It("resumes from the since query parameter", func() {
+			_, events := openStream("/routes/changes?since=1", "")
+			Expect(readEvent(events)[0]).To(Equal("id: 2"))
+		})
+
+		It("only streams new changes without a sequence number", func() {
+			_, events := openStream("/routes/changes", "")
+
+			routeRegistry.Register("baz.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-1", Host: "10.0.0.3", Port: 8080}))
+			Expect(readEvent(events)[0]).To(Equal("id: 3"))
+		})
+
+		It("filters the changes by host and app", func() {
+			_, events := openStream("/routes/changes?host=FOO.example.com&app_id=app-1", "")
+
+			routeRegistry.Register("bar.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-1", Host: "10.0.0.3", Port: 8080}))
+			routeRegistry.Register("foo.example.com/api", route.NewEndpoint(&route.EndpointOpts{AppId: "app-2", Host: "10.0.0.3", Port: 8080}))
+			routeRegistry.Register("foo.example.com/api", route.NewEndpoint(&route.EndpointOpts{AppId: "app-1", Host: "10.0.0.4", Port: 8080}))
+
+			lines := readEvent(events)
+			Expect(lines[0]).To(Equal("id: 5"))
+			c := eventData(lines)
+			Expect(c.Uri).To(Equal("foo.example.com/api"))
+			Expect(c.AppId).To(Equal("app-1"))
+			Expect(c.Endpoint.Address).To(Equal("10.0.0.4:8080"))
+		})
+
+		It("tells clients that changes were missed", func() {
+			_, events := openStream("/routes/changes", "42")
+			Expect(readEvent(events)).To(Equal([]string{"event: truncated", "data: {}"}))
+			Expect(readEvent(events)[0]).To(Equal("id: 1"))
+		})
+
+		It("rejects an invalid sequence number", func() {
+			status, body := doRequest("GET", "/routes/changes?since=latest", "")
+			Expect(status).To(Equal(http.StatusBadRequest))
+			Expect(body).To(ContainSubstring(`invalid sequence number: "latest"`))
+		})
+
+		Context("when no change feed is set", func() {
+			BeforeEach(func() {
+				routesListener.Changes = nil
+			})
+
+			It("does not serve the change feed", func() {
+				status, _ := doRequest("GET", "/routes/changes", "")
+				Expect(status).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
 })
@@ -0,0 +1,64 @@
+package routesource
+
+import (
+	"github.com/tedsuo/ifrit"
+
+	"code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+// RouteSource is a source of routes, such as NATS or the routing API, which
+// translates its own messages into events and applies them to the route
+// registry through a Handler. It runs as a member of the router's process
+// group and closes the ready channel once it delivers routes.
+type RouteSource interface {
+	ifrit.Runner
+
+	// Name identifies the source in the endpoints it registers, in logs and
+	// in metrics.
+	Name() string
+}
+
+type EventType string
+
+const (
+	RegisterEvent   EventType = "register"
+	UnregisterEvent EventType = "unregister"
+)
+
+// Event registers or unregisters the endpoint for the uri.
+type Event struct {
+	Type     EventType
+	Uri      route.Uri
+	Endpoint *route.Endpoint
+}
+
+// Handler applies the events of a route source to the route registry, and
+// records the source as the owner of their endpoints. The registry reports
+// the events of each source as route_source_events.
+type Handler struct {
+	source   string
+	registry registry.Registry
+}
+
+func NewHandler(source string, registry registry.Registry) *Handler {
+	return &Handler{
+		source:   source,
+		registry: registry,
+	}
+}
+
+// Source returns the name of the route source of the handler.
+func (h *Handler) Source() string {
+	return h.source
+}
+
+func (h *Handler) Handle(e Event) {
+	e.Endpoint.Source = h.source
+	switch e.Type {
+	case RegisterEvent:
+		h.registry.Register(e.Uri, e.Endpoint)
+	case UnregisterEvent:
+		h.registry.Unregister(e.Uri, e.Endpoint)
+	}
+}
@@ -0,0 +1,13 @@
+package routesource_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestRouteSource(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RouteSource Suite")
+}
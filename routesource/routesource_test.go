@@ -0,0 +1,49 @@
+package routesource_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/gorouter/registry/fakes"
+	"code.cloudfoundry.org/gorouter/route"
+	. "code.cloudfoundry.org/gorouter/routesource"
+)
+
+var _ = Describe("Handler", func() {
+	var (
+		registry *fakes.FakeRegistry
+		handler  *Handler
+		endpoint *route.Endpoint
+	)
+
+	BeforeEach(func() {
+		registry = &fakes.FakeRegistry{}
+		handler = NewHandler("nats", registry)
+		endpoint = route.NewEndpoint(&route.EndpointOpts{Host: "10.0.0.1", Port: 8080})
+	})
+
+	It("has the name of its route source", func() {
+		Expect(handler.Source()).To(Equal("nats"))
+	})
+
+	It("registers endpoints as endpoints of its route source", func() {
+		handler.Handle(Event{Type: RegisterEvent, Uri: "foo.example.com", Endpoint: endpoint})
+
+		Expect(registry.RegisterCallCount()).To(Equal(1))
+		uri, registered := registry.RegisterArgsForCall(0)
+		Expect(uri).To(Equal(route.Uri("foo.example.com")))
+		Expect(registered.CanonicalAddr()).To(Equal("10.0.0.1:8080"))
+		Expect(registered.Source).To(Equal("nats"))
+		Expect(registry.UnregisterCallCount()).To(BeZero())
+	})
+
+	It("unregisters endpoints as endpoints of its route source", func() {
+		handler.Handle(Event{Type: UnregisterEvent, Uri: "foo.example.com", Endpoint: endpoint})
+
+		Expect(registry.UnregisterCallCount()).To(Equal(1))
+		uri, unregistered := registry.UnregisterArgsForCall(0)
+		Expect(uri).To(Equal(route.Uri("foo.example.com")))
+		Expect(unregistered.Source).To(Equal("nats"))
+		Expect(registry.RegisterCallCount()).To(BeZero())
+	})
+})
@@ -0,0 +1,178 @@
+package static_routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"gopkg.in/yaml.v3"
+
+	"code.cloudfoundry.org/gorouter/config"
+	log "code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/mbus"
+	"code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/routesource"
+)
+
+// StaticRoutes registers the routes of a route file, and registers and
+// unregisters routes whenever the file changes. The route file is a YAML or
+// JSON list of entries with the same fields as the messages on
+// router.register. Static routes are never pruned as stale.
+type StaticRoutes struct {
+	Path           string
+	ReloadInterval time.Duration
+
+	logger       *slog.Logger
+	routes       *routesource.Handler
+	http2Enabled bool
+	clock        clock.Clock
+
+	data      []byte
+	endpoints map[routeKey]*route.Endpoint
+}
+
+type routeKey struct {
+	uri  route.Uri
+	addr string
+}
+
+func NewStaticRoutes(
+	logger *slog.Logger,
+	routeRegistry registry.Registry,
+	cfg *config.Config,
+	clock clock.Clock,
+) *StaticRoutes {
+	return &StaticRoutes{
+		Path:           cfg.StaticRoutes.Path,
+		ReloadInterval: cfg.StaticRoutes.ReloadInterval,
+
+		logger:       logger,
+		routes:       routesource.NewHandler("static", routeRegistry),
+		http2Enabled: cfg.EnableHTTP2,
+		clock:        clock,
+		endpoints:    map[routeKey]*route.Endpoint{},
+	}
+}
+
+var _ routesource.RouteSource = &StaticRoutes{}
+
+// Name identifies the route file as the route source of the static routes.
+func (s *StaticRoutes) Name() string {
+	return s.routes.Source()
+}
+
+// Run registers the routes of the route file and reloads it every reload
+// interval. It fails if the route file cannot be loaded on startup. Later,
+// an invalid route file is logged and the previous routes are kept.
+func (s *StaticRoutes) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	if err := s.Reload(); err != nil {
+		return err
+	}
+
+	ticker := s.clock.NewTicker(s.ReloadInterval)
+	s.logger.Info("static-routes-started", slog.String("path", s.Path))
+
+	close(ready)
+	for {
+		select {
+		case <-ticker.C():
+			if err := s.Reload(); err != nil {
+				s.logger.Error("failed-to-reload-static-routes", log.ErrAttr(err))
+			}
+		case <-signals:
+			s.logger.Info("stopping")
+			ticker.Stop()
+			return nil
+		}
+	}
+}
+
+// Reload reads the route file and, if it has changed, registers its routes
+// and unregisters the routes which have been removed from it.
+func (s *StaticRoutes) Reload() error {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return err
+	}
+	if s.data != nil && bytes.Equal(data, s.data) {
+		return nil
+	}
+
+	routes, err := s.parse(data)
+	if err != nil {
+		return fmt.Errorf("invalid static routes file %s: %w", s.Path, err)
+	}
+
+	for key, endpoint := range routes {
+		s.routes.Handle(routesource.Event{Type: routesource.RegisterEvent, Uri: key.uri, Endpoint: endpoint})
+	}
+	removed := 0
+	for key, endpoint := range s.endpoints {
+		if _, ok := routes[key]; !ok {
+			s.routes.Handle(routesource.Event{Type: routesource.UnregisterEvent, Uri: key.uri, Endpoint: endpoint})
+			removed++
+		}
+	}
+
+	s.data = data
+	s.endpoints = routes
+	s.logger.Info("static-routes-loaded",
+		slog.String("path", s.Path),
+		slog.Int("routes", len(routes)),
+		slog.Int("removed-routes", removed))
+	return nil
+}
+
+func (s *StaticRoutes) parse(data []byte) (map[routeKey]*route.Endpoint, error) {
+	// the registry messages only have JSON tags, so YAML is converted to JSON
+	// before it is decoded
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var msgs []mbus.RegistryMessage
+	if err := json.Unmarshal(data, &msgs); err != nil {
+		return nil, err
+	}
+
+	routes := map[routeKey]*route.Endpoint{}
+	for i, msg := range msgs {
+		if len(msg.Uris) == 0 {
+			return nil, fmt.Errorf("route %d has no uris", i)
+		}
+		if !msg.ValidateMessage() {
+			return nil, fmt.Errorf("route %d: route_service_url must be https", i)
+		}
+		// unlike registrations, a route file with invalid options is rejected
+		var invalidOptions []error
+		endpoint, err := msg.MakeEndpoint(s.http2Enabled, func(err error) {
+			invalidOptions = append(invalidOptions, err)
+		})
+		if err == nil {
+			err = errors.Join(invalidOptions...)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %w", i, err)
+		}
+		endpoint.Static = true
+
+		for _, uri := range msg.Uris {
+			key := routeKey{uri: uri, addr: endpoint.CanonicalAddr()}
+			if _, ok := routes[key]; ok {
+				return nil, fmt.Errorf("duplicate endpoint %s for uri %s", key.addr, uri)
+			}
+			routes[key] = endpoint
+		}
+	}
+	return routes, nil
+}
@@ -0,0 +1,13 @@
+package static_routes_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestStaticRoutes(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "StaticRoutes Suite")
+}
@@ -0,0 +1,191 @@
+package static_routes_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+
+	"code.cloudfoundry.org/gorouter/config"
+	testRegistry "code.cloudfoundry.org/gorouter/registry/fakes"
+	"code.cloudfoundry.org/gorouter/route"
+	. "code.cloudfoundry.org/gorouter/static_routes"
+	"code.cloudfoundry.org/gorouter/test_util"
+)
+
+var _ = Describe("StaticRoutes", func() {
+	var (
+		cfg          *config.Config
+		registry     *testRegistry.FakeRegistry
+		logger       *test_util.TestLogger
+		clock        *fakeclock.FakeClock
+		path         string
+		staticRoutes *StaticRoutes
+	)
+
+	writeRoutes := func(routes string) {
+		Expect(os.WriteFile(path, []byte(routes), 0o644)).To(Succeed())
+	}
+
+	registered := func() map[route.Uri]*route.Endpoint {
+		endpoints := map[route.Uri]*route.Endpoint{}
+		for i := 0; i < registry.RegisterCallCount(); i++ {
+			uri, endpoint := registry.RegisterArgsForCall(i)
+			endpoints[uri] = endpoint
+		}
+		return endpoints
+	}
+
+	BeforeEach(func() {
+		logger = test_util.NewTestLogger("test")
+		var err error
+		cfg, err = config.DefaultConfig()
+		Expect(err).ToNot(HaveOccurred())
+		path = filepath.Join(GinkgoT().TempDir(), "routes.yml")
+		cfg.StaticRoutes = config.StaticRoutesConfig{Path: path, ReloadInterval: time.Second}
+
+		registry = &testRegistry.FakeRegistry{}
+		clock = fakeclock.NewFakeClock(time.Now())
+		staticRoutes = NewStaticRoutes(logger.Logger, registry, cfg, clock)
+	})
+
+	Describe("Reload", func() {
+		It("registers the routes of a YAML route file as static routes", func() {
+			writeRoutes(`
+- uris: [foo.example.com, bar.example.com/baz]
+  host: 10.0.0.1
+  port: 8080
+  app: app-1
+  private_instance_id: instance-1
+  tags:
+    component: dora
+  options:
+    loadbalancing: least-connection
+`)
+			Expect(staticRoutes.Reload()).To(Succeed())
+
+			endpoints := registered()
+			Expect(endpoints).To(HaveLen(2))
+			Expect(endpoints).To(HaveKey(route.Uri("foo.example.com")))
+			Expect(endpoints).To(HaveKey(route.Uri("bar.example.com/baz")))
+
+			endpoint := endpoints["foo.example.com"]
+			Expect(endpoint.CanonicalAddr()).To(Equal("10.0.0.1:8080"))
+			Expect(endpoint.ApplicationId).To(Equal("app-1"))
+			Expect(endpoint.PrivateInstanceId).To(Equal("instance-1"))
+			Expect(endpoint.Tags).To(Equal(map[string]string{"component": "dora"}))
+			Expect(endpoint.LoadBalancingAlgorithm).To(Equal("least-connection"))
+			Expect(endpoint.Static).To(BeTrue())
+			Expect(endpoint.Source).To(Equal("static"))
+		})
+
+		It("registers the routes of a JSON route file", func() {
+			writeRoutes(`[{"uris": ["foo.example.com"], "host": "10.0.0.1", "tls_port": 8443, "server_cert_domain_san": "foo"}]`)
+			Expect(staticRoutes.Reload()).To(Succeed())
+
+			endpoint := registered()["foo.example.com"]
+			Expect(endpoint).NotTo(BeNil())
+			Expect(endpoint.CanonicalAddr()).To(Equal("10.0.0.1:8443"))
+			Expect(endpoint.IsTLS()).To(BeTrue())
+		})
+
+		It("unregisters routes which have been removed from the route file", func() {
+			writeRoutes(`
+- uris: [foo.example.com]
+  host: 10.0.0.1
+  port: 8080
+- uris: [bar.example.com]
+  host: 10.0.0.2
+  port: 8080
+`)
+			Expect(staticRoutes.Reload()).To(Succeed())
+			Expect(registry.RegisterCallCount()).To(Equal(2))
+
+			writeRoutes(`
+- uris: [foo.example.com]
+  host: 10.0.0.1
+  port: 8080
+`)
+			Expect(staticRoutes.Reload()).To(Succeed())
+			Expect(registry.RegisterCallCount()).To(Equal(3))
+			Expect(registry.UnregisterCallCount()).To(Equal(1))
+			uri, endpoint := registry.UnregisterArgsForCall(0)
+			Expect(uri).To(Equal(route.Uri("bar.example.com")))
+			Expect(endpoint.CanonicalAddr()).To(Equal("10.0.0.2:8080"))
+		})
+
+		It("does not register the routes again if the route file is unchanged", func() {
+			writeRoutes(`[{"uris": ["foo.example.com"], "host": "10.0.0.1", "port": 8080}]`)
+			Expect(staticRoutes.Reload()).To(Succeed())
+			Expect(staticRoutes.Reload()).To(Succeed())
+			Expect(registry.RegisterCallCount()).To(Equal(1))
+		})
+
+		It("keeps the previous routes if the route file is invalid", func() {
+			writeRoutes(`[{"uris": ["foo.example.com"], "host": "10.0.0.1", "port": 8080}]`)
+			Expect(staticRoutes.Reload()).To(Succeed())
+
+			writeRoutes(`[{"uris": ["foo.example.com"], "host": "10.0.0.1", "port": 8080, "options": {"dial_timeout": "soon"}}]`)
+			Expect(staticRoutes.Reload()).To(MatchError(ContainSubstring(`invalid dial_timeout option: "soon"`)))
+			Expect(registry.RegisterCallCount()).To(Equal(1))
+			Expect(registry.UnregisterCallCount()).To(BeZero())
+		})
+
+		It("rejects routes without uris", func() {
+			writeRoutes(`[{"host": "10.0.0.1", "port": 8080}]`)
+			Expect(staticRoutes.Reload()).To(MatchError(ContainSubstring("route 0 has no uris")))
+		})
+
+		It("rejects route services without https", func() {
+			writeRoutes(`[{"uris": ["foo.example.com"], "host": "10.0.0.1", "port": 8080, "route_service_url": "http://rs.example.com"}]`)
+			Expect(staticRoutes.Reload()).To(MatchError(ContainSubstring("route_service_url must be https")))
+		})
+
+		It("rejects the same endpoint twice for a uri", func() {
+			writeRoutes(`
+- uris: [foo.example.com]
+  host: 10.0.0.1
+  port: 8080
+- uris: [foo.example.com]
+  host: 10.0.0.1
+  port: 8080
+`)
+			Expect(staticRoutes.Reload()).To(MatchError(ContainSubstring("duplicate endpoint 10.0.0.1:8080 for uri foo.example.com")))
+		})
+	})
+
+	Describe("Run", func() {
+		var process ifrit.Process
+
+		BeforeEach(func() {
+			process = nil
+		})
+
+		AfterEach(func() {
+			if process != nil {
+				process.Signal(os.Interrupt)
+				Eventually(process.Wait()).Should(Receive())
+			}
+		})
+
+		It("fails if the route file cannot be loaded", func() {
+			err := <-ifrit.Invoke(staticRoutes).Wait()
+			Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
+		})
+
+		It("reloads the route file every reload interval", func() {
+			writeRoutes(`[{"uris": ["foo.example.com"], "host": "10.0.0.1", "port": 8080}]`)
+			process = ifrit.Invoke(staticRoutes)
+			Expect(registry.RegisterCallCount()).To(Equal(1))
+
+			writeRoutes(`[{"uris": ["bar.example.com"], "host": "10.0.0.1", "port": 8080}]`)
+			clock.WaitForWatcherAndIncrement(time.Second)
+			Eventually(registry.UnregisterCallCount).Should(Equal(1))
+			Expect(registered()).To(HaveKey(route.Uri("bar.example.com")))
+		})
+	})
+})